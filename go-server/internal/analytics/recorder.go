@@ -0,0 +1,104 @@
+// Package analytics records link clicks off the request path.
+package analytics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultWorkers   = 2
+	defaultQueueSize = 1024
+	writeTimeout     = 5 * time.Second
+)
+
+// Store persists a single click
+type Store interface {
+	Record(ctx context.Context, click *model.Click) error
+}
+
+// Recorder queues clicks and writes them in the background so resolving a
+// link never waits on the analytics database
+type Recorder struct {
+	store   Store
+	clicks  chan model.Click
+	workers int
+	wg      sync.WaitGroup
+	logger  *zap.Logger
+}
+
+func NewRecorder(store Store, workers, queueSize int) *Recorder {
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+
+	return &Recorder{
+		store:   store,
+		clicks:  make(chan model.Click, queueSize),
+		workers: workers,
+		logger:  zap.L().With(zap.String("component", "ClickRecorder")),
+	}
+}
+
+// Start launches the writer goroutines. Once ctx is cancelled they write the
+// clicks still queued and exit.
+func (r *Recorder) Start(ctx context.Context) {
+	for i := 0; i < r.workers; i++ {
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					r.drain(context.WithoutCancel(ctx))
+					return
+				case click := <-r.clicks:
+					r.write(ctx, click)
+				}
+			}
+		}()
+	}
+}
+
+// Wait blocks until all writer goroutines have exited
+func (r *Recorder) Wait() {
+	r.wg.Wait()
+}
+
+// RecordClick queues a click without blocking the caller. Clicks are dropped
+// when the queue is full.
+func (r *Recorder) RecordClick(click model.Click) {
+	select {
+	case r.clicks <- click:
+	default:
+		r.logger.Warn("Click queue full, dropping click", zap.String("id", click.URLID))
+	}
+}
+
+// drain writes the queued clicks, so a shutdown does not lose them
+func (r *Recorder) drain(ctx context.Context) {
+	for {
+		select {
+		case click := <-r.clicks:
+			r.write(ctx, click)
+		default:
+			return
+		}
+	}
+}
+
+func (r *Recorder) write(ctx context.Context, click model.Click) {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	if err := r.store.Record(ctx, &click); err != nil {
+		r.logger.Warn("Failed to record click", zap.Error(err), zap.String("id", click.URLID))
+	}
+}
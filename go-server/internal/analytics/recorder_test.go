@@ -0,0 +1,75 @@
+package analytics
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryStore struct {
+	mu     sync.Mutex
+	clicks []model.Click
+	done   chan struct{}
+}
+
+func (m *memoryStore) Record(ctx context.Context, click *model.Click) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clicks = append(m.clicks, *click)
+	if m.done != nil {
+		close(m.done)
+		m.done = nil
+	}
+	return nil
+}
+
+func TestRecorder_WritesClicks(t *testing.T) {
+	store := &memoryStore{done: make(chan struct{})}
+	done := store.done
+	recorder := NewRecorder(store, 1, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	recorder.Start(ctx)
+
+	variant := 1
+	recorder.RecordClick(model.Click{URLID: "abc123", Variant: &variant})
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("click was not recorded")
+	}
+	cancel()
+	recorder.Wait()
+
+	assert.Len(t, store.clicks, 1)
+	assert.Equal(t, 1, *store.clicks[0].Variant)
+}
+
+func TestRecorder_DropsWhenQueueFull(t *testing.T) {
+	recorder := NewRecorder(&memoryStore{}, 1, 1)
+
+	// Not started, so the second click cannot be queued
+	recorder.RecordClick(model.Click{URLID: "a"})
+	recorder.RecordClick(model.Click{URLID: "b"})
+
+	assert.Len(t, recorder.clicks, 1)
+}
+
+func TestRecorder_WritesQueuedClicksOnShutdown(t *testing.T) {
+	store := &memoryStore{}
+	recorder := NewRecorder(store, 2, 8)
+	for _, id := range []string{"a", "b", "c"} {
+		recorder.RecordClick(model.Click{URLID: id})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	recorder.Start(ctx)
+	recorder.Wait()
+
+	assert.Len(t, store.clicks, 3)
+}
@@ -0,0 +1,56 @@
+// Package geoip resolves client IP addresses to countries from a local
+// MaxMind-format database. No lookups leave the process.
+package geoip
+
+import (
+	"net"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+	"go.uber.org/zap"
+)
+
+// record holds the only field read from GeoIP2/GeoLite2 Country and City databases
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+}
+
+// Resolver looks up countries in an MMDB file
+type Resolver struct {
+	reader *maxminddb.Reader
+	logger *zap.Logger
+}
+
+// Open loads the MMDB file at path, e.g. a GeoLite2-Country.mmdb download
+func Open(path string) (*Resolver, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Resolver{
+		reader: reader,
+		logger: zap.L().With(zap.String("component", "GeoIPResolver")),
+	}, nil
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of ip, or "" when unknown
+func (r *Resolver) Country(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+
+	var rec record
+	if err := r.reader.Lookup(ip, &rec); err != nil {
+		r.logger.Debug("GeoIP lookup failed", zap.Error(err), zap.String("ip", ip.String()))
+		return ""
+	}
+	return strings.ToUpper(rec.Country.ISOCode)
+}
+
+// Close releases the database
+func (r *Resolver) Close() error {
+	return r.reader.Close()
+}
@@ -0,0 +1,55 @@
+package geoip
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testdata/country-test.mmdb maps:
+//
+//	81.2.69.0/24   GB
+//	200.160.0.0/16 BR
+//	203.0.113.0/24 JP
+//	2804:14c::/32  BR
+const fixturePath = "testdata/country-test.mmdb"
+
+func TestResolver_Country(t *testing.T) {
+	resolver, err := Open(fixturePath)
+	require.NoError(t, err)
+	defer func() { _ = resolver.Close() }()
+
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"81.2.69.160", "GB"},
+		{"200.160.2.3", "BR"},
+		{"203.0.113.7", "JP"},
+		{"2804:14c:65::1", "BR"},
+		{"::ffff:200.160.2.3", "BR"},
+		{"8.8.8.8", ""},
+		{"2001:db8::1", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.want, resolver.Country(net.ParseIP(tt.ip)))
+		})
+	}
+}
+
+func TestResolver_NilIP(t *testing.T) {
+	resolver, err := Open(fixturePath)
+	require.NoError(t, err)
+	defer func() { _ = resolver.Close() }()
+
+	assert.Empty(t, resolver.Country(nil))
+}
+
+func TestOpen_MissingFile(t *testing.T) {
+	_, err := Open("testdata/missing.mmdb")
+	assert.Error(t, err)
+}
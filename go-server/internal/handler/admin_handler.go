@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/middleware"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// DisableUserRequest optionally takes down every link of the account too
+type DisableUserRequest struct {
+	DisableLinks bool `json:"disable_links"`
+}
+
+// AdminHandler serves /api/admin, behind middleware.RequireRole
+type AdminHandler struct {
+	svc    service.AdminService
+	logger *zap.Logger
+}
+
+func NewAdminHandler(svc service.AdminService) *AdminHandler {
+	return &AdminHandler{
+		svc:    svc,
+		logger: zap.L().With(zap.String("component", "AdminHandler")),
+	}
+}
+
+// SearchLinks finds links of any account by id, code, destination or title
+func (h *AdminHandler) SearchLinks(c *gin.Context) {
+	filter := repository.AdminLinkFilter{Query: c.Query("q")}
+	if raw := c.Query("user_id"); raw != "" {
+		userID, err := uuid.Parse(raw)
+		if err != nil {
+			respondInvalidQuery(c, "user_id")
+			return
+		}
+		filter.UserID = &userID
+	}
+	var ok bool
+	if filter.Disabled, ok = parseBoolQuery(c, "disabled"); !ok {
+		return
+	}
+	if filter.Limit, filter.Offset, ok = parsePage(c); !ok {
+		return
+	}
+
+	links, err := h.svc.SearchLinks(c.Request.Context(), filter)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"links": links})
+}
+
+// SearchUsers finds accounts by email or username
+func (h *AdminHandler) SearchUsers(c *gin.Context) {
+	filter := repository.AdminUserFilter{Query: c.Query("q")}
+	var ok bool
+	if filter.Disabled, ok = parseBoolQuery(c, "disabled"); !ok {
+		return
+	}
+	if filter.Limit, filter.Offset, ok = parsePage(c); !ok {
+		return
+	}
+
+	users, err := h.svc.SearchUsers(c.Request.Context(), filter)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": users})
+}
+
+func (h *AdminHandler) DisableLink(c *gin.Context) {
+	h.setLinkDisabled(c, true)
+}
+
+func (h *AdminHandler) EnableLink(c *gin.Context) {
+	h.setLinkDisabled(c, false)
+}
+
+func (h *AdminHandler) setLinkDisabled(c *gin.Context, disabled bool) {
+	adminID := middleware.GetUserIDFromContext(c)
+	if adminID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	id := c.Param("id")
+	if err := h.svc.SetLinkDisabled(c.Request.Context(), *adminID, id, disabled); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": id, "disabled": disabled})
+}
+
+func (h *AdminHandler) DisableUser(c *gin.Context) {
+	var req DisableUserRequest
+	// The body is optional
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.logger.Warn("Invalid request body", zap.Error(err))
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error: "Invalid request format",
+				Code:  "INVALID_JSON",
+			})
+			return
+		}
+	}
+	h.setUserDisabled(c, true, req.DisableLinks)
+}
+
+func (h *AdminHandler) EnableUser(c *gin.Context) {
+	h.setUserDisabled(c, false, false)
+}
+
+func (h *AdminHandler) setUserDisabled(c *gin.Context, disabled, withLinks bool) {
+	adminID := middleware.GetUserIDFromContext(c)
+	if adminID == nil {
+		respondMissingUser(c)
+		return
+	}
+	userID, ok := parseUUIDParam(c, "userId")
+	if !ok {
+		return
+	}
+
+	user, links, err := h.svc.SetUserDisabled(c.Request.Context(), *adminID, userID, disabled, withLinks)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user, "disabled_links": links})
+}
+
+// EvictLinkCache drops a link from Redis so the next visit reads the database
+func (h *AdminHandler) EvictLinkCache(c *gin.Context) {
+	if err := h.svc.EvictLinkCache(c.Request.Context(), c.Param("id")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AdminHandler) GetStats(c *gin.Context) {
+	stats, err := h.svc.Stats(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
+
+// GetAudit lists the audit log of every account, newest first
+func (h *AdminHandler) GetAudit(c *gin.Context) {
+	filter, ok := parseAuditFilter(c)
+	if !ok {
+		return
+	}
+
+	entries, err := h.svc.Audit(c.Request.Context(), filter)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": entries})
+}
+
+func (h *AdminHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidToken):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid short URL format",
+			Code:  "INVALID_SHORT_URL",
+		})
+	case errors.Is(err, service.ErrSelfDisable):
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Error: "Admins cannot disable their own account",
+			Code:  "SELF_DISABLE",
+		})
+	case errors.Is(err, repository.ErrURLNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Short URL not found",
+			Code:  "URL_NOT_FOUND",
+		})
+	case errors.Is(err, repository.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "User not found",
+			Code:  "USER_NOT_FOUND",
+		})
+	case errors.Is(err, repository.ErrCacheError):
+		h.logger.Error("Cache error", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Error: "Cache unavailable",
+			Code:  "CACHE_ERROR",
+		})
+	case errors.Is(err, repository.ErrDatabaseError):
+		h.logger.Error("Database error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Database error",
+			Code:  "DB_ERROR",
+		})
+	default:
+		h.logger.Error("Unexpected error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Internal server error",
+			Code:  "INTERNAL_ERROR",
+		})
+	}
+}
+
+func parseBoolQuery(c *gin.Context, name string) (*bool, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		respondInvalidQuery(c, name)
+		return nil, false
+	}
+	return &v, true
+}
+
+func parsePage(c *gin.Context) (int, int, bool) {
+	var limit, offset int
+	for name, dst := range map[string]*int{"limit": &limit, "offset": &offset} {
+		raw := c.Query(name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			respondInvalidQuery(c, name)
+			return 0, 0, false
+		}
+		*dst = v
+	}
+	return limit, offset, true
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/middleware"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// GetLinkHistory lists the changes made to a link, newest first. Pass the
+// last id seen as ?before= to get the next page.
+func (h *URLHandler) GetLinkHistory(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	filter, ok := parseAuditFilter(c)
+	if !ok {
+		return
+	}
+
+	entries, err := h.service.GetLinkHistory(c.Request.Context(), *userID, strings.TrimSpace(c.Param("id")), filter)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": entries})
+}
+
+// GetWorkspaceAudit lists the changes made to all links of a workspace
+func (h *URLHandler) GetWorkspaceAudit(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	workspaceID, ok := parseUUIDParam(c, "workspaceId")
+	if !ok {
+		return
+	}
+	filter, ok := parseAuditFilter(c)
+	if !ok {
+		return
+	}
+
+	entries, err := h.service.GetWorkspaceAudit(c.Request.Context(), *userID, workspaceID, filter)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": entries})
+}
+
+func parseAuditFilter(c *gin.Context) (repository.AuditFilter, bool) {
+	var filter repository.AuditFilter
+	if raw := c.Query("before"); raw != "" {
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || v < 1 {
+			respondInvalidQuery(c, "before")
+			return filter, false
+		}
+		filter.BeforeID = v
+	}
+	if raw := c.Query("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 {
+			respondInvalidQuery(c, "limit")
+			return filter, false
+		}
+		filter.Limit = v
+	}
+	return filter, true
+}
+
+func respondInvalidQuery(c *gin.Context, name string) {
+	c.JSON(http.StatusBadRequest, ErrorResponse{
+		Error: "Invalid " + name + " parameter",
+		Code:  "INVALID_QUERY",
+	})
+}
@@ -2,9 +2,12 @@ package handler
 
 import (
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/fonsecaaso/TinyUrl/go-server/internal/service"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/token"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -33,6 +36,11 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req RegisterRequest
 
@@ -45,7 +53,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	user, err := h.svc.Register(c, req.Username, req.Email, req.Password)
+	user, err := h.svc.Register(c.Request.Context(), req.Username, req.Email, req.Password)
 	if err != nil {
 		h.handleError(c, err)
 		return
@@ -69,24 +77,80 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.svc.Login(c, req.Email, req.Password)
+	result, err := h.svc.Login(c.Request.Context(), req.Email, req.Password)
 	if err != nil {
 		h.handleError(c, err)
 		return
 	}
 
+	// Completed with a second factor at POST /api/login/mfa
+	if result.MFAToken != "" {
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required": true,
+			"mfa_token":    result.MFAToken,
+			"expires_in":   int(token.MFATokenTTL.Seconds()),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"token": token,
+		"token": result.Token,
+	})
+}
+
+// CompleteMFALogin signs in with the challenge from Login and a code from
+// the authenticator app or a recovery code
+func (h *AuthHandler) CompleteMFALogin(c *gin.Context) {
+	var req MFALoginRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid JSON in CompleteMFALogin", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request payload",
+			Code:  "INVALID_PAYLOAD",
+		})
+		return
+	}
+
+	sessionToken, err := h.svc.CompleteMFALogin(c.Request.Context(), req.MFAToken, req.Code)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token": sessionToken,
 	})
 }
 
 func (h *AuthHandler) handleError(c *gin.Context, err error) {
+	var locked *service.LoginLockedError
+	if errors.As(err, &locked) {
+		respondLoginLocked(c, locked)
+		return
+	}
+
 	switch {
 	case errors.Is(err, service.ErrInvalidCredentials):
 		c.JSON(http.StatusUnauthorized, ErrorResponse{
 			Error: "Invalid email or password",
 			Code:  "INVALID_CREDENTIALS",
 		})
+	case errors.Is(err, service.ErrInvalidMFAToken):
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Two-factor challenge is invalid or has expired, sign in again",
+			Code:  "INVALID_MFA_TOKEN",
+		})
+	case errors.Is(err, service.ErrInvalidMFACode):
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Invalid two-factor code",
+			Code:  "INVALID_MFA_CODE",
+		})
+	case errors.Is(err, service.ErrAccountDisabled):
+		c.JSON(http.StatusForbidden, ErrorResponse{
+			Error: "Account is disabled",
+			Code:  "ACCOUNT_DISABLED",
+		})
 	case errors.Is(err, service.ErrEmailAlreadyExists):
 		c.JSON(http.StatusConflict, ErrorResponse{
 			Error: "Email already registered",
@@ -100,3 +164,21 @@ func (h *AuthHandler) handleError(c *gin.Context, err error) {
 		})
 	}
 }
+
+// respondLoginLocked tells the client when it may sign in again
+func respondLoginLocked(c *gin.Context, locked *service.LoginLockedError) {
+	retryAfter := int(math.Ceil(locked.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+
+	if errors.Is(locked, service.ErrTooManyLoginAttempts) {
+		c.JSON(http.StatusTooManyRequests, ErrorResponse{
+			Error: "Too many failed login attempts, try again later",
+			Code:  "TOO_MANY_LOGIN_ATTEMPTS",
+		})
+		return
+	}
+	c.JSON(http.StatusTooManyRequests, ErrorResponse{
+		Error: "Account is temporarily locked after failed login attempts, try again later",
+		Code:  "ACCOUNT_LOCKED",
+	})
+}
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/middleware"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type AddDomainRequest struct {
+	Hostname string `json:"hostname" binding:"required"`
+}
+
+// DNSRecord is the record an owner publishes to prove control of a domain
+type DNSRecord struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type DomainResponse struct {
+	model.Domain
+	Verified     bool       `json:"verified"`
+	Verification *DNSRecord `json:"verification,omitempty"`
+}
+
+type DomainHandler struct {
+	svc    service.DomainService
+	logger *zap.Logger
+}
+
+func NewDomainHandler(svc service.DomainService) *DomainHandler {
+	return &DomainHandler{
+		svc:    svc,
+		logger: zap.L().With(zap.String("component", "DomainHandler")),
+	}
+}
+
+func (h *DomainHandler) ListDomains(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	domains, err := h.svc.ListDomains(c.Request.Context(), *userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	resp := make([]DomainResponse, 0, len(domains))
+	for i := range domains {
+		resp = append(resp, newDomainResponse(&domains[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"domains": resp})
+}
+
+func (h *DomainHandler) AddDomain(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	var req AddDomainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format",
+			Code:  "INVALID_JSON",
+		})
+		return
+	}
+
+	domain, err := h.svc.AddDomain(c.Request.Context(), *userID, req.Hostname)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"domain": newDomainResponse(domain)})
+}
+
+func (h *DomainHandler) VerifyDomain(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	domainID, ok := parseUUIDParam(c, "domainId")
+	if !ok {
+		return
+	}
+
+	domain, err := h.svc.VerifyDomain(c.Request.Context(), *userID, domainID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"domain": newDomainResponse(domain)})
+}
+
+func (h *DomainHandler) DeleteDomain(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	domainID, ok := parseUUIDParam(c, "domainId")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.DeleteDomain(c.Request.Context(), *userID, domainID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// newDomainResponse adds the TXT record to publish while the domain is unverified
+func newDomainResponse(domain *model.Domain) DomainResponse {
+	resp := DomainResponse{Domain: *domain, Verified: domain.Verified()}
+	if !domain.Verified() {
+		name, value := domain.VerificationRecord()
+		resp.Verification = &DNSRecord{Type: "TXT", Name: name, Value: value}
+	}
+	return resp
+}
+
+func (h *DomainHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidDomain):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid domain, expected a hostname such as go.example.com",
+			Code:  "INVALID_DOMAIN",
+		})
+	case errors.Is(err, service.ErrDomainNotVerified):
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+			Error:   "Verification TXT record not found",
+			Code:    "DOMAIN_NOT_VERIFIED",
+			Details: err.Error(),
+		})
+	case errors.Is(err, repository.ErrDomainNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Domain not found",
+			Code:  "DOMAIN_NOT_FOUND",
+		})
+	case errors.Is(err, repository.ErrDomainExists):
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Error: "Domain already added",
+			Code:  "DOMAIN_EXISTS",
+		})
+	case errors.Is(err, repository.ErrDomainTaken):
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Error: "Domain is already verified by another account",
+			Code:  "DOMAIN_TAKEN",
+		})
+	case errors.Is(err, repository.ErrDomainInUse):
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Error: "Domain still has links",
+			Code:  "DOMAIN_IN_USE",
+		})
+	default:
+		h.logger.Error("Unexpected error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Internal server error",
+			Code:  "INTERNAL_ERROR",
+		})
+	}
+}
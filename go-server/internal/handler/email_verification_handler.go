@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/middleware"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type EmailVerificationHandler struct {
+	svc    service.EmailVerificationService
+	logger *zap.Logger
+}
+
+func NewEmailVerificationHandler(svc service.EmailVerificationService) *EmailVerificationHandler {
+	return &EmailVerificationHandler{
+		svc:    svc,
+		logger: zap.L().With(zap.String("component", "EmailVerificationHandler")),
+	}
+}
+
+// VerifyEmail confirms the address with the token from the verification
+// email. No sign-in is needed, the token identifies the account.
+func (h *EmailVerificationHandler) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format",
+			Code:  "INVALID_JSON",
+		})
+		return
+	}
+
+	if err := h.svc.VerifyEmail(c.Request.Context(), req.Token); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// ResendVerification mails the signed-in account a new verification link
+func (h *EmailVerificationHandler) ResendVerification(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	if err := h.svc.ResendVerification(c.Request.Context(), *userID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Verification email sent"})
+}
+
+func (h *EmailVerificationHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrVerificationNotFound):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Verification link is invalid or has expired",
+			Code:  "INVALID_VERIFICATION_TOKEN",
+		})
+	case errors.Is(err, service.ErrEmailAlreadyVerified):
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Error: "Email already verified",
+			Code:  "EMAIL_ALREADY_VERIFIED",
+		})
+	case errors.Is(err, service.ErrMailUnavailable):
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Error: "Email delivery is not available",
+			Code:  "EMAIL_UNAVAILABLE",
+		})
+	case errors.Is(err, repository.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "User not found",
+			Code:  "USER_NOT_FOUND",
+		})
+	case errors.Is(err, repository.ErrDatabaseError):
+		h.logger.Error("Database error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Database error",
+			Code:  "DB_ERROR",
+		})
+	default:
+		h.logger.Error("Unexpected error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Internal server error",
+			Code:  "INTERNAL_ERROR",
+		})
+	}
+}
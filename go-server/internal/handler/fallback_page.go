@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultAccentColor = "#2563eb"
+
+// fallbackPageTemplate is rendered for browsers visiting an unavailable link.
+// html/template escapes every owner-supplied field.
+var fallbackPageTemplate = template.Must(template.New("fallback").Parse(`<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+{{if .RedirectURL}}<meta http-equiv="refresh" content="0; url={{.RedirectURL}}">{{end}}
+<title>{{.Title}}</title>
+<style>
+body{font-family:system-ui,-apple-system,sans-serif;margin:0;min-height:100vh;display:flex;align-items:center;justify-content:center;background:#f8fafc;color:#0f172a}
+main{max-width:32rem;padding:2rem;text-align:center}
+img{max-height:4rem;margin-bottom:1.5rem}
+a.button{display:inline-block;margin-top:1.5rem;padding:.75rem 1.5rem;border-radius:.5rem;color:#fff;text-decoration:none;background:{{.AccentColor}}}
+</style>
+</head>
+<body>
+<main>
+{{if .LogoURL}}<img src="{{.LogoURL}}" alt="">{{end}}
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+{{if .ButtonURL}}<a class="button" href="{{.ButtonURL}}">{{.ButtonText}}</a>{{end}}
+</main>
+</body>
+</html>
+`))
+
+type fallbackPageData struct {
+	Title       string
+	Message     string
+	LogoURL     string
+	ButtonText  string
+	ButtonURL   string
+	AccentColor template.CSS
+	RedirectURL string
+}
+
+// unavailableStatus keeps the states apart for clients and crawlers
+func unavailableStatus(state model.LinkState) (int, string) {
+	switch state {
+	case model.LinkExpired:
+		return http.StatusGone, "LINK_EXPIRED"
+	case model.LinkExhausted:
+		return http.StatusGone, "LINK_CLICK_LIMIT_REACHED"
+	case model.LinkBlocked:
+		return http.StatusUnavailableForLegalReasons, "LINK_BLOCKED"
+	case model.LinkTakenDown:
+		return http.StatusGone, "LINK_TAKEN_DOWN"
+	case model.LinkArchived:
+		return http.StatusGone, "LINK_ARCHIVED"
+	case model.LinkDeleted:
+		return http.StatusGone, "LINK_DELETED"
+	default:
+		return http.StatusNotFound, "LINK_DISABLED"
+	}
+}
+
+func defaultFallbackText(state model.LinkState) (string, string) {
+	switch state {
+	case model.LinkExpired:
+		return "This link has expired", "The link you followed is no longer active."
+	case model.LinkExhausted:
+		return "This link is no longer available", "The link you followed has reached its visit limit."
+	case model.LinkBlocked:
+		return "This link is unavailable", "The link you followed has been blocked for legal reasons."
+	case model.LinkTakenDown:
+		return "This link has been disabled", "The link you followed was reported for abuse and has been disabled."
+	case model.LinkArchived:
+		return "This link is no longer available", "The link you followed has been archived by its owner."
+	case model.LinkDeleted:
+		return "This link is no longer available", "The link you followed has been removed by its owner."
+	default:
+		return "This link is unavailable", "The link you followed has been disabled."
+	}
+}
+
+// respondUnavailable answers browsers with the branded page and API clients
+// with JSON, both carrying the status of the link's state
+func respondUnavailable(c *gin.Context, unavailable *service.UnavailableError) {
+	status, code := unavailableStatus(unavailable.State)
+	fallback := unavailable.Fallback
+
+	if c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) != gin.MIMEHTML {
+		c.JSON(status, ErrorResponse{
+			Error:    unavailable.Error(),
+			Code:     code,
+			Fallback: fallback,
+		})
+		return
+	}
+
+	title, message := defaultFallbackText(unavailable.State)
+	data := fallbackPageData{Title: title, Message: message, AccentColor: defaultAccentColor}
+	if fallback != nil {
+		data.RedirectURL = fallback.URL
+		if page := fallback.Page; page != nil {
+			if page.Title != "" {
+				data.Title = page.Title
+			}
+			if page.Message != "" {
+				data.Message = page.Message
+			}
+			if page.AccentColor != "" {
+				// Validated against a hex color pattern when saved
+				data.AccentColor = template.CSS(page.AccentColor)
+			}
+			data.LogoURL = page.LogoURL
+			data.ButtonURL = page.ButtonURL
+			data.ButtonText = page.ButtonText
+		}
+		if data.ButtonURL == "" && fallback.URL != "" {
+			data.ButtonURL = fallback.URL
+		}
+		if data.ButtonURL != "" && data.ButtonText == "" {
+			data.ButtonText = "Continue"
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := fallbackPageTemplate.Execute(&buf, data); err != nil {
+		c.JSON(status, ErrorResponse{Error: unavailable.Error(), Code: code})
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/middleware"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type MFAHandler struct {
+	svc    service.MFAService
+	logger *zap.Logger
+}
+
+func NewMFAHandler(svc service.MFAService) *MFAHandler {
+	return &MFAHandler{
+		svc:    svc,
+		logger: zap.L().With(zap.String("component", "MFAHandler")),
+	}
+}
+
+// Enroll returns a new authenticator secret and its otpauth:// URI, which
+// the client shows as a QR code. It only takes effect once confirmed.
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	enrollment, err := h.svc.Enroll(c.Request.Context(), *userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// Confirm enables two-factor authentication with a code from the enrolled
+// authenticator and returns the recovery codes
+func (h *MFAHandler) Confirm(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	var req MFACodeRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	codes, err := h.svc.Confirm(c.Request.Context(), *userID, req.Code)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes, given a current code
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	var req MFACodeRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	codes, err := h.svc.RegenerateRecoveryCodes(c.Request.Context(), *userID, req.Code)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// Disable turns two-factor authentication off, given a current code
+func (h *MFAHandler) Disable(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	var req MFACodeRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	if err := h.svc.Disable(c.Request.Context(), *userID, req.Code); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *MFAHandler) bind(c *gin.Context, req *MFACodeRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		h.logger.Warn("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format",
+			Code:  "INVALID_JSON",
+		})
+		return false
+	}
+	return true
+}
+
+func (h *MFAHandler) handleError(c *gin.Context, err error) {
+	var locked *service.LoginLockedError
+	if errors.As(err, &locked) {
+		respondLoginLocked(c, locked)
+		return
+	}
+
+	switch {
+	case errors.Is(err, service.ErrInvalidMFACode):
+		// Not 401, the session itself is fine
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid two-factor code",
+			Code:  "INVALID_MFA_CODE",
+		})
+	case errors.Is(err, repository.ErrMFAAlreadyEnabled):
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Error: "Two-factor authentication is already enabled",
+			Code:  "MFA_ALREADY_ENABLED",
+		})
+	case errors.Is(err, repository.ErrMFANotEnrolled):
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Error: "Two-factor authentication is not set up",
+			Code:  "MFA_NOT_ENROLLED",
+		})
+	case errors.Is(err, repository.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "User not found",
+			Code:  "USER_NOT_FOUND",
+		})
+	case errors.Is(err, repository.ErrDatabaseError):
+		h.logger.Error("Database error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Database error",
+			Code:  "DB_ERROR",
+		})
+	default:
+		h.logger.Error("Unexpected error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Internal server error",
+			Code:  "INTERNAL_ERROR",
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6,max=72"`
+}
+
+type PasswordResetHandler struct {
+	svc    service.PasswordResetService
+	logger *zap.Logger
+}
+
+func NewPasswordResetHandler(svc service.PasswordResetService) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		svc:    svc,
+		logger: zap.L().With(zap.String("component", "PasswordResetHandler")),
+	}
+}
+
+// ForgotPassword mails a reset link to the account registered with the
+// email. The answer is the same whether or not there is one.
+func (h *PasswordResetHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format",
+			Code:  "INVALID_JSON",
+		})
+		return
+	}
+
+	if err := h.svc.RequestReset(c.Request.Context(), req.Email); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If an account uses this email, a password reset link has been sent to it",
+	})
+}
+
+// ResetPassword sets a new password with the token from the reset email and
+// signs the account out everywhere
+func (h *PasswordResetHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format",
+			Code:  "INVALID_JSON",
+		})
+		return
+	}
+
+	if err := h.svc.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+func (h *PasswordResetHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrResetNotFound):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Reset link is invalid or has expired",
+			Code:  "INVALID_RESET_TOKEN",
+		})
+	case errors.Is(err, repository.ErrDatabaseError):
+		h.logger.Error("Database error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Database error",
+			Code:  "DB_ERROR",
+		})
+	default:
+		h.logger.Error("Unexpected error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Internal server error",
+			Code:  "INTERNAL_ERROR",
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/middleware"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type ReportRequest struct {
+	Reason      model.ReportReason `json:"reason" binding:"required"`
+	Description string             `json:"description"`
+}
+
+// ReportHandler takes abuse reports from visitors and serves the
+// moderation queue under /api/admin
+type ReportHandler struct {
+	svc    service.ReportService
+	logger *zap.Logger
+}
+
+func NewReportHandler(svc service.ReportService) *ReportHandler {
+	return &ReportHandler{
+		svc:    svc,
+		logger: zap.L().With(zap.String("component", "ReportHandler")),
+	}
+}
+
+// Report queues an abuse report on a link. Signing in is optional.
+func (h *ReportHandler) Report(c *gin.Context) {
+	var req ReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format",
+			Code:  "INVALID_JSON",
+		})
+		return
+	}
+
+	err := h.svc.Report(c.Request.Context(), service.ReportRequest{
+		ShortCode:   c.Param("id"),
+		Reason:      req.Reason,
+		Description: req.Description,
+		ReporterID:  middleware.GetUserIDFromContext(c),
+		ReporterIP:  c.ClientIP(),
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Report received, thank you"})
+}
+
+// ListReports returns the moderation queue, ?status=open by default
+func (h *ReportHandler) ListReports(c *gin.Context) {
+	limit, offset, ok := parsePage(c)
+	if !ok {
+		return
+	}
+
+	reports, err := h.svc.ListReports(c.Request.Context(), repository.ReportFilter{
+		Status: model.ReportStatus(c.Query("status")),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reports": reports})
+}
+
+func (h *ReportHandler) DismissReport(c *gin.Context) {
+	h.review(c, h.svc.Dismiss)
+}
+
+func (h *ReportHandler) TakeDownReport(c *gin.Context) {
+	h.review(c, h.svc.TakeDown)
+}
+
+func (h *ReportHandler) review(c *gin.Context, decide func(ctx context.Context, adminID, reportID uuid.UUID) (*model.Report, error)) {
+	adminID := middleware.GetUserIDFromContext(c)
+	if adminID == nil {
+		respondMissingUser(c)
+		return
+	}
+	reportID, ok := parseUUIDParam(c, "reportId")
+	if !ok {
+		return
+	}
+
+	report, err := decide(c.Request.Context(), *adminID, reportID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+func (h *ReportHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidToken):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid short URL format",
+			Code:  "INVALID_SHORT_URL",
+		})
+	case errors.Is(err, service.ErrInvalidReport):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Reason must be phishing, malware, spam, illegal or other, with at most 2000 characters of description",
+			Code:  "INVALID_REPORT",
+		})
+	case errors.Is(err, repository.ErrURLNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Short URL not found",
+			Code:  "URL_NOT_FOUND",
+		})
+	case errors.Is(err, repository.ErrReportNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Report not found or already reviewed",
+			Code:  "REPORT_NOT_FOUND",
+		})
+	case errors.Is(err, repository.ErrDatabaseError):
+		h.logger.Error("Database error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Database error",
+			Code:  "DB_ERROR",
+		})
+	default:
+		h.logger.Error("Unexpected error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Internal server error",
+			Code:  "INTERNAL_ERROR",
+		})
+	}
+}
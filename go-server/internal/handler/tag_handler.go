@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/middleware"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type RenameTagRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type MergeTagRequest struct {
+	Into uuid.UUID `json:"into" binding:"required"`
+}
+
+type TagHandler struct {
+	svc    service.TagService
+	logger *zap.Logger
+}
+
+func NewTagHandler(svc service.TagService) *TagHandler {
+	return &TagHandler{
+		svc:    svc,
+		logger: zap.L().With(zap.String("component", "TagHandler")),
+	}
+}
+
+func (h *TagHandler) ListTags(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	tags, err := h.svc.ListTags(c.Request.Context(), *userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tags retrieved successfully",
+		"tags":    tags,
+	})
+}
+
+func (h *TagHandler) RenameTag(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	tagID, ok := parseUUIDParam(c, "tagId")
+	if !ok {
+		return
+	}
+
+	var req RenameTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format",
+			Code:  "INVALID_JSON",
+		})
+		return
+	}
+
+	if err := h.svc.RenameTag(c.Request.Context(), *userID, tagID, req.Name); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag renamed successfully"})
+}
+
+func (h *TagHandler) MergeTag(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	tagID, ok := parseUUIDParam(c, "tagId")
+	if !ok {
+		return
+	}
+
+	var req MergeTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format",
+			Code:  "INVALID_JSON",
+		})
+		return
+	}
+
+	if err := h.svc.MergeTags(c.Request.Context(), *userID, tagID, req.Into); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tags merged successfully"})
+}
+
+func (h *TagHandler) DeleteTag(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	tagID, ok := parseUUIDParam(c, "tagId")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.DeleteTag(c.Request.Context(), *userID, tagID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted successfully"})
+}
+
+func (h *TagHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTag):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid tag",
+			Code:  "INVALID_TAG",
+		})
+	case errors.Is(err, repository.ErrTagNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Tag not found",
+			Code:  "TAG_NOT_FOUND",
+		})
+	case errors.Is(err, repository.ErrTagExists):
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Error: "A tag with this name already exists, merge the tags instead",
+			Code:  "TAG_EXISTS",
+		})
+	default:
+		h.logger.Error("Unexpected error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Internal server error",
+			Code:  "INTERNAL_ERROR",
+		})
+	}
+}
+
+func respondMissingUser(c *gin.Context) {
+	c.JSON(http.StatusUnauthorized, ErrorResponse{
+		Error: "User ID not found in context",
+		Code:  "MISSING_USER_ID",
+	})
+}
+
+func parseUUIDParam(c *gin.Context, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid " + name + " parameter",
+			Code:  "INVALID_ID",
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/middleware"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// StartTransferRequest hands over either the listed links or every link
+// tagged with Tag
+type StartTransferRequest struct {
+	Email   string   `json:"email" binding:"required"`
+	LinkIDs []string `json:"link_ids"`
+	Tag     string   `json:"tag"`
+}
+
+func (h *URLHandler) StartTransfer(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	var req StartTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format",
+			Code:  "INVALID_JSON",
+		})
+		return
+	}
+
+	transfer, err := h.service.StartTransfer(c.Request.Context(), *userID, service.TransferRequest{
+		Email:   req.Email,
+		LinkIDs: req.LinkIDs,
+		Tag:     req.Tag,
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"transfer": transfer})
+}
+
+func (h *URLHandler) ListTransfers(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	transfers, err := h.service.ListTransfers(c.Request.Context(), *userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"transfers": transfers})
+}
+
+func (h *URLHandler) AcceptTransfer(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	transferID, ok := parseUUIDParam(c, "transferId")
+	if !ok {
+		return
+	}
+
+	transfer, err := h.service.AcceptTransfer(c.Request.Context(), *userID, transferID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"transfer": transfer})
+}
+
+func (h *URLHandler) DeclineTransfer(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	transferID, ok := parseUUIDParam(c, "transferId")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeclineTransfer(c.Request.Context(), *userID, transferID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *URLHandler) CancelTransfer(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	transferID, ok := parseUUIDParam(c, "transferId")
+	if !ok {
+		return
+	}
+
+	if err := h.service.CancelTransfer(c.Request.Context(), *userID, transferID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
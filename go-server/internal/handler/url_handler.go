@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/fonsecaaso/TinyUrl/go-server/internal/middleware"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
 	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
 	"github.com/fonsecaaso/TinyUrl/go-server/internal/service"
 	"github.com/google/uuid"
@@ -15,20 +18,78 @@ import (
 )
 
 type CreateURLRequest struct {
-	URL    string     `json:"url" binding:"required"`
-	UserID *uuid.UUID `json:"user_id,omitempty"`
+	URL          string     `json:"url" binding:"required"`
+	UserID       *uuid.UUID `json:"user_id,omitempty"`
+	Title        string     `json:"title,omitempty"`
+	Description  string     `json:"description,omitempty"`
+	Tags         []string   `json:"tags,omitempty"`
+	ForwardQuery bool       `json:"forward_query,omitempty"`
+	ForwardPath  bool       `json:"forward_path,omitempty"`
+	StartsAt     *time.Time `json:"starts_at,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	MaxClicks    *int64     `json:"max_clicks,omitempty"`
+	Domain       string     `json:"domain,omitempty"`
+	Code         string     `json:"code,omitempty"`
+	WorkspaceID  *uuid.UUID `json:"workspace_id,omitempty"`
+	model.UTMParams
 }
 
+type UpdateURLRequest struct {
+	Title        *string   `json:"title"`
+	Description  *string   `json:"description"`
+	Tags         *[]string `json:"tags"`
+	ForwardQuery *bool     `json:"forward_query"`
+	ForwardPath  *bool     `json:"forward_path"`
+}
+
+// Sticky A/B assignments are kept in one cookie per link
+const (
+	variantCookiePrefix = "tu_ab_"
+	variantCookieMaxAge = 30 * 24 * 60 * 60
+)
+
+type VariantsRequest struct {
+	Variants []model.Variant `json:"variants"`
+	Sticky   bool            `json:"sticky"`
+}
+
+type AvailabilityRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxClicks *int64     `json:"max_clicks"`
+	Disabled  bool       `json:"disabled"`
+}
+
+type ScheduleRequest struct {
+	StartsAt *time.Time                   `json:"starts_at"`
+	Changes  []model.ScheduledDestination `json:"changes"`
+}
+
+type RoutingRulesRequest struct {
+	Rules []model.RoutingRule `json:"rules"`
+}
+
+// URLResponse carries the link id and domain only for links on a custom
+// domain, where the short code visitors use differs from the id. ClaimToken
+// is set for links created anonymously, see ClaimURLs.
 type URLResponse struct {
-	Message   string `json:"message"`
-	ShortCode string `json:"short_code,omitempty"`
-	URL       string `json:"url,omitempty"`
+	Message    string `json:"message"`
+	ShortCode  string `json:"short_code,omitempty"`
+	ID         string `json:"id,omitempty"`
+	Domain     string `json:"domain,omitempty"`
+	URL        string `json:"url,omitempty"`
+	ClaimToken string `json:"claim_token,omitempty"`
+}
+
+type ClaimURLsRequest struct {
+	Tokens []string `json:"tokens" binding:"required,min=1,max=100"`
 }
 
 type ErrorResponse struct {
 	Error   string `json:"error"`
 	Code    string `json:"code,omitempty"`
 	Details string `json:"details,omitempty"`
+	// Fallback is set for links that exist but are unavailable
+	Fallback *model.Fallback `json:"fallback,omitempty"`
 }
 
 type URLHandler struct {
@@ -56,33 +117,72 @@ func (h *URLHandler) CreateTinyURL(c *gin.Context) {
 		return
 	}
 
-	shortCode, isNew, err := h.service.ShortenURL(c.Request.Context(), req.URL, userID)
+	link, isNew, err := h.service.ShortenURL(c.Request.Context(), req.URL, userID, service.ShortenOptions{
+		Title:        req.Title,
+		Description:  req.Description,
+		Tags:         req.Tags,
+		ForwardQuery: req.ForwardQuery,
+		ForwardPath:  req.ForwardPath,
+		UTM:          req.UTMParams,
+		StartsAt:     req.StartsAt,
+		ExpiresAt:    req.ExpiresAt,
+		MaxClicks:    req.MaxClicks,
+		Domain:       req.Domain,
+		Code:         req.Code,
+		WorkspaceID:  req.WorkspaceID,
+	})
 	if err != nil {
 		h.handleError(c, err)
 		return
 	}
 
+	resp := URLResponse{ShortCode: link.ID, ClaimToken: link.ClaimToken}
+	if link.DomainID != nil {
+		resp.ShortCode, resp.ID, resp.Domain = link.Code, link.ID, link.Domain
+	}
+
 	if isNew {
-		c.JSON(http.StatusCreated, URLResponse{
-			Message:   "URL shortened successfully",
-			ShortCode: shortCode,
-		})
+		resp.Message = "URL shortened successfully"
+		c.JSON(http.StatusCreated, resp)
 	} else {
-		c.JSON(http.StatusOK, URLResponse{
-			Message:   "URL already exists, returning existing short code",
-			ShortCode: shortCode,
-		})
+		resp.Message = "URL already exists, returning existing short code"
+		c.JSON(http.StatusOK, resp)
 	}
 }
 
 func (h *URLHandler) GetURL(c *gin.Context) {
+	resolution, ok := h.resolve(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, URLResponse{
+		Message: "URL retrieved successfully",
+		URL:     resolution.URL,
+	})
+}
+
+// Redirect serves short links at the root of custom domains, sending the
+// visitor straight to the destination
+func (h *URLHandler) Redirect(c *gin.Context) {
+	resolution, ok := h.resolve(c)
+	if !ok {
+		return
+	}
+
+	c.Redirect(http.StatusFound, resolution.URL)
+}
+
+// resolve looks up the short code in the path for the current visit and
+// writes the error response when it cannot be resolved
+func (h *URLHandler) resolve(c *gin.Context) (*service.Resolution, bool) {
 	id := strings.TrimSpace(c.Param("id"))
 	if id == "" {
 		c.JSON(http.StatusBadRequest, ErrorResponse{
 			Error: "ID parameter is required",
 			Code:  "MISSING_ID",
 		})
-		return
+		return nil, false
 	}
 
 	// Reject reserved words to prevent conflicts with system endpoints
@@ -93,20 +193,35 @@ func (h *URLHandler) GetURL(c *gin.Context) {
 				Error: "Invalid short code - reserved word",
 				Code:  "RESERVED_WORD",
 			})
-			return
+			return nil, false
 		}
 	}
 
-	url, err := h.service.GetOriginalURL(c.Request.Context(), id)
+	// Served for both /:id and /:id/*path, the latter only matters for path passthrough
+	visit := service.Visit{
+		Query:          c.Request.URL.Query(),
+		ExtraPath:      c.Param("path"),
+		UserAgent:      c.Request.UserAgent(),
+		AcceptLanguage: c.GetHeader("Accept-Language"),
+		ClientIP:       c.ClientIP(),
+		Host:           c.Request.Host,
+	}
+
+	cookieName := variantCookiePrefix + id
+	visit.StickyVariant, _ = c.Cookie(cookieName)
+
+	resolution, err := h.service.Resolve(c.Request.Context(), id, visit)
 	if err != nil {
 		h.handleError(c, err)
-		return
+		return nil, false
 	}
 
-	c.JSON(http.StatusOK, URLResponse{
-		Message: "URL retrieved successfully",
-		URL:     url,
-	})
+	if resolution.StickyVariant != "" && resolution.StickyVariant != visit.StickyVariant {
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(cookieName, resolution.StickyVariant, variantCookieMaxAge, "/", "", c.Request.TLS != nil, true)
+	}
+
+	return resolution, true
 }
 
 func (h *URLHandler) GetUserURLs(c *gin.Context) {
@@ -119,7 +234,14 @@ func (h *URLHandler) GetUserURLs(c *gin.Context) {
 		return
 	}
 
-	urls, err := h.service.GetUserURLs(c.Request.Context(), *userID)
+	filter := repository.URLFilter{Tag: c.Query("tag"), State: model.LinkState(c.Query("state"))}
+	workspaceID, ok := workspaceQuery(c)
+	if !ok {
+		return
+	}
+	filter.WorkspaceID = workspaceID
+
+	urls, err := h.service.GetUserURLs(c.Request.Context(), *userID, filter)
 	if err != nil {
 		h.handleError(c, err)
 		return
@@ -131,18 +253,605 @@ func (h *URLHandler) GetUserURLs(c *gin.Context) {
 	})
 }
 
+// ClaimURLs attaches links created anonymously before signing in to the
+// account, given the claim tokens returned when they were created
+func (h *URLHandler) ClaimURLs(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	var req ClaimURLsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format",
+			Code:  "INVALID_JSON",
+		})
+		return
+	}
+
+	claimed, err := h.service.ClaimURLs(c.Request.Context(), *userID, req.Tokens)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "URLs claimed successfully",
+		"claimed": claimed,
+	})
+}
+
+func (h *URLHandler) UpdateURL(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "User ID not found in context",
+			Code:  "MISSING_USER_ID",
+		})
+		return
+	}
+
+	var req UpdateURLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format",
+			Code:  "INVALID_JSON",
+		})
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	err := h.service.UpdateURL(c.Request.Context(), *userID, id, service.URLUpdate{
+		Title:        req.Title,
+		Description:  req.Description,
+		Tags:         req.Tags,
+		ForwardQuery: req.ForwardQuery,
+		ForwardPath:  req.ForwardPath,
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, URLResponse{
+		Message:   "URL updated successfully",
+		ShortCode: id,
+	})
+}
+
+func (h *URLHandler) GetRoutingRules(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	rules, err := h.service.GetRoutingRules(c.Request.Context(), *userID, strings.TrimSpace(c.Param("id")))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+func (h *URLHandler) SetRoutingRules(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	var req RoutingRulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format",
+			Code:  "INVALID_JSON",
+		})
+		return
+	}
+
+	rules, err := h.service.SetRoutingRules(c.Request.Context(), *userID, strings.TrimSpace(c.Param("id")), req.Rules)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+func (h *URLHandler) SetVariants(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	var req VariantsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format",
+			Code:  "INVALID_JSON",
+		})
+		return
+	}
+
+	variants, err := h.service.SetVariants(c.Request.Context(), *userID, strings.TrimSpace(c.Param("id")), req.Variants, req.Sticky)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"variants": variants, "sticky": req.Sticky})
+}
+
+func (h *URLHandler) SetSchedule(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	var req ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format",
+			Code:  "INVALID_JSON",
+		})
+		return
+	}
+
+	changes, err := h.service.SetSchedule(c.Request.Context(), *userID, strings.TrimSpace(c.Param("id")), req.StartsAt, req.Changes)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"starts_at": req.StartsAt, "changes": changes})
+}
+
+func (h *URLHandler) SetAvailability(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	var req AvailabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format",
+			Code:  "INVALID_JSON",
+		})
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	err := h.service.SetAvailability(c.Request.Context(), *userID, id, repository.Availability{
+		ExpiresAt: req.ExpiresAt,
+		MaxClicks: req.MaxClicks,
+		Disabled:  req.Disabled,
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, URLResponse{
+		Message:   "URL availability updated successfully",
+		ShortCode: id,
+	})
+}
+
+// ArchiveURL takes a link out of service, it can be restored later
+func (h *URLHandler) ArchiveURL(c *gin.Context) {
+	h.changeLifecycle(c, h.service.ArchiveURL, "URL archived successfully")
+}
+
+// DeleteURL soft-deletes a link, it can be restored until it is purged
+func (h *URLHandler) DeleteURL(c *gin.Context) {
+	h.changeLifecycle(c, h.service.DeleteURL, "URL deleted successfully")
+}
+
+// RestoreURL puts an archived or deleted link back in service
+func (h *URLHandler) RestoreURL(c *gin.Context) {
+	h.changeLifecycle(c, h.service.RestoreURL, "URL restored successfully")
+}
+
+func (h *URLHandler) changeLifecycle(c *gin.Context, change func(context.Context, uuid.UUID, string) error, message string) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	if err := change(c.Request.Context(), *userID, id); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, URLResponse{
+		Message:   message,
+		ShortCode: id,
+	})
+}
+
+func (h *URLHandler) SetLinkFallback(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	var req model.Fallback
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format",
+			Code:  "INVALID_JSON",
+		})
+		return
+	}
+
+	fallback, err := h.service.SetLinkFallback(c.Request.Context(), *userID, strings.TrimSpace(c.Param("id")), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"fallback": fallback})
+}
+
+func (h *URLHandler) DeleteLinkFallback(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	if _, err := h.service.SetLinkFallback(c.Request.Context(), *userID, strings.TrimSpace(c.Param("id")), nil); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetFallbackDefault returns the fallback default of the workspace given by
+// the workspace query parameter, the caller's personal one without it
+func (h *URLHandler) GetFallbackDefault(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+	workspaceID, ok := workspaceQuery(c)
+	if !ok {
+		return
+	}
+
+	def, err := h.service.GetFallbackDefault(c.Request.Context(), *userID, workspaceID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"fallback": def})
+}
+
+func (h *URLHandler) SetFallbackDefault(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+	workspaceID, ok := workspaceQuery(c)
+	if !ok {
+		return
+	}
+
+	var req model.Fallback
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format",
+			Code:  "INVALID_JSON",
+		})
+		return
+	}
+
+	def, err := h.service.SetFallbackDefault(c.Request.Context(), *userID, workspaceID, req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"fallback": def})
+}
+
+func (h *URLHandler) DeleteFallbackDefault(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+	workspaceID, ok := workspaceQuery(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteFallbackDefault(c.Request.Context(), *userID, workspaceID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *URLHandler) GetStats(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	stats, err := h.service.GetLinkStats(c.Request.Context(), *userID, strings.TrimSpace(c.Param("id")))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
+
+// GetUTMTemplate returns the UTM template of the workspace given by the
+// workspace query parameter, the caller's personal one without it
+func (h *URLHandler) GetUTMTemplate(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+	workspaceID, ok := workspaceQuery(c)
+	if !ok {
+		return
+	}
+
+	tpl, err := h.service.GetUTMTemplate(c.Request.Context(), *userID, workspaceID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"template": tpl})
+}
+
+func (h *URLHandler) SetUTMTemplate(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+	workspaceID, ok := workspaceQuery(c)
+	if !ok {
+		return
+	}
+
+	var req model.UTMParams
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format",
+			Code:  "INVALID_JSON",
+		})
+		return
+	}
+
+	tpl, err := h.service.SetUTMTemplate(c.Request.Context(), *userID, workspaceID, req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"template": tpl})
+}
+
+func (h *URLHandler) DeleteUTMTemplate(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+	workspaceID, ok := workspaceQuery(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteUTMTemplate(c.Request.Context(), *userID, workspaceID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// workspaceQuery parses the optional workspace query parameter, answering
+// 400 when it is no workspace ID
+func workspaceQuery(c *gin.Context) (*uuid.UUID, bool) {
+	raw := c.Query("workspace")
+	if raw == "" {
+		return nil, true
+	}
+	workspaceID, err := uuid.Parse(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid workspace ID",
+			Code:  "INVALID_WORKSPACE_ID",
+		})
+		return nil, false
+	}
+	return &workspaceID, true
+}
+
 func (h *URLHandler) handleError(c *gin.Context, err error) {
+	var unavailable *service.UnavailableError
+	if errors.As(err, &unavailable) {
+		respondUnavailable(c, unavailable)
+		return
+	}
+
 	switch {
 	case errors.Is(err, service.ErrInvalidToken):
 		c.JSON(http.StatusBadRequest, ErrorResponse{
 			Error: "Invalid short URL format",
 			Code:  "INVALID_SHORT_URL",
 		})
+	case errors.Is(err, service.ErrInvalidCode):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Custom codes need a custom domain and may only use letters, digits, '-' and '_'",
+			Code:  "INVALID_CODE",
+		})
+	case errors.Is(err, repository.ErrCodeTaken):
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Error: "Short code already taken on this domain",
+			Code:  "CODE_TAKEN",
+		})
+	case errors.Is(err, service.ErrInvalidDomain), errors.Is(err, repository.ErrDomainNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Domain not found or not verified",
+			Code:  "DOMAIN_NOT_FOUND",
+		})
 	case errors.Is(err, service.ErrInvalidURL):
 		c.JSON(http.StatusBadRequest, ErrorResponse{
 			Error: "Invalid URL format",
 			Code:  "INVALID_URL",
 		})
+	case errors.Is(err, service.ErrInvalidDetails):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Title or description is too long",
+			Code:  "INVALID_DETAILS",
+		})
+	case errors.Is(err, service.ErrInvalidTag):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid tag",
+			Code:  "INVALID_TAG",
+		})
+	case errors.Is(err, service.ErrInvalidRule):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid routing rule",
+			Code:  "INVALID_RULE",
+		})
+	case errors.Is(err, service.ErrInvalidVariants):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Variants need 2 to 10 destinations with weights between 0 and 1000, at least one above 0",
+			Code:  "INVALID_VARIANTS",
+		})
+	case errors.Is(err, service.ErrInvalidSchedule):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Scheduled changes must be in the future and at distinct times",
+			Code:  "INVALID_SCHEDULE",
+		})
+	case errors.Is(err, service.ErrLinkNotStarted):
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Short URL is not active yet",
+			Code:  "LINK_NOT_STARTED",
+		})
+	case errors.Is(err, service.ErrInvalidLimits):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Expiry must be in the future and the click limit at least 1",
+			Code:  "INVALID_LIMITS",
+		})
+	case errors.Is(err, service.ErrInvalidFallback):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid fallback",
+			Code:  "INVALID_FALLBACK",
+		})
+	case errors.Is(err, repository.ErrFallbackNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Fallback not found",
+			Code:  "FALLBACK_NOT_FOUND",
+		})
+	case errors.Is(err, service.ErrInvalidUTM):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid UTM parameters",
+			Code:  "INVALID_UTM",
+		})
+	case errors.Is(err, repository.ErrUTMTemplateNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "UTM template not found",
+			Code:  "UTM_TEMPLATE_NOT_FOUND",
+		})
+	case errors.Is(err, service.ErrInvalidClaimToken):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid or expired claim token",
+			Code:  "INVALID_CLAIM_TOKEN",
+		})
+	case errors.Is(err, service.ErrInvalidTransfer):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Select up to 500 links either by link_ids or by tag",
+			Code:  "INVALID_TRANSFER",
+		})
+	case errors.Is(err, service.ErrNothingToMove):
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+			Error: "None of the selected links can be transferred",
+			Code:  "NOTHING_TO_TRANSFER",
+		})
+	case errors.Is(err, service.ErrInvalidEmail):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid email",
+			Code:  "INVALID_EMAIL",
+		})
+	case errors.Is(err, repository.ErrTransferNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Transfer not found, expired, already answered or addressed to an unverified email",
+			Code:  "TRANSFER_NOT_FOUND",
+		})
+	case errors.Is(err, service.ErrInvalidStateFilter):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "State must be archived or deleted",
+			Code:  "INVALID_STATE",
+		})
+	case errors.Is(err, service.ErrLinkDeleted):
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Error: "Deleted links must be restored first",
+			Code:  "LINK_DELETED",
+		})
+	case errors.Is(err, service.ErrNotArchived):
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Error: "Link is neither archived nor deleted",
+			Code:  "LINK_NOT_ARCHIVED",
+		})
+	case errors.Is(err, service.ErrRestoreExpired):
+		c.JSON(http.StatusGone, ErrorResponse{
+			Error: "The restore window of this link has passed",
+			Code:  "RESTORE_EXPIRED",
+		})
+	case errors.Is(err, repository.ErrDuplicateURL):
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Error: "Another live link already points to this destination",
+			Code:  "DUPLICATE_URL",
+		})
+	case errors.Is(err, service.ErrForbidden):
+		respondForbidden(c)
+	case errors.Is(err, repository.ErrWorkspaceNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Workspace not found",
+			Code:  "WORKSPACE_NOT_FOUND",
+		})
+	case errors.Is(err, service.ErrAuthRequired):
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+			Code:  "AUTH_REQUIRED",
+		})
 	case errors.Is(err, repository.ErrURLNotFound):
 		c.JSON(http.StatusNotFound, ErrorResponse{
 			Error: "Short URL not found",
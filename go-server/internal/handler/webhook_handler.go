@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/middleware"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type CreateWebhookRequest struct {
+	URL    string               `json:"url" binding:"required"`
+	Events []model.WebhookEvent `json:"events" binding:"required"`
+}
+
+type UpdateWebhookRequest struct {
+	URL    *string               `json:"url"`
+	Events *[]model.WebhookEvent `json:"events"`
+	Active *bool                 `json:"active"`
+}
+
+type WebhookHandler struct {
+	svc    service.WebhookService
+	logger *zap.Logger
+}
+
+func NewWebhookHandler(svc service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		svc:    svc,
+		logger: zap.L().With(zap.String("component", "WebhookHandler")),
+	}
+}
+
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	webhooks, err := h.svc.ListWebhooks(c.Request.Context(), *userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": webhooks})
+}
+
+// CreateWebhook registers an endpoint and returns its signing secret, the
+// only time it is shown
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format",
+			Code:  "INVALID_JSON",
+		})
+		return
+	}
+
+	webhook, err := h.svc.CreateWebhook(c.Request.Context(), *userID, req.URL, req.Events)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"webhook": webhook})
+}
+
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+	webhookID, ok := parseUUIDParam(c, "webhookId")
+	if !ok {
+		return
+	}
+
+	var req UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format",
+			Code:  "INVALID_JSON",
+		})
+		return
+	}
+
+	webhook, err := h.svc.UpdateWebhook(c.Request.Context(), *userID, webhookID, service.WebhookUpdate{
+		URL:    req.URL,
+		Events: req.Events,
+		Active: req.Active,
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhook": webhook})
+}
+
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+	webhookID, ok := parseUUIDParam(c, "webhookId")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.DeleteWebhook(c.Request.Context(), *userID, webhookID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// ListDeliveries returns the delivery log of a webhook, newest first,
+// optionally filtered by ?status=
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+	webhookID, ok := parseUUIDParam(c, "webhookId")
+	if !ok {
+		return
+	}
+	limit, offset, ok := parsePage(c)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.svc.ListDeliveries(c.Request.Context(), *userID, webhookID, repository.DeliveryFilter{
+		Status: model.DeliveryStatus(c.Query("status")),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// ReplayDelivery queues a past delivery to be sent again
+func (h *WebhookHandler) ReplayDelivery(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+	webhookID, ok := parseUUIDParam(c, "webhookId")
+	if !ok {
+		return
+	}
+	deliveryID, ok := parseUUIDParam(c, "deliveryId")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.svc.ReplayDelivery(c.Request.Context(), *userID, webhookID, deliveryID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"delivery": delivery})
+}
+
+func (h *WebhookHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidWebhook):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Webhooks need a public http(s) URL and at least one of link.created, link.updated, link.deleted or link.clicked",
+			Code:  "INVALID_WEBHOOK",
+		})
+	case errors.Is(err, repository.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Webhook not found",
+			Code:  "WEBHOOK_NOT_FOUND",
+		})
+	case errors.Is(err, repository.ErrDeliveryNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Delivery not found",
+			Code:  "DELIVERY_NOT_FOUND",
+		})
+	case errors.Is(err, repository.ErrDatabaseError):
+		h.logger.Error("Database error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Database error",
+			Code:  "DB_ERROR",
+		})
+	default:
+		h.logger.Error("Unexpected error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Internal server error",
+			Code:  "INTERNAL_ERROR",
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/middleware"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type CreateWorkspaceRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type UpdateMemberRequest struct {
+	Role model.Role `json:"role" binding:"required"`
+}
+
+type InviteRequest struct {
+	Email string     `json:"email" binding:"required"`
+	Role  model.Role `json:"role" binding:"required"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type WorkspaceHandler struct {
+	svc    service.WorkspaceService
+	logger *zap.Logger
+}
+
+func NewWorkspaceHandler(svc service.WorkspaceService) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		svc:    svc,
+		logger: zap.L().With(zap.String("component", "WorkspaceHandler")),
+	}
+}
+
+func (h *WorkspaceHandler) ListWorkspaces(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	workspaces, err := h.svc.ListWorkspaces(c.Request.Context(), *userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"workspaces": workspaces})
+}
+
+func (h *WorkspaceHandler) CreateWorkspace(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	var req CreateWorkspaceRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	workspace, err := h.svc.CreateWorkspace(c.Request.Context(), *userID, req.Name)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	workspace.Role = model.RoleOwner
+	c.JSON(http.StatusCreated, gin.H{"workspace": workspace})
+}
+
+func (h *WorkspaceHandler) ListMembers(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	workspaceID, ok := parseUUIDParam(c, "workspaceId")
+	if !ok {
+		return
+	}
+
+	members, err := h.svc.ListMembers(c.Request.Context(), *userID, workspaceID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"members": members})
+}
+
+func (h *WorkspaceHandler) UpdateMember(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	workspaceID, ok := parseUUIDParam(c, "workspaceId")
+	if !ok {
+		return
+	}
+	memberID, ok := parseUUIDParam(c, "memberId")
+	if !ok {
+		return
+	}
+
+	var req UpdateMemberRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	if err := h.svc.SetMemberRole(c.Request.Context(), *userID, workspaceID, memberID, req.Role); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *WorkspaceHandler) RemoveMember(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	workspaceID, ok := parseUUIDParam(c, "workspaceId")
+	if !ok {
+		return
+	}
+	memberID, ok := parseUUIDParam(c, "memberId")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.RemoveMember(c.Request.Context(), *userID, workspaceID, memberID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Invite responds with the invitation token once; it is not stored and
+// has to be passed on to the invitee
+func (h *WorkspaceHandler) Invite(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	workspaceID, ok := parseUUIDParam(c, "workspaceId")
+	if !ok {
+		return
+	}
+
+	var req InviteRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	invitation, token, err := h.svc.Invite(c.Request.Context(), *userID, workspaceID, req.Email, req.Role)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"invitation": invitation, "token": token})
+}
+
+func (h *WorkspaceHandler) ListInvitations(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	workspaceID, ok := parseUUIDParam(c, "workspaceId")
+	if !ok {
+		return
+	}
+
+	invitations, err := h.svc.ListInvitations(c.Request.Context(), *userID, workspaceID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitations": invitations})
+}
+
+func (h *WorkspaceHandler) RevokeInvitation(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	workspaceID, ok := parseUUIDParam(c, "workspaceId")
+	if !ok {
+		return
+	}
+	invitationID, ok := parseUUIDParam(c, "invitationId")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.RevokeInvitation(c.Request.Context(), *userID, workspaceID, invitationID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *WorkspaceHandler) AcceptInvitation(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		respondMissingUser(c)
+		return
+	}
+
+	var req AcceptInvitationRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	member, err := h.svc.AcceptInvitation(c.Request.Context(), *userID, req.Token)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"member": member})
+}
+
+func (h *WorkspaceHandler) bind(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		h.logger.Warn("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format",
+			Code:  "INVALID_JSON",
+		})
+		return false
+	}
+	return true
+}
+
+func (h *WorkspaceHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidWorkspace):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Workspace name must be 1 to 100 characters",
+			Code:  "INVALID_WORKSPACE",
+		})
+	case errors.Is(err, service.ErrInvalidRole):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Role must be owner, admin, editor or viewer",
+			Code:  "INVALID_ROLE",
+		})
+	case errors.Is(err, service.ErrInvalidEmail):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid email",
+			Code:  "INVALID_EMAIL",
+		})
+	case errors.Is(err, service.ErrPersonalWorkspace):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Personal workspaces cannot be shared",
+			Code:  "PERSONAL_WORKSPACE",
+		})
+	case errors.Is(err, service.ErrForbidden):
+		respondForbidden(c)
+	case errors.Is(err, repository.ErrWorkspaceNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Workspace not found",
+			Code:  "WORKSPACE_NOT_FOUND",
+		})
+	case errors.Is(err, repository.ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Member not found",
+			Code:  "MEMBER_NOT_FOUND",
+		})
+	case errors.Is(err, repository.ErrInvitationNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Invitation not found, expired or addressed to another or unverified email",
+			Code:  "INVITATION_NOT_FOUND",
+		})
+	case errors.Is(err, repository.ErrLastOwner):
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Error: "A workspace needs at least one owner",
+			Code:  "LAST_OWNER",
+		})
+	default:
+		h.logger.Error("Unexpected error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Internal server error",
+			Code:  "INTERNAL_ERROR",
+		})
+	}
+}
+
+func respondForbidden(c *gin.Context) {
+	c.JSON(http.StatusForbidden, ErrorResponse{
+		Error: "Your role in this workspace does not allow this",
+		Code:  "FORBIDDEN",
+	})
+}
@@ -0,0 +1,141 @@
+// Package healthcheck periodically probes link destinations and records
+// whether they still respond, so rotting links can be spotted before users do.
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/safehttp"
+)
+
+const (
+	defaultCheckTimeout = 10 * time.Second
+	defaultMaxRedirects = 10
+	userAgent           = "TinyUrlLinkChecker/1.0 (+https://fonsecaaso.com)"
+)
+
+// CheckerConfig controls how a single destination is probed
+type CheckerConfig struct {
+	Timeout      time.Duration
+	MaxRedirects int
+	// AllowPrivateNetworks lets tests probe httptest servers on loopback
+	AllowPrivateNetworks bool
+}
+
+type Checker struct {
+	client       *http.Client
+	maxRedirects int
+}
+
+func NewChecker(cfg CheckerConfig) *Checker {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultCheckTimeout
+	}
+	if cfg.MaxRedirects <= 0 {
+		cfg.MaxRedirects = defaultMaxRedirects
+	}
+
+	client := safehttp.NewClient(safehttp.Config{
+		Timeout:              cfg.Timeout,
+		AllowPrivateNetworks: cfg.AllowPrivateNetworks,
+	})
+	// Redirects are followed by hand to record the chain
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &Checker{client: client, maxRedirects: cfg.MaxRedirects}
+}
+
+// Check probes rawURL with HEAD, falling back to GET for servers that do not
+// support it, and follows redirects up to the configured limit
+func (c *Checker) Check(ctx context.Context, rawURL string) *model.LinkHealth {
+	health := &model.LinkHealth{}
+	defer func() {
+		health.CheckedAt = time.Now()
+		health.Healthy = health.Error == "" && model.IsHealthyStatus(health.StatusCode)
+	}()
+
+	current, err := url.Parse(rawURL)
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+
+	for hop := 0; ; hop++ {
+		if err := safehttp.CheckURL(current); err != nil {
+			health.Error = err.Error()
+			return health
+		}
+
+		status, location, err := c.probe(ctx, current.String())
+		if err != nil {
+			health.Error = describeError(err)
+			return health
+		}
+		health.StatusCode = status
+
+		if status < 300 || status > 399 || location == "" {
+			return health
+		}
+		if hop >= c.maxRedirects {
+			health.Error = safehttp.ErrTooManyRedirects.Error()
+			return health
+		}
+
+		next, err := current.Parse(location)
+		if err != nil {
+			health.Error = err.Error()
+			return health
+		}
+		health.RedirectChain = append(health.RedirectChain, next.String())
+		current = next
+	}
+}
+
+func (c *Checker) probe(ctx context.Context, target string) (int, string, error) {
+	status, location, err := c.do(ctx, http.MethodHead, target)
+	if err != nil {
+		return 0, "", err
+	}
+	if status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented {
+		return c.do(ctx, http.MethodGet, target)
+	}
+	return status, location, nil
+}
+
+func (c *Checker) do(ctx context.Context, method, target string) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	// The body is never needed, closing without reading drops the connection
+	_ = resp.Body.Close()
+
+	return resp.StatusCode, resp.Header.Get("Location"), nil
+}
+
+func describeError(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout"
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if urlErr.Timeout() {
+			return "timeout"
+		}
+		return urlErr.Err.Error()
+	}
+	return err.Error()
+}
@@ -0,0 +1,173 @@
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestChecker() *Checker {
+	return NewChecker(CheckerConfig{Timeout: time.Second, MaxRedirects: 3, AllowPrivateNetworks: true})
+}
+
+func TestCheck_Healthy(t *testing.T) {
+	var method string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+	}))
+	defer srv.Close()
+
+	health := newTestChecker().Check(context.Background(), srv.URL)
+
+	assert.True(t, health.Healthy)
+	assert.Equal(t, http.StatusOK, health.StatusCode)
+	assert.Equal(t, http.MethodHead, method)
+	assert.Empty(t, health.RedirectChain)
+	assert.False(t, health.CheckedAt.IsZero())
+}
+
+func TestCheck_FallsBackToGet(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	health := newTestChecker().Check(context.Background(), srv.URL)
+
+	assert.True(t, health.Healthy)
+	assert.Equal(t, http.StatusOK, health.StatusCode)
+}
+
+func TestCheck_RecordsRedirectChain(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/b", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/gone", http.StatusFound)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	health := newTestChecker().Check(context.Background(), srv.URL+"/a")
+
+	assert.False(t, health.Healthy)
+	assert.Equal(t, http.StatusNotFound, health.StatusCode)
+	assert.Equal(t, []string{srv.URL + "/b", srv.URL + "/gone"}, health.RedirectChain)
+}
+
+func TestCheck_TooManyRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Path+"x", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	health := newTestChecker().Check(context.Background(), srv.URL+"/")
+
+	assert.False(t, health.Healthy)
+	assert.Equal(t, "too many redirects", health.Error)
+	assert.Len(t, health.RedirectChain, 3)
+}
+
+func TestCheck_ConnectionError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	target := srv.URL
+	srv.Close()
+
+	health := newTestChecker().Check(context.Background(), target)
+
+	assert.False(t, health.Healthy)
+	assert.NotEmpty(t, health.Error)
+	assert.Zero(t, health.StatusCode)
+}
+
+func TestCheck_RejectsPrivateDestinations(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	health := NewChecker(CheckerConfig{}).Check(context.Background(), srv.URL)
+
+	assert.False(t, health.Healthy)
+	assert.Contains(t, health.Error, "not allowed")
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	pending []model.URL
+	saved   map[string]*model.LinkHealth
+}
+
+func (m *memoryStore) ClaimForHealthCheck(ctx context.Context, before time.Time, limit int) ([]model.URL, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if limit > len(m.pending) {
+		limit = len(m.pending)
+	}
+	claimed := m.pending[:limit]
+	m.pending = m.pending[limit:]
+	return claimed, nil
+}
+
+func (m *memoryStore) SaveHealth(ctx context.Context, id string, health *model.LinkHealth) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saved[id] = health
+	return nil
+}
+
+func TestScheduler_RunOnce(t *testing.T) {
+	var mu sync.Mutex
+	var hits []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits = append(hits, time.Now())
+		mu.Unlock()
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	store := &memoryStore{
+		pending: []model.URL{
+			{ID: "ok0001", OriginalURL: srv.URL + "/ok"},
+			{ID: "bad001", OriginalURL: srv.URL + "/broken"},
+			{ID: "later1", OriginalURL: srv.URL + "/later"},
+		},
+		saved: make(map[string]*model.LinkHealth),
+	}
+	scheduler := NewScheduler(newTestChecker(), store, SchedulerConfig{
+		BatchSize:   2,
+		Concurrency: 2,
+		HostDelay:   100 * time.Millisecond,
+	})
+
+	require.NoError(t, scheduler.RunOnce(context.Background()))
+
+	assert.Len(t, store.saved, 2)
+	assert.True(t, store.saved["ok0001"].Healthy)
+	assert.False(t, store.saved["bad001"].Healthy)
+	assert.Equal(t, http.StatusInternalServerError, store.saved["bad001"].StatusCode)
+
+	// Both links share a host, so the requests must be spaced out
+	require.Len(t, hits, 2)
+	gap := hits[1].Sub(hits[0])
+	if gap < 0 {
+		gap = -gap
+	}
+	assert.GreaterOrEqual(t, gap, 90*time.Millisecond)
+}
@@ -0,0 +1,194 @@
+package healthcheck
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/metrics"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultInterval     = 10 * time.Minute
+	defaultRecheckAfter = 24 * time.Hour
+	defaultBatchSize    = 200
+	defaultConcurrency  = 8
+	defaultHostDelay    = 2 * time.Second
+)
+
+// Store loads links due for a check and persists the results
+type Store interface {
+	ClaimForHealthCheck(ctx context.Context, before time.Time, limit int) ([]model.URL, error)
+	SaveHealth(ctx context.Context, id string, health *model.LinkHealth) error
+}
+
+// SchedulerConfig controls how often and how aggressively links are checked
+type SchedulerConfig struct {
+	Interval     time.Duration
+	RecheckAfter time.Duration
+	BatchSize    int
+	Concurrency  int
+	// HostDelay is the minimum time between two requests to the same host
+	HostDelay time.Duration
+}
+
+type Scheduler struct {
+	checker *Checker
+	store   Store
+	cfg     SchedulerConfig
+	hosts   *hostGate
+	wg      sync.WaitGroup
+	logger  *zap.Logger
+}
+
+func NewScheduler(checker *Checker, store Store, cfg SchedulerConfig) *Scheduler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultInterval
+	}
+	if cfg.RecheckAfter <= 0 {
+		cfg.RecheckAfter = defaultRecheckAfter
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaultConcurrency
+	}
+	if cfg.HostDelay < 0 {
+		cfg.HostDelay = 0
+	} else if cfg.HostDelay == 0 {
+		cfg.HostDelay = defaultHostDelay
+	}
+
+	return &Scheduler{
+		checker: checker,
+		store:   store,
+		cfg:     cfg,
+		hosts:   newHostGate(cfg.HostDelay),
+		logger:  zap.L().With(zap.String("component", "HealthCheckScheduler")),
+	}
+}
+
+// Start runs a check round every Interval until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.RunOnce(ctx); err != nil {
+					s.logger.Warn("Health check round failed", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// Wait blocks until the round in progress, if any, has finished after ctx
+// was cancelled
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// RunOnce checks one batch of links that are due
+func (s *Scheduler) RunOnce(ctx context.Context) error {
+	links, err := s.store.ClaimForHealthCheck(ctx, time.Now().Add(-s.cfg.RecheckAfter), s.cfg.BatchSize)
+	if err != nil {
+		return err
+	}
+	if len(links) == 0 {
+		return nil
+	}
+
+	sem := make(chan struct{}, s.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for _, link := range links {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(link model.URL) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			s.checkLink(ctx, link)
+		}(link)
+	}
+	wg.Wait()
+
+	s.logger.Info("Health check round completed", zap.Int("checked", len(links)))
+	return nil
+}
+
+func (s *Scheduler) checkLink(ctx context.Context, link model.URL) {
+	if u, err := url.Parse(link.OriginalURL); err == nil {
+		if err := s.hosts.wait(ctx, u.Hostname()); err != nil {
+			return
+		}
+	}
+
+	start := time.Now()
+	health := s.checker.Check(ctx, link.OriginalURL)
+
+	result := "healthy"
+	switch {
+	case health.Error != "":
+		result = "error"
+	case !health.Healthy:
+		result = "broken"
+	}
+	metrics.RecordLinkHealthCheck(ctx, result, time.Since(start))
+
+	if err := s.store.SaveHealth(ctx, link.ID, health); err != nil {
+		s.logger.Warn("Failed to save link health", zap.Error(err), zap.String("id", link.ID))
+	}
+}
+
+// hostGate spaces out requests to the same host
+type hostGate struct {
+	mu    sync.Mutex
+	delay time.Duration
+	next  map[string]time.Time
+}
+
+func newHostGate(delay time.Duration) *hostGate {
+	return &hostGate{delay: delay, next: make(map[string]time.Time)}
+}
+
+func (g *hostGate) wait(ctx context.Context, host string) error {
+	g.mu.Lock()
+	now := time.Now()
+	slot := g.next[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	g.next[host] = slot.Add(g.delay)
+	// Forget hosts whose slots are in the past to keep the map small
+	for h, t := range g.next {
+		if t.Before(now) {
+			delete(g.next, h)
+		}
+	}
+	g.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(slot))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,139 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompose(t *testing.T) {
+	msg := Message{To: "ana@example.com", Subject: "Confirm your email address", Text: "Hi,\nthanks"}
+	raw, err := compose("TinyUrl <no-reply@example.com>", msg, time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	body := string(raw)
+	assert.Contains(t, body, "From: TinyUrl <no-reply@example.com>\r\n")
+	assert.Contains(t, body, "To: ana@example.com\r\n")
+	assert.Contains(t, body, "Subject: Confirm your email address\r\n")
+	assert.Contains(t, body, "Date: Sun, 01 Jun 2025 12:00:00 +0000\r\n")
+	assert.True(t, strings.HasSuffix(body, "\r\n\r\nHi,\r\nthanks"))
+
+	for _, bad := range []Message{
+		{To: "not an address", Subject: "Hi"},
+		{To: "ana@example.com", Subject: "Hi\r\nBcc: eve@example.com"},
+		{To: "ana@example.com\r\nBcc: eve@example.com", Subject: "Hi"},
+	} {
+		_, err := compose("no-reply@example.com", bad, time.Now())
+		assert.ErrorIs(t, err, ErrInvalidMessage)
+	}
+}
+
+func TestFileMailer_DropsMessages(t *testing.T) {
+	dir := t.TempDir()
+	mailer, err := NewFileMailer(dir, "no-reply@example.com")
+	require.NoError(t, err)
+
+	require.NoError(t, mailer.Send(context.Background(), Message{To: "ana@example.com", Subject: "One", Text: "1"}))
+	require.NoError(t, mailer.Send(context.Background(), Message{To: "ana@example.com", Subject: "Two", Text: "2"}))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	raw, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), "To: ana@example.com")
+}
+
+func TestNewMailer(t *testing.T) {
+	_, err := NewMailer(Config{Kind: "file", From: "no-reply@example.com", Dir: t.TempDir()})
+	assert.NoError(t, err)
+	_, err = NewMailer(Config{Kind: "file", From: "", Dir: t.TempDir()})
+	assert.Error(t, err)
+	_, err = NewMailer(Config{Kind: "smtp", From: "no-reply@example.com", SMTPAddr: "no-port"})
+	assert.Error(t, err)
+	_, err = NewMailer(Config{Kind: "pigeon", From: "no-reply@example.com"})
+	assert.Error(t, err)
+}
+
+// fakeSMTPServer accepts one plain-text SMTP session and returns what the
+// client sent
+func fakeSMTPServer(t *testing.T) (string, <-chan []string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	lines := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+
+		var got []string
+		r := bufio.NewReader(conn)
+		reply := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				break
+			}
+			line = strings.TrimRight(line, "\r\n")
+			got = append(got, line)
+			switch {
+			case inData && line == ".":
+				inData = false
+				reply("250 queued")
+			case inData:
+			case strings.HasPrefix(line, "EHLO"):
+				reply("250 localhost")
+			case line == "DATA":
+				inData = true
+				reply("354 go ahead")
+			case line == "QUIT":
+				reply("221 bye")
+				lines <- got
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+		lines <- got
+	}()
+	return ln.Addr().String(), lines
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	addr, lines := fakeSMTPServer(t)
+	mailer, err := NewSMTPMailer(addr, "", "", "TinyUrl <no-reply@example.com>")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, mailer.Send(ctx, Message{To: "ana@example.com", Subject: "Hello", Text: "Body"}))
+
+	got := strings.Join(<-lines, "\n")
+	assert.Contains(t, got, "MAIL FROM:<no-reply@example.com>")
+	assert.Contains(t, got, "RCPT TO:<ana@example.com>")
+	assert.Contains(t, got, "Subject: Hello")
+	assert.Contains(t, got, "Body")
+}
+
+func TestSMTPMailer_RefusesPlaintextAuth(t *testing.T) {
+	addr, _ := fakeSMTPServer(t)
+	mailer, err := NewSMTPMailer(addr, "user", "secret", "no-reply@example.com")
+	require.NoError(t, err)
+
+	err = mailer.Send(context.Background(), Message{To: "ana@example.com", Subject: "Hello", Text: "Body"})
+	assert.ErrorContains(t, err, "STARTTLS")
+}
@@ -0,0 +1,82 @@
+// Package mail sends the transactional emails of the service, such as
+// address verification, through SMTP or, for local use and tests, to a
+// directory or memory.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+var ErrInvalidMessage = errors.New("invalid email message")
+
+// Message is a plain-text email to a single recipient
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+// Mailer delivers messages. Send returns once the message was handed over,
+// not when it reached the inbox.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config selects how mail is delivered. Kind is smtp or file.
+type Config struct {
+	Kind string
+	From string
+	// SMTPAddr is the host:port of the SMTP server. STARTTLS is used when
+	// the server offers it and required when Username is set.
+	SMTPAddr string
+	Username string
+	Password string
+	// Dir is where the file mailer drops messages
+	Dir string
+}
+
+// NewMailer builds the mailer cfg selects
+func NewMailer(cfg Config) (Mailer, error) {
+	if _, err := mail.ParseAddress(cfg.From); err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", cfg.From, err)
+	}
+
+	switch cfg.Kind {
+	case "smtp":
+		return NewSMTPMailer(cfg.SMTPAddr, cfg.Username, cfg.Password, cfg.From)
+	case "file":
+		return NewFileMailer(cfg.Dir, cfg.From)
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", cfg.Kind)
+	}
+}
+
+// compose renders msg as an RFC 5322 message from sender
+func compose(from string, msg Message, now time.Time) ([]byte, error) {
+	if _, err := mail.ParseAddress(msg.To); err != nil {
+		return nil, ErrInvalidMessage
+	}
+	// Header injection
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, ErrInvalidMessage
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Text, "\r\n", "\n"), "\n", "\r\n"))
+	return buf.Bytes(), nil
+}
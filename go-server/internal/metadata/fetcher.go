@@ -0,0 +1,93 @@
+// Package metadata fetches page titles, Open Graph tags and favicons from link
+// destinations so links can be labelled without user input.
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/safehttp"
+)
+
+var ErrNotHTML = errors.New("destination is not an HTML page")
+
+const (
+	defaultFetchTimeout = 5 * time.Second
+	defaultMaxBodyBytes = 512 << 10
+	userAgent           = "TinyUrlBot/1.0 (+https://fonsecaaso.com)"
+)
+
+// FetcherConfig controls the limits applied when fetching a destination
+type FetcherConfig struct {
+	Timeout      time.Duration
+	MaxBodyBytes int64
+	// AllowPrivateNetworks lets tests fetch from httptest servers on loopback
+	AllowPrivateNetworks bool
+}
+
+type Fetcher struct {
+	client       *http.Client
+	maxBodyBytes int64
+}
+
+func NewFetcher(cfg FetcherConfig) *Fetcher {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultFetchTimeout
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = defaultMaxBodyBytes
+	}
+
+	return &Fetcher{
+		client: safehttp.NewClient(safehttp.Config{
+			Timeout:              cfg.Timeout,
+			AllowPrivateNetworks: cfg.AllowPrivateNetworks,
+		}),
+		maxBodyBytes: cfg.MaxBodyBytes,
+	}
+}
+
+// Fetch downloads at most MaxBodyBytes of rawURL and parses its metadata
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*model.LinkMetadata, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := safehttp.CheckURL(target); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, ErrNotHTML
+	}
+
+	// Use the final URL after redirects to resolve relative links
+	meta := parseHTML(io.LimitReader(resp.Body, f.maxBodyBytes), resp.Request.URL)
+	meta.FetchedAt = time.Now()
+	return meta, nil
+}
@@ -0,0 +1,194 @@
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/safehttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFetcher() *Fetcher {
+	return NewFetcher(FetcherConfig{
+		Timeout:              2 * time.Second,
+		MaxBodyBytes:         64 << 10,
+		AllowPrivateNetworks: true,
+	})
+}
+
+func serveHTML(body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(body))
+	}))
+}
+
+func TestFetch_OpenGraph(t *testing.T) {
+	srv := serveHTML(`<!doctype html><html><head>
+		<title>Plain title</title>
+		<meta property="og:title" content="OG Title">
+		<meta property="og:description" content="  An   OG description ">
+		<meta property="og:image" content="/img/cover.png">
+		<link rel="shortcut icon" href="/static/icon.png">
+	</head><body><title>ignored</title></body></html>`)
+	defer srv.Close()
+
+	meta, err := newTestFetcher().Fetch(context.Background(), srv.URL+"/page")
+
+	require.NoError(t, err)
+	assert.Equal(t, "OG Title", meta.Title)
+	assert.Equal(t, "An OG description", meta.Description)
+	assert.Equal(t, srv.URL+"/img/cover.png", meta.ImageURL)
+	assert.Equal(t, srv.URL+"/static/icon.png", meta.FaviconURL)
+	assert.False(t, meta.FetchedAt.IsZero())
+}
+
+func TestFetch_FallsBackToTitleAndDescription(t *testing.T) {
+	srv := serveHTML(`<html><head>
+		<title>
+			Plain   title
+		</title>
+		<meta name="description" content="Meta description">
+	</head></html>`)
+	defer srv.Close()
+
+	meta, err := newTestFetcher().Fetch(context.Background(), srv.URL)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Plain title", meta.Title)
+	assert.Equal(t, "Meta description", meta.Description)
+	assert.Equal(t, srv.URL+"/favicon.ico", meta.FaviconURL)
+	assert.Empty(t, meta.ImageURL)
+}
+
+func TestFetch_FollowsRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new/", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/new/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<head><title>New</title><link rel="icon" href="icon.svg"></head>`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	meta, err := newTestFetcher().Fetch(context.Background(), srv.URL+"/old")
+
+	require.NoError(t, err)
+	assert.Equal(t, "New", meta.Title)
+	assert.Equal(t, srv.URL+"/new/icon.svg", meta.FaviconURL)
+}
+
+func TestFetch_BodySizeCap(t *testing.T) {
+	// The title starts beyond the size cap and must not be read
+	srv := serveHTML("<html><head><!--" + strings.Repeat("x", 128<<10) + "--><title>Too far</title></head>")
+	defer srv.Close()
+
+	meta, err := newTestFetcher().Fetch(context.Background(), srv.URL)
+
+	require.NoError(t, err)
+	assert.Empty(t, meta.Title)
+}
+
+func TestFetch_NotHTML(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write([]byte("%PDF"))
+	}))
+	defer srv.Close()
+
+	_, err := newTestFetcher().Fetch(context.Background(), srv.URL)
+
+	assert.ErrorIs(t, err, ErrNotHTML)
+}
+
+func TestFetch_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	_, err := newTestFetcher().Fetch(context.Background(), srv.URL)
+
+	assert.Error(t, err)
+}
+
+func TestFetch_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	fetcher := NewFetcher(FetcherConfig{Timeout: 50 * time.Millisecond, AllowPrivateNetworks: true})
+	_, err := fetcher.Fetch(context.Background(), srv.URL)
+
+	assert.Error(t, err)
+}
+
+func TestFetch_RejectsPrivateDestinations(t *testing.T) {
+	srv := serveHTML(`<title>internal</title>`)
+	defer srv.Close()
+
+	fetcher := NewFetcher(FetcherConfig{})
+	_, err := fetcher.Fetch(context.Background(), srv.URL)
+
+	assert.ErrorIs(t, err, safehttp.ErrUnsafeDestination)
+}
+
+func TestFetch_RejectsNonHTTPSchemes(t *testing.T) {
+	_, err := newTestFetcher().Fetch(context.Background(), "file:///etc/passwd")
+
+	assert.ErrorIs(t, err, safehttp.ErrUnsafeDestination)
+}
+
+type memoryStore struct {
+	mu    sync.Mutex
+	saved map[string]*model.LinkMetadata
+	done  chan struct{}
+}
+
+func (m *memoryStore) SaveMetadata(ctx context.Context, id string, meta *model.LinkMetadata) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saved[id] = meta
+	close(m.done)
+	return nil
+}
+
+func TestWorker_StoresFetchedMetadata(t *testing.T) {
+	srv := serveHTML(`<head><title>Queued</title></head>`)
+	defer srv.Close()
+
+	store := &memoryStore{saved: make(map[string]*model.LinkMetadata), done: make(chan struct{})}
+	worker := NewWorker(newTestFetcher(), store, 1, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	worker.Start(ctx)
+
+	worker.Enqueue("abc123", srv.URL)
+
+	select {
+	case <-store.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("metadata was not stored")
+	}
+	cancel()
+	worker.Wait()
+
+	assert.Equal(t, "Queued", store.saved["abc123"].Title)
+}
+
+func TestWorker_DropsWhenQueueFull(t *testing.T) {
+	worker := NewWorker(newTestFetcher(), &memoryStore{}, 1, 1)
+
+	// Not started, so the second job cannot be queued
+	worker.Enqueue("a", "https://example.com")
+	worker.Enqueue("b", "https://example.com")
+
+	assert.Len(t, worker.jobs, 1)
+}
@@ -0,0 +1,139 @@
+package metadata
+
+import (
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"golang.org/x/net/html"
+)
+
+const maxFieldLength = 1000
+
+// parseHTML extracts title, Open Graph tags and favicon from the document head.
+// Relative URLs are resolved against base.
+func parseHTML(r io.Reader, base *url.URL) *model.LinkMetadata {
+	meta := &model.LinkMetadata{}
+	var htmlTitle, metaDescription, favicon string
+	inTitle := false
+
+	z := html.NewTokenizer(r)
+loop:
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			break loop
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			switch tok.Data {
+			case "title":
+				inTitle = htmlTitle == ""
+			case "meta":
+				key := strings.ToLower(attr(tok, "property"))
+				if key == "" {
+					key = strings.ToLower(attr(tok, "name"))
+				}
+				content := attr(tok, "content")
+				switch key {
+				case "og:title":
+					meta.Title = content
+				case "og:description":
+					meta.Description = content
+				case "og:image":
+					meta.ImageURL = resolve(base, content)
+				case "description":
+					metaDescription = content
+				}
+			case "link":
+				rel := strings.ToLower(attr(tok, "rel"))
+				if favicon == "" && containsWord(rel, "icon") {
+					favicon = resolve(base, attr(tok, "href"))
+				}
+			case "body":
+				// Everything we care about lives in <head>
+				break loop
+			}
+		case html.TextToken:
+			if inTitle {
+				htmlTitle += string(z.Text())
+			}
+		case html.EndTagToken:
+			tok := z.Token()
+			if tok.Data == "title" {
+				inTitle = false
+			}
+			if tok.Data == "head" {
+				break loop
+			}
+		}
+	}
+
+	if meta.Title == "" {
+		meta.Title = htmlTitle
+	}
+	if meta.Description == "" {
+		meta.Description = metaDescription
+	}
+	if favicon == "" {
+		favicon = resolve(base, "/favicon.ico")
+	}
+	meta.FaviconURL = favicon
+
+	meta.Title = clean(meta.Title)
+	meta.Description = clean(meta.Description)
+	meta.ImageURL = truncate(meta.ImageURL)
+	meta.FaviconURL = truncate(meta.FaviconURL)
+	return meta
+}
+
+func attr(tok html.Token, name string) string {
+	for _, a := range tok.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func containsWord(s, word string) bool {
+	for _, f := range strings.Fields(s) {
+		if f == word {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve makes ref absolute and drops anything that is not http(s)
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
+
+func clean(s string) string {
+	return truncate(strings.Join(strings.Fields(s), " "))
+}
+
+func truncate(s string) string {
+	if len(s) <= maxFieldLength {
+		return s
+	}
+	// Avoid cutting a multi-byte rune in half
+	cut := maxFieldLength
+	for cut > 0 && !isRuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,105 @@
+package metadata
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultWorkers   = 4
+	defaultQueueSize = 256
+	jobTimeout       = 15 * time.Second
+)
+
+// Store persists fetched metadata on a link
+type Store interface {
+	SaveMetadata(ctx context.Context, id string, meta *model.LinkMetadata) error
+}
+
+type job struct {
+	id  string
+	url string
+}
+
+// Worker fetches metadata for newly created links in the background
+type Worker struct {
+	fetcher *Fetcher
+	store   Store
+	jobs    chan job
+	workers int
+	wg      sync.WaitGroup
+	logger  *zap.Logger
+}
+
+func NewWorker(fetcher *Fetcher, store Store, workers, queueSize int) *Worker {
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+
+	return &Worker{
+		fetcher: fetcher,
+		store:   store,
+		jobs:    make(chan job, queueSize),
+		workers: workers,
+		logger:  zap.L().With(zap.String("component", "MetadataWorker")),
+	}
+}
+
+// Start launches the worker goroutines. They exit when ctx is cancelled;
+// queued jobs are dropped, the fetch would outlast a shutdown.
+func (w *Worker) Start(ctx context.Context) {
+	for i := 0; i < w.workers; i++ {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-w.jobs:
+					w.process(ctx, j)
+				}
+			}
+		}()
+	}
+}
+
+// Wait blocks until all worker goroutines have exited
+func (w *Worker) Wait() {
+	w.wg.Wait()
+}
+
+// Enqueue schedules a metadata fetch without blocking the caller.
+// Jobs are dropped when the queue is full, metadata is best effort.
+func (w *Worker) Enqueue(id, rawURL string) {
+	select {
+	case w.jobs <- job{id: id, url: rawURL}:
+	default:
+		w.logger.Warn("Metadata queue full, dropping job", zap.String("id", id))
+	}
+}
+
+func (w *Worker) process(ctx context.Context, j job) {
+	ctx, cancel := context.WithTimeout(ctx, jobTimeout)
+	defer cancel()
+
+	meta, err := w.fetcher.Fetch(ctx, j.url)
+	if err != nil {
+		w.logger.Info("Failed to fetch link metadata", zap.Error(err), zap.String("id", j.id))
+		return
+	}
+
+	if err := w.store.SaveMetadata(ctx, j.id, meta); err != nil {
+		w.logger.Warn("Failed to store link metadata", zap.Error(err), zap.String("id", j.id))
+		return
+	}
+
+	w.logger.Debug("Link metadata stored", zap.String("id", j.id), zap.String("title", meta.Title))
+}
@@ -30,6 +30,10 @@ var (
 	cacheHitsTotal   metric.Int64Counter
 	cacheMissesTotal metric.Int64Counter
 
+	// Link Health Metrics
+	linkHealthChecksTotal   metric.Int64Counter
+	linkHealthCheckDuration metric.Float64Histogram
+
 	// Database Metrics
 	dbQueryDuration metric.Float64Histogram
 
@@ -165,6 +169,36 @@ func InitMetrics() error {
 			return
 		}
 
+		// Link Health Metrics
+		linkHealthChecksTotal, err = meter.Int64Counter(
+			"link.health.checks.total",
+			metric.WithDescription("Total number of destination health checks by result"),
+		)
+		if err != nil {
+			initError = err
+			return
+		}
+
+		linkHealthCheckDuration, err = meter.Float64Histogram(
+			"link.health.check.duration",
+			metric.WithDescription("Destination health check duration in seconds"),
+			metric.WithUnit("s"),
+			metric.WithExplicitBucketBoundaries(
+				0.050, // 50ms
+				0.100, // 100ms
+				0.250, // 250ms
+				0.500, // 500ms
+				1.0,   // 1s
+				2.5,   // 2.5s
+				5.0,   // 5s
+				10.0,  // 10s
+			),
+		)
+		if err != nil {
+			initError = err
+			return
+		}
+
 		// Database Metrics
 		dbQueryDuration, err = meter.Float64Histogram(
 			"db.query.duration",
@@ -346,6 +380,16 @@ func RecordCacheMiss(ctx context.Context, cacheType string) {
 	cacheMissesTotal.Add(ctx, 1, metric.WithAttributes(attribute.String("cache_type", cacheType)))
 }
 
+// RecordLinkHealthCheck records the result of a destination health check
+func RecordLinkHealthCheck(ctx context.Context, result string, duration time.Duration) {
+	if !metricsReady {
+		return
+	}
+	attrs := metric.WithAttributes(attribute.String("result", result))
+	linkHealthChecksTotal.Add(ctx, 1, attrs)
+	linkHealthCheckDuration.Record(ctx, duration.Seconds(), attrs)
+}
+
 // RecordDBQueryDuration records database query duration
 func RecordDBQueryDuration(ctx context.Context, queryType string, duration time.Duration) {
 	if !metricsReady {
@@ -54,6 +54,15 @@ func AuthMiddleware() gin.HandlerFunc {
 }
 
 func GetUserIDFromContext(c *gin.Context) *uuid.UUID {
+	claims := bearerClaims(c)
+	if claims == nil {
+		return nil
+	}
+	return claims.UserID
+}
+
+// bearerClaims returns the claims of a valid bearer token, if any
+func bearerClaims(c *gin.Context) *token.CustomClaims {
 	auth := c.GetHeader("Authorization")
 	if !strings.HasPrefix(auth, "Bearer ") {
 		return nil
@@ -66,7 +75,7 @@ func GetUserIDFromContext(c *gin.Context) *uuid.UUID {
 		return nil
 	}
 
-	return claims.UserID
+	return claims
 }
 
 func GetClaimsFromContext(c *gin.Context) (*token.CustomClaims, error) {
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	maxIdempotentRequestBytes = 1 << 20
+)
+
+// IdempotencyMiddleware replays the stored response when a request is retried
+// with the same Idempotency-Key, and rejects reuse of a key with a different body.
+// Requests without the header are passed through untouched.
+func IdempotencyMiddleware(repo repository.IdempotencyRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Idempotency-Key must be at most 255 characters",
+				"code":  "INVALID_IDEMPOTENCY_KEY",
+			})
+			c.Abort()
+			return
+		}
+
+		// One byte more than allowed tells an oversize body apart; hashing a
+		// truncated one would let different requests share a fingerprint
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxIdempotentRequestBytes+1))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Failed to read request body",
+				"code":  "INVALID_PAYLOAD",
+			})
+			c.Abort()
+			return
+		}
+		if len(body) > maxIdempotentRequestBytes {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": "Request body is too large for an idempotent request",
+				"code":  "PAYLOAD_TOO_LARGE",
+			})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		scopedKey := idempotencyScope(c) + ":" + key
+		requestHash := hashIdempotentRequest(c.Request, body)
+
+		existing, reserved, err := repo.Reserve(ctx, scopedKey, requestHash)
+		if err != nil {
+			// Fail open: a cache outage should not block link creation
+			zap.L().Warn("Idempotency store unavailable, processing request without it", zap.Error(err))
+			c.Next()
+			return
+		}
+
+		if !reserved {
+			switch {
+			case existing.RequestHash != requestHash:
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"error": "Idempotency-Key was already used with a different request",
+					"code":  "IDEMPOTENCY_KEY_REUSED",
+				})
+			case !existing.Completed:
+				c.JSON(http.StatusConflict, gin.H{
+					"error": "A request with this Idempotency-Key is still being processed",
+					"code":  "IDEMPOTENCY_REQUEST_IN_PROGRESS",
+				})
+			default:
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(existing.StatusCode, existing.ContentType, existing.Body)
+			}
+			c.Abort()
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = rec
+
+		c.Next()
+
+		// Persist even if the client went away, that is exactly the retry case
+		storeCtx := context.WithoutCancel(ctx)
+		status := rec.Status()
+		if status >= http.StatusInternalServerError {
+			_ = repo.Release(storeCtx, scopedKey)
+			return
+		}
+
+		_ = repo.Save(storeCtx, scopedKey, &model.IdempotencyRecord{
+			RequestHash: requestHash,
+			StatusCode:  status,
+			ContentType: rec.Header().Get("Content-Type"),
+			Body:        rec.body.Bytes(),
+		})
+	}
+}
+
+// idempotencyScope keeps keys from different callers apart
+func idempotencyScope(c *gin.Context) string {
+	if userID := GetUserIDFromContext(c); userID != nil {
+		return "user:" + userID.String()
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func hashIdempotentRequest(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.Path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
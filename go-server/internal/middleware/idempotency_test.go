@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryIdempotencyRepository is an in-memory IdempotencyRepository for tests
+type memoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]*model.IdempotencyRecord
+	err     error
+}
+
+func newMemoryIdempotencyRepository() *memoryIdempotencyRepository {
+	return &memoryIdempotencyRepository{records: make(map[string]*model.IdempotencyRecord)}
+}
+
+func (m *memoryIdempotencyRepository) Reserve(ctx context.Context, key, requestHash string) (*model.IdempotencyRecord, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return nil, false, m.err
+	}
+	if existing, ok := m.records[key]; ok {
+		return existing, false, nil
+	}
+	m.records[key] = &model.IdempotencyRecord{RequestHash: requestHash}
+	return nil, true, nil
+}
+
+func (m *memoryIdempotencyRepository) Save(ctx context.Context, key string, record *model.IdempotencyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	record.Completed = true
+	m.records[key] = record
+	return nil
+}
+
+func (m *memoryIdempotencyRepository) Release(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, key)
+	return nil
+}
+
+func setupIdempotencyRouter(repo *memoryIdempotencyRepository, status int) (*gin.Engine, *int) {
+	calls := 0
+	router := gin.New()
+	router.POST("/api", IdempotencyMiddleware(repo), func(c *gin.Context) {
+		calls++
+		c.JSON(status, gin.H{"call": calls})
+	})
+	return router, &calls
+}
+
+func doIdempotentRequest(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, "/api", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_NoHeaderPassesThrough(t *testing.T) {
+	setupTest(t)
+	router, calls := setupIdempotencyRouter(newMemoryIdempotencyRepository(), http.StatusCreated)
+
+	doIdempotentRequest(router, "", `{"url":"example.com"}`)
+	doIdempotentRequest(router, "", `{"url":"example.com"}`)
+
+	assert.Equal(t, 2, *calls)
+}
+
+func TestIdempotency_ReplaysOriginalResponse(t *testing.T) {
+	setupTest(t)
+	router, calls := setupIdempotencyRouter(newMemoryIdempotencyRepository(), http.StatusCreated)
+
+	first := doIdempotentRequest(router, "key-1", `{"url":"example.com"}`)
+	second := doIdempotentRequest(router, "key-1", `{"url":"example.com"}`)
+
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get(IdempotentReplayedHeader))
+	assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+}
+
+func TestIdempotency_RejectsDifferentBody(t *testing.T) {
+	setupTest(t)
+	router, calls := setupIdempotencyRouter(newMemoryIdempotencyRepository(), http.StatusCreated)
+
+	doIdempotentRequest(router, "key-1", `{"url":"example.com"}`)
+	w := doIdempotentRequest(router, "key-1", `{"url":"other.com"}`)
+
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "IDEMPOTENCY_KEY_REUSED")
+}
+
+func TestIdempotency_InProgress(t *testing.T) {
+	setupTest(t)
+	repo := newMemoryIdempotencyRepository()
+	router, _ := setupIdempotencyRouter(repo, http.StatusCreated)
+
+	body := `{"url":"example.com"}`
+	req, _ := http.NewRequest(http.MethodPost, "/api", strings.NewReader(body))
+	// Simulate a concurrent request holding the key (test requests have no client IP)
+	_, _, _ = repo.Reserve(context.Background(), "ip::key-1", hashIdempotentRequest(req, []byte(body)))
+
+	w := doIdempotentRequest(router, "key-1", body)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "IDEMPOTENCY_REQUEST_IN_PROGRESS")
+}
+
+func TestIdempotency_ServerErrorReleasesKey(t *testing.T) {
+	setupTest(t)
+	repo := newMemoryIdempotencyRepository()
+	router, calls := setupIdempotencyRouter(repo, http.StatusInternalServerError)
+
+	doIdempotentRequest(router, "key-1", `{"url":"example.com"}`)
+	doIdempotentRequest(router, "key-1", `{"url":"example.com"}`)
+
+	assert.Equal(t, 2, *calls)
+	assert.Empty(t, repo.records)
+}
+
+func TestIdempotency_StoreErrorFailsOpen(t *testing.T) {
+	setupTest(t)
+	repo := newMemoryIdempotencyRepository()
+	repo.err = errors.New("redis down")
+	router, calls := setupIdempotencyRouter(repo, http.StatusCreated)
+
+	w := doIdempotentRequest(router, "key-1", `{"url":"example.com"}`)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 1, *calls)
+}
+
+func TestIdempotency_KeyTooLong(t *testing.T) {
+	setupTest(t)
+	router, calls := setupIdempotencyRouter(newMemoryIdempotencyRepository(), http.StatusCreated)
+
+	w := doIdempotentRequest(router, strings.Repeat("k", maxIdempotencyKeyLength+1), `{}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, 0, *calls)
+}
+
+func TestIdempotency_BodyTooLarge(t *testing.T) {
+	setupTest(t)
+	repo := newMemoryIdempotencyRepository()
+	router, calls := setupIdempotencyRouter(repo, http.StatusCreated)
+
+	w := doIdempotentRequest(router, "key-1", strings.Repeat("a", maxIdempotentRequestBytes+1))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, 0, *calls)
+	assert.Empty(t, repo.records)
+}
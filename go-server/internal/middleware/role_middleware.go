@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RequireRole lets through signed-in accounts that are not disabled and
+// have one of roles. The account is read on every request so a revoked
+// role or a disabled account takes effect before the token expires. Use it
+// after AuthMiddleware.
+func RequireRole(users repository.UserRepository, roles ...model.UserRole) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := loadAccount(c, users)
+		if !ok {
+			return
+		}
+		if user.DisabledAt != nil {
+			abortDisabled(c)
+			return
+		}
+		if !slices.Contains(roles, user.Role) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "insufficient role",
+				"code":  "FORBIDDEN",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RejectDisabledAccounts stops requests made with the token of a disabled
+// account. Anonymous requests are passed through, so it also fits routes
+// where signing in is optional.
+func RejectDisabledAccounts(users repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if GetUserIDFromContext(c) == nil {
+			c.Next()
+			return
+		}
+
+		user, ok := loadAccount(c, users)
+		if !ok {
+			return
+		}
+		if user.DisabledAt != nil {
+			abortDisabled(c)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RejectUnverifiedAccounts works like RejectDisabledAccounts and also stops
+// accounts that have not verified their email address yet
+func RejectUnverifiedAccounts(users repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if GetUserIDFromContext(c) == nil {
+			c.Next()
+			return
+		}
+
+		user, ok := loadAccount(c, users)
+		if !ok {
+			return
+		}
+		if user.DisabledAt != nil {
+			abortDisabled(c)
+			return
+		}
+		if !user.EmailVerified {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "email address not verified",
+				"code":  "EMAIL_NOT_VERIFIED",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// loadAccount reads the account of the request's token. Tokens of a revoked
+// session are rejected like invalid ones.
+func loadAccount(c *gin.Context, users repository.UserRepository) (*model.User, bool) {
+	claims := bearerClaims(c)
+	if claims == nil || claims.UserID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": ErrMissingUserID.Error(),
+			"code":  "MISSING_USER_ID",
+		})
+		c.Abort()
+		return nil, false
+	}
+
+	userID := claims.UserID
+	user, err := users.GetByID(c.Request.Context(), *userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": ErrInvalidToken.Error(),
+				"code":  "INVALID_TOKEN",
+			})
+		} else {
+			zap.L().Error("Failed to load account", zap.Error(err), zap.String("user_id", userID.String()))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+				"code":  "INTERNAL_ERROR",
+			})
+		}
+		c.Abort()
+		return nil, false
+	}
+	if claims.SessionVersion != user.SessionVersion {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": ErrInvalidToken.Error(),
+			"code":  "INVALID_TOKEN",
+		})
+		c.Abort()
+		return nil, false
+	}
+
+	return user, true
+}
+
+func abortDisabled(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"error": "account is disabled",
+		"code":  "ACCOUNT_DISABLED",
+	})
+	c.Abort()
+}
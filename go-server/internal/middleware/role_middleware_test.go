@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/token"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryUsers is an in-memory UserRepository for tests
+type memoryUsers map[uuid.UUID]*model.User
+
+func (m memoryUsers) Create(ctx context.Context, user *model.User) error {
+	return nil
+}
+
+func (m memoryUsers) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	return nil, repository.ErrUserNotFound
+}
+
+func (m memoryUsers) GetByID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	if user, ok := m[id]; ok {
+		return user, nil
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func bearer(t *testing.T, userID uuid.UUID) string {
+	tokenStr, err := token.GenerateToken(userID.String(), 0)
+	require.NoError(t, err)
+	return "Bearer " + tokenStr
+}
+
+func TestRequireRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Now()
+	admin, member, disabledAdmin, revokedAdmin, unknown := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	users := memoryUsers{
+		admin:         {ID: admin.String(), Role: model.UserRoleAdmin},
+		member:        {ID: member.String(), Role: model.UserRoleUser},
+		disabledAdmin: {ID: disabledAdmin.String(), Role: model.UserRoleAdmin, DisabledAt: &now},
+		revokedAdmin:  {ID: revokedAdmin.String(), Role: model.UserRoleAdmin, SessionVersion: 1},
+	}
+
+	router := gin.New()
+	router.GET("/admin", AuthMiddleware(), RequireRole(users, model.UserRoleAdmin), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	testCases := []struct {
+		name   string
+		userID uuid.UUID
+		status int
+		code   string
+	}{
+		{"admin", admin, http.StatusNoContent, ""},
+		{"regular user", member, http.StatusForbidden, "FORBIDDEN"},
+		{"disabled admin", disabledAdmin, http.StatusForbidden, "ACCOUNT_DISABLED"},
+		{"unknown account", unknown, http.StatusUnauthorized, "INVALID_TOKEN"},
+		{"revoked session", revokedAdmin, http.StatusUnauthorized, "INVALID_TOKEN"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			req.Header.Set("Authorization", bearer(t, tc.userID))
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.status, w.Code)
+			assert.Contains(t, w.Body.String(), tc.code)
+		})
+	}
+}
+
+func TestRejectDisabledAccounts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Now()
+	active, disabled := uuid.New(), uuid.New()
+	users := memoryUsers{
+		active:   {ID: active.String(), Role: model.UserRoleUser},
+		disabled: {ID: disabled.String(), Role: model.UserRoleUser, DisabledAt: &now},
+	}
+
+	router := gin.New()
+	router.POST("/api", RejectDisabledAccounts(users), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	testCases := []struct {
+		name   string
+		auth   string
+		status int
+	}{
+		{"anonymous", "", http.StatusCreated},
+		{"active account", bearer(t, active), http.StatusCreated},
+		{"disabled account", bearer(t, disabled), http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api", nil)
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.status, w.Code)
+		})
+	}
+}
+
+func TestRejectUnverifiedAccounts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Now()
+	verified, unverified, disabled := uuid.New(), uuid.New(), uuid.New()
+	users := memoryUsers{
+		verified:   {ID: verified.String(), Role: model.UserRoleUser, EmailVerified: true},
+		unverified: {ID: unverified.String(), Role: model.UserRoleUser},
+		disabled:   {ID: disabled.String(), Role: model.UserRoleUser, EmailVerified: true, DisabledAt: &now},
+	}
+
+	router := gin.New()
+	router.POST("/api", RejectUnverifiedAccounts(users), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	testCases := []struct {
+		name   string
+		auth   string
+		status int
+		code   string
+	}{
+		{"anonymous", "", http.StatusCreated, ""},
+		{"verified account", bearer(t, verified), http.StatusCreated, ""},
+		{"unverified account", bearer(t, unverified), http.StatusForbidden, "EMAIL_NOT_VERIFIED"},
+		{"disabled account", bearer(t, disabled), http.StatusForbidden, "ACCOUNT_DISABLED"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api", nil)
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.status, w.Code)
+			assert.Contains(t, w.Body.String(), tc.code)
+		})
+	}
+}
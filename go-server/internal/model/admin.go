@@ -0,0 +1,18 @@
+package model
+
+// AdminStats are site-wide counters for the admin dashboard. Live links
+// are neither disabled, archived nor deleted.
+type AdminStats struct {
+	Users         int64 `json:"users"`
+	DisabledUsers int64 `json:"disabled_users"`
+	NewUsers24h   int64 `json:"new_users_24h"`
+	Links         int64 `json:"links"`
+	LiveLinks     int64 `json:"live_links"`
+	DisabledLinks int64 `json:"disabled_links"`
+	ArchivedLinks int64 `json:"archived_links"`
+	DeletedLinks  int64 `json:"deleted_links"`
+	NewLinks24h   int64 `json:"new_links_24h"`
+	Clicks        int64 `json:"clicks"`
+	Workspaces    int64 `json:"workspaces"`
+	Domains       int64 `json:"domains"`
+}
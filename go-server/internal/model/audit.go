@@ -0,0 +1,57 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit actions, named <target>.<verb>
+const (
+	AuditLinkCreated       = "link.created"
+	AuditLinkUpdated       = "link.updated"
+	AuditLinkRules         = "link.rules_updated"
+	AuditLinkVariants      = "link.variants_updated"
+	AuditLinkSchedule      = "link.schedule_updated"
+	AuditLinkAvailability  = "link.availability_updated"
+	AuditLinkFallback      = "link.fallback_updated"
+	AuditLinkClaimed       = "link.claimed"
+	AuditLinkTransferred   = "link.transferred"
+	AuditLinkArchived      = "link.archived"
+	AuditLinkDeleted       = "link.deleted"
+	AuditLinkRestored      = "link.restored"
+	AuditLinkPurged        = "link.purged"
+	AuditLinkAdminDisable  = "link.admin_disabled"
+	AuditLinkAdminEnable   = "link.admin_enabled"
+	AuditLinkTakenDown     = "link.taken_down"
+	AuditReportDismissed   = "report.dismissed"
+	AuditTransferStarted   = "transfer.started"
+	AuditTransferDeclined  = "transfer.declined"
+	AuditTransferCancelled = "transfer.cancelled"
+	AuditUserRegistered    = "user.registered"
+	AuditUserVerified      = "user.email_verified"
+	AuditUserPasswordReset = "user.password_reset"
+	AuditUserMFAEnabled    = "user.mfa_enabled"
+	AuditUserMFADisabled   = "user.mfa_disabled"
+	AuditUserMFACodesReset = "user.mfa_recovery_codes_reset"
+	AuditUserDisabled      = "user.disabled"
+	AuditUserEnabled       = "user.enabled"
+)
+
+// AuditEntry records one change: who (ActorID, nil for anonymous visitors)
+// did what to which target, with its state before and after. RequestID and
+// IP identify the HTTP request it came from.
+type AuditEntry struct {
+	ID          int64           `json:"id" db:"id"`
+	ActorID     *uuid.UUID      `json:"actor_id,omitempty" db:"actor_id"`
+	Action      string          `json:"action" db:"action"`
+	TargetType  string          `json:"target_type" db:"target_type"`
+	TargetID    string          `json:"target_id" db:"target_id"`
+	WorkspaceID *uuid.UUID      `json:"workspace_id,omitempty" db:"workspace_id"`
+	Before      json.RawMessage `json:"before,omitempty" db:"before"`
+	After       json.RawMessage `json:"after,omitempty" db:"after"`
+	RequestID   string          `json:"request_id,omitempty" db:"request_id"`
+	IP          string          `json:"ip,omitempty" db:"ip"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+}
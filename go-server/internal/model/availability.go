@@ -0,0 +1,94 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LinkState describes whether a link can be resolved and, if not, why
+type LinkState string
+
+const (
+	LinkActive  LinkState = "active"
+	LinkExpired LinkState = "expired"
+	// LinkExhausted links have reached their click limit
+	LinkExhausted LinkState = "click_limit_reached"
+	LinkDisabled  LinkState = "disabled"
+	// LinkBlocked links are unavailable for legal reasons
+	LinkBlocked LinkState = "blocked"
+	// LinkTakenDown links were disabled by an admin after an abuse report
+	LinkTakenDown LinkState = "taken_down"
+	// LinkArchived and LinkDeleted links are kept for restoring until purged
+	LinkArchived LinkState = "archived"
+	LinkDeleted  LinkState = "deleted"
+)
+
+// Reasons stored with a disabled link
+const (
+	DisabledByOwner = "owner"
+	DisabledLegal   = "legal"
+	// DisabledByAdmin links were taken down by a site admin
+	DisabledByAdmin = "admin"
+	// DisabledForAbuse links were taken down after an abuse report
+	DisabledForAbuse = "abuse"
+)
+
+// Fallback is shown to visitors of an unavailable link. With a URL the
+// visitor is sent there, otherwise the branded page is rendered.
+type Fallback struct {
+	URL  string        `json:"url,omitempty"`
+	Page *FallbackPage `json:"page,omitempty"`
+}
+
+// FallbackPage holds the branding of the page rendered for unavailable links.
+// Empty fields fall back to defaults for the link's state.
+type FallbackPage struct {
+	Title       string `json:"title,omitempty"`
+	Message     string `json:"message,omitempty"`
+	LogoURL     string `json:"logo_url,omitempty"`
+	ButtonText  string `json:"button_text,omitempty"`
+	ButtonURL   string `json:"button_url,omitempty"`
+	AccentColor string `json:"accent_color,omitempty"`
+}
+
+// FallbackDefault is the fallback applied to every link of a workspace
+type FallbackDefault struct {
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+	Fallback
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Moderated reports whether the link was disabled by the site rather than
+// its owner: by an admin, for legal reasons or after an abuse report
+func (u *URL) Moderated() bool {
+	if !u.Disabled {
+		return false
+	}
+	switch u.DisabledReason {
+	case DisabledByAdmin, DisabledLegal, DisabledForAbuse:
+		return true
+	}
+	return false
+}
+
+// State reports whether the link can be resolved at now. The click limit is
+// enforced when a click is counted, see ClickCount.
+func (u *URL) State(now time.Time) LinkState {
+	switch {
+	case u.DeletedAt != nil:
+		return LinkDeleted
+	case u.ArchivedAt != nil:
+		return LinkArchived
+	case u.Disabled && u.DisabledReason == DisabledLegal:
+		return LinkBlocked
+	case u.Disabled && u.DisabledReason == DisabledForAbuse:
+		return LinkTakenDown
+	case u.Disabled:
+		return LinkDisabled
+	case u.ExpiresAt != nil && !now.Before(*u.ExpiresAt):
+		return LinkExpired
+	default:
+		return LinkActive
+	}
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Prefixes of the DNS TXT record proving control of a custom domain
+const (
+	DomainVerificationPrefix = "_tinyurl-challenge."
+	DomainVerificationValue  = "tinyurl-verification="
+)
+
+// Domain is a custom hostname an account serves its links from. Until
+// VerifiedAt is set it is not used to resolve links.
+type Domain struct {
+	ID                uuid.UUID  `json:"id" db:"id"`
+	UserID            uuid.UUID  `json:"user_id" db:"user_id"`
+	Hostname          string     `json:"hostname" db:"hostname"`
+	VerificationToken string     `json:"-" db:"verification_token"`
+	VerifiedAt        *time.Time `json:"verified_at,omitempty" db:"verified_at"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Verified reports whether ownership of the domain was proven
+func (d *Domain) Verified() bool {
+	return d.VerifiedAt != nil
+}
+
+// VerificationRecord returns the name and value of the TXT record the owner
+// has to publish to verify the domain
+func (d *Domain) VerificationRecord() (name, value string) {
+	return DomainVerificationPrefix + d.Hostname, DomainVerificationValue + d.VerificationToken
+}
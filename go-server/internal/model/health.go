@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// LinkHealth is the outcome of the last destination health check
+type LinkHealth struct {
+	StatusCode    int       `json:"status_code,omitempty"`
+	RedirectChain []string  `json:"redirect_chain,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	Healthy       bool      `json:"healthy"`
+	CheckedAt     time.Time `json:"checked_at"`
+}
+
+// IsHealthyStatus reports whether a final response status means the destination works
+func IsHealthyStatus(code int) bool {
+	return code >= 200 && code < 400
+}
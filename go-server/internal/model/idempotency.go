@@ -0,0 +1,10 @@
+package model
+
+// IdempotencyRecord stores the outcome of a request made with an Idempotency-Key
+type IdempotencyRecord struct {
+	RequestHash string `json:"request_hash"`
+	Completed   bool   `json:"completed"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
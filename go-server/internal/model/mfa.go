@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// MFAEnrollment is what an authenticator app needs to generate codes for
+// an account. URI is usually shown as a QR code.
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_url"`
+}
+
+// MFASettings is the authenticator of an account. It only takes effect
+// from EnabledAt, once a code confirmed the owner set it up.
+type MFASettings struct {
+	Secret    string
+	EnabledAt *time.Time
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// LinkEventType names a change to a link recorded in the outbox
+type LinkEventType string
+
+// Click counts and health checks change with every visit and probe and are
+// not published as link events.
+const (
+	LinkEventCreated             LinkEventType = "link.created"
+	LinkEventDetailsUpdated      LinkEventType = "link.details_updated"
+	LinkEventTagsUpdated         LinkEventType = "link.tags_updated"
+	LinkEventRulesUpdated        LinkEventType = "link.rules_updated"
+	LinkEventVariantsUpdated     LinkEventType = "link.variants_updated"
+	LinkEventScheduleUpdated     LinkEventType = "link.schedule_updated"
+	LinkEventAvailabilityUpdated LinkEventType = "link.availability_updated"
+	LinkEventFallbackUpdated     LinkEventType = "link.fallback_updated"
+	LinkEventClaimed             LinkEventType = "link.claimed"
+	LinkEventTransferred         LinkEventType = "link.transferred"
+	LinkEventModerated           LinkEventType = "link.moderated"
+	LinkEventArchived            LinkEventType = "link.archived"
+	LinkEventDeleted             LinkEventType = "link.deleted"
+	LinkEventRestored            LinkEventType = "link.restored"
+	LinkEventPurged              LinkEventType = "link.purged"
+	LinkEventMetadataFetched     LinkEventType = "link.metadata_fetched"
+)
+
+// OutboxEvent is a link event waiting to be published. IDs grow with every
+// event, so consumers can use them to drop the duplicates at-least-once
+// delivery may produce and to check the order of a link's events.
+type OutboxEvent struct {
+	ID         int64           `json:"id"`
+	LinkID     string          `json:"link_id"`
+	Type       LinkEventType   `json:"type"`
+	Data       json.RawMessage `json:"data"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReportReason string
+
+const (
+	ReportPhishing ReportReason = "phishing"
+	ReportMalware  ReportReason = "malware"
+	ReportSpam     ReportReason = "spam"
+	ReportIllegal  ReportReason = "illegal"
+	ReportOther    ReportReason = "other"
+)
+
+// Valid reports whether r is one of the known reasons
+func (r ReportReason) Valid() bool {
+	switch r {
+	case ReportPhishing, ReportMalware, ReportSpam, ReportIllegal, ReportOther:
+		return true
+	}
+	return false
+}
+
+type ReportStatus string
+
+const (
+	ReportOpen      ReportStatus = "open"
+	ReportDismissed ReportStatus = "dismissed"
+	ReportTakenDown ReportStatus = "taken_down"
+)
+
+// Report is an abuse report on a link, queued for admin review. URL,
+// LinkDisabled and OpenReports describe the reported link when listing the
+// queue. Reports are kept when their link is purged, URLID then being empty.
+type Report struct {
+	ID           uuid.UUID    `json:"id" db:"id"`
+	URLID        string       `json:"url_id" db:"url_id"`
+	Reason       ReportReason `json:"reason" db:"reason"`
+	Description  string       `json:"description,omitempty" db:"description"`
+	ReporterID   *uuid.UUID   `json:"reporter_id,omitempty" db:"reporter_id"`
+	ReporterIP   string       `json:"reporter_ip,omitempty" db:"reporter_ip"`
+	Status       ReportStatus `json:"status" db:"status"`
+	CreatedAt    time.Time    `json:"created_at" db:"created_at"`
+	ReviewedAt   *time.Time   `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	ReviewedBy   *uuid.UUID   `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	URL          string       `json:"url,omitempty" db:"-"`
+	LinkDisabled bool         `json:"link_disabled" db:"-"`
+	OpenReports  int          `json:"open_reports,omitempty" db:"-"`
+}
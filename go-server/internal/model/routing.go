@@ -0,0 +1,14 @@
+package model
+
+// RoutingRule sends visitors matching all of its non-empty conditions to URL.
+// Rules are evaluated in order and the first match wins. Language is a BCP 47
+// tag matched against the visitor's preferred Accept-Language, Country an ISO
+// 3166-1 alpha-2 code resolved from the client IP.
+type RoutingRule struct {
+	OS       string `json:"os,omitempty" db:"os"`
+	Device   string `json:"device,omitempty" db:"device"`
+	Browser  string `json:"browser,omitempty" db:"browser"`
+	Language string `json:"language,omitempty" db:"language"`
+	Country  string `json:"country,omitempty" db:"country"`
+	URL      string `json:"url" db:"destination"`
+}
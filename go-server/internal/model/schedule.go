@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+// ScheduledDestination switches a link to URL from EffectiveAt on
+type ScheduledDestination struct {
+	URL         string    `json:"url" db:"destination"`
+	EffectiveAt time.Time `json:"effective_at" db:"effective_at"`
+}
+
+// ActiveDestination returns the URL a link points to at now, taking the
+// latest schedule entry that has come into effect
+func (u *URL) ActiveDestination(now time.Time) string {
+	dest := u.OriginalURL
+	var latest time.Time
+	for _, change := range u.Schedule {
+		if !change.EffectiveAt.After(now) && !change.EffectiveAt.Before(latest) {
+			dest, latest = change.URL, change.EffectiveAt
+		}
+	}
+	return dest
+}
+
+// IsActive reports whether the link has reached its starts_at
+func (u *URL) IsActive(now time.Time) bool {
+	return u.StartsAt == nil || !now.Before(*u.StartsAt)
+}
+
+// NextTransition returns the next moment the link's resolution changes, the
+// zero time when nothing is scheduled
+func (u *URL) NextTransition(now time.Time) time.Time {
+	var next time.Time
+	consider := func(t time.Time) {
+		if t.After(now) && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	if u.StartsAt != nil {
+		consider(*u.StartsAt)
+	}
+	if u.ExpiresAt != nil {
+		consider(*u.ExpiresAt)
+	}
+	for _, change := range u.Schedule {
+		consider(change.EffectiveAt)
+	}
+	return next
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tag is a user-defined label that can be attached to many URLs
+type Tag struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	UserID    uuid.UUID `json:"-" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	URLCount  int       `json:"url_count" db:"-"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
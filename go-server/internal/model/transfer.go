@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TransferStatus string
+
+const (
+	TransferPending   TransferStatus = "pending"
+	TransferAccepted  TransferStatus = "accepted"
+	TransferDeclined  TransferStatus = "declined"
+	TransferCancelled TransferStatus = "cancelled"
+)
+
+// Transfer hands links over to the account signed in with ToEmail once it
+// accepts. Transfers are never deleted and double as the record of who
+// moved which links when. LinkIDs are the links offered while pending and
+// the links that actually moved once accepted.
+type Transfer struct {
+	ID          uuid.UUID      `json:"id" db:"id"`
+	FromUserID  uuid.UUID      `json:"from_user_id" db:"from_user_id"`
+	ToEmail     string         `json:"to_email" db:"to_email"`
+	ToUserID    *uuid.UUID     `json:"to_user_id,omitempty" db:"to_user_id"`
+	Status      TransferStatus `json:"status" db:"status"`
+	LinkIDs     []string       `json:"link_ids" db:"-"`
+	CreatedAt   time.Time      `json:"created_at" db:"created_at"`
+	ExpiresAt   time.Time      `json:"expires_at" db:"expires_at"`
+	RespondedAt *time.Time     `json:"responded_at,omitempty" db:"responded_at"`
+}
@@ -8,8 +8,54 @@ import (
 
 // URL represents a shortened URL entry in the system
 type URL struct {
-	ID          string     `json:"id" db:"id"`
+	ID string `json:"id" db:"id"`
+	// Code is the path segment visitors use, unique within the link's
+	// Domain; on the shared domain it equals ID
+	Code        string     `json:"code,omitempty" db:"code"`
+	DomainID    *uuid.UUID `json:"domain_id,omitempty" db:"domain_id"`
+	Domain      string     `json:"domain,omitempty" db:"-"`
 	OriginalURL string     `json:"url" db:"url" validate:"required,url"`
 	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	// UserID is the creator. Links of signed-in users belong to a
+	// workspace.
 	UserID      *uuid.UUID `json:"user_id,omitempty" db:"user_id,omitempty"`
+	WorkspaceID *uuid.UUID `json:"workspace_id,omitempty" db:"workspace_id"`
+	Title       string     `json:"title,omitempty" db:"title"`
+	Description string     `json:"description,omitempty" db:"description"`
+	Tags        []string   `json:"tags,omitempty" db:"-"`
+	// ForwardQuery and ForwardPath pass the visitor's query string and extra
+	// path segments on to the destination
+	ForwardQuery bool          `json:"forward_query" db:"forward_query"`
+	ForwardPath  bool          `json:"forward_path" db:"forward_path"`
+	UTM          *UTMParams    `json:"utm,omitempty" db:"-"`
+	Rules        []RoutingRule `json:"rules,omitempty" db:"-"`
+	Variants     []Variant     `json:"variants,omitempty" db:"-"`
+	// StickyVariants keeps a visitor on the same variant across visits
+	StickyVariants bool `json:"sticky_variants,omitempty" db:"sticky_variants"`
+	// StartsAt is when the link starts resolving
+	StartsAt *time.Time `json:"starts_at,omitempty" db:"starts_at"`
+	// Schedule switches the destination at set times
+	Schedule []ScheduledDestination `json:"schedule,omitempty" db:"-"`
+	// ExpiresAt, MaxClicks and Disabled end the link's life, after which
+	// visitors get its Fallback
+	ExpiresAt      *time.Time    `json:"expires_at,omitempty" db:"expires_at"`
+	MaxClicks      *int64        `json:"max_clicks,omitempty" db:"max_clicks"`
+	ClickCount     int64         `json:"click_count" db:"click_count"`
+	Disabled       bool          `json:"disabled" db:"disabled"`
+	DisabledReason string        `json:"disabled_reason,omitempty" db:"disabled_reason"`
+	Fallback       *Fallback     `json:"fallback,omitempty" db:"fallback"`
+	Metadata       *LinkMetadata `json:"metadata,omitempty" db:"-"`
+	Health         *LinkHealth   `json:"health,omitempty" db:"-"`
+	// ArchivedAt and DeletedAt take the link out of service until it is
+	// restored or purged
+	ArchivedAt *time.Time `json:"archived_at,omitempty" db:"archived_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	// ClaimToken is set on anonymous links when they are created and
+	// attaches them to an account later, unless they were handed out to
+	// another anonymous creator first
+	ClaimToken string `json:"-" db:"-"`
+	ClaimHash  string `json:"-" db:"claim_token_hash"`
+	// Reusable links, created without per-link options, are handed out
+	// again when their destination is shortened once more
+	Reusable bool `json:"-" db:"reusable"`
 }
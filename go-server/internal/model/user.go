@@ -2,10 +2,27 @@ package model
 
 import "time"
 
+// UserRole is an account's site-wide role, unrelated to workspace roles
+type UserRole string
+
+const (
+	UserRoleUser  UserRole = "user"
+	UserRoleAdmin UserRole = "admin"
+)
+
+// User is an account. Disabled accounts cannot sign in or use the API.
+// EmailVerified is set once the owner followed the link mailed to Email.
+// MFAEnabled accounts sign in with a code from their authenticator too.
+// Sessions issued before the last change of SessionVersion are revoked.
 type User struct {
-	ID           string    `json:"id"`
-	Username     string    `json:"username"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID             string     `json:"id"`
+	Username       string     `json:"username"`
+	Email          string     `json:"email"`
+	EmailVerified  bool       `json:"email_verified"`
+	MFAEnabled     bool       `json:"mfa_enabled"`
+	PasswordHash   string     `json:"-"`
+	Role           UserRole   `json:"role"`
+	DisabledAt     *time.Time `json:"disabled_at,omitempty"`
+	SessionVersion int        `json:"-"`
+	CreatedAt      time.Time  `json:"created_at"`
 }
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UTMParams are the Google Analytics campaign parameters of a link
+type UTMParams struct {
+	Source   string `json:"utm_source,omitempty" db:"utm_source"`
+	Medium   string `json:"utm_medium,omitempty" db:"utm_medium"`
+	Campaign string `json:"utm_campaign,omitempty" db:"utm_campaign"`
+	Term     string `json:"utm_term,omitempty" db:"utm_term"`
+	Content  string `json:"utm_content,omitempty" db:"utm_content"`
+}
+
+// IsEmpty reports whether no parameter is set
+func (p UTMParams) IsEmpty() bool {
+	return p == UTMParams{}
+}
+
+// UTMTemplate holds the default UTM parameters applied to new links of a
+// workspace
+type UTMTemplate struct {
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+	UTMParams
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// Variant is one weighted destination of an A/B split link
+type Variant struct {
+	URL    string `json:"url" db:"destination"`
+	Weight int    `json:"weight" db:"weight"`
+}
+
+// Click records a resolved visit. Variant is the index of the destination
+// that served it and VariantURL that destination, both unset when the link
+// has no variants. Owner is whose webhooks hear of it.
+type Click struct {
+	URLID      string    `json:"url_id" db:"url_id"`
+	Variant    *int      `json:"variant,omitempty" db:"variant"`
+	VariantURL string    `json:"variant_url,omitempty" db:"variant_url"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	Owner      LinkOwner `json:"-" db:"-"`
+}
+
+// LinkStats summarizes the clicks of a link
+type LinkStats struct {
+	TotalClicks int64          `json:"total_clicks"`
+	Variants    []VariantStats `json:"variants,omitempty"`
+}
+
+// VariantStats is the click count of a single A/B destination
+type VariantStats struct {
+	Variant int    `json:"variant"`
+	URL     string `json:"url"`
+	Weight  int    `json:"weight"`
+	Clicks  int64  `json:"clicks"`
+}
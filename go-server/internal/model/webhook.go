@@ -0,0 +1,94 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookEvent is the kind of change a webhook is notified of
+type WebhookEvent string
+
+const (
+	EventLinkCreated WebhookEvent = "link.created"
+	EventLinkUpdated WebhookEvent = "link.updated"
+	EventLinkDeleted WebhookEvent = "link.deleted"
+	EventLinkClicked WebhookEvent = "link.clicked"
+)
+
+func (e WebhookEvent) Valid() bool {
+	switch e {
+	case EventLinkCreated, EventLinkUpdated, EventLinkDeleted, EventLinkClicked:
+		return true
+	}
+	return false
+}
+
+// Webhook is an endpoint of an account notified of the Events it subscribed
+// to on the links of the workspaces the account belongs to, and on those it
+// created outside any workspace. Secret is only shown on creation.
+type Webhook struct {
+	ID        uuid.UUID      `json:"id" db:"id"`
+	UserID    uuid.UUID      `json:"user_id" db:"user_id"`
+	URL       string         `json:"url" db:"url"`
+	Secret    string         `json:"secret,omitempty" db:"secret"`
+	Events    []WebhookEvent `json:"events" db:"events"`
+	Active    bool           `json:"active" db:"active"`
+	CreatedAt time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// Subscribed reports whether the webhook wants event
+func (w *Webhook) Subscribed(event WebhookEvent) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// LinkOwner is who hears of the events of a link: the current members of
+// WorkspaceID, or UserID when the link is in no workspace
+type LinkOwner struct {
+	WorkspaceID *uuid.UUID
+	UserID      *uuid.UUID
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// WebhookEnvelope is the JSON body of every delivery. ID identifies the
+// event, so receivers can drop repeats of the same event.
+type WebhookEnvelope struct {
+	ID         uuid.UUID       `json:"id"`
+	Event      WebhookEvent    `json:"event"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// WebhookDelivery is one event queued for one webhook, along with the
+// outcome of its last attempt. Pending deliveries are retried at
+// NextAttemptAt. URL and Secret are those of the webhook, loaded for sending.
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id" db:"id"`
+	WebhookID      uuid.UUID       `json:"webhook_id" db:"webhook_id"`
+	Event          WebhookEvent    `json:"event" db:"event"`
+	Payload        json.RawMessage `json:"payload" db:"payload"`
+	Status         DeliveryStatus  `json:"status" db:"status"`
+	Attempts       int             `json:"attempts" db:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at" db:"next_attempt_at"`
+	LastStatusCode *int            `json:"last_status_code,omitempty" db:"last_status_code"`
+	LastError      string          `json:"last_error,omitempty" db:"last_error"`
+	ReplayOf       *uuid.UUID      `json:"replay_of,omitempty" db:"replay_of"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty" db:"delivered_at"`
+	URL            string          `json:"-" db:"-"`
+	Secret         string          `json:"-" db:"-"`
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Role is a member's level of access to a workspace and its links
+type Role string
+
+const (
+	// RoleOwner can do everything, including managing other owners
+	RoleOwner Role = "owner"
+	// RoleAdmin manages members and invitations besides editing links
+	RoleAdmin Role = "admin"
+	// RoleEditor creates and edits links
+	RoleEditor Role = "editor"
+	// RoleViewer only sees links and their stats
+	RoleViewer Role = "viewer"
+)
+
+var roleRanks = map[Role]int{RoleViewer: 1, RoleEditor: 2, RoleAdmin: 3, RoleOwner: 4}
+
+// Valid reports whether r is one of the known roles
+func (r Role) Valid() bool {
+	return roleRanks[r] > 0
+}
+
+// AtLeast reports whether r grants everything min does
+func (r Role) AtLeast(min Role) bool {
+	return r.Valid() && roleRanks[r] >= roleRanks[min]
+}
+
+// Workspace groups links shared by its members. Every account has a
+// personal workspace that only it belongs to. Role is the requesting
+// member's role when listing.
+type Workspace struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Personal  bool      `json:"personal" db:"personal"`
+	Role      Role      `json:"role,omitempty" db:"-"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type WorkspaceMember struct {
+	WorkspaceID uuid.UUID `json:"workspace_id" db:"workspace_id"`
+	UserID      uuid.UUID `json:"user_id" db:"user_id"`
+	Username    string    `json:"username,omitempty" db:"username"`
+	Email       string    `json:"email" db:"email"`
+	Role        Role      `json:"role" db:"role"`
+	JoinedAt    time.Time `json:"joined_at" db:"created_at"`
+}
+
+// Invitation lets whoever holds its token and signs in with Email join the
+// workspace with Role. Only a hash of the token is stored.
+type Invitation struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	WorkspaceID uuid.UUID  `json:"workspace_id" db:"workspace_id"`
+	Email       string     `json:"email" db:"email"`
+	Role        Role       `json:"role" db:"role"`
+	InvitedBy   uuid.UUID  `json:"invited_by" db:"invited_by"`
+	ExpiresAt   time.Time  `json:"expires_at" db:"expires_at"`
+	AcceptedAt  *time.Time `json:"accepted_at,omitempty" db:"accepted_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
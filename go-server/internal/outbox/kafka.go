@@ -0,0 +1,98 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+)
+
+const (
+	kafkaContentType = "application/vnd.kafka.json.v2+json"
+	kafkaAccept      = "application/vnd.kafka.v2+json"
+	maxErrorBody     = 512
+)
+
+// KafkaRESTPublisher produces events to a Kafka topic through the REST
+// proxy API (v2) that Confluent REST Proxy and Redpanda serve. Records are
+// keyed by link id, so the events of a link land on one partition and keep
+// their order.
+type KafkaRESTPublisher struct {
+	endpoint string
+	client   *http.Client
+}
+
+func NewKafkaRESTPublisher(baseURL, topic string, client *http.Client) *KafkaRESTPublisher {
+	return &KafkaRESTPublisher{
+		endpoint: strings.TrimRight(baseURL, "/") + "/topics/" + url.PathEscape(topic),
+		client:   client,
+	}
+}
+
+type kafkaRecords struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+type kafkaRecord struct {
+	Key   string            `json:"key"`
+	Value model.OutboxEvent `json:"value"`
+}
+
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		ErrorCode *int    `json:"error_code"`
+		Error     *string `json:"error"`
+	} `json:"offsets"`
+}
+
+func (p *KafkaRESTPublisher) Publish(ctx context.Context, event model.OutboxEvent) error {
+	body, err := json.Marshal(kafkaRecords{Records: []kafkaRecord{{Key: event.LinkID, Value: event}}})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", kafkaContentType)
+	req.Header.Set("Accept", kafkaAccept)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return fmt.Errorf("kafka proxy returned %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	}
+
+	var produced kafkaProduceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&produced); err != nil {
+		return fmt.Errorf("failed to decode kafka proxy response: %w", err)
+	}
+	if len(produced.Offsets) != 1 {
+		return fmt.Errorf("kafka proxy acknowledged %d records, want 1", len(produced.Offsets))
+	}
+	if offset := produced.Offsets[0]; offset.ErrorCode != nil {
+		msg := ""
+		if offset.Error != nil {
+			msg = *offset.Error
+		}
+		return fmt.Errorf("kafka rejected the record (%d): %s", *offset.ErrorCode, msg)
+	}
+	return nil
+}
+
+// Close does nothing, the proxy holds no connection state
+func (p *KafkaRESTPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,102 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/go-redis/redis/v8"
+)
+
+const defaultTarget = "tinyurl.links"
+
+// Config selects the broker events are published to. Kind is nats, kafka,
+// redis, file or stdout. Target is the NATS subject prefix, the Kafka topic
+// or the Redis stream and defaults to tinyurl.links.
+type Config struct {
+	Kind string
+	// NATSURL is the server to connect to, e.g. nats://localhost:4222
+	NATSURL string
+	// KafkaRESTURL is the base URL of a Kafka REST proxy, e.g. Confluent
+	// REST Proxy or the Redpanda HTTP proxy
+	KafkaRESTURL string
+	Target       string
+	// FilePath is the file the file publisher appends to
+	FilePath string
+}
+
+// NewPublisher builds the publisher cfg selects. The redis publisher writes
+// through redisClient.
+func NewPublisher(cfg Config, redisClient *redis.Client) (Publisher, error) {
+	if cfg.Target == "" {
+		cfg.Target = defaultTarget
+	}
+
+	switch cfg.Kind {
+	case "nats":
+		return NewNATSPublisher(cfg.NATSURL, cfg.Target)
+	case "kafka":
+		if cfg.KafkaRESTURL == "" {
+			return nil, fmt.Errorf("the kafka publisher needs a REST proxy URL")
+		}
+		return NewKafkaRESTPublisher(cfg.KafkaRESTURL, cfg.Target, &http.Client{Timeout: defaultPublishTimeout}), nil
+	case "redis":
+		if redisClient == nil {
+			return nil, fmt.Errorf("the redis publisher needs a Redis client")
+		}
+		return NewRedisStreamPublisher(redisClient, cfg.Target, 0), nil
+	case "file":
+		return OpenFilePublisher(cfg.FilePath)
+	case "stdout":
+		return NewFilePublisher(os.Stdout), nil
+	default:
+		return nil, fmt.Errorf("unknown event publisher %q", cfg.Kind)
+	}
+}
+
+// FilePublisher writes events as JSON lines, for local use
+type FilePublisher struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+func NewFilePublisher(w io.Writer) *FilePublisher {
+	return &FilePublisher{w: w}
+}
+
+// OpenFilePublisher appends events to the file at path, creating it if needed
+func OpenFilePublisher(path string) (*FilePublisher, error) {
+	if path == "" {
+		return nil, fmt.Errorf("the file publisher needs a path")
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FilePublisher{w: f, closer: f}, nil
+}
+
+func (p *FilePublisher) Publish(ctx context.Context, event model.OutboxEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err = p.w.Write(append(line, '\n'))
+	return err
+}
+
+func (p *FilePublisher) Close() error {
+	if p.closer == nil {
+		return nil
+	}
+	return p.closer.Close()
+}
@@ -0,0 +1,101 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilePublisher_WritesJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	publisher := NewFilePublisher(&buf)
+
+	require.NoError(t, publisher.Publish(context.Background(), newEvent(1, "abc123", model.LinkEventCreated)))
+	require.NoError(t, publisher.Publish(context.Background(), newEvent(2, "abc123", model.LinkEventPurged)))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	var event model.OutboxEvent
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &event))
+	assert.Equal(t, int64(2), event.ID)
+	assert.Equal(t, "abc123", event.LinkID)
+	assert.Equal(t, model.LinkEventPurged, event.Type)
+}
+
+func TestNewPublisher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	publisher, err := NewPublisher(Config{Kind: "file", FilePath: path}, nil)
+	require.NoError(t, err)
+	require.NoError(t, publisher.Publish(context.Background(), newEvent(1, "abc123", model.LinkEventCreated)))
+	require.NoError(t, publisher.Close())
+
+	_, err = NewPublisher(Config{Kind: "kafka"}, nil)
+	assert.Error(t, err)
+	_, err = NewPublisher(Config{Kind: "redis"}, nil)
+	assert.Error(t, err)
+	_, err = NewPublisher(Config{Kind: "carrier-pigeon"}, nil)
+	assert.Error(t, err)
+}
+
+func TestKafkaRESTPublisher_KeysByLink(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`{"offsets":[{"partition":3,"offset":42,"error_code":null,"error":null}]}`))
+	}))
+	defer srv.Close()
+
+	publisher := NewKafkaRESTPublisher(srv.URL+"/", "tinyurl.links", srv.Client())
+	require.NoError(t, publisher.Publish(context.Background(), newEvent(7, "abc123", model.LinkEventCreated)))
+
+	require.NotNil(t, got)
+	assert.Equal(t, http.MethodPost, got.Method)
+	assert.Equal(t, "/topics/tinyurl.links", got.URL.Path)
+	assert.Equal(t, kafkaContentType, got.Header.Get("Content-Type"))
+
+	var records kafkaRecords
+	require.NoError(t, json.Unmarshal(body, &records))
+	require.Len(t, records.Records, 1)
+	assert.Equal(t, "abc123", records.Records[0].Key)
+	assert.Equal(t, int64(7), records.Records[0].Value.ID)
+}
+
+func TestKafkaRESTPublisher_Errors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"proxy error", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error_code":40401,"message":"Topic not found"}`))
+		}},
+		{"record rejected", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"offsets":[{"partition":null,"offset":null,"error_code":50003,"error":"leader not available"}]}`))
+		}},
+		{"no acknowledgement", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"offsets":[]}`))
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(tc.handler)
+			defer srv.Close()
+
+			publisher := NewKafkaRESTPublisher(srv.URL, "tinyurl.links", srv.Client())
+			assert.Error(t, publisher.Publish(context.Background(), newEvent(1, "abc123", model.LinkEventCreated)))
+		})
+	}
+}
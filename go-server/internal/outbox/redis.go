@@ -0,0 +1,51 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/go-redis/redis/v8"
+)
+
+// Streams are trimmed to roughly this many entries unless told otherwise
+const defaultStreamMaxLen = 1_000_000
+
+// RedisStreamPublisher appends events to a Redis stream. Entries carry the
+// event id, link id and type next to the full event, so consumer groups can
+// filter without decoding it.
+type RedisStreamPublisher struct {
+	client *redis.Client
+	stream string
+	maxLen int64
+}
+
+func NewRedisStreamPublisher(client *redis.Client, stream string, maxLen int64) *RedisStreamPublisher {
+	if maxLen <= 0 {
+		maxLen = defaultStreamMaxLen
+	}
+	return &RedisStreamPublisher{client: client, stream: stream, maxLen: maxLen}
+}
+
+func (p *RedisStreamPublisher) Publish(ctx context.Context, event model.OutboxEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: p.stream,
+		MaxLen: p.maxLen,
+		Approx: true,
+		Values: map[string]any{
+			"id":      event.ID,
+			"link_id": event.LinkID,
+			"type":    string(event.Type),
+			"event":   data,
+		},
+	}).Err()
+}
+
+// Close leaves the client open, it is shared with the rest of the server
+func (p *RedisStreamPublisher) Close() error {
+	return nil
+}
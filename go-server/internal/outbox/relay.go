@@ -0,0 +1,150 @@
+// Package outbox publishes the link events PostgresURLRepository writes to
+// the outbox table, in the transaction of the change they describe, to a
+// message broker.
+//
+// Events are published one at a time in the order they were written and
+// only removed from the outbox once the broker acknowledged them. Delivery
+// is at-least-once: an event may be published again when the relay fails
+// between publishing and removing it, so consumers should drop event ids
+// they have already seen. A single replica relays at a time, which keeps
+// the events of every link in order.
+package outbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultInterval       = time.Second
+	defaultBatchSize      = 100
+	defaultPublishTimeout = 10 * time.Second
+	// Rounds in a row a busy relay runs before waiting for the next tick
+	maxRoundsPerTick = 10
+)
+
+// Publisher sends events to a broker. Publish returns once the broker
+// acknowledged the event.
+type Publisher interface {
+	Publish(ctx context.Context, event model.OutboxEvent) error
+	Close() error
+}
+
+// Store hands out unpublished events, see repository.OutboxRepository
+type Store interface {
+	RelayOutbox(ctx context.Context, limit int, publish func(ctx context.Context, events []model.OutboxEvent) int) (int, error)
+}
+
+// RelayConfig controls how often the outbox is polled. PublishTimeout
+// bounds each event; a batch may take BatchSize times as long.
+type RelayConfig struct {
+	Interval       time.Duration
+	BatchSize      int
+	PublishTimeout time.Duration
+}
+
+// Relay moves events from the outbox to a Publisher
+type Relay struct {
+	store     Store
+	publisher Publisher
+	cfg       RelayConfig
+	wg        sync.WaitGroup
+	logger    *zap.Logger
+}
+
+func NewRelay(store Store, publisher Publisher, cfg RelayConfig) *Relay {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.PublishTimeout <= 0 {
+		cfg.PublishTimeout = defaultPublishTimeout
+	}
+
+	return &Relay{
+		store:     store,
+		publisher: publisher,
+		cfg:       cfg,
+		logger:    zap.L().With(zap.String("component", "OutboxRelay")),
+	}
+}
+
+// Start publishes pending events every Interval until ctx is cancelled,
+// then closes the publisher
+func (r *Relay) Start(ctx context.Context) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.cfg.Interval)
+		defer ticker.Stop()
+		defer func() {
+			if err := r.publisher.Close(); err != nil {
+				r.logger.Warn("Failed to close event publisher", zap.Error(err))
+			}
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// Keep going while full batches come back to catch up on a backlog
+				for i := 0; i < maxRoundsPerTick; i++ {
+					published, err := r.RunOnce(ctx)
+					if err != nil {
+						r.logger.Warn("Outbox relay round failed", zap.Error(err))
+						break
+					}
+					if published < r.cfg.BatchSize {
+						break
+					}
+				}
+			}
+		}
+	}()
+}
+
+// Wait blocks until the relay has stopped and closed the publisher
+func (r *Relay) Wait() {
+	r.wg.Wait()
+}
+
+// RunOnce publishes one batch of pending events and returns how many went
+// out. It stops at the first event the broker rejects, so no later event
+// of the same link overtakes it; the rest are tried again next round.
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.PublishTimeout*time.Duration(r.cfg.BatchSize+1))
+	defer cancel()
+
+	var publishErr error
+	published, err := r.store.RelayOutbox(ctx, r.cfg.BatchSize, func(ctx context.Context, events []model.OutboxEvent) int {
+		for i, event := range events {
+			if err := r.publish(ctx, event); err != nil {
+				publishErr = err
+				r.logger.Warn("Failed to publish link event", zap.Error(err), zap.Int64("event_id", event.ID),
+					zap.String("link_id", event.LinkID), zap.String("event", string(event.Type)))
+				return i
+			}
+		}
+		return len(events)
+	})
+	if err != nil {
+		return published, err
+	}
+	if published > 0 {
+		r.logger.Debug("Link events published", zap.Int("events", published))
+	}
+	return published, publishErr
+}
+
+func (r *Relay) publish(ctx context.Context, event model.OutboxEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.PublishTimeout)
+	defer cancel()
+	return r.publisher.Publish(ctx, event)
+}
@@ -0,0 +1,141 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is an in-memory outbox
+type memoryStore struct {
+	mu     sync.Mutex
+	events []model.OutboxEvent
+}
+
+func (m *memoryStore) RelayOutbox(ctx context.Context, limit int, publish func(ctx context.Context, events []model.OutboxEvent) int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	batch := m.events[:min(limit, len(m.events))]
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	published := publish(ctx, batch)
+	m.events = m.events[published:]
+	return published, nil
+}
+
+// recordingPublisher keeps what it published and fails once it reaches failAt
+type recordingPublisher struct {
+	mu        sync.Mutex
+	published []model.OutboxEvent
+	failAt    int64
+	closed    bool
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event model.OutboxEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.failAt != 0 && event.ID == p.failAt {
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, event)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return nil
+}
+
+func (p *recordingPublisher) ids() []int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var ids []int64
+	for _, event := range p.published {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+func newEvent(id int64, linkID string, eventType model.LinkEventType) model.OutboxEvent {
+	return model.OutboxEvent{
+		ID:         id,
+		LinkID:     linkID,
+		Type:       eventType,
+		Data:       json.RawMessage(`{}`),
+		OccurredAt: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestRunOnce_PublishesInOrder(t *testing.T) {
+	store := &memoryStore{events: []model.OutboxEvent{
+		newEvent(1, "abc123", model.LinkEventCreated),
+		newEvent(2, "xyz789", model.LinkEventCreated),
+		newEvent(3, "abc123", model.LinkEventArchived),
+	}}
+	publisher := &recordingPublisher{}
+	relay := NewRelay(store, publisher, RelayConfig{BatchSize: 2})
+
+	published, err := relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+
+	published, err = relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+
+	assert.Equal(t, []int64{1, 2, 3}, publisher.ids())
+	assert.Empty(t, store.events)
+}
+
+func TestRunOnce_StopsAtFailure(t *testing.T) {
+	store := &memoryStore{events: []model.OutboxEvent{
+		newEvent(1, "abc123", model.LinkEventCreated),
+		newEvent(2, "abc123", model.LinkEventDetailsUpdated),
+		newEvent(3, "abc123", model.LinkEventDeleted),
+	}}
+	publisher := &recordingPublisher{failAt: 2}
+	relay := NewRelay(store, publisher, RelayConfig{})
+
+	published, err := relay.RunOnce(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, 1, published)
+	// The later event of the link waits for the failed one
+	assert.Equal(t, []int64{1}, publisher.ids())
+	require.Len(t, store.events, 2)
+	assert.Equal(t, int64(2), store.events[0].ID)
+
+	// Once the broker is back the events go out again, still in order
+	publisher.failAt = 0
+	published, err = relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+	assert.Equal(t, []int64{1, 2, 3}, publisher.ids())
+}
+
+func TestStart_RelaysUntilCancelled(t *testing.T) {
+	store := &memoryStore{events: []model.OutboxEvent{newEvent(1, "abc123", model.LinkEventCreated)}}
+	publisher := &recordingPublisher{}
+	ctx, cancel := context.WithCancel(context.Background())
+	NewRelay(store, publisher, RelayConfig{Interval: 10 * time.Millisecond}).Start(ctx)
+
+	assert.Eventually(t, func() bool { return len(publisher.ids()) == 1 }, time.Second, 10*time.Millisecond)
+	cancel()
+	assert.Eventually(t, func() bool {
+		publisher.mu.Lock()
+		defer publisher.mu.Unlock()
+		return publisher.closed
+	}, time.Second, 10*time.Millisecond)
+}
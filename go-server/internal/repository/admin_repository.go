@@ -0,0 +1,313 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// AdminLinkFilter narrows down a site-wide link search. Query matches the
+// id, code, destination and title.
+type AdminLinkFilter struct {
+	Query    string
+	UserID   *uuid.UUID
+	Disabled *bool
+	Limit    int
+	Offset   int
+}
+
+// AdminUserFilter narrows down an account search. Query matches the email
+// and username.
+type AdminUserFilter struct {
+	Query    string
+	Disabled *bool
+	Limit    int
+	Offset   int
+}
+
+// AdminRepository backs the site-wide moderation tools. It works across
+// accounts and workspaces, callers must check the admin role.
+type AdminRepository interface {
+	SearchLinks(ctx context.Context, filter AdminLinkFilter) ([]model.URL, error)
+	SearchUsers(ctx context.Context, filter AdminUserFilter) ([]model.User, error)
+	SetLinkDisabled(ctx context.Context, id string, disabled bool) (before, after *model.URL, err error)
+	SetUserDisabled(ctx context.Context, id uuid.UUID, disabled bool) (*model.User, error)
+	DisableUserLinks(ctx context.Context, userID uuid.UUID) ([]string, error)
+	EvictLink(ctx context.Context, id string) error
+	Stats(ctx context.Context) (*model.AdminStats, error)
+}
+
+type PostgresAdminRepository struct {
+	linkStore
+}
+
+func NewPostgresAdminRepository(db *pgxpool.Pool, redisClient *redis.Client) *PostgresAdminRepository {
+	return &PostgresAdminRepository{linkStore: newLinkStore(db, redisClient, "PostgresAdminRepository")}
+}
+
+func (r *PostgresAdminRepository) SearchLinks(ctx context.Context, filter AdminLinkFilter) ([]model.URL, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	query := `
+		SELECT u.id, u.code, u.domain_id, COALESCE(d.hostname, ''), u.original_url, u.created_at, u.user_id,
+			u.workspace_id, COALESCE(u.title, ''), u.click_count, u.disabled, COALESCE(u.disabled_reason, ''),
+			u.archived_at, u.deleted_at
+		FROM urls u
+		LEFT JOIN domains d ON d.id = u.domain_id
+		WHERE ($1::text = '' OR u.id = $1 OR u.code = $1
+				OR u.original_url ILIKE '%' || $1 || '%' OR u.title ILIKE '%' || $1 || '%')
+			AND ($2::uuid IS NULL OR u.user_id = $2)
+			AND ($3::boolean IS NULL OR u.disabled = $3)
+		ORDER BY u.created_at DESC, u.id
+		LIMIT $4 OFFSET $5
+	`
+	rows, err := conn(ctx, r.db).Query(ctx, query, filter.Query, filter.UserID, filter.Disabled, filter.Limit, filter.Offset)
+	if err != nil {
+		r.logger.Error("Database query error", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	urls := []model.URL{}
+	for rows.Next() {
+		var url model.URL
+		if err := rows.Scan(&url.ID, &url.Code, &url.DomainID, &url.Domain, &url.OriginalURL, &url.CreatedAt,
+			&url.UserID, &url.WorkspaceID, &url.Title, &url.ClickCount, &url.Disabled, &url.DisabledReason,
+			&url.ArchivedAt, &url.DeletedAt); err != nil {
+			r.logger.Error("Failed to scan URL row", zap.Error(err))
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		urls = append(urls, url)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Row iteration error", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return urls, nil
+}
+
+func (r *PostgresAdminRepository) SearchUsers(ctx context.Context, filter AdminUserFilter) ([]model.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	query := `
+		SELECT id, COALESCE(username, ''), email, email_verified,
+			EXISTS (SELECT 1 FROM user_mfa m WHERE m.user_id = users.id AND m.enabled_at IS NOT NULL),
+			role, disabled_at, created_at
+		FROM users
+		WHERE deleted_at IS NULL
+			AND ($1::text = '' OR email ILIKE '%' || $1 || '%' OR username ILIKE '%' || $1 || '%')
+			AND ($2::boolean IS NULL OR (disabled_at IS NOT NULL) = $2)
+		ORDER BY created_at DESC, id
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := conn(ctx, r.db).Query(ctx, query, filter.Query, filter.Disabled, filter.Limit, filter.Offset)
+	if err != nil {
+		r.logger.Error("Database query error", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	users := []model.User{}
+	for rows.Next() {
+		var user model.User
+		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.EmailVerified, &user.MFAEnabled,
+			&user.Role, &user.DisabledAt, &user.CreatedAt); err != nil {
+			r.logger.Error("Failed to scan user row", zap.Error(err))
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Row iteration error", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return users, nil
+}
+
+// SetLinkDisabled takes a link down or puts it back up whatever its owner
+// or a legal block set, returning its state before and after
+func (r *PostgresAdminRepository) SetLinkDisabled(ctx context.Context, id string, disabled bool) (*model.URL, *model.URL, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	before := model.URL{ID: id}
+	after := model.URL{ID: id}
+	err := r.withEvents(ctx, func(q querier) error {
+		err := q.QueryRow(ctx, `
+			UPDATE urls u
+			SET disabled = $2, disabled_reason = CASE WHEN $2 THEN 'admin' END
+			FROM (SELECT id, disabled, disabled_reason FROM urls WHERE id = $1 FOR UPDATE) old
+			WHERE u.id = old.id
+			RETURNING old.disabled, COALESCE(old.disabled_reason, ''), u.disabled, COALESCE(u.disabled_reason, ''),
+				u.user_id, u.workspace_id
+		`, id, disabled).Scan(&before.Disabled, &before.DisabledReason, &after.Disabled, &after.DisabledReason,
+			&after.UserID, &after.WorkspaceID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrURLNotFound
+			}
+			r.logger.Error("Failed to update URL", zap.Error(err), zap.String("id", id))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		return r.appendEvent(ctx, q, id, model.LinkEventModerated, moderation(after.Disabled, after.DisabledReason))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	before.UserID, before.WorkspaceID = after.UserID, after.WorkspaceID
+
+	r.evictCache(ctx, id)
+	return &before, &after, nil
+}
+
+// SetUserDisabled disables or enables an account. Disabling keeps the
+// original date when the account already was.
+func (r *PostgresAdminRepository) SetUserDisabled(ctx context.Context, id uuid.UUID, disabled bool) (*model.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	user := &model.User{}
+	err := conn(ctx, r.db).QueryRow(ctx, `
+		UPDATE users
+		SET disabled_at = CASE WHEN $2 THEN COALESCE(disabled_at, NOW()) END, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id, COALESCE(username, ''), email, role, disabled_at, created_at
+	`, id, disabled).Scan(&user.ID, &user.Username, &user.Email, &user.Role, &user.DisabledAt, &user.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		r.logger.Error("Failed to update user", zap.Error(err), zap.String("id", id.String()))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return user, nil
+}
+
+// DisableUserLinks takes down every link created by userID that is still
+// enabled and returns their ids
+func (r *PostgresAdminRepository) DisableUserLinks(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	var ids []string
+	err := r.withEvents(ctx, func(q querier) error {
+		rows, err := q.Query(ctx, `
+			UPDATE urls SET disabled = TRUE, disabled_reason = 'admin'
+			WHERE user_id = $1 AND NOT disabled
+			RETURNING id
+		`, userID)
+		if err != nil {
+			r.logger.Error("Failed to disable user URLs", zap.Error(err), zap.String("user_id", userID.String()))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		ids, err = pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			r.logger.Error("Failed to read disabled URLs", zap.Error(err))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		for _, id := range ids {
+			if err := r.appendEvent(ctx, q, id, model.LinkEventModerated, moderation(true, model.DisabledByAdmin)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		r.evictCache(ctx, id)
+	}
+	return ids, nil
+}
+
+// EvictLink drops every cache entry of a link: the link itself and, on a
+// custom domain, its code mapping. Ids of links that no longer exist are
+// evicted too.
+func (r *PostgresAdminRepository) EvictLink(ctx context.Context, id string) error {
+	if r.redisClient == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	keys := []string{id}
+	var code string
+	var domainID *uuid.UUID
+	err := conn(ctx, r.db).QueryRow(ctx, `SELECT code, domain_id FROM urls WHERE id = $1`, id).Scan(&code, &domainID)
+	switch {
+	case err == nil:
+		if domainID != nil {
+			keys = append(keys, codeCachePrefix+domainID.String()+":"+code)
+		}
+	case !errors.Is(err, pgx.ErrNoRows):
+		r.logger.Error("Database query error", zap.Error(err), zap.String("id", id))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	if err := r.redisClient.Del(ctx, keys...).Err(); err != nil {
+		r.logger.Error("Failed to evict cached URL", zap.Error(err), zap.String("id", id))
+		return fmt.Errorf("%w: %v", ErrCacheError, err)
+	}
+	return nil
+}
+
+func (r *PostgresAdminRepository) Stats(ctx context.Context) (*model.AdminStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	var stats model.AdminStats
+	err := conn(ctx, r.db).QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM users WHERE deleted_at IS NULL),
+			(SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND disabled_at IS NOT NULL),
+			(SELECT COUNT(*) FROM users WHERE created_at > NOW() - INTERVAL '24 hours'),
+			COUNT(*),
+			COUNT(*) FILTER (WHERE NOT disabled AND archived_at IS NULL AND deleted_at IS NULL),
+			COUNT(*) FILTER (WHERE disabled),
+			COUNT(*) FILTER (WHERE archived_at IS NOT NULL AND deleted_at IS NULL),
+			COUNT(*) FILTER (WHERE deleted_at IS NOT NULL),
+			COUNT(*) FILTER (WHERE created_at > NOW() - INTERVAL '24 hours'),
+			COALESCE(SUM(click_count), 0),
+			(SELECT COUNT(*) FROM workspaces),
+			(SELECT COUNT(*) FROM domains)
+		FROM urls
+	`).Scan(&stats.Users, &stats.DisabledUsers, &stats.NewUsers24h,
+		&stats.Links, &stats.LiveLinks, &stats.DisabledLinks, &stats.ArchivedLinks, &stats.DeletedLinks,
+		&stats.NewLinks24h, &stats.Clicks, &stats.Workspaces, &stats.Domains)
+	if err != nil {
+		r.logger.Error("Failed to load admin stats", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return &stats, nil
+}
+
+// moderation is the data of a link.moderated event
+func moderation(disabled bool, reason string) map[string]any {
+	return map[string]any{"disabled": disabled, "disabled_reason": reason}
+}
+
+func (r *PostgresAdminRepository) evictCache(ctx context.Context, id string) {
+	if r.redisClient == nil {
+		return
+	}
+	afterCommit(ctx, func(ctx context.Context) {
+		if err := r.redisClient.Del(ctx, id).Err(); err != nil {
+			r.logger.Warn("Failed to evict cached URL", zap.Error(err), zap.String("id", id))
+		}
+	})
+}
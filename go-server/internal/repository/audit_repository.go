@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// AuditFilter pages through entries newest first. BeforeID continues after
+// the last entry of the previous page.
+type AuditFilter struct {
+	BeforeID int64
+	Limit    int
+}
+
+// AuditRepository only appends; the table rejects updates and deletes.
+// Changes are made in InTx with the entries that record them, so neither
+// commits without the other.
+type AuditRepository interface {
+	Transactor
+	Append(ctx context.Context, entry *model.AuditEntry) error
+	ListForTarget(ctx context.Context, targetType, targetID string, filter AuditFilter) ([]model.AuditEntry, error)
+	ListForWorkspace(ctx context.Context, workspaceID uuid.UUID, filter AuditFilter) ([]model.AuditEntry, error)
+	ListAll(ctx context.Context, filter AuditFilter) ([]model.AuditEntry, error)
+}
+
+type PostgresAuditRepository struct {
+	*PostgresTransactor
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewPostgresAuditRepository(db *pgxpool.Pool) *PostgresAuditRepository {
+	return &PostgresAuditRepository{
+		PostgresTransactor: NewPostgresTransactor(db),
+		db:                 db,
+		logger:             zap.L().With(zap.String("component", "PostgresAuditRepository")),
+	}
+}
+
+func (r *PostgresAuditRepository) Append(ctx context.Context, entry *model.AuditEntry) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	err := conn(ctx, r.db).QueryRow(ctx, `
+		INSERT INTO audit_log (actor_id, action, target_type, target_id, workspace_id, before, after, request_id, ip)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, '')::inet)
+		RETURNING id, created_at
+	`, entry.ActorID, entry.Action, entry.TargetType, entry.TargetID, entry.WorkspaceID,
+		nullJSON(entry.Before), nullJSON(entry.After), entry.RequestID, entry.IP).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		r.logger.Error("Failed to append audit entry", zap.Error(err), zap.String("action", entry.Action))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return nil
+}
+
+func (r *PostgresAuditRepository) ListForTarget(ctx context.Context, targetType, targetID string, filter AuditFilter) ([]model.AuditEntry, error) {
+	return r.list(ctx, `target_type = $1 AND target_id = $2`, filter, targetType, targetID)
+}
+
+func (r *PostgresAuditRepository) ListForWorkspace(ctx context.Context, workspaceID uuid.UUID, filter AuditFilter) ([]model.AuditEntry, error) {
+	return r.list(ctx, `workspace_id = $1`, filter, workspaceID)
+}
+
+// ListAll returns entries of every account, for site admins
+func (r *PostgresAuditRepository) ListAll(ctx context.Context, filter AuditFilter) ([]model.AuditEntry, error) {
+	return r.list(ctx, `TRUE`, filter)
+}
+
+func (r *PostgresAuditRepository) list(ctx context.Context, where string, filter AuditFilter, args ...any) ([]model.AuditEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	n := len(args)
+	query := fmt.Sprintf(`
+		SELECT id, actor_id, action, target_type, target_id, workspace_id, before, after,
+			COALESCE(request_id, ''), COALESCE(host(ip), ''), created_at
+		FROM audit_log
+		WHERE %s AND ($%d = 0 OR id < $%d)
+		ORDER BY id DESC
+		LIMIT $%d
+	`, where, n+1, n+1, n+2)
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, append(args, filter.BeforeID, filter.Limit)...)
+	if err != nil {
+		r.logger.Error("Database query error", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	entries := []model.AuditEntry{}
+	for rows.Next() {
+		var e model.AuditEntry
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.TargetType, &e.TargetID, &e.WorkspaceID,
+			&e.Before, &e.After, &e.RequestID, &e.IP, &e.CreatedAt); err != nil {
+			r.logger.Error("Failed to scan audit row", zap.Error(err))
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Row iteration error", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return entries, nil
+}
+
+// nullJSON stores missing states as NULL rather than a JSON null
+func nullJSON(raw []byte) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
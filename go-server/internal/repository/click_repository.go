@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type ClickRepository interface {
+	Record(ctx context.Context, click *model.Click) error
+	GetStats(ctx context.Context, id string) (*model.LinkStats, error)
+}
+
+type PostgresClickRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewPostgresClickRepository(db *pgxpool.Pool) *PostgresClickRepository {
+	return &PostgresClickRepository{
+		db:     db,
+		logger: zap.L().With(zap.String("component", "PostgresClickRepository")),
+	}
+}
+
+func (r *PostgresClickRepository) Record(ctx context.Context, click *model.Click) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	_, err := conn(ctx, r.db).Exec(ctx, `
+		INSERT INTO clicks (url_id, variant, variant_url, created_at) VALUES ($1, $2, NULLIF($3, ''), $4)
+	`, click.URLID, click.Variant, click.VariantURL, click.CreatedAt)
+	if err != nil {
+		r.logger.Error("Failed to record click", zap.Error(err), zap.String("id", click.URLID))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return nil
+}
+
+// GetStats counts the clicks of a URL, broken down by its current
+// variants. A variant only counts clicks served by the same destination at
+// the same position, so replacing a variant starts its count over.
+func (r *PostgresClickRepository) GetStats(ctx context.Context, id string) (*model.LinkStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	var exists bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM urls WHERE id = $1)`, id).Scan(&exists)
+	if err != nil {
+		r.logger.Error("Failed to check URL existence", zap.Error(err), zap.String("id", id))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if !exists {
+		return nil, ErrURLNotFound
+	}
+
+	var stats model.LinkStats
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM clicks WHERE url_id = $1`, id).Scan(&stats.TotalClicks); err != nil {
+		r.logger.Error("Failed to count clicks", zap.Error(err), zap.String("id", id))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT v.position, v.destination, v.weight, COUNT(c.id)
+		FROM url_variants v
+		LEFT JOIN clicks c ON c.url_id = v.url_id AND c.variant = v.position AND c.variant_url = v.destination
+		WHERE v.url_id = $1
+		GROUP BY v.position, v.destination, v.weight
+		ORDER BY v.position
+	`, id)
+	if err != nil {
+		r.logger.Error("Failed to count variant clicks", zap.Error(err), zap.String("id", id))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var variant model.VariantStats
+		if err := rows.Scan(&variant.Variant, &variant.URL, &variant.Weight, &variant.Clicks); err != nil {
+			r.logger.Error("Failed to scan variant stats", zap.Error(err), zap.String("id", id))
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		stats.Variants = append(stats.Variants, variant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return &stats, nil
+}
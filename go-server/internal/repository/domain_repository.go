@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrDomainNotFound = errors.New("domain not found")
+	ErrDomainExists   = errors.New("domain already added")
+	ErrDomainTaken    = errors.New("domain verified by another account")
+	ErrDomainInUse    = errors.New("domain still has links")
+)
+
+// foreignKeyViolation is the PostgreSQL error code for rows still referenced elsewhere
+const foreignKeyViolation = "23503"
+
+// Every redirect looks up its Host, so unknown hosts are cached as well,
+// for a shorter time
+const (
+	domainCachePrefix   = "domain:"
+	unknownHostCacheTTL = 5 * time.Minute
+)
+
+type DomainRepository interface {
+	Create(ctx context.Context, domain *model.Domain) error
+	List(ctx context.Context, userID uuid.UUID) ([]model.Domain, error)
+	Get(ctx context.Context, userID, id uuid.UUID) (*model.Domain, error)
+	MarkVerified(ctx context.Context, userID, id uuid.UUID, at time.Time) error
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+	FindVerified(ctx context.Context, hostname string) (*model.Domain, error)
+}
+
+type PostgresDomainRepository struct {
+	db          *pgxpool.Pool
+	redisClient *redis.Client
+	logger      *zap.Logger
+}
+
+func NewPostgresDomainRepository(db *pgxpool.Pool, redisClient *redis.Client) *PostgresDomainRepository {
+	return &PostgresDomainRepository{
+		db:          db,
+		redisClient: redisClient,
+		logger:      zap.L().With(zap.String("component", "PostgresDomainRepository")),
+	}
+}
+
+func (r *PostgresDomainRepository) Create(ctx context.Context, domain *model.Domain) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO domains (user_id, hostname, verification_token)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRow(ctx, query, domain.UserID, domain.Hostname, domain.VerificationToken).
+		Scan(&domain.ID, &domain.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return ErrDomainExists
+		}
+		r.logger.Error("Failed to insert domain", zap.Error(err), zap.String("hostname", domain.Hostname))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return nil
+}
+
+func (r *PostgresDomainRepository) List(ctx context.Context, userID uuid.UUID) ([]model.Domain, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, `
+		SELECT id, user_id, hostname, verification_token, verified_at, created_at
+		FROM domains WHERE user_id = $1
+		ORDER BY hostname
+	`, userID)
+	if err != nil {
+		r.logger.Error("Database query error", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	domains := []model.Domain{}
+	for rows.Next() {
+		var domain model.Domain
+		if err := rows.Scan(&domain.ID, &domain.UserID, &domain.Hostname, &domain.VerificationToken,
+			&domain.VerifiedAt, &domain.CreatedAt); err != nil {
+			r.logger.Error("Failed to scan domain row", zap.Error(err))
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		domains = append(domains, domain)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Row iteration error", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return domains, nil
+}
+
+func (r *PostgresDomainRepository) Get(ctx context.Context, userID, id uuid.UUID) (*model.Domain, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	var domain model.Domain
+	err := r.db.QueryRow(ctx, `
+		SELECT id, user_id, hostname, verification_token, verified_at, created_at
+		FROM domains WHERE id = $1 AND user_id = $2
+	`, id, userID).Scan(&domain.ID, &domain.UserID, &domain.Hostname, &domain.VerificationToken,
+		&domain.VerifiedAt, &domain.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDomainNotFound
+		}
+		r.logger.Error("Database query error", zap.Error(err), zap.String("domain_id", id.String()))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return &domain, nil
+}
+
+// MarkVerified records that the owner proved control of the domain. It fails
+// with ErrDomainTaken when another account verified the hostname first.
+func (r *PostgresDomainRepository) MarkVerified(ctx context.Context, userID, id uuid.UUID, at time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	var hostname string
+	err := r.db.QueryRow(ctx, `
+		UPDATE domains SET verified_at = COALESCE(verified_at, $3)
+		WHERE id = $1 AND user_id = $2
+		RETURNING hostname
+	`, id, userID, at).Scan(&hostname)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrDomainNotFound
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return ErrDomainTaken
+		}
+		r.logger.Error("Failed to verify domain", zap.Error(err), zap.String("domain_id", id.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	r.evictHost(ctx, hostname)
+	return nil
+}
+
+// Delete removes a domain. Domains still serving links cannot be removed.
+func (r *PostgresDomainRepository) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	var hostname string
+	err := r.db.QueryRow(ctx, `DELETE FROM domains WHERE id = $1 AND user_id = $2 RETURNING hostname`, id, userID).
+		Scan(&hostname)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrDomainNotFound
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return ErrDomainInUse
+		}
+		r.logger.Error("Failed to delete domain", zap.Error(err), zap.String("domain_id", id.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	r.evictHost(ctx, hostname)
+	return nil
+}
+
+// FindVerified returns the verified domain serving hostname, or
+// ErrDomainNotFound when links on that host use the shared namespace
+func (r *PostgresDomainRepository) FindVerified(ctx context.Context, hostname string) (*model.Domain, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	key := domainCachePrefix + hostname
+	if r.redisClient != nil {
+		val, err := r.redisClient.Get(ctx, key).Result()
+		if err == nil {
+			if val == "" {
+				return nil, ErrDomainNotFound
+			}
+			var domain model.Domain
+			if err := json.Unmarshal([]byte(val), &domain); err == nil {
+				return &domain, nil
+			}
+		} else if err != redis.Nil {
+			r.logger.Warn("Cache error", zap.Error(err), zap.String("hostname", hostname))
+		}
+	}
+
+	var domain model.Domain
+	err := r.db.QueryRow(ctx, `
+		SELECT id, user_id, hostname, verified_at, created_at
+		FROM domains WHERE hostname = $1 AND verified_at IS NOT NULL
+	`, hostname).Scan(&domain.ID, &domain.UserID, &domain.Hostname, &domain.VerifiedAt, &domain.CreatedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		r.logger.Error("Database query error", zap.Error(err), zap.String("hostname", hostname))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	found := err == nil
+
+	if r.redisClient != nil {
+		val, ttl := "", unknownHostCacheTTL
+		if found {
+			encoded, _ := json.Marshal(&domain)
+			val, ttl = string(encoded), cacheTimeout
+		}
+		if err := r.redisClient.Set(ctx, key, val, ttl).Err(); err != nil {
+			r.logger.Warn("Failed to cache domain", zap.Error(err), zap.String("hostname", hostname))
+		}
+	}
+
+	if !found {
+		return nil, ErrDomainNotFound
+	}
+	return &domain, nil
+}
+
+// evictHost drops the cached namespace of a hostname after it changed
+func (r *PostgresDomainRepository) evictHost(ctx context.Context, hostname string) {
+	if r.redisClient == nil {
+		return
+	}
+	if err := r.redisClient.Del(ctx, domainCachePrefix+hostname).Err(); err != nil {
+		r.logger.Warn("Failed to evict cached domain", zap.Error(err), zap.String("hostname", hostname))
+	}
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var ErrVerificationNotFound = errors.New("verification token not found or expired")
+
+// EmailVerificationRepository stores pending address verifications by the
+// hash of their token
+type EmailVerificationRepository interface {
+	// Save replaces the pending verification of userID
+	Save(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error
+	// Verify marks the account of an unexpired token verified and returns
+	// it. The token cannot be used again.
+	Verify(ctx context.Context, tokenHash string, now time.Time) (uuid.UUID, error)
+}
+
+type PostgresEmailVerificationRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewPostgresEmailVerificationRepository(db *pgxpool.Pool) *PostgresEmailVerificationRepository {
+	return &PostgresEmailVerificationRepository{
+		db:     db,
+		logger: zap.L().With(zap.String("component", "PostgresEmailVerificationRepository")),
+	}
+}
+
+func (r *PostgresEmailVerificationRepository) Save(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	_, err := conn(ctx, r.db).Exec(ctx, `
+		INSERT INTO email_verifications (user_id, token_hash, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET token_hash = EXCLUDED.token_hash, expires_at = EXCLUDED.expires_at, created_at = NOW()
+	`, userID, tokenHash, expiresAt)
+	if err != nil {
+		r.logger.Error("Failed to save email verification", zap.Error(err), zap.String("user_id", userID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return nil
+}
+
+func (r *PostgresEmailVerificationRepository) Verify(ctx context.Context, tokenHash string, now time.Time) (uuid.UUID, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	// The token is consumed even when expired, a new one has to be sent then
+	var userID uuid.UUID
+	err := conn(ctx, r.db).QueryRow(ctx, `
+		WITH token AS (
+			DELETE FROM email_verifications WHERE token_hash = $1
+			RETURNING user_id, expires_at
+		)
+		UPDATE users SET email_verified = TRUE, updated_at = $2
+		FROM token
+		WHERE users.id = token.user_id AND token.expires_at > $2 AND users.deleted_at IS NULL
+		RETURNING users.id
+	`, tokenHash, now).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, ErrVerificationNotFound
+		}
+		r.logger.Error("Failed to verify email", zap.Error(err))
+		return uuid.Nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var ErrFallbackNotFound = errors.New("fallback not found")
+
+// FallbackRepository stores the fallback defaults of workspaces
+type FallbackRepository interface {
+	Get(ctx context.Context, workspaceID uuid.UUID) (*model.FallbackDefault, error)
+	Upsert(ctx context.Context, workspaceID uuid.UUID, fallback model.Fallback) (*model.FallbackDefault, error)
+	Delete(ctx context.Context, workspaceID uuid.UUID) error
+}
+
+type PostgresFallbackRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewPostgresFallbackRepository(db *pgxpool.Pool) *PostgresFallbackRepository {
+	return &PostgresFallbackRepository{
+		db:     db,
+		logger: zap.L().With(zap.String("component", "PostgresFallbackRepository")),
+	}
+}
+
+func (r *PostgresFallbackRepository) Get(ctx context.Context, workspaceID uuid.UUID) (*model.FallbackDefault, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	def := model.FallbackDefault{WorkspaceID: workspaceID}
+	err := r.db.QueryRow(ctx, `SELECT fallback, updated_at FROM fallback_defaults WHERE workspace_id = $1`, workspaceID).
+		Scan(&def.Fallback, &def.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrFallbackNotFound
+		}
+		r.logger.Error("Database query error", zap.Error(err), zap.String("workspace_id", workspaceID.String()))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return &def, nil
+}
+
+func (r *PostgresFallbackRepository) Upsert(ctx context.Context, workspaceID uuid.UUID, fallback model.Fallback) (*model.FallbackDefault, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO fallback_defaults (workspace_id, fallback, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (workspace_id) DO UPDATE SET
+			fallback = EXCLUDED.fallback,
+			updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`
+	def := model.FallbackDefault{WorkspaceID: workspaceID, Fallback: fallback}
+	if err := r.db.QueryRow(ctx, query, workspaceID, fallback).Scan(&def.UpdatedAt); err != nil {
+		r.logger.Error("Failed to save fallback", zap.Error(err), zap.String("workspace_id", workspaceID.String()))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return &def, nil
+}
+
+func (r *PostgresFallbackRepository) Delete(ctx context.Context, workspaceID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	tag, err := r.db.Exec(ctx, `DELETE FROM fallback_defaults WHERE workspace_id = $1`, workspaceID)
+	if err != nil {
+		r.logger.Error("Failed to delete fallback", zap.Error(err), zap.String("workspace_id", workspaceID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrFallbackNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+const (
+	idempotencyKeyPrefix = "idempotency:"
+	idempotencyTTL       = 24 * time.Hour
+	// idempotencyLockTTL bounds how long a crashed request can hold a key
+	idempotencyLockTTL = 30 * time.Second
+)
+
+type IdempotencyRepository interface {
+	// Reserve claims the key for a new request. When the key is already taken,
+	// the existing record is returned and reserved is false.
+	Reserve(ctx context.Context, key, requestHash string) (existing *model.IdempotencyRecord, reserved bool, err error)
+	Save(ctx context.Context, key string, record *model.IdempotencyRecord) error
+	Release(ctx context.Context, key string) error
+}
+
+type RedisIdempotencyRepository struct {
+	redisClient *redis.Client
+	logger      *zap.Logger
+}
+
+func NewRedisIdempotencyRepository(redisClient *redis.Client) *RedisIdempotencyRepository {
+	return &RedisIdempotencyRepository{
+		redisClient: redisClient,
+		logger:      zap.L().With(zap.String("component", "RedisIdempotencyRepository")),
+	}
+}
+
+func (r *RedisIdempotencyRepository) Reserve(ctx context.Context, key, requestHash string) (*model.IdempotencyRecord, bool, error) {
+	pending, err := json.Marshal(&model.IdempotencyRecord{RequestHash: requestHash})
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrCacheError, err)
+	}
+
+	ok, err := r.redisClient.SetNX(ctx, idempotencyKeyPrefix+key, pending, idempotencyLockTTL).Result()
+	if err != nil {
+		r.logger.Warn("Failed to reserve idempotency key", zap.Error(err), zap.String("key", key))
+		return nil, false, fmt.Errorf("%w: %v", ErrCacheError, err)
+	}
+	if ok {
+		return nil, true, nil
+	}
+
+	val, err := r.redisClient.Get(ctx, idempotencyKeyPrefix+key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			// The previous holder expired between SETNX and GET, try again once
+			return r.reserveAfterExpiry(ctx, key, pending)
+		}
+		r.logger.Warn("Failed to read idempotency key", zap.Error(err), zap.String("key", key))
+		return nil, false, fmt.Errorf("%w: %v", ErrCacheError, err)
+	}
+
+	var record model.IdempotencyRecord
+	if err := json.Unmarshal(val, &record); err != nil {
+		r.logger.Warn("Corrupted idempotency record", zap.Error(err), zap.String("key", key))
+		return nil, false, fmt.Errorf("%w: %v", ErrCacheError, err)
+	}
+
+	return &record, false, nil
+}
+
+func (r *RedisIdempotencyRepository) reserveAfterExpiry(ctx context.Context, key string, pending []byte) (*model.IdempotencyRecord, bool, error) {
+	ok, err := r.redisClient.SetNX(ctx, idempotencyKeyPrefix+key, pending, idempotencyLockTTL).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrCacheError, err)
+	}
+	if !ok {
+		return nil, false, fmt.Errorf("%w: idempotency key %q changed concurrently", ErrCacheError, key)
+	}
+	return nil, true, nil
+}
+
+func (r *RedisIdempotencyRepository) Save(ctx context.Context, key string, record *model.IdempotencyRecord) error {
+	record.Completed = true
+	val, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCacheError, err)
+	}
+
+	if err := r.redisClient.Set(ctx, idempotencyKeyPrefix+key, val, idempotencyTTL).Err(); err != nil {
+		r.logger.Warn("Failed to store idempotent response", zap.Error(err), zap.String("key", key))
+		return fmt.Errorf("%w: %v", ErrCacheError, err)
+	}
+	return nil
+}
+
+func (r *RedisIdempotencyRepository) Release(ctx context.Context, key string) error {
+	if err := r.redisClient.Del(ctx, idempotencyKeyPrefix+key).Err(); err != nil {
+		r.logger.Warn("Failed to release idempotency key", zap.Error(err), zap.String("key", key))
+		return fmt.Errorf("%w: %v", ErrCacheError, err)
+	}
+	return nil
+}
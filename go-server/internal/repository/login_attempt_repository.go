@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+const (
+	loginFailuresKeyPrefix = "login:failures:"
+	loginLockKeyPrefix     = "login:lock:"
+)
+
+// LoginAttemptRepository counts failed sign-ins and locks sign-in for a
+// while, by account or by client. Keys name what is counted, e.g. an email
+// address or an IP. Counts are shared by all replicas.
+type LoginAttemptRepository interface {
+	// AddFailure counts a failed attempt for key and returns the failures
+	// counted so far. They are forgotten window after the last one.
+	AddFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	// Lock keeps key locked for ttl, replacing any lock it had
+	Lock(ctx context.Context, key string, ttl time.Duration) error
+	// LockedFor returns how long each of keys stays locked, zero when it is not
+	LockedFor(ctx context.Context, keys ...string) ([]time.Duration, error)
+	// Clear forgets the failures and lock of key
+	Clear(ctx context.Context, key string) error
+}
+
+type RedisLoginAttemptRepository struct {
+	redisClient *redis.Client
+	logger      *zap.Logger
+}
+
+func NewRedisLoginAttemptRepository(redisClient *redis.Client) *RedisLoginAttemptRepository {
+	return &RedisLoginAttemptRepository{
+		redisClient: redisClient,
+		logger:      zap.L().With(zap.String("component", "RedisLoginAttemptRepository")),
+	}
+}
+
+func (r *RedisLoginAttemptRepository) AddFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	var incr *redis.IntCmd
+	_, err := r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, loginFailuresKeyPrefix+key)
+		pipe.PExpire(ctx, loginFailuresKeyPrefix+key, window)
+		return nil
+	})
+	if err != nil {
+		r.logger.Warn("Failed to count failed login", zap.Error(err), zap.String("key", key))
+		return 0, fmt.Errorf("%w: %v", ErrCacheError, err)
+	}
+	return int(incr.Val()), nil
+}
+
+func (r *RedisLoginAttemptRepository) Lock(ctx context.Context, key string, ttl time.Duration) error {
+	if err := r.redisClient.Set(ctx, loginLockKeyPrefix+key, 1, ttl).Err(); err != nil {
+		r.logger.Warn("Failed to lock login", zap.Error(err), zap.String("key", key))
+		return fmt.Errorf("%w: %v", ErrCacheError, err)
+	}
+	return nil
+}
+
+func (r *RedisLoginAttemptRepository) LockedFor(ctx context.Context, keys ...string) ([]time.Duration, error) {
+	cmds := make([]*redis.DurationCmd, len(keys))
+	_, err := r.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.PTTL(ctx, loginLockKeyPrefix+key)
+		}
+		return nil
+	})
+	if err != nil {
+		r.logger.Warn("Failed to read login locks", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrCacheError, err)
+	}
+
+	locked := make([]time.Duration, len(keys))
+	for i, cmd := range cmds {
+		// Missing keys come back negative
+		if ttl := cmd.Val(); ttl > 0 {
+			locked[i] = ttl
+		}
+	}
+	return locked, nil
+}
+
+func (r *RedisLoginAttemptRepository) Clear(ctx context.Context, key string) error {
+	if err := r.redisClient.Del(ctx, loginFailuresKeyPrefix+key, loginLockKeyPrefix+key).Err(); err != nil {
+		r.logger.Warn("Failed to clear failed logins", zap.Error(err), zap.String("key", key))
+		return fmt.Errorf("%w: %v", ErrCacheError, err)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrMFANotEnrolled    = errors.New("two-factor authentication is not set up")
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+)
+
+// MFARepository stores the TOTP authenticator and recovery codes of
+// accounts
+type MFARepository interface {
+	// Get returns the authenticator of userID, ErrMFANotEnrolled without one
+	Get(ctx context.Context, userID uuid.UUID) (*model.MFASettings, error)
+	// SavePending stores a new authenticator secret, replacing one not
+	// confirmed yet. It fails with ErrMFAAlreadyEnabled once one is.
+	SavePending(ctx context.Context, userID uuid.UUID, secret string) error
+	// Enable confirms the pending authenticator with the code of time step
+	// step and replaces the recovery codes with recoveryHashes
+	Enable(ctx context.Context, userID uuid.UUID, step int64, recoveryHashes []string) error
+	// UseStep records that the code of time step step was used. It returns
+	// false when that or a later step was used already.
+	UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	// UseRecoveryCode removes the recovery code with the hash and reports
+	// whether there was one
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+	// ReplaceRecoveryCodes invalidates all recovery codes of userID for new ones
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, recoveryHashes []string) error
+	// Disable removes the authenticator and recovery codes of userID
+	Disable(ctx context.Context, userID uuid.UUID) error
+}
+
+type PostgresMFARepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewPostgresMFARepository(db *pgxpool.Pool) *PostgresMFARepository {
+	return &PostgresMFARepository{
+		db:     db,
+		logger: zap.L().With(zap.String("component", "PostgresMFARepository")),
+	}
+}
+
+func (r *PostgresMFARepository) Get(ctx context.Context, userID uuid.UUID) (*model.MFASettings, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	settings := &model.MFASettings{}
+	err := conn(ctx, r.db).QueryRow(ctx, `SELECT secret, enabled_at FROM user_mfa WHERE user_id = $1`, userID).
+		Scan(&settings.Secret, &settings.EnabledAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMFANotEnrolled
+		}
+		r.logger.Error("Failed to get authenticator", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return settings, nil
+}
+
+func (r *PostgresMFARepository) SavePending(ctx context.Context, userID uuid.UUID, secret string) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	tag, err := conn(ctx, r.db).Exec(ctx, `
+		INSERT INTO user_mfa (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_step = NULL, created_at = NOW()
+		WHERE user_mfa.enabled_at IS NULL
+	`, userID, secret)
+	if err != nil {
+		r.logger.Error("Failed to save authenticator", zap.Error(err), zap.String("user_id", userID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMFAAlreadyEnabled
+	}
+	return nil
+}
+
+func (r *PostgresMFARepository) Enable(ctx context.Context, userID uuid.UUID, step int64, recoveryHashes []string) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE user_mfa SET enabled_at = NOW(), last_step = $2
+		WHERE user_id = $1 AND enabled_at IS NULL
+	`, userID, step)
+	if err != nil {
+		r.logger.Error("Failed to enable authenticator", zap.Error(err), zap.String("user_id", userID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	// Confirmed concurrently, or replaced by a newer enrolment
+	if tag.RowsAffected() == 0 {
+		return ErrMFAAlreadyEnabled
+	}
+
+	if err := r.replaceRecoveryCodes(ctx, tx, userID, recoveryHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("Failed to commit authenticator", zap.Error(err), zap.String("user_id", userID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return nil
+}
+
+func (r *PostgresMFARepository) UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	// Conditional, so of two replicas accepting the same code only one wins
+	tag, err := conn(ctx, r.db).Exec(ctx, `
+		UPDATE user_mfa SET last_step = $2
+		WHERE user_id = $1 AND enabled_at IS NOT NULL AND (last_step IS NULL OR last_step < $2)
+	`, userID, step)
+	if err != nil {
+		r.logger.Error("Failed to record code use", zap.Error(err), zap.String("user_id", userID.String()))
+		return false, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *PostgresMFARepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	tag, err := conn(ctx, r.db).Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1 AND code_hash = $2`, userID, codeHash)
+	if err != nil {
+		r.logger.Error("Failed to use recovery code", zap.Error(err), zap.String("user_id", userID.String()))
+		return false, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *PostgresMFARepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, recoveryHashes []string) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := r.replaceRecoveryCodes(ctx, tx, userID, recoveryHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("Failed to commit recovery codes", zap.Error(err), zap.String("user_id", userID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return nil
+}
+
+func (r *PostgresMFARepository) replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID uuid.UUID, recoveryHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		r.logger.Error("Failed to remove recovery codes", zap.Error(err), zap.String("user_id", userID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO mfa_recovery_codes (user_id, code_hash)
+		SELECT $1, code_hash FROM unnest($2::text[]) AS code_hash
+	`, userID, recoveryHashes)
+	if err != nil {
+		r.logger.Error("Failed to store recovery codes", zap.Error(err), zap.String("user_id", userID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return nil
+}
+
+func (r *PostgresMFARepository) Disable(ctx context.Context, userID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		r.logger.Error("Failed to remove recovery codes", zap.Error(err), zap.String("user_id", userID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID)
+	if err != nil {
+		r.logger.Error("Failed to remove authenticator", zap.Error(err), zap.String("user_id", userID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMFANotEnrolled
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("Failed to commit authenticator removal", zap.Error(err), zap.String("user_id", userID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return nil
+}
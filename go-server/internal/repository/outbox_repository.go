@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Arbitrary key of the advisory lock held by the replica relaying events
+const outboxRelayLock = 0x6f7574626f78
+
+// OutboxRepository hands unpublished link events to the relay
+type OutboxRepository interface {
+	// RelayOutbox passes the oldest unpublished events, at most limit, to
+	// publish, which returns how many of them, from the first, it published.
+	// Those are removed from the outbox. Only one caller at a time gets
+	// events, so they go out in order; the others get none. ctx bounds the
+	// whole call, publish included.
+	RelayOutbox(ctx context.Context, limit int, publish func(ctx context.Context, events []model.OutboxEvent) int) (int, error)
+}
+
+type PostgresOutboxRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewPostgresOutboxRepository(db *pgxpool.Pool) *PostgresOutboxRepository {
+	return &PostgresOutboxRepository{
+		db:     db,
+		logger: zap.L().With(zap.String("component", "PostgresOutboxRepository")),
+	}
+}
+
+func (r *PostgresOutboxRepository) RelayOutbox(ctx context.Context, limit int, publish func(ctx context.Context, events []model.OutboxEvent) int) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", zap.Error(err))
+		return 0, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Held until the transaction ends, so another replica relaying at the
+	// same time cannot publish a link's later events first
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, outboxRelayLock).Scan(&locked); err != nil {
+		r.logger.Error("Failed to take the outbox relay lock", zap.Error(err))
+		return 0, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, aggregate_id, event_type, payload, created_at
+		FROM outbox_events
+		ORDER BY id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		r.logger.Error("Failed to query outbox events", zap.Error(err))
+		return 0, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	var events []model.OutboxEvent
+	for rows.Next() {
+		var event model.OutboxEvent
+		var payload []byte
+		if err := rows.Scan(&event.ID, &event.LinkID, &event.Type, &payload, &event.OccurredAt); err != nil {
+			r.logger.Error("Failed to scan outbox event", zap.Error(err))
+			return 0, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		event.Data = payload
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Row iteration error", zap.Error(err))
+		return 0, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	published := publish(ctx, events)
+	if published <= 0 {
+		return 0, nil
+	}
+
+	ids := make([]int64, 0, published)
+	for _, event := range events[:min(published, len(events))] {
+		ids = append(ids, event.ID)
+	}
+	// By id rather than up to the last one: an event with a lower id may
+	// still be committing and has not been published
+	_, err = tx.Exec(ctx, `DELETE FROM outbox_events WHERE id = ANY($1)`, ids)
+	if err != nil {
+		r.logger.Error("Failed to remove published outbox events", zap.Error(err))
+		return 0, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	// Should this fail the events are published again by the next round
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("Failed to commit published outbox events", zap.Error(err))
+		return 0, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return len(ids), nil
+}
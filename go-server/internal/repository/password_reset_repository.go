@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var ErrResetNotFound = errors.New("password reset token not found or expired")
+
+// PasswordResetRepository stores pending password resets by the hash of
+// their token
+type PasswordResetRepository interface {
+	// Save replaces the pending reset of userID
+	Save(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error
+	// Reset sets the password of the account of an unexpired token and
+	// returns the account. The token cannot be used again.
+	Reset(ctx context.Context, tokenHash, passwordHash string, now time.Time) (uuid.UUID, error)
+}
+
+type PostgresPasswordResetRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewPostgresPasswordResetRepository(db *pgxpool.Pool) *PostgresPasswordResetRepository {
+	return &PostgresPasswordResetRepository{
+		db:     db,
+		logger: zap.L().With(zap.String("component", "PostgresPasswordResetRepository")),
+	}
+}
+
+func (r *PostgresPasswordResetRepository) Save(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	_, err := conn(ctx, r.db).Exec(ctx, `
+		INSERT INTO password_resets (user_id, token_hash, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET token_hash = EXCLUDED.token_hash, expires_at = EXCLUDED.expires_at, created_at = NOW()
+	`, userID, tokenHash, expiresAt)
+	if err != nil {
+		r.logger.Error("Failed to save password reset", zap.Error(err), zap.String("user_id", userID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return nil
+}
+
+// Reset also revokes every session of the account and drops its other
+// pending tokens. Following the emailed link proves the address, so the
+// account counts as verified afterwards.
+func (r *PostgresPasswordResetRepository) Reset(ctx context.Context, tokenHash, passwordHash string, now time.Time) (uuid.UUID, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", zap.Error(err))
+		return uuid.Nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var userID uuid.UUID
+	err = tx.QueryRow(ctx, `
+		DELETE FROM password_resets WHERE token_hash = $1 AND expires_at > $2
+		RETURNING user_id
+	`, tokenHash, now).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, ErrResetNotFound
+		}
+		r.logger.Error("Failed to consume password reset", zap.Error(err))
+		return uuid.Nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE users
+		SET password_hash = $2, session_version = session_version + 1, email_verified = TRUE, updated_at = $3
+		WHERE id = $1 AND deleted_at IS NULL
+	`, userID, passwordHash, now)
+	if err != nil {
+		r.logger.Error("Failed to update password", zap.Error(err), zap.String("user_id", userID.String()))
+		return uuid.Nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return uuid.Nil, ErrResetNotFound
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM email_verifications WHERE user_id = $1`, userID); err != nil {
+		r.logger.Error("Failed to drop email verification", zap.Error(err), zap.String("user_id", userID.String()))
+		return uuid.Nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("Failed to commit password reset", zap.Error(err), zap.String("user_id", userID.String()))
+		return uuid.Nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var ErrReportNotFound = errors.New("report not found")
+
+// ReportFilter pages through the moderation queue, oldest first
+type ReportFilter struct {
+	Status model.ReportStatus
+	Limit  int
+	Offset int
+}
+
+type ReportRepository interface {
+	Create(ctx context.Context, report *model.Report) error
+	List(ctx context.Context, filter ReportFilter) ([]model.Report, error)
+	Dismiss(ctx context.Context, id, adminID uuid.UUID, now time.Time) (*model.Report, error)
+	TakeDown(ctx context.Context, id, adminID uuid.UUID, now time.Time) (*model.Report, error)
+}
+
+type PostgresReportRepository struct {
+	linkStore
+}
+
+func NewPostgresReportRepository(db *pgxpool.Pool, redisClient *redis.Client) *PostgresReportRepository {
+	return &PostgresReportRepository{linkStore: newLinkStore(db, redisClient, "PostgresReportRepository")}
+}
+
+// Create queues a report. A repeat of an open report from the same address
+// is accepted but not stored again.
+func (r *PostgresReportRepository) Create(ctx context.Context, report *model.Report) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	report.Status = model.ReportOpen
+	err := conn(ctx, r.db).QueryRow(ctx, `
+		INSERT INTO link_reports (url_id, reason, description, reporter_id, reporter_ip)
+		SELECT id, $2, NULLIF($3, ''), $4, NULLIF($5, '')::inet FROM urls WHERE id = $1
+		ON CONFLICT (url_id, reporter_ip) WHERE status = 'open' DO NOTHING
+		RETURNING id, created_at
+	`, report.URLID, report.Reason, report.Description, report.ReporterID, report.ReporterIP).
+		Scan(&report.ID, &report.CreatedAt)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		r.logger.Error("Failed to insert report", zap.Error(err), zap.String("url_id", report.URLID))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	// Either the link does not exist or this is a repeat
+	var exists bool
+	if err := conn(ctx, r.db).QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM urls WHERE id = $1)`, report.URLID).Scan(&exists); err != nil {
+		r.logger.Error("Database query error", zap.Error(err), zap.String("url_id", report.URLID))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if !exists {
+		return ErrURLNotFound
+	}
+	return nil
+}
+
+func (r *PostgresReportRepository) List(ctx context.Context, filter ReportFilter) ([]model.Report, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	rows, err := conn(ctx, r.db).Query(ctx, `
+		SELECT rp.id, COALESCE(rp.url_id, ''), rp.reason, COALESCE(rp.description, ''), rp.reporter_id,
+			COALESCE(host(rp.reporter_ip), ''), rp.status, rp.created_at, rp.reviewed_at, rp.reviewed_by,
+			COALESCE(u.original_url, ''), COALESCE(u.disabled, FALSE),
+			(SELECT COUNT(*) FROM link_reports o WHERE o.url_id = rp.url_id AND o.status = 'open')
+		FROM link_reports rp
+		LEFT JOIN urls u ON u.id = rp.url_id
+		WHERE ($1::text = '' OR rp.status = $1)
+		ORDER BY rp.created_at, rp.id
+		LIMIT $2 OFFSET $3
+	`, string(filter.Status), filter.Limit, filter.Offset)
+	if err != nil {
+		r.logger.Error("Database query error", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	reports := []model.Report{}
+	for rows.Next() {
+		var rp model.Report
+		if err := rows.Scan(&rp.ID, &rp.URLID, &rp.Reason, &rp.Description, &rp.ReporterID, &rp.ReporterIP,
+			&rp.Status, &rp.CreatedAt, &rp.ReviewedAt, &rp.ReviewedBy, &rp.URL, &rp.LinkDisabled, &rp.OpenReports); err != nil {
+			r.logger.Error("Failed to scan report row", zap.Error(err))
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		reports = append(reports, rp)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Row iteration error", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return reports, nil
+}
+
+// Dismiss closes an open report and every other open report on the same
+// link, the decision being about the link
+func (r *PostgresReportRepository) Dismiss(ctx context.Context, id, adminID uuid.UUID, now time.Time) (*model.Report, error) {
+	return r.review(ctx, id, adminID, now, model.ReportDismissed)
+}
+
+// TakeDown disables the reported link for abuse and closes its open reports
+func (r *PostgresReportRepository) TakeDown(ctx context.Context, id, adminID uuid.UUID, now time.Time) (*model.Report, error) {
+	return r.review(ctx, id, adminID, now, model.ReportTakenDown)
+}
+
+func (r *PostgresReportRepository) review(ctx context.Context, id, adminID uuid.UUID, now time.Time, status model.ReportStatus) (*model.Report, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	report := model.Report{ID: id, Status: status, ReviewedAt: &now, ReviewedBy: &adminID}
+	err := r.inTx(ctx, func(q querier) error {
+		err := q.QueryRow(ctx, `
+			SELECT COALESCE(url_id, ''), reason, COALESCE(description, ''), created_at FROM link_reports
+			WHERE id = $1 AND status = 'open'
+			FOR UPDATE
+		`, id).Scan(&report.URLID, &report.Reason, &report.Description, &report.CreatedAt)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrReportNotFound
+			}
+			r.logger.Error("Failed to load report", zap.Error(err), zap.String("id", id.String()))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+
+		if status == model.ReportTakenDown {
+			// Reports outlive purged links, which have nothing left to take down
+			if report.URLID == "" {
+				return ErrURLNotFound
+			}
+			err = q.QueryRow(ctx, `
+				UPDATE urls SET disabled = TRUE, disabled_reason = 'abuse' WHERE id = $1
+				RETURNING original_url
+			`, report.URLID).Scan(&report.URL)
+			if err != nil {
+				r.logger.Error("Failed to take down URL", zap.Error(err), zap.String("url_id", report.URLID))
+				return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+			}
+			report.LinkDisabled = true
+			err = r.appendEvent(ctx, q, report.URLID, model.LinkEventModerated, moderation(true, model.DisabledForAbuse))
+			if err != nil {
+				return err
+			}
+		}
+
+		if _, err := q.Exec(ctx, `
+			UPDATE link_reports SET status = $2, reviewed_at = $3, reviewed_by = $4
+			WHERE (id = $5 OR url_id = $1) AND status = 'open'
+		`, report.URLID, status, now, adminID, id); err != nil {
+			r.logger.Error("Failed to close reports", zap.Error(err), zap.String("url_id", report.URLID))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if status == model.ReportTakenDown && r.redisClient != nil {
+		afterCommit(ctx, func(ctx context.Context) {
+			if err := r.redisClient.Del(ctx, report.URLID).Err(); err != nil {
+				r.logger.Warn("Failed to evict cached URL", zap.Error(err), zap.String("id", report.URLID))
+			}
+		})
+	}
+	return &report, nil
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrTagNotFound = errors.New("tag not found")
+	ErrTagExists   = errors.New("tag already exists")
+)
+
+// uniqueViolation is the PostgreSQL error code for unique constraint violations
+const uniqueViolation = "23505"
+
+type TagRepository interface {
+	List(ctx context.Context, userID uuid.UUID) ([]model.Tag, error)
+	Rename(ctx context.Context, userID, tagID uuid.UUID, name string) error
+	Merge(ctx context.Context, userID, sourceID, targetID uuid.UUID) error
+	Delete(ctx context.Context, userID, tagID uuid.UUID) error
+}
+
+type PostgresTagRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewPostgresTagRepository(db *pgxpool.Pool) *PostgresTagRepository {
+	return &PostgresTagRepository{
+		db:     db,
+		logger: zap.L().With(zap.String("component", "PostgresTagRepository")),
+	}
+}
+
+func (r *PostgresTagRepository) List(ctx context.Context, userID uuid.UUID) ([]model.Tag, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	query := `
+		SELECT t.id, t.user_id, t.name, t.created_at, COUNT(ut.url_id)
+		FROM tags t
+		LEFT JOIN url_tags ut ON ut.tag_id = t.id
+		WHERE t.user_id = $1
+		GROUP BY t.id
+		ORDER BY t.name
+	`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		r.logger.Error("Database query error", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	var tags []model.Tag
+	for rows.Next() {
+		var tag model.Tag
+		if err := rows.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.CreatedAt, &tag.URLCount); err != nil {
+			r.logger.Error("Failed to scan tag row", zap.Error(err))
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Row iteration error", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return tags, nil
+}
+
+func (r *PostgresTagRepository) Rename(ctx context.Context, userID, tagID uuid.UUID, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	tag, err := r.db.Exec(ctx, `UPDATE tags SET name = $3 WHERE id = $1 AND user_id = $2`, tagID, userID, name)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return ErrTagExists
+		}
+		r.logger.Error("Failed to rename tag", zap.Error(err), zap.String("tag_id", tagID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTagNotFound
+	}
+
+	return nil
+}
+
+// Merge moves every URL from the source tag to the target tag and deletes the source
+func (r *PostgresTagRepository) Merge(ctx context.Context, userID, sourceID, targetID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var owned int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM tags WHERE user_id = $1 AND id IN ($2, $3)`, userID, sourceID, targetID).Scan(&owned)
+	if err != nil {
+		r.logger.Error("Failed to check tag ownership", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if owned != 2 {
+		return ErrTagNotFound
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO url_tags (url_id, tag_id)
+		SELECT url_id, $2 FROM url_tags WHERE tag_id = $1
+		ON CONFLICT DO NOTHING
+	`, sourceID, targetID)
+	if err != nil {
+		r.logger.Error("Failed to move tagged URLs", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM tags WHERE id = $1`, sourceID); err != nil {
+		r.logger.Error("Failed to delete merged tag", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("Failed to commit tag merge", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return nil
+}
+
+func (r *PostgresTagRepository) Delete(ctx context.Context, userID, tagID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	tag, err := r.db.Exec(ctx, `DELETE FROM tags WHERE id = $1 AND user_id = $2`, tagID, userID)
+	if err != nil {
+		r.logger.Error("Failed to delete tag", zap.Error(err), zap.String("tag_id", tagID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTagNotFound
+	}
+
+	return nil
+}
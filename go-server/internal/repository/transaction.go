@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Transactor runs fn in one database transaction. The repositories fn calls
+// with the ctx it is given join that transaction, so a change and the audit
+// entries and webhook deliveries recorded about it commit or roll back
+// together. InTx called again inside fn runs in the outer transaction.
+type Transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// querier runs statements on the pool or inside a transaction
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type txKey struct{}
+
+type txState struct {
+	tx pgx.Tx
+	// Run once the transaction committed, such as cache evictions that
+	// would otherwise let a reader cache the state being replaced
+	onCommit []func(ctx context.Context)
+}
+
+type PostgresTransactor struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewPostgresTransactor(db *pgxpool.Pool) *PostgresTransactor {
+	return &PostgresTransactor{
+		db:     db,
+		logger: zap.L().With(zap.String("component", "PostgresTransactor")),
+	}
+}
+
+func (t *PostgresTransactor) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if inTransaction(ctx) {
+		return fn(ctx)
+	}
+
+	tx, err := t.db.Begin(ctx)
+	if err != nil {
+		t.logger.Error("Failed to begin transaction", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	state := &txState{tx: tx}
+	if err := fn(context.WithValue(ctx, txKey{}, state)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		t.logger.Error("Failed to commit transaction", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	for _, hook := range state.onCommit {
+		hook(ctx)
+	}
+	return nil
+}
+
+// inTransaction reports whether ctx carries a transaction opened by InTx
+func inTransaction(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(*txState)
+	return ok
+}
+
+// conn returns the transaction ctx carries, or db outside of one
+func conn(ctx context.Context, db *pgxpool.Pool) querier {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return state.tx
+	}
+	return db
+}
+
+// begin starts a transaction, as a savepoint of the one ctx carries if any
+func begin(ctx context.Context, db *pgxpool.Pool) (pgx.Tx, error) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return state.tx.Begin(ctx)
+	}
+	return db.Begin(ctx)
+}
+
+// afterCommit runs fn once the transaction ctx carries committed, and right
+// away outside of one. fn is dropped when the transaction rolls back.
+func afterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		state.onCommit = append(state.onCommit, fn)
+		return
+	}
+	fn(ctx)
+}
+
+// linkStore is embedded by the repositories that change links, so every
+// change appends its domain event to the outbox the same way
+type linkStore struct {
+	db          *pgxpool.Pool
+	redisClient *redis.Client
+	logger      *zap.Logger
+	outbox      bool
+}
+
+func newLinkStore(db *pgxpool.Pool, redisClient *redis.Client, component string) linkStore {
+	return linkStore{
+		db:          db,
+		redisClient: redisClient,
+		logger:      zap.L().With(zap.String("component", component)),
+	}
+}
+
+// EnableOutbox makes every link change append its domain event to the
+// outbox in the same transaction. Without a relay publishing them the
+// events would pile up, so it is off by default.
+func (r *linkStore) EnableOutbox() {
+	r.outbox = true
+}
+
+// withEvents runs fn, in a transaction when the outbox is on so the events
+// fn appends commit or roll back with the change
+func (r *linkStore) withEvents(ctx context.Context, fn func(q querier) error) error {
+	if !r.outbox {
+		return fn(conn(ctx, r.db))
+	}
+	return r.inTx(ctx, fn)
+}
+
+// inTx runs fn in a transaction, nested in the one ctx carries if any
+func (r *linkStore) inTx(ctx context.Context, fn func(q querier) error) error {
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("Failed to commit link change", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return nil
+}
+
+// appendEvent adds a domain event about link id to the outbox. It does
+// nothing while the outbox is off.
+func (r *linkStore) appendEvent(ctx context.Context, q querier, id string, eventType model.LinkEventType, data any) error {
+	if !r.outbox {
+		return nil
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	_, err = q.Exec(ctx, `
+		INSERT INTO outbox_events (aggregate_id, event_type, payload) VALUES ($1, $2, $3)
+	`, id, string(eventType), string(payload))
+	if err != nil {
+		r.logger.Error("Failed to append outbox event", zap.Error(err), zap.String("id", id),
+			zap.String("event", string(eventType)))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var ErrTransferNotFound = errors.New("transfer not found")
+
+type TransferRepository interface {
+	Create(ctx context.Context, transfer *model.Transfer) error
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]model.Transfer, error)
+	Accept(ctx context.Context, id, userID uuid.UUID, workspaceID *uuid.UUID, now time.Time) (*model.Transfer, error)
+	Decline(ctx context.Context, id, userID uuid.UUID, now time.Time) error
+	Cancel(ctx context.Context, id, userID uuid.UUID, now time.Time) error
+}
+
+type PostgresTransferRepository struct {
+	linkStore
+}
+
+func NewPostgresTransferRepository(db *pgxpool.Pool, redisClient *redis.Client) *PostgresTransferRepository {
+	return &PostgresTransferRepository{linkStore: newLinkStore(db, redisClient, "PostgresTransferRepository")}
+}
+
+// Create stores a pending transfer of LinkIDs, remembering the workspace
+// each link is in at this point
+func (r *PostgresTransferRepository) Create(ctx context.Context, transfer *model.Transfer) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	transfer.Status = model.TransferPending
+	return r.inTx(ctx, func(q querier) error {
+		err := q.QueryRow(ctx, `
+			INSERT INTO link_transfers (from_user_id, to_email, expires_at) VALUES ($1, $2, $3)
+			RETURNING id, created_at
+		`, transfer.FromUserID, transfer.ToEmail, transfer.ExpiresAt).Scan(&transfer.ID, &transfer.CreatedAt)
+		if err != nil {
+			r.logger.Error("Failed to insert transfer", zap.Error(err))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+
+		_, err = q.Exec(ctx, `
+			INSERT INTO link_transfer_items (transfer_id, url_id, from_workspace_id)
+			SELECT $1, id, workspace_id FROM urls WHERE id = ANY($2)
+		`, transfer.ID, transfer.LinkIDs)
+		if err != nil {
+			r.logger.Error("Failed to insert transfer items", zap.Error(err))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		return nil
+	})
+}
+
+// ListForUser returns the transfers userID started or that are addressed
+// to their email once verified, newest first
+func (r *PostgresTransferRepository) ListForUser(ctx context.Context, userID uuid.UUID) ([]model.Transfer, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	rows, err := conn(ctx, r.db).Query(ctx, `
+		SELECT t.id, t.from_user_id, t.to_email, t.to_user_id, t.status, t.created_at, t.expires_at, t.responded_at,
+			COALESCE(array_agg(i.url_id ORDER BY i.url_id)
+				FILTER (WHERE i.url_id IS NOT NULL AND (t.status <> 'accepted' OR i.transferred)), '{}')
+		FROM link_transfers t
+		LEFT JOIN link_transfer_items i ON i.transfer_id = t.id
+		WHERE t.from_user_id = $1
+			OR lower(t.to_email) = (SELECT lower(email) FROM users WHERE id = $1 AND email_verified)
+		GROUP BY t.id
+		ORDER BY t.created_at DESC
+	`, userID)
+	if err != nil {
+		r.logger.Error("Database query error", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	transfers := []model.Transfer{}
+	for rows.Next() {
+		var transfer model.Transfer
+		if err := rows.Scan(&transfer.ID, &transfer.FromUserID, &transfer.ToEmail, &transfer.ToUserID, &transfer.Status,
+			&transfer.CreatedAt, &transfer.ExpiresAt, &transfer.RespondedAt, &transfer.LinkIDs); err != nil {
+			r.logger.Error("Failed to scan transfer row", zap.Error(err))
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		transfers = append(transfers, transfer)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Row iteration error", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return transfers, nil
+}
+
+// Accept moves the links of a pending transfer addressed to the verified
+// email of userID into workspaceID. Links that left their workspace since
+// the transfer started, or whose workspace the sender no longer owns, stay
+// where they are. The sender's tags are taken off the moved links, which
+// are not de-duplicated against the recipient's own links.
+func (r *PostgresTransferRepository) Accept(ctx context.Context, id, userID uuid.UUID, workspaceID *uuid.UUID, now time.Time) (*model.Transfer, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	transfer := model.Transfer{ID: id, ToUserID: &userID, Status: model.TransferAccepted, RespondedAt: &now}
+	err := r.inTx(ctx, func(q querier) error {
+		err := q.QueryRow(ctx, `
+			SELECT t.from_user_id, t.to_email, t.created_at, t.expires_at
+			FROM link_transfers t
+			JOIN users u ON u.id = $2 AND lower(u.email) = lower(t.to_email) AND u.email_verified
+			WHERE t.id = $1 AND t.status = 'pending' AND t.expires_at > $3 AND t.from_user_id <> $2
+			FOR UPDATE OF t
+		`, id, userID, now).Scan(&transfer.FromUserID, &transfer.ToEmail, &transfer.CreatedAt, &transfer.ExpiresAt)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrTransferNotFound
+			}
+			r.logger.Error("Failed to load transfer", zap.Error(err), zap.String("id", id.String()))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+
+		rows, err := q.Query(ctx, `
+			UPDATE urls u SET user_id = $3, workspace_id = $4, reusable = FALSE
+			FROM link_transfer_items i
+			WHERE i.transfer_id = $1 AND u.id = i.url_id
+				AND u.workspace_id IS NOT DISTINCT FROM i.from_workspace_id
+				AND (
+					(i.from_workspace_id IS NULL AND u.user_id = $2)
+					OR EXISTS (
+						SELECT 1 FROM workspace_members m
+						WHERE m.workspace_id = i.from_workspace_id AND m.user_id = $2 AND m.role = 'owner'
+					)
+				)
+			RETURNING u.id
+		`, id, transfer.FromUserID, userID, workspaceID)
+		if err != nil {
+			r.logger.Error("Failed to move transferred URLs", zap.Error(err), zap.String("id", id.String()))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		transfer.LinkIDs, err = pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			r.logger.Error("Failed to read transferred URLs", zap.Error(err))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+
+		if _, err := q.Exec(ctx, `
+			UPDATE link_transfer_items SET transferred = TRUE WHERE transfer_id = $1 AND url_id = ANY($2)
+		`, id, transfer.LinkIDs); err != nil {
+			r.logger.Error("Failed to mark transfer items", zap.Error(err))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+
+		if _, err := q.Exec(ctx, `
+			DELETE FROM url_tags ut USING tags t
+			WHERE ut.tag_id = t.id AND t.user_id = $1 AND ut.url_id = ANY($2)
+		`, transfer.FromUserID, transfer.LinkIDs); err != nil {
+			r.logger.Error("Failed to untag transferred URLs", zap.Error(err))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+
+		if _, err := q.Exec(ctx, `
+			UPDATE link_transfers SET status = 'accepted', to_user_id = $2, responded_at = $3 WHERE id = $1
+		`, id, userID, now); err != nil {
+			r.logger.Error("Failed to mark transfer accepted", zap.Error(err))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+
+		for _, linkID := range transfer.LinkIDs {
+			err := r.appendEvent(ctx, q, linkID, model.LinkEventTransferred, map[string]any{
+				"transfer_id": id, "user_id": userID, "workspace_id": workspaceID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.evictCache(ctx, transfer.LinkIDs)
+	return &transfer, nil
+}
+
+// Decline lets the recipient, once their email is verified, turn down a
+// pending transfer
+func (r *PostgresTransferRepository) Decline(ctx context.Context, id, userID uuid.UUID, now time.Time) error {
+	return r.close(ctx, `
+		UPDATE link_transfers t SET status = 'declined', to_user_id = $2, responded_at = $3
+		FROM users u
+		WHERE t.id = $1 AND t.status = 'pending' AND u.id = $2 AND lower(u.email) = lower(t.to_email) AND u.email_verified
+	`, id, userID, now)
+}
+
+// Cancel lets the sender withdraw a pending transfer
+func (r *PostgresTransferRepository) Cancel(ctx context.Context, id, userID uuid.UUID, now time.Time) error {
+	return r.close(ctx, `
+		UPDATE link_transfers SET status = 'cancelled', responded_at = $3
+		WHERE id = $1 AND status = 'pending' AND from_user_id = $2
+	`, id, userID, now)
+}
+
+func (r *PostgresTransferRepository) close(ctx context.Context, query string, id, userID uuid.UUID, now time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	tag, err := conn(ctx, r.db).Exec(ctx, query, id, userID, now)
+	if err != nil {
+		r.logger.Error("Failed to close transfer", zap.Error(err), zap.String("id", id.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTransferNotFound
+	}
+	return nil
+}
+
+func (r *PostgresTransferRepository) evictCache(ctx context.Context, ids []string) {
+	if r.redisClient == nil || len(ids) == 0 {
+		return
+	}
+	afterCommit(ctx, func(ctx context.Context) {
+		if err := r.redisClient.Del(ctx, ids...).Err(); err != nil {
+			r.logger.Warn("Failed to evict cached URLs", zap.Error(err), zap.Strings("ids", ids))
+		}
+	})
+}
@@ -2,8 +2,10 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/fonsecaaso/TinyUrl/go-server/internal/metrics"
@@ -12,12 +14,15 @@ import (
 
 	"github.com/go-redis/redis/v8"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
 
 var (
 	ErrURLNotFound   = errors.New("URL not found")
+	ErrCodeTaken     = errors.New("short code already taken on this domain")
+	ErrDuplicateURL  = errors.New("a live link to this destination already exists on this domain")
 	ErrDatabaseError = errors.New("database error")
 	ErrCacheError    = errors.New("cache error")
 )
@@ -25,29 +30,66 @@ var (
 const (
 	cacheTimeout = 24 * time.Hour
 	dbTimeout    = 5 * time.Second
+	// Entries expiring sooner than this are not worth caching
+	minCacheTTL = time.Second
+	// Custom domain codes are cached as pointers to the link's internal id
+	codeCachePrefix = "code:"
 )
 
 type URLRepository interface {
 	Create(ctx context.Context, url *model.URL) error
 	CreateOrGet(ctx context.Context, url *model.URL) (shortCode string, isNew bool, err error)
 	FindByID(ctx context.Context, id string) (*model.URL, error)
+	FindByCode(ctx context.Context, domainID uuid.UUID, code string) (*model.URL, error)
 	FindByURL(ctx context.Context, url string) (string, error)
 	IDExists(ctx context.Context, id string) (bool, error)
-	GetUserURLs(ctx context.Context, userId uuid.UUID) ([]model.URL, error)
+	GetUserURLs(ctx context.Context, userId uuid.UUID, filter URLFilter) ([]model.URL, error)
+	UpdateDetails(ctx context.Context, id string, changes URLChanges) error
+	SetTags(ctx context.Context, userId uuid.UUID, id string, tags []string) error
+	GetRules(ctx context.Context, id string) ([]model.RoutingRule, error)
+	SetRules(ctx context.Context, id string, rules []model.RoutingRule) error
+	SetVariants(ctx context.Context, id string, variants []model.Variant, sticky bool) error
+	SetSchedule(ctx context.Context, id string, startsAt *time.Time, changes []model.ScheduledDestination) error
+	SetAvailability(ctx context.Context, id string, availability Availability) error
+	SetFallback(ctx context.Context, id string, fallback *model.Fallback) error
+	ConsumeClick(ctx context.Context, id string) (bool, error)
+	Claim(ctx context.Context, id, claimHash string, userId uuid.UUID, workspaceID *uuid.UUID) error
+	SetLifecycle(ctx context.Context, id string, archivedAt, deletedAt *time.Time) error
+	Purge(ctx context.Context, archivedBefore, deletedBefore time.Time, limit int) ([]model.URL, error)
+}
+
+// Availability holds the owner-controlled lifetime limits of a link.
+// Nil fields remove the limit.
+type Availability struct {
+	ExpiresAt *time.Time
+	MaxClicks *int64
+	Disabled  bool
+}
+
+// URLFilter narrows down the URLs returned by GetUserURLs. Without a
+// WorkspaceID the links created by the user are listed, leaving out those of
+// workspaces the user no longer belongs to. State selects archived or
+// deleted links instead of live ones.
+type URLFilter struct {
+	Tag         string
+	WorkspaceID *uuid.UUID
+	State       model.LinkState
+}
+
+// URLChanges lists the editable link fields. Nil fields are left untouched.
+type URLChanges struct {
+	Title        *string
+	Description  *string
+	ForwardQuery *bool
+	ForwardPath  *bool
 }
 
 type PostgresURLRepository struct {
-	db          *pgxpool.Pool
-	redisClient *redis.Client
-	logger      *zap.Logger
+	linkStore
 }
 
 func NewPostgresURLRepository(db *pgxpool.Pool, redisClient *redis.Client) *PostgresURLRepository {
-	return &PostgresURLRepository{
-		db:          db,
-		redisClient: redisClient,
-		logger:      zap.L().With(zap.String("component", "PostgresURLRepository")),
-	}
+	return &PostgresURLRepository{linkStore: newLinkStore(db, redisClient, "PostgresURLRepository")}
 }
 
 func (r *PostgresURLRepository) Create(ctx context.Context, url *model.URL) error {
@@ -55,13 +97,14 @@ func (r *PostgresURLRepository) Create(ctx context.Context, url *model.URL) erro
 	defer cancel()
 
 	query := `INSERT INTO urls (id, url, created_at) VALUES ($1, $2, $3)`
-	_, err := r.db.Exec(ctx, query, url.ID, url.OriginalURL, time.Now())
-	if err != nil {
-		r.logger.Error("Failed to insert URL", zap.Error(err), zap.String("id", url.ID))
-		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
-	}
-
-	return nil
+	return r.withEvents(ctx, func(q querier) error {
+		_, err := q.Exec(ctx, query, url.ID, url.OriginalURL, time.Now())
+		if err != nil {
+			r.logger.Error("Failed to insert URL", zap.Error(err), zap.String("id", url.ID))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		return r.appendEvent(ctx, q, url.ID, model.LinkEventCreated, url)
+	})
 }
 
 func (r *PostgresURLRepository) CreateOrGet(ctx context.Context, url *model.URL) (string, bool, error) {
@@ -69,24 +112,80 @@ func (r *PostgresURLRepository) CreateOrGet(ctx context.Context, url *model.URL)
 	defer cancel()
 
 	// Use UPSERT with INSERT ... ON CONFLICT DO NOTHING
-	// This reduces from 4 roundtrips (BEGIN + SELECT + INSERT + COMMIT) to 1 single query
+	// This reduces from 4 roundtrips (BEGIN + SELECT + INSERT + COMMIT) to 1 single query.
+	// Reusable destinations are de-duplicated within the link's domain and
+	// owner, its workspace or else its creator, so a link is never handed to
+	// someone outside it; a code already used on that domain is reported as
+	// ErrCodeTaken.
 	query := `
-		INSERT INTO urls (id, original_url, created_at, user_id)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (original_url) DO NOTHING
+		INSERT INTO urls (id, original_url, created_at, user_id, title, description, forward_query, forward_path,
+			utm_source, utm_medium, utm_campaign, utm_term, utm_content, starts_at, expires_at, max_clicks,
+			domain_id, code, workspace_id, claim_token_hash, reusable)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8,
+			NULLIF($9, ''), NULLIF($10, ''), NULLIF($11, ''), NULLIF($12, ''), NULLIF($13, ''), $14, $15, $16,
+			$17, COALESCE(NULLIF($18, ''), $1), $19, NULLIF($20, ''), $21)
+		ON CONFLICT (domain_id, original_url, (COALESCE(workspace_id, user_id)))
+			WHERE archived_at IS NULL AND deleted_at IS NULL AND reusable DO NOTHING
 		RETURNING id, (xmax = 0) AS inserted
 	`
 
+	var utm model.UTMParams
+	if url.UTM != nil {
+		utm = *url.UTM
+	}
+
 	var returnedID string
 	var inserted bool
-	err := r.db.QueryRow(ctx, query, url.ID, url.OriginalURL, time.Now(), url.UserID).Scan(&returnedID, &inserted)
+	createdAt := time.Now()
+	// Tags are only set on new links, which the tags must not outlive
+	run := r.withEvents
+	if len(url.Tags) > 0 && url.UserID != nil {
+		run = r.inTx
+	}
+	err := run(ctx, func(q querier) error {
+		err := q.QueryRow(ctx, query, url.ID, url.OriginalURL, createdAt, url.UserID, url.Title, url.Description,
+			url.ForwardQuery, url.ForwardPath,
+			utm.Source, utm.Medium, utm.Campaign, utm.Term, utm.Content, url.StartsAt, url.ExpiresAt, url.MaxClicks,
+			url.DomainID, url.Code, url.WorkspaceID, url.ClaimHash, url.Reusable).Scan(&returnedID, &inserted)
+		if err != nil || !inserted {
+			return err
+		}
+		if len(url.Tags) > 0 && url.UserID != nil {
+			if err := r.setTags(ctx, q, *url.UserID, returnedID, url.Tags); err != nil {
+				return err
+			}
+		}
+
+		created := *url
+		created.ID = returnedID
+		created.CreatedAt = createdAt
+		if created.Code == "" {
+			created.Code = returnedID
+		}
+		return r.appendEvent(ctx, q, returnedID, model.LinkEventCreated, &created)
+	})
 
 	if err != nil {
 		// If the UPSERT failed to return (conflict but no RETURNING), we need to fetch the existing ID
 		if errors.Is(err, pgx.ErrNoRows) {
 			// This means the INSERT was skipped due to conflict, need to get existing ID
+			existingQuery := `
+				SELECT id FROM urls
+				WHERE original_url = $1 AND domain_id IS NOT DISTINCT FROM $2 AND archived_at IS NULL AND deleted_at IS NULL
+					AND reusable AND COALESCE(workspace_id, user_id) IS NOT DISTINCT FROM COALESCE($3::uuid, $4::uuid)
+			`
+			if url.UserID == nil && url.WorkspaceID == nil {
+				// An anonymous link handed to a second creator can no longer be
+				// claimed by either of them
+				existingQuery = `
+					UPDATE urls SET claim_token_hash = NULL
+					WHERE original_url = $1 AND domain_id IS NOT DISTINCT FROM $2 AND archived_at IS NULL AND deleted_at IS NULL
+						AND reusable AND COALESCE(workspace_id, user_id) IS NOT DISTINCT FROM COALESCE($3::uuid, $4::uuid)
+					RETURNING id
+				`
+			}
 			var existingID string
-			selectErr := r.db.QueryRow(ctx, "SELECT id FROM urls WHERE original_url = $1", url.OriginalURL).Scan(&existingID)
+			selectErr := conn(ctx, r.db).QueryRow(ctx, existingQuery, url.OriginalURL, url.DomainID, url.WorkspaceID, url.UserID).Scan(&existingID)
 			if selectErr != nil {
 				r.logger.Error("Failed to fetch existing URL after conflict", zap.Error(selectErr), zap.String("url", url.OriginalURL))
 				return "", false, fmt.Errorf("%w: %v", ErrDatabaseError, selectErr)
@@ -97,6 +196,14 @@ func (r *PostgresURLRepository) CreateOrGet(ctx context.Context, url *model.URL)
 			return existingID, false, nil
 		}
 
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == "urls_domain_code_unique" {
+			return "", false, ErrCodeTaken
+		}
+		if errors.Is(err, ErrDatabaseError) {
+			return "", false, err
+		}
+
 		r.logger.Error("Failed to insert URL", zap.Error(err), zap.String("id", url.ID))
 		return "", false, fmt.Errorf("%w: %v", ErrDatabaseError, err)
 	}
@@ -116,12 +223,15 @@ func (r *PostgresURLRepository) FindByID(ctx context.Context, id string) (*model
 	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
 	defer cancel()
 
-	if r.redisClient != nil {
+	// Inside a transaction the link may have changed already, which the
+	// cache must neither answer for nor be filled with
+	cached := r.redisClient != nil && !inTransaction(ctx)
+	if cached {
 		val, err := r.redisClient.Get(ctx, id).Result()
 		if err == nil {
 			r.logger.Debug("URL found in cache", zap.String("id", id))
 			metrics.RecordCacheHit(ctx, "redis")
-			return &model.URL{ID: id, OriginalURL: val}, nil
+			return decodeCachedURL(id, val), nil
 		}
 
 		if err == redis.Nil {
@@ -132,8 +242,20 @@ func (r *PostgresURLRepository) FindByID(ctx context.Context, id string) (*model
 	}
 
 	var urlModel model.URL
-	query := `SELECT id, original_url, created_at FROM urls WHERE id = $1`
-	err := r.db.QueryRow(ctx, query, id).Scan(&urlModel.ID, &urlModel.OriginalURL, &urlModel.CreatedAt)
+	query := `
+		SELECT u.id, u.code, u.domain_id, COALESCE(d.hostname, ''), u.original_url, u.created_at, u.user_id,
+			u.workspace_id, u.forward_query, u.forward_path, u.sticky_variants, u.starts_at,
+			u.expires_at, u.max_clicks, u.disabled, COALESCE(u.disabled_reason, ''), u.fallback,
+			u.archived_at, u.deleted_at
+		FROM urls u
+		LEFT JOIN domains d ON d.id = u.domain_id
+		WHERE u.id = $1
+	`
+	err := conn(ctx, r.db).QueryRow(ctx, query, id).Scan(&urlModel.ID, &urlModel.Code, &urlModel.DomainID, &urlModel.Domain,
+		&urlModel.OriginalURL, &urlModel.CreatedAt, &urlModel.UserID, &urlModel.WorkspaceID,
+		&urlModel.ForwardQuery, &urlModel.ForwardPath, &urlModel.StickyVariants, &urlModel.StartsAt,
+		&urlModel.ExpiresAt, &urlModel.MaxClicks, &urlModel.Disabled, &urlModel.DisabledReason, &urlModel.Fallback,
+		&urlModel.ArchivedAt, &urlModel.DeletedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			r.logger.Debug("URL not found", zap.String("id", id))
@@ -143,8 +265,21 @@ func (r *PostgresURLRepository) FindByID(ctx context.Context, id string) (*model
 		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
 	}
 
-	if r.redisClient != nil {
-		if err := r.redisClient.Set(ctx, id, urlModel.OriginalURL, cacheTimeout).Err(); err != nil {
+	urlModel.Rules, err = r.loadRules(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	urlModel.Variants, err = r.loadVariants(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	urlModel.Schedule, err = r.loadSchedule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl := cacheTTL(&urlModel, time.Now()); cached && ttl >= minCacheTTL {
+		if err := r.redisClient.Set(ctx, id, encodeCachedURL(&urlModel), ttl).Err(); err != nil {
 			r.logger.Warn("Failed to cache URL", zap.Error(err), zap.String("id", id))
 		}
 	}
@@ -153,12 +288,103 @@ func (r *PostgresURLRepository) FindByID(ctx context.Context, id string) (*model
 	return &urlModel, nil
 }
 
+// FindByCode looks up a link by the code visitors use on a custom domain.
+// The code to id mapping never changes, so it is cached for the full period.
+func (r *PostgresURLRepository) FindByCode(ctx context.Context, domainID uuid.UUID, code string) (*model.URL, error) {
+	key := codeCachePrefix + domainID.String() + ":" + code
+
+	var id string
+	if r.redisClient != nil {
+		if val, err := r.redisClient.Get(ctx, key).Result(); err == nil {
+			id = val
+		} else if err != redis.Nil {
+			r.logger.Warn("Cache error", zap.Error(err), zap.String("code", code))
+		}
+	}
+
+	if id == "" {
+		queryCtx, cancel := context.WithTimeout(ctx, dbTimeout)
+		defer cancel()
+
+		err := conn(ctx, r.db).QueryRow(queryCtx, `SELECT id FROM urls WHERE domain_id = $1 AND code = $2`, domainID, code).Scan(&id)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrURLNotFound
+			}
+			r.logger.Error("Database query error", zap.Error(err), zap.String("code", code))
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+
+		if r.redisClient != nil {
+			if err := r.redisClient.Set(queryCtx, key, id, cacheTimeout).Err(); err != nil {
+				r.logger.Warn("Failed to cache code", zap.Error(err), zap.String("code", code))
+			}
+		}
+	}
+
+	url, err := r.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Guards against a stale mapping outliving its link
+	if url.DomainID == nil || *url.DomainID != domainID || url.Code != code {
+		return nil, ErrURLNotFound
+	}
+	return url, nil
+}
+
+// cacheTTL caps the cache lifetime at the link's next scheduled transition so
+// activations and destination switches are never served late from Redis
+func cacheTTL(url *model.URL, now time.Time) time.Duration {
+	ttl := cacheTimeout
+	if next := url.NextTransition(now); !next.IsZero() && next.Sub(now) < ttl {
+		ttl = next.Sub(now)
+	}
+	return ttl
+}
+
+// encodeCachedURL serializes the fields needed to resolve a link
+func encodeCachedURL(url *model.URL) string {
+	val, err := json.Marshal(url)
+	if err != nil {
+		return url.OriginalURL
+	}
+	return string(val)
+}
+
+// decodeCachedURL also accepts the plain destination strings cached by
+// earlier versions
+func decodeCachedURL(id, val string) *model.URL {
+	if strings.HasPrefix(val, "{") {
+		var url model.URL
+		if err := json.Unmarshal([]byte(val), &url); err == nil {
+			return &url
+		}
+	}
+	return &model.URL{ID: id, OriginalURL: val}
+}
+
+// evictCache drops a cached link after it changed, once the change is
+// committed
+func (r *PostgresURLRepository) evictCache(ctx context.Context, id string) {
+	if r.redisClient == nil {
+		return
+	}
+	afterCommit(ctx, func(ctx context.Context) {
+		if err := r.redisClient.Del(ctx, id).Err(); err != nil {
+			r.logger.Warn("Failed to evict cached URL", zap.Error(err), zap.String("id", id))
+		}
+	})
+}
+
 func (r *PostgresURLRepository) FindByURL(ctx context.Context, url string) (string, error) {
 	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
 	defer cancel()
 
 	var id string
-	err := r.db.QueryRow(ctx, "SELECT id FROM urls WHERE original_url = $1 LIMIT 1", url).Scan(&id)
+	err := conn(ctx, r.db).QueryRow(ctx, `
+		SELECT id FROM urls WHERE original_url = $1 AND archived_at IS NULL AND deleted_at IS NULL LIMIT 1
+	`, url).Scan(&id)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			r.logger.Debug("URL not found", zap.String("url", url))
@@ -177,7 +403,7 @@ func (r *PostgresURLRepository) IDExists(ctx context.Context, id string) (bool,
 	defer cancel()
 
 	var count int
-	err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM urls WHERE id = $1", id).Scan(&count)
+	err := conn(ctx, r.db).QueryRow(ctx, "SELECT COUNT(*) FROM urls WHERE id = $1", id).Scan(&count)
 	if err != nil {
 		r.logger.Error("Failed to check ID existence", zap.Error(err), zap.String("id", id))
 		return false, fmt.Errorf("%w: %v", ErrDatabaseError, err)
@@ -186,12 +412,44 @@ func (r *PostgresURLRepository) IDExists(ctx context.Context, id string) (bool,
 	return count > 0, nil
 }
 
-func (r *PostgresURLRepository) GetUserURLs(ctx context.Context, userId uuid.UUID) ([]model.URL, error) {
+func (r *PostgresURLRepository) GetUserURLs(ctx context.Context, userId uuid.UUID, filter URLFilter) ([]model.URL, error) {
 	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
 	defer cancel()
 
-	query := `SELECT id, original_url, created_at FROM urls WHERE user_id = $1`
-	rows, err := r.db.Query(ctx, query, userId)
+	query := `
+		SELECT u.id, u.code, u.domain_id, COALESCE(d.hostname, ''), u.workspace_id, u.user_id,
+			u.original_url, u.created_at, u.forward_query, u.forward_path,
+			COALESCE(u.title, ''), COALESCE(u.description, ''),
+			COALESCE(array_agg(t.name ORDER BY t.name) FILTER (WHERE t.id IS NOT NULL), '{}'),
+			COALESCE(u.meta_title, ''), COALESCE(u.meta_description, ''),
+			COALESCE(u.meta_image_url, ''), COALESCE(u.favicon_url, ''), u.metadata_fetched_at,
+			COALESCE(u.health_status_code, 0), COALESCE(u.health_redirect_chain, '{}'),
+			COALESCE(u.health_error, ''), u.health_checked_at,
+			COALESCE(u.utm_source, ''), COALESCE(u.utm_medium, ''), COALESCE(u.utm_campaign, ''),
+			COALESCE(u.utm_term, ''), COALESCE(u.utm_content, ''), u.starts_at,
+			u.expires_at, u.max_clicks, u.click_count, u.disabled, COALESCE(u.disabled_reason, ''), u.fallback,
+			u.archived_at, u.deleted_at
+		FROM urls u
+		LEFT JOIN domains d ON d.id = u.domain_id
+		LEFT JOIN url_tags ut ON ut.url_id = u.id
+		LEFT JOIN tags t ON t.id = ut.tag_id AND t.user_id = $1
+		WHERE (($3::uuid IS NULL AND u.user_id = $1 AND (u.workspace_id IS NULL OR EXISTS (
+				SELECT 1 FROM workspace_members m WHERE m.workspace_id = u.workspace_id AND m.user_id = $1
+			))) OR u.workspace_id = $3)
+			AND ($2::text = '' OR EXISTS (
+				SELECT 1 FROM url_tags fut
+				JOIN tags ft ON ft.id = fut.tag_id
+				WHERE fut.url_id = u.id AND ft.user_id = $1 AND ft.name = $2
+			))
+			AND CASE $4::text
+				WHEN 'archived' THEN u.archived_at IS NOT NULL AND u.deleted_at IS NULL
+				WHEN 'deleted' THEN u.deleted_at IS NOT NULL
+				ELSE u.archived_at IS NULL AND u.deleted_at IS NULL
+			END
+		GROUP BY u.id, d.hostname
+		ORDER BY u.created_at DESC
+	`
+	rows, err := conn(ctx, r.db).Query(ctx, query, userId, filter.Tag, filter.WorkspaceID, string(filter.State))
 	if err != nil {
 		r.logger.Error("Database query error", zap.Error(err), zap.String("user_id", userId.String()))
 		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
@@ -201,10 +459,34 @@ func (r *PostgresURLRepository) GetUserURLs(ctx context.Context, userId uuid.UUI
 	var urls []model.URL
 	for rows.Next() {
 		var url model.URL
-		if err := rows.Scan(&url.ID, &url.OriginalURL, &url.CreatedAt); err != nil {
+		var meta model.LinkMetadata
+		var health model.LinkHealth
+		var utm model.UTMParams
+		var fetchedAt, checkedAt *time.Time
+		if err := rows.Scan(&url.ID, &url.Code, &url.DomainID, &url.Domain, &url.WorkspaceID, &url.UserID,
+			&url.OriginalURL, &url.CreatedAt,
+			&url.ForwardQuery, &url.ForwardPath,
+			&url.Title, &url.Description, &url.Tags,
+			&meta.Title, &meta.Description, &meta.ImageURL, &meta.FaviconURL, &fetchedAt,
+			&health.StatusCode, &health.RedirectChain, &health.Error, &checkedAt,
+			&utm.Source, &utm.Medium, &utm.Campaign, &utm.Term, &utm.Content, &url.StartsAt,
+			&url.ExpiresAt, &url.MaxClicks, &url.ClickCount, &url.Disabled, &url.DisabledReason, &url.Fallback,
+			&url.ArchivedAt, &url.DeletedAt); err != nil {
 			r.logger.Error("Failed to scan URL row", zap.Error(err))
 			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
 		}
+		if fetchedAt != nil {
+			meta.FetchedAt = *fetchedAt
+			url.Metadata = &meta
+		}
+		if checkedAt != nil && (health.StatusCode != 0 || health.Error != "") {
+			health.CheckedAt = *checkedAt
+			health.Healthy = model.IsHealthyStatus(health.StatusCode) && health.Error == ""
+			url.Health = &health
+		}
+		if !utm.IsEmpty() {
+			url.UTM = &utm
+		}
 		urls = append(urls, url)
 	}
 
@@ -215,3 +497,664 @@ func (r *PostgresURLRepository) GetUserURLs(ctx context.Context, userId uuid.UUI
 
 	return urls, nil
 }
+
+// UpdateDetails changes the editable fields of a URL. Nil values are left untouched.
+func (r *PostgresURLRepository) UpdateDetails(ctx context.Context, id string, changes URLChanges) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE urls
+		SET title = CASE WHEN $2::text IS NULL THEN title ELSE NULLIF($2, '') END,
+			description = CASE WHEN $3::text IS NULL THEN description ELSE NULLIF($3, '') END,
+			forward_query = COALESCE($4, forward_query),
+			forward_path = COALESCE($5, forward_path)
+		WHERE id = $1
+	`
+	err := r.withEvents(ctx, func(q querier) error {
+		tag, err := q.Exec(ctx, query, id, changes.Title, changes.Description,
+			changes.ForwardQuery, changes.ForwardPath)
+		if err != nil {
+			r.logger.Error("Failed to update URL details", zap.Error(err), zap.String("id", id))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrURLNotFound
+		}
+		return r.appendEvent(ctx, q, id, model.LinkEventDetailsUpdated, detailsChanged(changes))
+	})
+	if err != nil {
+		return err
+	}
+
+	r.evictCache(ctx, id)
+	return nil
+}
+
+// detailsChanged lists the fields UpdateDetails changed with their new value
+func detailsChanged(changes URLChanges) map[string]any {
+	changed := make(map[string]any)
+	if changes.Title != nil {
+		changed["title"] = *changes.Title
+	}
+	if changes.Description != nil {
+		changed["description"] = *changes.Description
+	}
+	if changes.ForwardQuery != nil {
+		changed["forward_query"] = *changes.ForwardQuery
+	}
+	if changes.ForwardPath != nil {
+		changed["forward_path"] = *changes.ForwardPath
+	}
+	return changed
+}
+
+// SetTags replaces the tags userId put on a URL, creating any tag the user
+// does not have yet. Tags are personal, other members' tags are kept.
+func (r *PostgresURLRepository) SetTags(ctx context.Context, userId uuid.UUID, id string, tags []string) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	return r.inTx(ctx, func(q querier) error {
+		_, err := q.Exec(ctx, `
+			DELETE FROM url_tags WHERE url_id = $1 AND tag_id IN (SELECT id FROM tags WHERE user_id = $2)
+		`, id, userId)
+		if err != nil {
+			r.logger.Error("Failed to clear URL tags", zap.Error(err), zap.String("id", id))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		if err := r.setTags(ctx, q, userId, id, tags); err != nil {
+			return err
+		}
+		return r.appendEvent(ctx, q, id, model.LinkEventTagsUpdated, map[string]any{"user_id": userId, "tags": tags})
+	})
+}
+
+// setTags attaches tags of userId to a URL, creating those the user does
+// not have yet
+func (r *PostgresURLRepository) setTags(ctx context.Context, q querier, userId uuid.UUID, id string, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	_, err := q.Exec(ctx, `
+		INSERT INTO tags (user_id, name)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT (user_id, name) DO NOTHING
+	`, userId, tags)
+	if err != nil {
+		r.logger.Error("Failed to create tags", zap.Error(err), zap.String("id", id))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	_, err = q.Exec(ctx, `
+		INSERT INTO url_tags (url_id, tag_id)
+		SELECT $1, id FROM tags WHERE user_id = $2 AND name = ANY($3)
+	`, id, userId, tags)
+	if err != nil {
+		r.logger.Error("Failed to attach tags", zap.Error(err), zap.String("id", id))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return nil
+}
+
+func (r *PostgresURLRepository) loadRules(ctx context.Context, id string) ([]model.RoutingRule, error) {
+	rows, err := conn(ctx, r.db).Query(ctx, `
+		SELECT COALESCE(os, ''), COALESCE(device, ''), COALESCE(browser, ''),
+			COALESCE(language, ''), COALESCE(country, ''), destination
+		FROM url_routing_rules
+		WHERE url_id = $1
+		ORDER BY position
+	`, id)
+	if err != nil {
+		r.logger.Error("Failed to query routing rules", zap.Error(err), zap.String("id", id))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	var rules []model.RoutingRule
+	for rows.Next() {
+		var rule model.RoutingRule
+		if err := rows.Scan(&rule.OS, &rule.Device, &rule.Browser, &rule.Language, &rule.Country, &rule.URL); err != nil {
+			r.logger.Error("Failed to scan routing rule", zap.Error(err), zap.String("id", id))
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return rules, nil
+}
+
+// GetRules returns the routing rules of a URL
+func (r *PostgresURLRepository) GetRules(ctx context.Context, id string) ([]model.RoutingRule, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	var exists bool
+	err := conn(ctx, r.db).QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM urls WHERE id = $1)`, id).Scan(&exists)
+	if err != nil {
+		r.logger.Error("Failed to check URL existence", zap.Error(err), zap.String("id", id))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if !exists {
+		return nil, ErrURLNotFound
+	}
+
+	return r.loadRules(ctx, id)
+}
+
+// SetRules replaces the routing rules of a URL
+func (r *PostgresURLRepository) SetRules(ctx context.Context, id string, rules []model.RoutingRule) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var locked string
+	err = tx.QueryRow(ctx, `SELECT id FROM urls WHERE id = $1 FOR UPDATE`, id).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrURLNotFound
+		}
+		r.logger.Error("Failed to lock URL", zap.Error(err), zap.String("id", id))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM url_routing_rules WHERE url_id = $1`, id); err != nil {
+		r.logger.Error("Failed to clear routing rules", zap.Error(err), zap.String("id", id))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	for i, rule := range rules {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO url_routing_rules (url_id, position, os, device, browser, language, country, destination)
+			VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), $8)
+		`, id, i, rule.OS, rule.Device, rule.Browser, rule.Language, rule.Country, rule.URL)
+		if err != nil {
+			r.logger.Error("Failed to insert routing rule", zap.Error(err), zap.String("id", id))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+	}
+
+	if err := r.appendEvent(ctx, tx, id, model.LinkEventRulesUpdated, map[string]any{"rules": rules}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("Failed to commit routing rules", zap.Error(err), zap.String("id", id))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	r.evictCache(ctx, id)
+	return nil
+}
+
+func (r *PostgresURLRepository) loadVariants(ctx context.Context, id string) ([]model.Variant, error) {
+	rows, err := conn(ctx, r.db).Query(ctx, `
+		SELECT destination, weight FROM url_variants WHERE url_id = $1 ORDER BY position
+	`, id)
+	if err != nil {
+		r.logger.Error("Failed to query variants", zap.Error(err), zap.String("id", id))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	var variants []model.Variant
+	for rows.Next() {
+		var variant model.Variant
+		if err := rows.Scan(&variant.URL, &variant.Weight); err != nil {
+			r.logger.Error("Failed to scan variant", zap.Error(err), zap.String("id", id))
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		variants = append(variants, variant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return variants, nil
+}
+
+// SetVariants replaces the A/B destinations of a URL
+func (r *PostgresURLRepository) SetVariants(ctx context.Context, id string, variants []model.Variant, sticky bool) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	tag, err := tx.Exec(ctx, `UPDATE urls SET sticky_variants = $2 WHERE id = $1`, id, sticky)
+	if err != nil {
+		r.logger.Error("Failed to update URL", zap.Error(err), zap.String("id", id))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrURLNotFound
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM url_variants WHERE url_id = $1`, id); err != nil {
+		r.logger.Error("Failed to clear variants", zap.Error(err), zap.String("id", id))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	for i, variant := range variants {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO url_variants (url_id, position, destination, weight) VALUES ($1, $2, $3, $4)
+		`, id, i, variant.URL, variant.Weight)
+		if err != nil {
+			r.logger.Error("Failed to insert variant", zap.Error(err), zap.String("id", id))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+	}
+
+	err = r.appendEvent(ctx, tx, id, model.LinkEventVariantsUpdated, map[string]any{"variants": variants, "sticky": sticky})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("Failed to commit variants", zap.Error(err), zap.String("id", id))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	r.evictCache(ctx, id)
+	return nil
+}
+
+func (r *PostgresURLRepository) loadSchedule(ctx context.Context, id string) ([]model.ScheduledDestination, error) {
+	rows, err := conn(ctx, r.db).Query(ctx, `
+		SELECT destination, effective_at FROM url_schedule WHERE url_id = $1 ORDER BY effective_at
+	`, id)
+	if err != nil {
+		r.logger.Error("Failed to query schedule", zap.Error(err), zap.String("id", id))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	var schedule []model.ScheduledDestination
+	for rows.Next() {
+		var change model.ScheduledDestination
+		if err := rows.Scan(&change.URL, &change.EffectiveAt); err != nil {
+			r.logger.Error("Failed to scan scheduled destination", zap.Error(err), zap.String("id", id))
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		schedule = append(schedule, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return schedule, nil
+}
+
+// SetSchedule replaces the activation time and the pending destination
+// changes of a URL. Changes already in effect are kept.
+func (r *PostgresURLRepository) SetSchedule(ctx context.Context, id string, startsAt *time.Time, changes []model.ScheduledDestination) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	tag, err := tx.Exec(ctx, `UPDATE urls SET starts_at = $2 WHERE id = $1`, id, startsAt)
+	if err != nil {
+		r.logger.Error("Failed to update URL", zap.Error(err), zap.String("id", id))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrURLNotFound
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM url_schedule WHERE url_id = $1 AND effective_at > NOW()`, id); err != nil {
+		r.logger.Error("Failed to clear schedule", zap.Error(err), zap.String("id", id))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	for _, change := range changes {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO url_schedule (url_id, effective_at, destination) VALUES ($1, $2, $3)
+			ON CONFLICT (url_id, effective_at) DO UPDATE SET destination = EXCLUDED.destination
+		`, id, change.EffectiveAt, change.URL)
+		if err != nil {
+			r.logger.Error("Failed to insert scheduled destination", zap.Error(err), zap.String("id", id))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+	}
+
+	err = r.appendEvent(ctx, tx, id, model.LinkEventScheduleUpdated, map[string]any{"starts_at": startsAt, "schedule": changes})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("Failed to commit schedule", zap.Error(err), zap.String("id", id))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	r.evictCache(ctx, id)
+	return nil
+}
+
+// SetAvailability replaces the lifetime limits of a URL. Links blocked for
+// legal reasons, by an admin or for abuse stay disabled whatever the owner
+// asks.
+func (r *PostgresURLRepository) SetAvailability(ctx context.Context, id string, availability Availability) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE urls
+		SET expires_at = $2,
+			max_clicks = $3,
+			disabled = CASE WHEN disabled_reason IN ('legal', 'admin', 'abuse') THEN disabled ELSE $4 END,
+			disabled_reason = CASE
+				WHEN disabled_reason IN ('legal', 'admin', 'abuse') THEN disabled_reason
+				WHEN $4 THEN 'owner'
+				ELSE NULL
+			END
+		WHERE id = $1
+	`
+	err := r.withEvents(ctx, func(q querier) error {
+		tag, err := q.Exec(ctx, query, id, availability.ExpiresAt, availability.MaxClicks, availability.Disabled)
+		if err != nil {
+			r.logger.Error("Failed to update URL availability", zap.Error(err), zap.String("id", id))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrURLNotFound
+		}
+		return r.appendEvent(ctx, q, id, model.LinkEventAvailabilityUpdated, map[string]any{
+			"expires_at": availability.ExpiresAt,
+			"max_clicks": availability.MaxClicks,
+			"disabled":   availability.Disabled,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	r.evictCache(ctx, id)
+	return nil
+}
+
+// SetFallback sets or, when fallback is nil, removes the fallback of a URL
+func (r *PostgresURLRepository) SetFallback(ctx context.Context, id string, fallback *model.Fallback) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	err := r.withEvents(ctx, func(q querier) error {
+		tag, err := q.Exec(ctx, `UPDATE urls SET fallback = $2 WHERE id = $1`, id, fallback)
+		if err != nil {
+			r.logger.Error("Failed to update URL fallback", zap.Error(err), zap.String("id", id))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrURLNotFound
+		}
+		return r.appendEvent(ctx, q, id, model.LinkEventFallbackUpdated, map[string]any{"fallback": fallback})
+	})
+	if err != nil {
+		return err
+	}
+
+	r.evictCache(ctx, id)
+	return nil
+}
+
+// Claim attaches an anonymous link to userId when claimHash matches. The
+// hash is cleared in the same statement so a token only works once. Claimed
+// links are not de-duplicated against their new owner's links.
+func (r *PostgresURLRepository) Claim(ctx context.Context, id, claimHash string, userId uuid.UUID, workspaceID *uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	err := r.withEvents(ctx, func(q querier) error {
+		tag, err := q.Exec(ctx, `
+			UPDATE urls SET user_id = $3, workspace_id = $4, claim_token_hash = NULL, reusable = FALSE
+			WHERE id = $1 AND claim_token_hash = $2 AND user_id IS NULL
+		`, id, claimHash, userId, workspaceID)
+		if err != nil {
+			r.logger.Error("Failed to claim URL", zap.Error(err), zap.String("id", id))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrURLNotFound
+		}
+		return r.appendEvent(ctx, q, id, model.LinkEventClaimed, map[string]any{"user_id": userId, "workspace_id": workspaceID})
+	})
+	if err != nil {
+		return err
+	}
+
+	r.evictCache(ctx, id)
+	return nil
+}
+
+// SetLifecycle archives, deletes or, with both nil, restores a URL. A link
+// cannot be restored while another live link of the same owner has the
+// same destination on its domain.
+func (r *PostgresURLRepository) SetLifecycle(ctx context.Context, id string, archivedAt, deletedAt *time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	event := model.LinkEventRestored
+	switch {
+	case deletedAt != nil:
+		event = model.LinkEventDeleted
+	case archivedAt != nil:
+		event = model.LinkEventArchived
+	}
+
+	err := r.withEvents(ctx, func(q querier) error {
+		tag, err := q.Exec(ctx, `UPDATE urls SET archived_at = $2, deleted_at = $3 WHERE id = $1`, id, archivedAt, deletedAt)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == "urls_domain_original_url_unique" {
+				return ErrDuplicateURL
+			}
+			r.logger.Error("Failed to update URL lifecycle", zap.Error(err), zap.String("id", id))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrURLNotFound
+		}
+		return r.appendEvent(ctx, q, id, event, map[string]any{"archived_at": archivedAt, "deleted_at": deletedAt})
+	})
+	if err != nil {
+		return err
+	}
+
+	r.evictCache(ctx, id)
+	return nil
+}
+
+// Purge permanently removes up to limit links archived before
+// archivedBefore and not deleted since, or deleted before deletedBefore, and
+// returns them as they were. A deleted link only counts from its deletion,
+// however long it was archived before. Their rules, variants, clicks and tags go
+// with them; abuse reports and transfer records are kept.
+func (r *PostgresURLRepository) Purge(ctx context.Context, archivedBefore, deletedBefore time.Time, limit int) ([]model.URL, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	query := `
+		DELETE FROM urls
+		WHERE id IN (
+			SELECT id FROM urls
+			WHERE (deleted_at IS NULL AND archived_at < $1) OR deleted_at < $2
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, code, domain_id, original_url, user_id, workspace_id, archived_at, deleted_at
+	`
+	var urls []model.URL
+	err := r.withEvents(ctx, func(q querier) error {
+		rows, err := q.Query(ctx, query, archivedBefore, deletedBefore, limit)
+		if err != nil {
+			r.logger.Error("Failed to purge URLs", zap.Error(err))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var url model.URL
+			if err := rows.Scan(&url.ID, &url.Code, &url.DomainID, &url.OriginalURL, &url.UserID, &url.WorkspaceID,
+				&url.ArchivedAt, &url.DeletedAt); err != nil {
+				r.logger.Error("Failed to scan URL row", zap.Error(err))
+				return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+			}
+			urls = append(urls, url)
+		}
+
+		if err := rows.Err(); err != nil {
+			r.logger.Error("Row iteration error", zap.Error(err))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+
+		for i := range urls {
+			if err := r.appendEvent(ctx, q, urls[i].ID, model.LinkEventPurged, &urls[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, url := range urls {
+		r.evictCache(ctx, url.ID)
+		if url.DomainID != nil {
+			r.evictCache(ctx, codeCachePrefix+url.DomainID.String()+":"+url.Code)
+		}
+	}
+	return urls, nil
+}
+
+// ConsumeClick counts a click against the link's limit and reports whether
+// it was still allowed. The check and increment are one statement so
+// concurrent visits across replicas cannot overshoot the limit.
+func (r *PostgresURLRepository) ConsumeClick(ctx context.Context, id string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	tag, err := conn(ctx, r.db).Exec(ctx, `
+		UPDATE urls SET click_count = click_count + 1
+		WHERE id = $1 AND (max_clicks IS NULL OR click_count < max_clicks)
+	`, id)
+	if err != nil {
+		r.logger.Error("Failed to count click", zap.Error(err), zap.String("id", id))
+		return false, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// SaveMetadata stores fetched page metadata and uses it as title and
+// description when the owner did not provide them
+func (r *PostgresURLRepository) SaveMetadata(ctx context.Context, id string, meta *model.LinkMetadata) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE urls
+		SET meta_title = NULLIF($2, ''),
+			meta_description = NULLIF($3, ''),
+			meta_image_url = NULLIF($4, ''),
+			favicon_url = NULLIF($5, ''),
+			metadata_fetched_at = $6,
+			title = COALESCE(title, NULLIF($2, '')),
+			description = COALESCE(description, NULLIF($3, ''))
+		WHERE id = $1
+	`
+	return r.withEvents(ctx, func(q querier) error {
+		tag, err := q.Exec(ctx, query, id, meta.Title, meta.Description, meta.ImageURL, meta.FaviconURL, meta.FetchedAt)
+		if err != nil {
+			r.logger.Error("Failed to save URL metadata", zap.Error(err), zap.String("id", id))
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrURLNotFound
+		}
+		return r.appendEvent(ctx, q, id, model.LinkEventMetadataFetched, meta)
+	})
+}
+
+// ClaimForHealthCheck returns up to limit links not checked since before and
+// stamps them, so concurrent replicas do not pick the same links
+func (r *PostgresURLRepository) ClaimForHealthCheck(ctx context.Context, before time.Time, limit int) ([]model.URL, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE urls SET health_checked_at = NOW()
+		WHERE id IN (
+			SELECT id FROM urls
+			WHERE health_checked_at IS NULL OR health_checked_at < $1
+			ORDER BY health_checked_at NULLS FIRST
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, original_url
+	`
+	rows, err := conn(ctx, r.db).Query(ctx, query, before, limit)
+	if err != nil {
+		r.logger.Error("Failed to claim links for health check", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	var urls []model.URL
+	for rows.Next() {
+		var url model.URL
+		if err := rows.Scan(&url.ID, &url.OriginalURL); err != nil {
+			r.logger.Error("Failed to scan URL row", zap.Error(err))
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		urls = append(urls, url)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Row iteration error", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return urls, nil
+}
+
+func (r *PostgresURLRepository) SaveHealth(ctx context.Context, id string, health *model.LinkHealth) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE urls
+		SET health_status_code = NULLIF($2, 0),
+			health_redirect_chain = $3,
+			health_error = NULLIF($4, ''),
+			health_checked_at = $5
+		WHERE id = $1
+	`
+	_, err := conn(ctx, r.db).Exec(ctx, query, id, health.StatusCode, health.RedirectChain, health.Error, health.CheckedAt)
+	if err != nil {
+		r.logger.Error("Failed to save link health", zap.Error(err), zap.String("id", id))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return nil
+}
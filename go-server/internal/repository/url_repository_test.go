@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheTTL(t *testing.T) {
+	now := time.Now()
+	soon := now.Add(10 * time.Minute)
+	past := now.Add(-time.Hour)
+
+	testCases := []struct {
+		name     string
+		link     model.URL
+		expected time.Duration
+	}{
+		{"nothing scheduled", model.URL{}, cacheTimeout},
+		{"capped at activation", model.URL{StartsAt: &soon}, 10 * time.Minute},
+		{"past activation ignored", model.URL{StartsAt: &past}, cacheTimeout},
+		{"capped at next destination change", model.URL{Schedule: []model.ScheduledDestination{
+			{URL: "https://example.com/old", EffectiveAt: past},
+			{URL: "https://example.com/later", EffectiveAt: now.Add(48 * time.Hour)},
+			{URL: "https://example.com/next", EffectiveAt: now.Add(time.Hour)},
+		}}, time.Hour},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, cacheTTL(&tc.link, now))
+		})
+	}
+}
+
+func TestCachedURLKeepsLifecycle(t *testing.T) {
+	archivedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	deletedAt := archivedAt.Add(time.Hour)
+	link := &model.URL{ID: "abc123", OriginalURL: "https://example.com", ArchivedAt: &archivedAt, DeletedAt: &deletedAt}
+
+	cached := decodeCachedURL("abc123", encodeCachedURL(link))
+
+	assert.Equal(t, model.LinkDeleted, cached.State(time.Now()))
+	assert.True(t, archivedAt.Equal(*cached.ArchivedAt))
+	assert.True(t, deletedAt.Equal(*cached.DeletedAt))
+}
+
+func TestCachedURLKeepsOwner(t *testing.T) {
+	userID, workspaceID := uuid.New(), uuid.New()
+	link := &model.URL{ID: "abc123", OriginalURL: "https://example.com", UserID: &userID, WorkspaceID: &workspaceID}
+
+	// Clicks on cached links are reported to the workspace's webhooks
+	cached := decodeCachedURL("abc123", encodeCachedURL(link))
+
+	assert.Equal(t, &userID, cached.UserID)
+	assert.Equal(t, &workspaceID, cached.WorkspaceID)
+}
@@ -2,14 +2,21 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var ErrUserNotFound = errors.New("user not found")
+
 type UserRepository interface {
 	Create(ctx context.Context, user *model.User) error
 	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*model.User, error)
 }
 
 type userRepository struct {
@@ -24,19 +31,51 @@ func (r *userRepository) Create(ctx context.Context, user *model.User) error {
 	query :=
 		`INSERT INTO users (username, email, password_hash) 
 		VALUES ($1, $2, $3)
-		RETURNING id, created_at`
+		RETURNING id, role, created_at`
 
-	return r.db.QueryRow(ctx, query, user.Username, user.Email, user.PasswordHash).
-		Scan(&user.ID, &user.CreatedAt)
+	return conn(ctx, r.db).QueryRow(ctx, query, user.Username, user.Email, user.PasswordHash).
+		Scan(&user.ID, &user.Role, &user.CreatedAt)
 }
 
 func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
-	query := `SELECT id, username, email, password_hash, created_at FROM users WHERE email = $1`
+	query := `
+		SELECT id, username, email, email_verified,
+			EXISTS (SELECT 1 FROM user_mfa m WHERE m.user_id = users.id AND m.enabled_at IS NOT NULL),
+			password_hash, role, disabled_at, session_version, created_at
+		FROM users WHERE email = $1
+	`
+
+	user := &model.User{}
+	err := conn(ctx, r.db).QueryRow(ctx, query, email).Scan(&user.ID, &user.Username, &user.Email, &user.EmailVerified,
+		&user.MFAEnabled, &user.PasswordHash, &user.Role, &user.DisabledAt, &user.SessionVersion, &user.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return user, nil
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	query := `
+		SELECT id, COALESCE(username, ''), email, email_verified,
+			EXISTS (SELECT 1 FROM user_mfa m WHERE m.user_id = users.id AND m.enabled_at IS NOT NULL),
+			role, disabled_at, session_version, created_at
+		FROM users WHERE id = $1 AND deleted_at IS NULL
+	`
 
 	user := &model.User{}
-	err := r.db.QueryRow(ctx, query, email).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	err := conn(ctx, r.db).QueryRow(ctx, query, id).Scan(&user.ID, &user.Username, &user.Email, &user.EmailVerified,
+		&user.MFAEnabled, &user.Role, &user.DisabledAt, &user.SessionVersion, &user.CreatedAt)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
 	}
 	return user, nil
 }
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var ErrUTMTemplateNotFound = errors.New("UTM template not found")
+
+// UTMTemplateRepository stores the default UTM parameters of workspaces
+type UTMTemplateRepository interface {
+	Get(ctx context.Context, workspaceID uuid.UUID) (*model.UTMTemplate, error)
+	Upsert(ctx context.Context, workspaceID uuid.UUID, params model.UTMParams) (*model.UTMTemplate, error)
+	Delete(ctx context.Context, workspaceID uuid.UUID) error
+}
+
+type PostgresUTMTemplateRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewPostgresUTMTemplateRepository(db *pgxpool.Pool) *PostgresUTMTemplateRepository {
+	return &PostgresUTMTemplateRepository{
+		db:     db,
+		logger: zap.L().With(zap.String("component", "PostgresUTMTemplateRepository")),
+	}
+}
+
+func (r *PostgresUTMTemplateRepository) Get(ctx context.Context, workspaceID uuid.UUID) (*model.UTMTemplate, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	query := `
+		SELECT COALESCE(utm_source, ''), COALESCE(utm_medium, ''), COALESCE(utm_campaign, ''),
+			COALESCE(utm_term, ''), COALESCE(utm_content, ''), updated_at
+		FROM utm_templates WHERE workspace_id = $1
+	`
+	tpl := model.UTMTemplate{WorkspaceID: workspaceID}
+	err := r.db.QueryRow(ctx, query, workspaceID).Scan(&tpl.Source, &tpl.Medium, &tpl.Campaign,
+		&tpl.Term, &tpl.Content, &tpl.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUTMTemplateNotFound
+		}
+		r.logger.Error("Database query error", zap.Error(err), zap.String("workspace_id", workspaceID.String()))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return &tpl, nil
+}
+
+func (r *PostgresUTMTemplateRepository) Upsert(ctx context.Context, workspaceID uuid.UUID, params model.UTMParams) (*model.UTMTemplate, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO utm_templates (workspace_id, utm_source, utm_medium, utm_campaign, utm_term, utm_content, updated_at)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NOW())
+		ON CONFLICT (workspace_id) DO UPDATE SET
+			utm_source = EXCLUDED.utm_source,
+			utm_medium = EXCLUDED.utm_medium,
+			utm_campaign = EXCLUDED.utm_campaign,
+			utm_term = EXCLUDED.utm_term,
+			utm_content = EXCLUDED.utm_content,
+			updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`
+	tpl := model.UTMTemplate{WorkspaceID: workspaceID, UTMParams: params}
+	err := r.db.QueryRow(ctx, query, workspaceID, params.Source, params.Medium, params.Campaign,
+		params.Term, params.Content).Scan(&tpl.UpdatedAt)
+	if err != nil {
+		r.logger.Error("Failed to save UTM template", zap.Error(err), zap.String("workspace_id", workspaceID.String()))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return &tpl, nil
+}
+
+func (r *PostgresUTMTemplateRepository) Delete(ctx context.Context, workspaceID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	tag, err := r.db.Exec(ctx, `DELETE FROM utm_templates WHERE workspace_id = $1`, workspaceID)
+	if err != nil {
+		r.logger.Error("Failed to delete UTM template", zap.Error(err), zap.String("workspace_id", workspaceID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUTMTemplateNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,327 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// DeliveryFilter pages through the delivery log of a webhook, newest first
+type DeliveryFilter struct {
+	Status model.DeliveryStatus
+	Limit  int
+	Offset int
+}
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *model.Webhook) error
+	List(ctx context.Context, userID uuid.UUID) ([]model.Webhook, error)
+	Get(ctx context.Context, userID, id uuid.UUID) (*model.Webhook, error)
+	Update(ctx context.Context, webhook *model.Webhook) error
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+	// EnqueueEvent queues payload for every active webhook of owner
+	// subscribed to event and returns how many deliveries were queued
+	EnqueueEvent(ctx context.Context, owner model.LinkOwner, event model.WebhookEvent, payload json.RawMessage) (int, error)
+	// ClaimDueDeliveries returns up to limit pending deliveries due at now
+	// and holds them until leaseUntil, so concurrent replicas do not send
+	// the same ones
+	ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]model.WebhookDelivery, error)
+	SaveDeliveryAttempt(ctx context.Context, delivery *model.WebhookDelivery) error
+	ListDeliveries(ctx context.Context, webhookID uuid.UUID, filter DeliveryFilter) ([]model.WebhookDelivery, error)
+	// Replay queues a copy of a delivery of one of userID's webhooks
+	Replay(ctx context.Context, userID, webhookID, deliveryID uuid.UUID) (*model.WebhookDelivery, error)
+}
+
+type PostgresWebhookRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewPostgresWebhookRepository(db *pgxpool.Pool) *PostgresWebhookRepository {
+	return &PostgresWebhookRepository{
+		db:     db,
+		logger: zap.L().With(zap.String("component", "PostgresWebhookRepository")),
+	}
+}
+
+func (r *PostgresWebhookRepository) Create(ctx context.Context, webhook *model.Webhook) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	err := conn(ctx, r.db).QueryRow(ctx, `
+		INSERT INTO webhooks (user_id, url, secret, events, active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`, webhook.UserID, webhook.URL, webhook.Secret, eventNames(webhook.Events), webhook.Active).
+		Scan(&webhook.ID, &webhook.CreatedAt, &webhook.UpdatedAt)
+	if err != nil {
+		r.logger.Error("Failed to insert webhook", zap.Error(err), zap.String("user_id", webhook.UserID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return nil
+}
+
+func (r *PostgresWebhookRepository) List(ctx context.Context, userID uuid.UUID) ([]model.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	rows, err := conn(ctx, r.db).Query(ctx, `
+		SELECT id, user_id, url, events, active, created_at, updated_at
+		FROM webhooks WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		r.logger.Error("Database query error", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	webhooks := []model.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			r.logger.Error("Failed to scan webhook row", zap.Error(err))
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Row iteration error", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return webhooks, nil
+}
+
+func (r *PostgresWebhookRepository) Get(ctx context.Context, userID, id uuid.UUID) (*model.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	webhook, err := scanWebhook(conn(ctx, r.db).QueryRow(ctx, `
+		SELECT id, user_id, url, events, active, created_at, updated_at
+		FROM webhooks WHERE id = $1 AND user_id = $2
+	`, id, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		r.logger.Error("Database query error", zap.Error(err), zap.String("webhook_id", id.String()))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return webhook, nil
+}
+
+// Update saves the URL, events and active flag of a webhook
+func (r *PostgresWebhookRepository) Update(ctx context.Context, webhook *model.Webhook) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	err := conn(ctx, r.db).QueryRow(ctx, `
+		UPDATE webhooks SET url = $3, events = $4, active = $5, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING updated_at
+	`, webhook.ID, webhook.UserID, webhook.URL, eventNames(webhook.Events), webhook.Active).Scan(&webhook.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrWebhookNotFound
+		}
+		r.logger.Error("Failed to update webhook", zap.Error(err), zap.String("webhook_id", webhook.ID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return nil
+}
+
+// Delete removes a webhook along with its delivery log
+func (r *PostgresWebhookRepository) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	tag, err := conn(ctx, r.db).Exec(ctx, `DELETE FROM webhooks WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		r.logger.Error("Failed to delete webhook", zap.Error(err), zap.String("webhook_id", id.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (r *PostgresWebhookRepository) EnqueueEvent(ctx context.Context, owner model.LinkOwner, event model.WebhookEvent, payload json.RawMessage) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	// Membership is read now, so members who left no longer hear of the links
+	tag, err := conn(ctx, r.db).Exec(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		SELECT w.id, $3, $4 FROM webhooks w
+		WHERE w.active AND $3 = ANY(w.events) AND CASE
+			WHEN $1::uuid IS NOT NULL THEN EXISTS (
+				SELECT 1 FROM workspace_members m WHERE m.workspace_id = $1 AND m.user_id = w.user_id
+			)
+			ELSE w.user_id = $2
+		END
+	`, owner.WorkspaceID, owner.UserID, string(event), string(payload))
+	if err != nil {
+		r.logger.Error("Failed to enqueue webhook event", zap.Error(err), zap.String("event", string(event)))
+		return 0, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func (r *PostgresWebhookRepository) ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]model.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	// Deliveries of paused webhooks stay queued until they are resumed
+	rows, err := conn(ctx, r.db).Query(ctx, `
+		UPDATE webhook_deliveries d SET next_attempt_at = $2
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT q.id FROM webhook_deliveries q
+			JOIN webhooks qw ON qw.id = q.webhook_id AND qw.active
+			WHERE q.status = 'pending' AND q.next_attempt_at <= $1
+			ORDER BY q.next_attempt_at
+			LIMIT $3
+			FOR UPDATE OF q SKIP LOCKED
+		)
+		RETURNING d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at,
+			d.replay_of, d.created_at, w.url, w.secret
+	`, now, leaseUntil, limit)
+	if err != nil {
+		r.logger.Error("Failed to claim webhook deliveries", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	var deliveries []model.WebhookDelivery
+	for rows.Next() {
+		var d model.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.ReplayOf, &d.CreatedAt, &d.URL, &d.Secret); err != nil {
+			r.logger.Error("Failed to scan delivery row", zap.Error(err))
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Row iteration error", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return deliveries, nil
+}
+
+// SaveDeliveryAttempt records the outcome of sending a delivery
+func (r *PostgresWebhookRepository) SaveDeliveryAttempt(ctx context.Context, delivery *model.WebhookDelivery) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	_, err := conn(ctx, r.db).Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_status_code = $5,
+			last_error = NULLIF($6, ''), delivered_at = $7
+		WHERE id = $1
+	`, delivery.ID, delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.LastStatusCode,
+		delivery.LastError, delivery.DeliveredAt)
+	if err != nil {
+		r.logger.Error("Failed to save delivery attempt", zap.Error(err), zap.String("delivery_id", delivery.ID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return nil
+}
+
+func (r *PostgresWebhookRepository) ListDeliveries(ctx context.Context, webhookID uuid.UUID, filter DeliveryFilter) ([]model.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	rows, err := conn(ctx, r.db).Query(ctx, `
+		SELECT id, webhook_id, event, payload, status, attempts, next_attempt_at, last_status_code,
+			COALESCE(last_error, ''), replay_of, created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1 AND ($2::text = '' OR status = $2)
+		ORDER BY created_at DESC, id
+		LIMIT $3 OFFSET $4
+	`, webhookID, string(filter.Status), filter.Limit, filter.Offset)
+	if err != nil {
+		r.logger.Error("Database query error", zap.Error(err), zap.String("webhook_id", webhookID.String()))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	deliveries := []model.WebhookDelivery{}
+	for rows.Next() {
+		var d model.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastStatusCode, &d.LastError, &d.ReplayOf, &d.CreatedAt, &d.DeliveredAt); err != nil {
+			r.logger.Error("Failed to scan delivery row", zap.Error(err))
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Row iteration error", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return deliveries, nil
+}
+
+func (r *PostgresWebhookRepository) Replay(ctx context.Context, userID, webhookID, deliveryID uuid.UUID) (*model.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	var d model.WebhookDelivery
+	err := conn(ctx, r.db).QueryRow(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, replay_of)
+		SELECT d.webhook_id, d.event, d.payload, d.id
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.id = $1 AND d.webhook_id = $2 AND w.user_id = $3
+		RETURNING id, webhook_id, event, payload, status, attempts, next_attempt_at, replay_of, created_at
+	`, deliveryID, webhookID, userID).Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.ReplayOf, &d.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDeliveryNotFound
+		}
+		r.logger.Error("Failed to replay delivery", zap.Error(err), zap.String("delivery_id", deliveryID.String()))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return &d, nil
+}
+
+func scanWebhook(row pgx.Row) (*model.Webhook, error) {
+	var webhook model.Webhook
+	var events []string
+	if err := row.Scan(&webhook.ID, &webhook.UserID, &webhook.URL, &events, &webhook.Active,
+		&webhook.CreatedAt, &webhook.UpdatedAt); err != nil {
+		return nil, err
+	}
+	webhook.Events = make([]model.WebhookEvent, len(events))
+	for i, e := range events {
+		webhook.Events[i] = model.WebhookEvent(e)
+	}
+	return &webhook, nil
+}
+
+func eventNames(events []model.WebhookEvent) []string {
+	names := make([]string, len(events))
+	for i, e := range events {
+		names[i] = string(e)
+	}
+	return names
+}
@@ -0,0 +1,440 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrWorkspaceNotFound  = errors.New("workspace not found")
+	ErrMemberNotFound     = errors.New("workspace member not found")
+	ErrLastOwner          = errors.New("workspace needs at least one owner")
+	ErrInvitationNotFound = errors.New("invitation not found")
+)
+
+type WorkspaceRepository interface {
+	Create(ctx context.Context, workspace *model.Workspace, ownerID uuid.UUID) error
+	Personal(ctx context.Context, userID uuid.UUID) (*model.Workspace, error)
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]model.Workspace, error)
+	Get(ctx context.Context, workspaceID uuid.UUID) (*model.Workspace, error)
+	Role(ctx context.Context, workspaceID, userID uuid.UUID) (model.Role, error)
+	LinkRole(ctx context.Context, linkID string, userID uuid.UUID) (model.Role, error)
+	Members(ctx context.Context, workspaceID uuid.UUID) ([]model.WorkspaceMember, error)
+	SetRole(ctx context.Context, workspaceID, userID uuid.UUID, role model.Role) error
+	RemoveMember(ctx context.Context, workspaceID, userID uuid.UUID) error
+	CreateInvitation(ctx context.Context, invitation *model.Invitation, tokenHash string) error
+	ListInvitations(ctx context.Context, workspaceID uuid.UUID) ([]model.Invitation, error)
+	DeleteInvitation(ctx context.Context, workspaceID, invitationID uuid.UUID) error
+	AcceptInvitation(ctx context.Context, tokenHash string, userID uuid.UUID, now time.Time) (*model.WorkspaceMember, error)
+}
+
+type PostgresWorkspaceRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewPostgresWorkspaceRepository(db *pgxpool.Pool) *PostgresWorkspaceRepository {
+	return &PostgresWorkspaceRepository{
+		db:     db,
+		logger: zap.L().With(zap.String("component", "PostgresWorkspaceRepository")),
+	}
+}
+
+// Create stores a shared workspace with ownerID as its first owner
+func (r *PostgresWorkspaceRepository) Create(ctx context.Context, workspace *model.Workspace, ownerID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO workspaces (name, created_by) VALUES ($1, $2)
+		RETURNING id, created_at
+	`, workspace.Name, ownerID).Scan(&workspace.ID, &workspace.CreatedAt)
+	if err != nil {
+		r.logger.Error("Failed to insert workspace", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, 'owner')
+	`, workspace.ID, ownerID); err != nil {
+		r.logger.Error("Failed to add workspace owner", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("Failed to commit workspace", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	workspace.Role = model.RoleOwner
+	return nil
+}
+
+// Personal returns the personal workspace of userID, creating it for
+// accounts registered after workspaces were introduced
+func (r *PostgresWorkspaceRepository) Personal(ctx context.Context, userID uuid.UUID) (*model.Workspace, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	workspace := model.Workspace{Personal: true, Role: model.RoleOwner}
+	query := `
+		WITH created AS (
+			INSERT INTO workspaces (name, personal, created_by) VALUES ('Personal', TRUE, $1)
+			ON CONFLICT (created_by) WHERE personal DO NOTHING
+			RETURNING id, name, created_at
+		), member AS (
+			INSERT INTO workspace_members (workspace_id, user_id, role)
+			SELECT id, $1, 'owner' FROM created
+		)
+		SELECT id, name, created_at FROM created
+		UNION ALL
+		SELECT id, name, created_at FROM workspaces WHERE personal AND created_by = $1
+		LIMIT 1
+	`
+	if err := r.db.QueryRow(ctx, query, userID).Scan(&workspace.ID, &workspace.Name, &workspace.CreatedAt); err != nil {
+		r.logger.Error("Failed to load personal workspace", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return &workspace, nil
+}
+
+func (r *PostgresWorkspaceRepository) ListForUser(ctx context.Context, userID uuid.UUID) ([]model.Workspace, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, `
+		SELECT w.id, w.name, w.personal, m.role, w.created_at
+		FROM workspaces w
+		JOIN workspace_members m ON m.workspace_id = w.id
+		WHERE m.user_id = $1
+		ORDER BY w.personal DESC, w.name
+	`, userID)
+	if err != nil {
+		r.logger.Error("Database query error", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	workspaces := []model.Workspace{}
+	for rows.Next() {
+		var workspace model.Workspace
+		if err := rows.Scan(&workspace.ID, &workspace.Name, &workspace.Personal, &workspace.Role, &workspace.CreatedAt); err != nil {
+			r.logger.Error("Failed to scan workspace row", zap.Error(err))
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		workspaces = append(workspaces, workspace)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Row iteration error", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return workspaces, nil
+}
+
+func (r *PostgresWorkspaceRepository) Get(ctx context.Context, workspaceID uuid.UUID) (*model.Workspace, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	var workspace model.Workspace
+	err := r.db.QueryRow(ctx, `SELECT id, name, personal, created_at FROM workspaces WHERE id = $1`, workspaceID).
+		Scan(&workspace.ID, &workspace.Name, &workspace.Personal, &workspace.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWorkspaceNotFound
+		}
+		r.logger.Error("Database query error", zap.Error(err), zap.String("workspace_id", workspaceID.String()))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return &workspace, nil
+}
+
+// Role returns the role of userID in a workspace, ErrWorkspaceNotFound when
+// they are not a member
+func (r *PostgresWorkspaceRepository) Role(ctx context.Context, workspaceID, userID uuid.UUID) (model.Role, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	var role model.Role
+	err := r.db.QueryRow(ctx, `
+		SELECT role FROM workspace_members WHERE workspace_id = $1 AND user_id = $2
+	`, workspaceID, userID).Scan(&role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrWorkspaceNotFound
+		}
+		r.logger.Error("Database query error", zap.Error(err), zap.String("workspace_id", workspaceID.String()))
+		return "", fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return role, nil
+}
+
+// LinkRole returns the role of userID in the workspace owning a link.
+// Links created before workspaces without one count as owned by their
+// creator. ErrURLNotFound hides links the user has no access to.
+func (r *PostgresWorkspaceRepository) LinkRole(ctx context.Context, linkID string, userID uuid.UUID) (model.Role, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	var role *model.Role
+	err := r.db.QueryRow(ctx, `
+		SELECT COALESCE(m.role, CASE WHEN u.workspace_id IS NULL AND u.user_id = $2 THEN 'owner' END)
+		FROM urls u
+		LEFT JOIN workspace_members m ON m.workspace_id = u.workspace_id AND m.user_id = $2
+		WHERE u.id = $1
+	`, linkID, userID).Scan(&role)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		r.logger.Error("Database query error", zap.Error(err), zap.String("id", linkID))
+		return "", fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if role == nil {
+		return "", ErrURLNotFound
+	}
+
+	return *role, nil
+}
+
+func (r *PostgresWorkspaceRepository) Members(ctx context.Context, workspaceID uuid.UUID) ([]model.WorkspaceMember, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, `
+		SELECT m.workspace_id, m.user_id, COALESCE(u.username, ''), u.email, m.role, m.created_at
+		FROM workspace_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.workspace_id = $1
+		ORDER BY m.created_at
+	`, workspaceID)
+	if err != nil {
+		r.logger.Error("Database query error", zap.Error(err), zap.String("workspace_id", workspaceID.String()))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	var members []model.WorkspaceMember
+	for rows.Next() {
+		var member model.WorkspaceMember
+		if err := rows.Scan(&member.WorkspaceID, &member.UserID, &member.Username, &member.Email,
+			&member.Role, &member.JoinedAt); err != nil {
+			r.logger.Error("Failed to scan member row", zap.Error(err))
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Row iteration error", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return members, nil
+}
+
+// SetRole changes a member's role. Demoting the last owner fails with ErrLastOwner.
+func (r *PostgresWorkspaceRepository) SetRole(ctx context.Context, workspaceID, userID uuid.UUID, role model.Role) error {
+	return r.changeMember(ctx, workspaceID, userID, func(tx pgx.Tx) (int64, error) {
+		tag, err := tx.Exec(ctx, `
+			UPDATE workspace_members SET role = $3 WHERE workspace_id = $1 AND user_id = $2
+		`, workspaceID, userID, role)
+		return tag.RowsAffected(), err
+	})
+}
+
+// RemoveMember takes a member out of a workspace. Their links stay in it.
+// Removing the last owner fails with ErrLastOwner.
+func (r *PostgresWorkspaceRepository) RemoveMember(ctx context.Context, workspaceID, userID uuid.UUID) error {
+	return r.changeMember(ctx, workspaceID, userID, func(tx pgx.Tx) (int64, error) {
+		tag, err := tx.Exec(ctx, `
+			DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2
+		`, workspaceID, userID)
+		return tag.RowsAffected(), err
+	})
+}
+
+// changeMember applies change under a lock on the workspace's members so
+// concurrent demotions cannot leave it without an owner
+func (r *PostgresWorkspaceRepository) changeMember(ctx context.Context, workspaceID, userID uuid.UUID, change func(pgx.Tx) (int64, error)) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM workspaces WHERE id = $1 FOR UPDATE`, workspaceID); err != nil {
+		r.logger.Error("Failed to lock workspace", zap.Error(err), zap.String("workspace_id", workspaceID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	affected, err := change(tx)
+	if err != nil {
+		r.logger.Error("Failed to change workspace member", zap.Error(err), zap.String("workspace_id", workspaceID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if affected == 0 {
+		return ErrMemberNotFound
+	}
+
+	var owners int
+	if err := tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM workspace_members WHERE workspace_id = $1 AND role = 'owner'
+	`, workspaceID).Scan(&owners); err != nil {
+		r.logger.Error("Failed to count owners", zap.Error(err), zap.String("workspace_id", workspaceID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if owners == 0 {
+		return ErrLastOwner
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("Failed to commit member change", zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return nil
+}
+
+func (r *PostgresWorkspaceRepository) CreateInvitation(ctx context.Context, invitation *model.Invitation, tokenHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO workspace_invitations (workspace_id, email, role, token_hash, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, invitation.WorkspaceID, invitation.Email, invitation.Role, tokenHash, invitation.InvitedBy, invitation.ExpiresAt).
+		Scan(&invitation.ID, &invitation.CreatedAt)
+	if err != nil {
+		r.logger.Error("Failed to insert invitation", zap.Error(err), zap.String("workspace_id", invitation.WorkspaceID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return nil
+}
+
+// ListInvitations returns the invitations of a workspace not accepted yet
+func (r *PostgresWorkspaceRepository) ListInvitations(ctx context.Context, workspaceID uuid.UUID) ([]model.Invitation, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, `
+		SELECT id, workspace_id, email, role, invited_by, expires_at, accepted_at, created_at
+		FROM workspace_invitations
+		WHERE workspace_id = $1 AND accepted_at IS NULL
+		ORDER BY created_at DESC
+	`, workspaceID)
+	if err != nil {
+		r.logger.Error("Database query error", zap.Error(err), zap.String("workspace_id", workspaceID.String()))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	invitations := []model.Invitation{}
+	for rows.Next() {
+		var invitation model.Invitation
+		if err := rows.Scan(&invitation.ID, &invitation.WorkspaceID, &invitation.Email, &invitation.Role,
+			&invitation.InvitedBy, &invitation.ExpiresAt, &invitation.AcceptedAt, &invitation.CreatedAt); err != nil {
+			r.logger.Error("Failed to scan invitation row", zap.Error(err))
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		invitations = append(invitations, invitation)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Row iteration error", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return invitations, nil
+}
+
+func (r *PostgresWorkspaceRepository) DeleteInvitation(ctx context.Context, workspaceID, invitationID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	tag, err := r.db.Exec(ctx, `
+		DELETE FROM workspace_invitations WHERE id = $1 AND workspace_id = $2 AND accepted_at IS NULL
+	`, invitationID, workspaceID)
+	if err != nil {
+		r.logger.Error("Failed to delete invitation", zap.Error(err), zap.String("invitation_id", invitationID.String()))
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInvitationNotFound
+	}
+
+	return nil
+}
+
+// AcceptInvitation adds userID to the invitation's workspace when the token
+// is pending, unexpired and addressed to the user's verified email. Members
+// keep their current role. The invitation cannot be used again.
+func (r *PostgresWorkspaceRepository) AcceptInvitation(ctx context.Context, tokenHash string, userID uuid.UUID, now time.Time) (*model.WorkspaceMember, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var invitationID uuid.UUID
+	member := model.WorkspaceMember{UserID: userID}
+	err = tx.QueryRow(ctx, `
+		SELECT i.id, i.workspace_id, i.role, u.email, COALESCE(u.username, '')
+		FROM workspace_invitations i
+		JOIN users u ON u.id = $2 AND lower(u.email) = lower(i.email) AND u.email_verified
+		WHERE i.token_hash = $1 AND i.accepted_at IS NULL AND i.expires_at > $3
+		FOR UPDATE OF i
+	`, tokenHash, userID, now).Scan(&invitationID, &member.WorkspaceID, &member.Role, &member.Email, &member.Username)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvitationNotFound
+		}
+		r.logger.Error("Failed to load invitation", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO workspace_members (workspace_id, user_id, role, created_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (workspace_id, user_id) DO UPDATE SET role = workspace_members.role
+		RETURNING role, created_at
+	`, member.WorkspaceID, userID, member.Role, now).Scan(&member.Role, &member.JoinedAt)
+	if err != nil {
+		r.logger.Error("Failed to add workspace member", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE workspace_invitations SET accepted_at = $2 WHERE id = $1`, invitationID, now); err != nil {
+		r.logger.Error("Failed to mark invitation accepted", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("Failed to commit invitation", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return &member, nil
+}
@@ -0,0 +1,97 @@
+package retention
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultInterval  = time.Hour
+	defaultBatchSize = 500
+)
+
+// Store permanently removes links whose retention window has passed
+type Store interface {
+	PurgeExpiredLinks(ctx context.Context, limit int) (int, error)
+}
+
+// PurgerConfig controls how often and in which batches links are purged
+type PurgerConfig struct {
+	Interval  time.Duration
+	BatchSize int
+}
+
+// Purger removes archived and deleted links once they can no longer be
+// restored. Batches lock their rows with SKIP LOCKED, so every replica
+// may run one.
+type Purger struct {
+	store  Store
+	cfg    PurgerConfig
+	wg     sync.WaitGroup
+	logger *zap.Logger
+}
+
+func NewPurger(store Store, cfg PurgerConfig) *Purger {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+
+	return &Purger{
+		store:  store,
+		cfg:    cfg,
+		logger: zap.L().With(zap.String("component", "LinkPurger")),
+	}
+}
+
+// Start runs a purge round every Interval until ctx is cancelled
+func (p *Purger) Start(ctx context.Context) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := p.RunOnce(ctx); err != nil {
+					p.logger.Warn("Link purge round failed", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// Wait blocks until the round in progress, if any, has finished after ctx
+// was cancelled
+func (p *Purger) Wait() {
+	p.wg.Wait()
+}
+
+// RunOnce purges batches until no expired link is left
+func (p *Purger) RunOnce(ctx context.Context) error {
+	total := 0
+	for {
+		n, err := p.store.PurgeExpiredLinks(ctx, p.cfg.BatchSize)
+		total += n
+		if err != nil {
+			return err
+		}
+		if n < p.cfg.BatchSize || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if total > 0 {
+		p.logger.Info("Link purge round completed", zap.Int("purged", total))
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore hands out the batch sizes in batches, then 0, and records
+// the limit of every call. purged is called after each batch when set.
+type memoryStore struct {
+	mu      sync.Mutex
+	batches []int
+	limits  []int
+	err     error
+	purged  func()
+}
+
+func (m *memoryStore) PurgeExpiredLinks(ctx context.Context, limit int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limits = append(m.limits, limit)
+	if m.err != nil {
+		return 0, m.err
+	}
+	n := 0
+	if len(m.batches) > 0 {
+		n, m.batches = m.batches[0], m.batches[1:]
+	}
+	if m.purged != nil {
+		m.purged()
+	}
+	return n, nil
+}
+
+func (m *memoryStore) calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.limits)
+}
+
+func TestRunOnce_PurgesUntilShortBatch(t *testing.T) {
+	store := &memoryStore{batches: []int{3, 3, 1, 3}}
+	purger := NewPurger(store, PurgerConfig{BatchSize: 3})
+
+	require.NoError(t, purger.RunOnce(context.Background()))
+
+	// The short third batch means nothing is left; the fourth is not asked for
+	assert.Equal(t, []int{3, 3, 3}, store.limits)
+}
+
+func TestRunOnce_StopsWhenExactBatchIsFollowedByNone(t *testing.T) {
+	store := &memoryStore{batches: []int{2}}
+	purger := NewPurger(store, PurgerConfig{BatchSize: 2})
+
+	require.NoError(t, purger.RunOnce(context.Background()))
+
+	assert.Equal(t, []int{2, 2}, store.limits)
+}
+
+func TestRunOnce_DefaultBatchSize(t *testing.T) {
+	store := &memoryStore{}
+	purger := NewPurger(store, PurgerConfig{})
+
+	require.NoError(t, purger.RunOnce(context.Background()))
+
+	assert.Equal(t, []int{defaultBatchSize}, store.limits)
+}
+
+func TestRunOnce_ReturnsStoreError(t *testing.T) {
+	store := &memoryStore{err: errors.New("database is down")}
+	purger := NewPurger(store, PurgerConfig{BatchSize: 3})
+
+	assert.ErrorIs(t, purger.RunOnce(context.Background()), store.err)
+	assert.Equal(t, 1, store.calls())
+}
+
+func TestRunOnce_StopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Full batches would go on, but shutdown starts after the first
+	store := &memoryStore{batches: []int{3, 3, 3}, purged: cancel}
+	purger := NewPurger(store, PurgerConfig{BatchSize: 3})
+
+	require.NoError(t, purger.RunOnce(ctx))
+
+	assert.Equal(t, 1, store.calls())
+}
+
+func TestStart_RunsEveryIntervalUntilCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	store := &memoryStore{}
+	purger := NewPurger(store, PurgerConfig{Interval: 10 * time.Millisecond})
+
+	purger.Start(ctx)
+	require.Eventually(t, func() bool { return store.calls() >= 2 }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	purger.Wait()
+	calls := store.calls()
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, calls, store.calls())
+}
@@ -1,8 +1,13 @@
 package route
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -13,15 +18,55 @@ import (
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/analytics"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/geoip"
 	"github.com/fonsecaaso/TinyUrl/go-server/internal/handler"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/healthcheck"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/mail"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/metadata"
 	"github.com/fonsecaaso/TinyUrl/go-server/internal/metrics"
 	"github.com/fonsecaaso/TinyUrl/go-server/internal/middleware"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/outbox"
 	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/retention"
 	"github.com/fonsecaaso/TinyUrl/go-server/internal/service"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/webhook"
 )
 
-func SetupRouter(redisClient *redis.Client, pgClient *pgxpool.Pool, prometheusHandler http.Handler) *gin.Engine {
+// Workers are the background goroutines SetupRouter starts
+type Workers struct {
+	waiters []interface{ Wait() }
+}
+
+// Wait blocks until every worker has stopped, once the context passed to
+// SetupRouter is cancelled. Queued clicks, and the webhook events that
+// come with them, are written before the click recorder stops.
+func (w *Workers) Wait() {
+	for _, waiter := range w.waiters {
+		waiter.Wait()
+	}
+}
+
+// SetupRouter builds the API and starts its background workers, which run
+// until ctx is cancelled. It fails before starting anything when the
+// configuration asks for checks it cannot enforce.
+func SetupRouter(ctx context.Context, redisClient *redis.Client, pgClient *pgxpool.Pool, prometheusHandler http.Handler) (*gin.Engine, *Workers, error) {
 	r := gin.New()
+	workers := &Workers{}
+
+	// Client IPs key the login throttle and are kept in the audit log, so
+	// X-Forwarded-For is only believed when a known proxy sent it
+	if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+		return nil, nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+
+	// Account emails, e.g. address verification and password resets, need a mailer
+	mailer := accountMailer()
+	verificationRequired, err := requireEmailVerification(mailer)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// Start system metrics collection
 	if err := metrics.StartSystemMetricsCollection(); err != nil {
@@ -37,10 +82,119 @@ func SetupRouter(redisClient *redis.Client, pgClient *pgxpool.Pool, prometheusHa
 
 	urlRepo := repository.NewPostgresURLRepository(pgClient, redisClient)
 	userRepo := repository.NewUserRepository(pgClient)
-	urlService := service.NewURLService(urlRepo)
-	authService := service.NewAuthService(userRepo)
+	tagRepo := repository.NewPostgresTagRepository(pgClient)
+	utmTemplateRepo := repository.NewPostgresUTMTemplateRepository(pgClient)
+	clickRepo := repository.NewPostgresClickRepository(pgClient)
+	fallbackRepo := repository.NewPostgresFallbackRepository(pgClient)
+	domainRepo := repository.NewPostgresDomainRepository(pgClient, redisClient)
+	workspaceRepo := repository.NewPostgresWorkspaceRepository(pgClient)
+	transferRepo := repository.NewPostgresTransferRepository(pgClient, redisClient)
+	auditRepo := repository.NewPostgresAuditRepository(pgClient)
+	adminRepo := repository.NewPostgresAdminRepository(pgClient, redisClient)
+	reportRepo := repository.NewPostgresReportRepository(pgClient, redisClient)
+	webhookRepo := repository.NewPostgresWebhookRepository(pgClient)
+	outboxRepo := repository.NewPostgresOutboxRepository(pgClient)
+	emailVerificationRepo := repository.NewPostgresEmailVerificationRepository(pgClient)
+	passwordResetRepo := repository.NewPostgresPasswordResetRepository(pgClient)
+	mfaRepo := repository.NewPostgresMFARepository(pgClient)
+
+	// Record link changes in the outbox and relay them to the event broker
+	if publisher := eventPublisher(redisClient); publisher != nil {
+		urlRepo.EnableOutbox()
+		adminRepo.EnableOutbox()
+		reportRepo.EnableOutbox()
+		transferRepo.EnableOutbox()
+		relay := outbox.NewRelay(outboxRepo, publisher, outbox.RelayConfig{})
+		relay.Start(ctx)
+		workers.waiters = append(workers.waiters, relay)
+	}
+
+	// Fetch page titles and Open Graph data for new links in the background
+	metadataWorker := metadata.NewWorker(metadata.NewFetcher(metadata.FetcherConfig{}), urlRepo, 0, 0)
+	metadataWorker.Start(ctx)
+
+	// Periodically probe stored destinations for broken links
+	healthScheduler := healthcheck.NewScheduler(healthcheck.NewChecker(healthcheck.CheckerConfig{}), urlRepo, healthcheck.SchedulerConfig{})
+	healthScheduler.Start(ctx)
+
+	// Link events for owners' webhooks are queued in the transaction of the
+	// change or click they report and delivered with retries
+	webhookEmitter := webhook.NewEmitter(webhookRepo)
+	webhookDispatcher := webhook.NewDispatcher(webhookRepo, webhook.DispatcherConfig{})
+	webhookDispatcher.Start(ctx)
+
+	// Record clicks in the background so redirects never wait on analytics writes
+	clickStore := webhook.NewClickStore(repository.NewPostgresTransactor(pgClient), clickRepo, webhookEmitter)
+	clickRecorder := analytics.NewRecorder(clickStore, 0, 0)
+	clickRecorder.Start(ctx)
+	workers.waiters = append(workers.waiters, metadataWorker, healthScheduler, clickRecorder, webhookDispatcher)
+
+	urlOptions := []service.URLServiceOption{
+		service.WithMetadataQueue(metadataWorker),
+		service.WithUTMTemplates(utmTemplateRepo),
+		service.WithClickAnalytics(clickRecorder, clickRepo),
+		service.WithFallbacks(fallbackRepo, globalFallback()),
+		service.WithDomains(domainRepo),
+		service.WithWorkspaces(workspaceRepo),
+		service.WithTransfers(transferRepo),
+		service.WithAudit(auditRepo),
+		service.WithRetention(linkRetention()),
+		service.WithWebhooks(webhookEmitter),
+	}
+
+	// Country routing needs a local MaxMind-format database, e.g. GeoLite2-Country.mmdb
+	if geoIPPath := os.Getenv("GEOIP_DB_PATH"); geoIPPath != "" {
+		resolver, err := geoip.Open(geoIPPath)
+		if err != nil {
+			zap.L().Warn("Failed to open GeoIP database, country routing disabled", zap.Error(err), zap.String("path", geoIPPath))
+		} else {
+			urlOptions = append(urlOptions, service.WithCountryResolver(resolver))
+		}
+	}
+
+	urlService := service.NewURLService(urlRepo, urlOptions...)
+
+	// Remove archived and deleted links once they can no longer be restored
+	purger := retention.NewPurger(urlService, retention.PurgerConfig{})
+	purger.Start(ctx)
+	workers.waiters = append(workers.waiters, purger)
+
+	emailVerificationService := service.NewEmailVerificationService(userRepo, emailVerificationRepo, mailer, auditRepo,
+		service.VerificationConfig{LinkURL: os.Getenv("EMAIL_VERIFICATION_URL")})
+	passwordResetService := service.NewPasswordResetService(userRepo, passwordResetRepo, mailer, auditRepo,
+		service.PasswordResetConfig{LinkURL: os.Getenv("PASSWORD_RESET_URL")})
+	var mfaOptions []service.MFAServiceOption
+	var authOptions []service.AuthServiceOption
+	// Failed sign-ins are counted in Redis so every replica sees them
+	if redisClient != nil {
+		loginAttempts := repository.NewRedisLoginAttemptRepository(redisClient)
+		mfaOptions = append(mfaOptions, service.WithMFALoginThrottle(loginAttempts, mailer, service.LoginThrottleConfig{}))
+		authOptions = append(authOptions, service.WithLoginThrottle(loginAttempts, mailer, service.LoginThrottleConfig{}))
+	}
+	mfaService := service.NewMFAService(userRepo, mfaRepo, auditRepo, os.Getenv("MFA_ISSUER"), mfaOptions...)
+	authOptions = append(authOptions, service.WithMFA(mfaService))
+	if mailer != nil {
+		authOptions = append(authOptions, service.WithEmailVerification(emailVerificationService))
+	}
+
+	authService := service.NewAuthService(userRepo, auditRepo, authOptions...)
+	tagService := service.NewTagService(tagRepo)
+	domainService := service.NewDomainService(domainRepo, nil)
+	workspaceService := service.NewWorkspaceService(workspaceRepo)
+	adminService := service.NewAdminService(adminRepo, auditRepo)
+	reportService := service.NewReportService(reportRepo, auditRepo)
+	webhookService := service.NewWebhookService(webhookRepo)
 	urlHandler := handler.NewURLHandler(urlService)
 	authHandler := handler.NewAuthHandler(authService)
+	emailVerificationHandler := handler.NewEmailVerificationHandler(emailVerificationService)
+	passwordResetHandler := handler.NewPasswordResetHandler(passwordResetService)
+	mfaHandler := handler.NewMFAHandler(mfaService)
+	tagHandler := handler.NewTagHandler(tagService)
+	domainHandler := handler.NewDomainHandler(domainService)
+	workspaceHandler := handler.NewWorkspaceHandler(workspaceService)
+	adminHandler := handler.NewAdminHandler(adminService)
+	reportHandler := handler.NewReportHandler(reportService)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
 
 	// Permite /api e /api/ funcionarem igual
 	r.RedirectTrailingSlash = true
@@ -66,9 +220,9 @@ func SetupRouter(redisClient *redis.Client, pgClient *pgxpool.Pool, prometheusHa
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     allowedOrigins,
-		AllowMethods:     []string{"GET", "POST", "OPTIONS"},
-		AllowHeaders:     []string{"Content-Type", "Authorization", requestIDHeader, "Origin", "Accept"},
-		ExposeHeaders:    []string{"Content-Length", requestIDHeader, "Cache-Hit"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Content-Type", "Authorization", requestIDHeader, "Origin", "Accept", middleware.IdempotencyKeyHeader},
+		ExposeHeaders:    []string{"Content-Length", requestIDHeader, "Cache-Hit", middleware.IdempotentReplayedHeader},
 		AllowCredentials: os.Getenv("ENV") == "local", // Only allow credentials in local dev
 		MaxAge:           12 * time.Hour,
 	}))
@@ -81,6 +235,10 @@ func SetupRouter(redisClient *redis.Client, pgClient *pgxpool.Pool, prometheusHa
 	// Healthz endpoint for observability status
 	r.GET("/healthz", healthzCheck())
 
+	// Custom domains point their DNS here and serve links from the root
+	r.GET("/:id", urlHandler.Redirect)
+	r.GET("/:id/*path", urlHandler.Redirect)
+
 	// API
 	api := r.Group("/api")
 
@@ -96,32 +254,149 @@ func SetupRouter(redisClient *redis.Client, pgClient *pgxpool.Pool, prometheusHa
 		})
 	}
 
-	api.POST("/", urlHandler.CreateTinyURL)
-	api.POST("", urlHandler.CreateTinyURL)
+	// Idempotency-Key support for link creation (needs Redis, skipped without it)
+	createHandlers := []gin.HandlerFunc{urlHandler.CreateTinyURL}
+	if redisClient != nil {
+		idempotencyRepo := repository.NewRedisIdempotencyRepository(redisClient)
+		createHandlers = append([]gin.HandlerFunc{middleware.IdempotencyMiddleware(idempotencyRepo)}, createHandlers...)
+	}
+	// Signing in is optional, but disabled accounts may not create links, nor
+	// unverified ones when REQUIRE_EMAIL_VERIFICATION is set
+	accountCheck := middleware.RejectDisabledAccounts(userRepo)
+	if verificationRequired {
+		accountCheck = middleware.RejectUnverifiedAccounts(userRepo)
+	}
+	createHandlers = append([]gin.HandlerFunc{accountCheck}, createHandlers...)
+
+	api.POST("/", createHandlers...)
+	api.POST("", createHandlers...)
 	api.GET("/:id", urlHandler.GetURL)
+	api.GET("/:id/*path", urlHandler.GetURL)
+	// Abuse reports need no account, so they get a much tighter limit
+	reportLimiter := middleware.NewRateLimiter(10, time.Hour)
+	api.POST("/:id/report", reportLimiter.Middleware(), reportHandler.Report)
 	api.POST("/signup", authHandler.Register)
 	api.POST("/login", authHandler.Login)
+	// Each attempt guesses a code; the login throttle also counts them, but
+	// only with Redis
+	mfaLimiter := middleware.NewRateLimiter(10, time.Minute)
+	api.POST("/login/mfa", mfaLimiter.Middleware(), authHandler.CompleteMFALogin)
+	api.POST("/verify-email", emailVerificationHandler.VerifyEmail)
+	// Every resend mails the account, so they are limited per client
+	verificationLimiter := middleware.NewRateLimiter(5, time.Hour)
+	api.POST("/verify-email/resend", middleware.AuthMiddleware(), middleware.RejectDisabledAccounts(userRepo),
+		verificationLimiter.Middleware(), emailVerificationHandler.ResendVerification)
+	// Each request may mail someone and each reset guesses a token
+	passwordResetLimiter := middleware.NewRateLimiter(10, time.Hour)
+	api.POST("/password/forgot", passwordResetLimiter.Middleware(), passwordResetHandler.ForgotPassword)
+	api.POST("/password/reset", passwordResetLimiter.Middleware(), passwordResetHandler.ResetPassword)
 
 	// Rotas protegidas (requerem autenticação)
 	protected := api.Group("/user")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.AuthMiddleware(), middleware.RejectDisabledAccounts(userRepo))
 	{
 		protected.GET("/urls", urlHandler.GetUserURLs)
+		protected.POST("/urls/claim", urlHandler.ClaimURLs)
+		protected.PATCH("/urls/:id", urlHandler.UpdateURL)
+		protected.DELETE("/urls/:id", urlHandler.DeleteURL)
+		protected.POST("/urls/:id/archive", urlHandler.ArchiveURL)
+		protected.POST("/urls/:id/restore", urlHandler.RestoreURL)
+		protected.GET("/urls/:id/rules", urlHandler.GetRoutingRules)
+		protected.PUT("/urls/:id/rules", urlHandler.SetRoutingRules)
+		protected.PUT("/urls/:id/variants", urlHandler.SetVariants)
+		protected.PUT("/urls/:id/schedule", urlHandler.SetSchedule)
+		protected.PUT("/urls/:id/availability", urlHandler.SetAvailability)
+		protected.PUT("/urls/:id/fallback", urlHandler.SetLinkFallback)
+		protected.DELETE("/urls/:id/fallback", urlHandler.DeleteLinkFallback)
+		protected.GET("/urls/:id/stats", urlHandler.GetStats)
+		protected.GET("/urls/:id/history", urlHandler.GetLinkHistory)
+
+		protected.GET("/fallback", urlHandler.GetFallbackDefault)
+		protected.PUT("/fallback", urlHandler.SetFallbackDefault)
+		protected.DELETE("/fallback", urlHandler.DeleteFallbackDefault)
+
+		protected.GET("/utm-template", urlHandler.GetUTMTemplate)
+		protected.PUT("/utm-template", urlHandler.SetUTMTemplate)
+		protected.DELETE("/utm-template", urlHandler.DeleteUTMTemplate)
+
+		protected.GET("/domains", domainHandler.ListDomains)
+		protected.POST("/domains", domainHandler.AddDomain)
+		protected.POST("/domains/:domainId/verify", domainHandler.VerifyDomain)
+		protected.DELETE("/domains/:domainId", domainHandler.DeleteDomain)
+
+		protected.GET("/workspaces", workspaceHandler.ListWorkspaces)
+		protected.POST("/workspaces", workspaceHandler.CreateWorkspace)
+		protected.GET("/workspaces/:workspaceId/members", workspaceHandler.ListMembers)
+		protected.PATCH("/workspaces/:workspaceId/members/:memberId", workspaceHandler.UpdateMember)
+		protected.DELETE("/workspaces/:workspaceId/members/:memberId", workspaceHandler.RemoveMember)
+		protected.GET("/workspaces/:workspaceId/invitations", workspaceHandler.ListInvitations)
+		protected.POST("/workspaces/:workspaceId/invitations", workspaceHandler.Invite)
+		protected.DELETE("/workspaces/:workspaceId/invitations/:invitationId", workspaceHandler.RevokeInvitation)
+		protected.GET("/workspaces/:workspaceId/audit", urlHandler.GetWorkspaceAudit)
+		protected.POST("/invitations/accept", workspaceHandler.AcceptInvitation)
+
+		protected.GET("/transfers", urlHandler.ListTransfers)
+		protected.POST("/transfers", urlHandler.StartTransfer)
+		protected.POST("/transfers/:transferId/accept", urlHandler.AcceptTransfer)
+		protected.POST("/transfers/:transferId/decline", urlHandler.DeclineTransfer)
+		protected.DELETE("/transfers/:transferId", urlHandler.CancelTransfer)
+
+		protected.GET("/webhooks", webhookHandler.ListWebhooks)
+		protected.POST("/webhooks", webhookHandler.CreateWebhook)
+		protected.PATCH("/webhooks/:webhookId", webhookHandler.UpdateWebhook)
+		protected.DELETE("/webhooks/:webhookId", webhookHandler.DeleteWebhook)
+		protected.GET("/webhooks/:webhookId/deliveries", webhookHandler.ListDeliveries)
+		protected.POST("/webhooks/:webhookId/deliveries/:deliveryId/replay", webhookHandler.ReplayDelivery)
+
+		protected.GET("/tags", tagHandler.ListTags)
+		protected.PATCH("/tags/:tagId", tagHandler.RenameTag)
+		protected.POST("/tags/:tagId/merge", tagHandler.MergeTag)
+		protected.DELETE("/tags/:tagId", tagHandler.DeleteTag)
+
+		protected.POST("/mfa/enroll", mfaHandler.Enroll)
+		protected.POST("/mfa/confirm", mfaLimiter.Middleware(), mfaHandler.Confirm)
+		protected.POST("/mfa/recovery-codes", mfaLimiter.Middleware(), mfaHandler.RegenerateRecoveryCodes)
+		protected.POST("/mfa/disable", mfaLimiter.Middleware(), mfaHandler.Disable)
 	}
 
-	return r
+	// Site-wide moderation, for accounts with the admin role
+	admin := api.Group("/admin")
+	admin.Use(middleware.AuthMiddleware(), middleware.RequireRole(userRepo, model.UserRoleAdmin))
+	{
+		admin.GET("/links", adminHandler.SearchLinks)
+		admin.POST("/links/:id/disable", adminHandler.DisableLink)
+		admin.POST("/links/:id/enable", adminHandler.EnableLink)
+		admin.DELETE("/links/:id/cache", adminHandler.EvictLinkCache)
+		admin.GET("/users", adminHandler.SearchUsers)
+		admin.POST("/users/:userId/disable", adminHandler.DisableUser)
+		admin.POST("/users/:userId/enable", adminHandler.EnableUser)
+		admin.GET("/stats", adminHandler.GetStats)
+		admin.GET("/audit", adminHandler.GetAudit)
+		admin.GET("/reports", reportHandler.ListReports)
+		admin.POST("/reports/:reportId/dismiss", reportHandler.DismissReport)
+		admin.POST("/reports/:reportId/takedown", reportHandler.TakeDownReport)
+	}
+
+	return r, workers, nil
 }
 
-const requestIDHeader = "X-Request-ID"
+const (
+	requestIDHeader = "X-Request-ID"
+	// Longest request ID taken from a client, enough for a UUID or a trace id
+	maxRequestIDLength = 64
+)
 
 func requestIDMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Request IDs end up in the audit log, so odd ones are replaced
 		requestID := c.GetHeader(requestIDHeader)
-		if requestID == "" {
+		if !validRequestID(requestID) {
 			requestID = generateRequestID()
 		}
 		c.Header(requestIDHeader, requestID)
 		c.Set("requestID", requestID)
+		// Lets the service layer tie audit entries to this request
+		c.Request = c.Request.WithContext(service.WithRequestInfo(c.Request.Context(), requestID, c.ClientIP()))
 		c.Next()
 	}
 }
@@ -265,6 +540,142 @@ func healthzCheck() gin.HandlerFunc {
 	}
 }
 
+// globalFallback reads the fallback shown for unavailable links whose owner
+// configured none. Without LINK_FALLBACK_* the built-in page is used.
+func globalFallback() *model.Fallback {
+	fallbackURL := os.Getenv("LINK_FALLBACK_URL")
+	title := os.Getenv("LINK_FALLBACK_TITLE")
+	message := os.Getenv("LINK_FALLBACK_MESSAGE")
+
+	if fallbackURL == "" && title == "" && message == "" {
+		return nil
+	}
+
+	fallback := &model.Fallback{URL: fallbackURL}
+	if title != "" || message != "" {
+		fallback.Page = &model.FallbackPage{Title: title, Message: message}
+	}
+	return fallback
+}
+
+// linkRetention reads how long archived and deleted links stay restorable
+// from LINK_ARCHIVE_RETENTION and LINK_DELETE_RETENTION, e.g. "720h".
+// Unset or invalid values keep the defaults.
+func linkRetention() service.Retention {
+	var retention service.Retention
+	for env, dst := range map[string]*time.Duration{
+		"LINK_ARCHIVE_RETENTION": &retention.Archived,
+		"LINK_DELETE_RETENTION":  &retention.Deleted,
+	} {
+		raw := os.Getenv(env)
+		if raw == "" {
+			continue
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			zap.L().Warn("Invalid link retention, using the default", zap.String("env", env), zap.String("value", raw))
+			continue
+		}
+		*dst = d
+	}
+	return retention
+}
+
+// eventPublisher builds the publisher link events go to from
+// EVENT_PUBLISHER (nats, kafka, redis, file or stdout). EVENT_TOPIC names
+// the subject prefix, topic or stream. Unset, no link events are recorded.
+func eventPublisher(redisClient *redis.Client) outbox.Publisher {
+	kind := os.Getenv("EVENT_PUBLISHER")
+	if kind == "" {
+		return nil
+	}
+
+	publisher, err := outbox.NewPublisher(outbox.Config{
+		Kind:         kind,
+		NATSURL:      os.Getenv("EVENT_NATS_URL"),
+		KafkaRESTURL: os.Getenv("EVENT_KAFKA_REST_URL"),
+		Target:       os.Getenv("EVENT_TOPIC"),
+		FilePath:     os.Getenv("EVENT_FILE_PATH"),
+	}, redisClient)
+	if err != nil {
+		zap.L().Warn("Failed to set up the event publisher, link events disabled", zap.Error(err), zap.String("publisher", kind))
+		return nil
+	}
+	return publisher
+}
+
+// accountMailer builds the mailer account emails go out with from MAILER
+// (smtp or file), MAIL_FROM, SMTP_ADDR, SMTP_USERNAME, SMTP_PASSWORD and
+// MAIL_DIR. Unset, no account emails are sent.
+func accountMailer() mail.Mailer {
+	kind := os.Getenv("MAILER")
+	if kind == "" {
+		return nil
+	}
+
+	mailer, err := mail.NewMailer(mail.Config{
+		Kind:     kind,
+		From:     os.Getenv("MAIL_FROM"),
+		SMTPAddr: os.Getenv("SMTP_ADDR"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		Dir:      os.Getenv("MAIL_DIR"),
+	})
+	if err != nil {
+		zap.L().Warn("Failed to set up the mailer, account emails disabled", zap.Error(err), zap.String("mailer", kind))
+		return nil
+	}
+	return mailer
+}
+
+// requireEmailVerification reports whether REQUIRE_EMAIL_VERIFICATION asks
+// to keep unverified accounts from creating links. An unreadable value or a
+// missing mailer, without which no account could ever verify, is an error
+// rather than a reason to let unverified accounts in.
+func requireEmailVerification(mailer mail.Mailer) (bool, error) {
+	raw := os.Getenv("REQUIRE_EMAIL_VERIFICATION")
+	if raw == "" {
+		return false, nil
+	}
+	required, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid REQUIRE_EMAIL_VERIFICATION %q: %w", raw, err)
+	}
+	if required && mailer == nil {
+		return false, errors.New("REQUIRE_EMAIL_VERIFICATION needs a working MAILER")
+	}
+	return required, nil
+}
+
+// trustedProxies reads the addresses or CIDR ranges of the reverse proxies
+// in front of the server from TRUSTED_PROXIES, comma separated. Unset, no
+// proxy is trusted and the client IP is the address of the peer.
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// validRequestID accepts IDs of up to maxRequestIDLength letters, digits,
+// dots, dashes and underscores
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 func generateRequestID() string {
 	const chars = "abcdefghijklmnopqrstuvwxyz0123456789"
 	const length = 12
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAdminPageSize = 50
+	maxAdminPageSize     = 200
+)
+
+// ErrSelfDisable keeps admins from locking themselves out
+var ErrSelfDisable = errors.New("admins cannot disable their own account")
+
+// AdminService holds the site-wide moderation tools. Callers are expected
+// to have checked the admin role, see middleware.RequireRole.
+type AdminService interface {
+	SearchLinks(ctx context.Context, filter repository.AdminLinkFilter) ([]model.URL, error)
+	SearchUsers(ctx context.Context, filter repository.AdminUserFilter) ([]model.User, error)
+	SetLinkDisabled(ctx context.Context, adminID uuid.UUID, id string, disabled bool) error
+	// SetUserDisabled disables or enables an account; withLinks also takes
+	// down the links it created when disabling. It returns how many were.
+	SetUserDisabled(ctx context.Context, adminID, userID uuid.UUID, disabled, withLinks bool) (*model.User, int, error)
+	EvictLinkCache(ctx context.Context, id string) error
+	Stats(ctx context.Context) (*model.AdminStats, error)
+	Audit(ctx context.Context, filter repository.AuditFilter) ([]model.AuditEntry, error)
+}
+
+type adminService struct {
+	repo   repository.AdminRepository
+	audit  repository.AuditRepository
+	logger *zap.Logger
+}
+
+// NewAdminService records moderation in audit when it is not nil
+func NewAdminService(repo repository.AdminRepository, audit repository.AuditRepository) AdminService {
+	return &adminService{
+		repo:   repo,
+		audit:  audit,
+		logger: zap.L().With(zap.String("component", "AdminService")),
+	}
+}
+
+func (s *adminService) SearchLinks(ctx context.Context, filter repository.AdminLinkFilter) ([]model.URL, error) {
+	filter.Query = strings.TrimSpace(filter.Query)
+	filter.Limit, filter.Offset = normalizeAdminPage(filter.Limit, filter.Offset)
+	return s.repo.SearchLinks(ctx, filter)
+}
+
+func (s *adminService) SearchUsers(ctx context.Context, filter repository.AdminUserFilter) ([]model.User, error) {
+	filter.Query = strings.TrimSpace(filter.Query)
+	filter.Limit, filter.Offset = normalizeAdminPage(filter.Limit, filter.Offset)
+	return s.repo.SearchUsers(ctx, filter)
+}
+
+func (s *adminService) SetLinkDisabled(ctx context.Context, adminID uuid.UUID, id string, disabled bool) error {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return ErrInvalidToken
+	}
+
+	action := model.AuditLinkAdminEnable
+	if disabled {
+		action = model.AuditLinkAdminDisable
+	}
+	err := audited(ctx, s.audit, func(ctx context.Context) error {
+		before, after, err := s.repo.SetLinkDisabled(ctx, id, disabled)
+		if err != nil {
+			return err
+		}
+		return appendAudit(ctx, s.audit, s.logger, model.AuditEntry{
+			ActorID:     &adminID,
+			Action:      action,
+			TargetType:  "link",
+			TargetID:    id,
+			WorkspaceID: after.WorkspaceID,
+			Before:      newLinkSnapshot(before).raw(),
+			After:       newLinkSnapshot(after).raw(),
+		})
+	})
+	if err != nil {
+		if !errors.Is(err, repository.ErrURLNotFound) {
+			s.logger.Error("Failed to moderate link", zap.Error(err), zap.String("shortCode", id))
+		}
+		return err
+	}
+	s.logger.Info("Link moderated", zap.String("shortCode", id), zap.Bool("disabled", disabled),
+		zap.String("admin", adminID.String()))
+	return nil
+}
+
+func (s *adminService) SetUserDisabled(ctx context.Context, adminID, userID uuid.UUID, disabled, withLinks bool) (*model.User, int, error) {
+	if disabled && adminID == userID {
+		return nil, 0, ErrSelfDisable
+	}
+
+	action := model.AuditUserEnabled
+	if disabled {
+		action = model.AuditUserDisabled
+	}
+	var user *model.User
+	var linkIDs []string
+	err := audited(ctx, s.audit, func(ctx context.Context) error {
+		var err error
+		if user, err = s.repo.SetUserDisabled(ctx, userID, disabled); err != nil {
+			return err
+		}
+		if disabled && withLinks {
+			if linkIDs, err = s.repo.DisableUserLinks(ctx, userID); err != nil {
+				return err
+			}
+		}
+		after, _ := json.Marshal(map[string]any{"disabled_at": user.DisabledAt, "disabled_links": linkIDs})
+		return appendAudit(ctx, s.audit, s.logger, model.AuditEntry{
+			ActorID:    &adminID,
+			Action:     action,
+			TargetType: "user",
+			TargetID:   userID.String(),
+			After:      after,
+		})
+	})
+	if err != nil {
+		if !errors.Is(err, repository.ErrUserNotFound) {
+			s.logger.Error("Failed to moderate user", zap.Error(err), zap.String("userID", userID.String()))
+		}
+		return nil, 0, err
+	}
+	s.logger.Info("User moderated", zap.String("userID", userID.String()), zap.Bool("disabled", disabled),
+		zap.Int("links", len(linkIDs)), zap.String("admin", adminID.String()))
+	return user, len(linkIDs), nil
+}
+
+func (s *adminService) EvictLinkCache(ctx context.Context, id string) error {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return ErrInvalidToken
+	}
+	return s.repo.EvictLink(ctx, id)
+}
+
+func (s *adminService) Stats(ctx context.Context) (*model.AdminStats, error) {
+	return s.repo.Stats(ctx)
+}
+
+// Audit returns the audit entries of every account
+func (s *adminService) Audit(ctx context.Context, filter repository.AuditFilter) ([]model.AuditEntry, error) {
+	if s.audit == nil {
+		return []model.AuditEntry{}, nil
+	}
+	return s.audit.ListAll(ctx, normalizeAuditFilter(filter))
+}
+
+func normalizeAdminPage(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = defaultAdminPageSize
+	}
+	if limit > maxAdminPageSize {
+		limit = maxAdminPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
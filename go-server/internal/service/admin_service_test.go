@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryAdmin is an in-memory AdminRepository for tests
+type memoryAdmin struct {
+	links       map[string]*model.URL
+	users       map[uuid.UUID]*model.User
+	evicted     []string
+	linkFilters []repository.AdminLinkFilter
+}
+
+func newMemoryAdmin() *memoryAdmin {
+	return &memoryAdmin{links: map[string]*model.URL{}, users: map[uuid.UUID]*model.User{}}
+}
+
+func (m *memoryAdmin) SearchLinks(ctx context.Context, filter repository.AdminLinkFilter) ([]model.URL, error) {
+	m.linkFilters = append(m.linkFilters, filter)
+	return []model.URL{}, nil
+}
+
+func (m *memoryAdmin) SearchUsers(ctx context.Context, filter repository.AdminUserFilter) ([]model.User, error) {
+	return []model.User{}, nil
+}
+
+func (m *memoryAdmin) SetLinkDisabled(ctx context.Context, id string, disabled bool) (*model.URL, *model.URL, error) {
+	link, ok := m.links[id]
+	if !ok {
+		return nil, nil, repository.ErrURLNotFound
+	}
+	before := *link
+	link.Disabled, link.DisabledReason = disabled, ""
+	if disabled {
+		link.DisabledReason = model.DisabledByAdmin
+	}
+	after := *link
+	return &before, &after, nil
+}
+
+func (m *memoryAdmin) SetUserDisabled(ctx context.Context, id uuid.UUID, disabled bool) (*model.User, error) {
+	user, ok := m.users[id]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	if !disabled {
+		user.DisabledAt = nil
+	} else if user.DisabledAt == nil {
+		now := time.Now()
+		user.DisabledAt = &now
+	}
+	return user, nil
+}
+
+func (m *memoryAdmin) DisableUserLinks(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	var ids []string
+	for id, link := range m.links {
+		if link.UserID != nil && *link.UserID == userID && !link.Disabled {
+			link.Disabled, link.DisabledReason = true, model.DisabledByAdmin
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (m *memoryAdmin) EvictLink(ctx context.Context, id string) error {
+	m.evicted = append(m.evicted, id)
+	return nil
+}
+
+func (m *memoryAdmin) Stats(ctx context.Context) (*model.AdminStats, error) {
+	return &model.AdminStats{Links: int64(len(m.links)), Users: int64(len(m.users))}, nil
+}
+
+func TestAdminSetLinkDisabled(t *testing.T) {
+	setupService(t)
+	repo := newMemoryAdmin()
+	audit := &memoryAudit{}
+	service := NewAdminService(repo, audit)
+	ctx := context.Background()
+	adminID, ownerID, wsID := uuid.New(), uuid.New(), uuid.New()
+	repo.links["abc123"] = &model.URL{ID: "abc123", UserID: &ownerID, WorkspaceID: &wsID}
+
+	require.NoError(t, service.SetLinkDisabled(ctx, adminID, "abc123", true))
+
+	assert.True(t, repo.links["abc123"].Disabled)
+	assert.Equal(t, model.DisabledByAdmin, repo.links["abc123"].DisabledReason)
+	require.Len(t, audit.entries, 1)
+	entry := audit.entries[0]
+	assert.Equal(t, model.AuditLinkAdminDisable, entry.Action)
+	assert.Equal(t, &adminID, entry.ActorID)
+	assert.Equal(t, &wsID, entry.WorkspaceID)
+	assert.JSONEq(t, `{"disabled":false,"forward_path":false,"forward_query":false,"user_id":"`+ownerID.String()+`","workspace_id":"`+wsID.String()+`"}`, string(entry.Before))
+
+	require.NoError(t, service.SetLinkDisabled(ctx, adminID, "abc123", false))
+	assert.False(t, repo.links["abc123"].Disabled)
+	assert.Equal(t, model.AuditLinkAdminEnable, audit.entries[1].Action)
+
+	assert.ErrorIs(t, service.SetLinkDisabled(ctx, adminID, "zzz999", true), repository.ErrURLNotFound)
+}
+
+func TestAdminSetUserDisabled(t *testing.T) {
+	setupService(t)
+	repo := newMemoryAdmin()
+	audit := &memoryAudit{}
+	service := NewAdminService(repo, audit)
+	ctx := context.Background()
+	adminID, spammer, other := uuid.New(), uuid.New(), uuid.New()
+	repo.users[spammer] = &model.User{ID: spammer.String(), Role: model.UserRoleUser}
+	repo.links["spam01"] = &model.URL{ID: "spam01", UserID: &spammer}
+	repo.links["spam02"] = &model.URL{ID: "spam02", UserID: &spammer}
+	repo.links["fine01"] = &model.URL{ID: "fine01", UserID: &other}
+
+	user, links, err := service.SetUserDisabled(ctx, adminID, spammer, true, true)
+
+	require.NoError(t, err)
+	assert.NotNil(t, user.DisabledAt)
+	assert.Equal(t, 2, links)
+	assert.True(t, repo.links["spam01"].Disabled)
+	assert.True(t, repo.links["spam02"].Disabled)
+	assert.False(t, repo.links["fine01"].Disabled)
+	require.Len(t, audit.entries, 1)
+	assert.Equal(t, model.AuditUserDisabled, audit.entries[0].Action)
+	assert.Equal(t, spammer.String(), audit.entries[0].TargetID)
+
+	// Enabling the account leaves its links taken down
+	user, links, err = service.SetUserDisabled(ctx, adminID, spammer, false, false)
+	require.NoError(t, err)
+	assert.Nil(t, user.DisabledAt)
+	assert.Zero(t, links)
+	assert.True(t, repo.links["spam01"].Disabled)
+}
+
+func TestAdminSetUserDisabled_Self(t *testing.T) {
+	setupService(t)
+	repo := newMemoryAdmin()
+	service := NewAdminService(repo, nil)
+	adminID := uuid.New()
+	repo.users[adminID] = &model.User{ID: adminID.String(), Role: model.UserRoleAdmin}
+
+	_, _, err := service.SetUserDisabled(context.Background(), adminID, adminID, true, false)
+
+	assert.ErrorIs(t, err, ErrSelfDisable)
+	assert.Nil(t, repo.users[adminID].DisabledAt)
+}
+
+func TestAdminSearchLinks_NormalizesPage(t *testing.T) {
+	setupService(t)
+	repo := newMemoryAdmin()
+	service := NewAdminService(repo, nil)
+	ctx := context.Background()
+
+	_, err := service.SearchLinks(ctx, repository.AdminLinkFilter{Query: "  spam.example  ", Limit: 5000, Offset: -3})
+	require.NoError(t, err)
+	_, err = service.SearchLinks(ctx, repository.AdminLinkFilter{})
+	require.NoError(t, err)
+
+	require.Len(t, repo.linkFilters, 2)
+	assert.Equal(t, repository.AdminLinkFilter{Query: "spam.example", Limit: maxAdminPageSize}, repo.linkFilters[0])
+	assert.Equal(t, defaultAdminPageSize, repo.linkFilters[1].Limit)
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+type requestInfoKey struct{}
+
+type requestInfo struct {
+	id string
+	ip string
+}
+
+// WithRequestInfo attaches the request ID and client IP to ctx so audit
+// entries can point back at the request that made a change
+func WithRequestInfo(ctx context.Context, requestID, clientIP string) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, requestInfo{id: requestID, ip: clientIP})
+}
+
+// requestIP returns the client IP WithRequestInfo attached to ctx, if any
+func requestIP(ctx context.Context) string {
+	info, _ := ctx.Value(requestInfoKey{}).(requestInfo)
+	return info.ip
+}
+
+// audited runs fn, which makes a change and records it, in one transaction
+// so the audit entries it appends commit or roll back with the change
+func audited(ctx context.Context, repo repository.AuditRepository, fn func(ctx context.Context) error) error {
+	if repo == nil {
+		return fn(ctx)
+	}
+	return repo.InTx(ctx, fn)
+}
+
+// appendAudit stamps entry with the request it came from and stores it.
+// Called within audited, a failure undoes the change it describes.
+func appendAudit(ctx context.Context, repo repository.AuditRepository, logger *zap.Logger, entry model.AuditEntry) error {
+	if repo == nil {
+		return nil
+	}
+	if info, ok := ctx.Value(requestInfoKey{}).(requestInfo); ok {
+		entry.RequestID, entry.IP = info.id, info.ip
+	}
+	if err := repo.Append(ctx, &entry); err != nil {
+		logger.Error("Failed to write audit entry", zap.Error(err),
+			zap.String("action", entry.Action), zap.String("target", entry.TargetID))
+		return err
+	}
+	return nil
+}
+
+// linkSnapshot is the owner-controlled state of a link kept in its history
+type linkSnapshot struct {
+	URL            string                       `json:"url,omitempty"`
+	UserID         *uuid.UUID                   `json:"user_id,omitempty"`
+	WorkspaceID    *uuid.UUID                   `json:"workspace_id,omitempty"`
+	Title          string                       `json:"title,omitempty"`
+	Description    string                       `json:"description,omitempty"`
+	ForwardQuery   bool                         `json:"forward_query"`
+	ForwardPath    bool                         `json:"forward_path"`
+	Rules          []model.RoutingRule          `json:"rules,omitempty"`
+	Variants       []model.Variant              `json:"variants,omitempty"`
+	StickyVariants bool                         `json:"sticky_variants,omitempty"`
+	StartsAt       *time.Time                   `json:"starts_at,omitempty"`
+	Schedule       []model.ScheduledDestination `json:"schedule,omitempty"`
+	ExpiresAt      *time.Time                   `json:"expires_at,omitempty"`
+	MaxClicks      *int64                       `json:"max_clicks,omitempty"`
+	Disabled       bool                         `json:"disabled"`
+	Fallback       *model.Fallback              `json:"fallback,omitempty"`
+	ArchivedAt     *time.Time                   `json:"archived_at,omitempty"`
+	DeletedAt      *time.Time                   `json:"deleted_at,omitempty"`
+}
+
+func newLinkSnapshot(u *model.URL) *linkSnapshot {
+	if u == nil {
+		return nil
+	}
+	return &linkSnapshot{
+		URL:            u.OriginalURL,
+		UserID:         u.UserID,
+		WorkspaceID:    u.WorkspaceID,
+		Title:          u.Title,
+		Description:    u.Description,
+		ForwardQuery:   u.ForwardQuery,
+		ForwardPath:    u.ForwardPath,
+		Rules:          u.Rules,
+		Variants:       u.Variants,
+		StickyVariants: u.StickyVariants,
+		StartsAt:       u.StartsAt,
+		Schedule:       u.Schedule,
+		ExpiresAt:      u.ExpiresAt,
+		MaxClicks:      u.MaxClicks,
+		Disabled:       u.Disabled,
+		Fallback:       u.Fallback,
+		ArchivedAt:     u.ArchivedAt,
+		DeletedAt:      u.DeletedAt,
+	}
+}
+
+func (l *linkSnapshot) raw() json.RawMessage {
+	if l == nil {
+		return nil
+	}
+	raw, err := json.Marshal(l)
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+// WithAudit records changes to links in the audit log
+func WithAudit(repo repository.AuditRepository) URLServiceOption {
+	return func(s *URLService) {
+		s.audit = repo
+	}
+}
+
+// snapshotLink captures a link before a change, nil when neither auditing
+// nor webhooks are on
+func (s *URLService) snapshotLink(ctx context.Context, id string) *linkSnapshot {
+	if s.audit == nil && s.webhooks == nil {
+		return nil
+	}
+	link, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		s.logger.Warn("Failed to snapshot link for audit", zap.Error(err), zap.String("id", id))
+		return nil
+	}
+	return newLinkSnapshot(link)
+}
+
+// auditLink records a change userID made to a link, reading its new state
+func (s *URLService) auditLink(ctx context.Context, userID uuid.UUID, action, id string, before *linkSnapshot) error {
+	if s.audit == nil && s.webhooks == nil {
+		return nil
+	}
+	return s.recordLinkAudit(ctx, &userID, action, id, before, s.snapshotLink(ctx, id))
+}
+
+// recordLinkAudit also passes the change on to the owner's webhooks
+func (s *URLService) recordLinkAudit(ctx context.Context, actorID *uuid.UUID, action, id string, before, after *linkSnapshot) error {
+	if err := s.emitLinkChange(ctx, action, id, before, after); err != nil {
+		return err
+	}
+	if s.audit == nil {
+		return nil
+	}
+	entry := model.AuditEntry{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: "link",
+		TargetID:   id,
+		Before:     before.raw(),
+		After:      after.raw(),
+	}
+	switch {
+	case after != nil && after.WorkspaceID != nil:
+		entry.WorkspaceID = after.WorkspaceID
+	case before != nil:
+		entry.WorkspaceID = before.WorkspaceID
+	}
+	return appendAudit(ctx, s.audit, s.logger, entry)
+}
+
+// GetLinkHistory returns the audit entries of a link userID can see
+func (s *URLService) GetLinkHistory(ctx context.Context, userID uuid.UUID, shortCode string, filter repository.AuditFilter) ([]model.AuditEntry, error) {
+	if !s.isValidID(shortCode) {
+		return nil, ErrInvalidToken
+	}
+	if err := s.authorizeLink(ctx, userID, shortCode, model.RoleViewer); err != nil {
+		return nil, err
+	}
+	if s.audit == nil {
+		return []model.AuditEntry{}, nil
+	}
+	return s.audit.ListForTarget(ctx, "link", shortCode, normalizeAuditFilter(filter))
+}
+
+// GetWorkspaceAudit returns the audit entries of every link in a workspace
+// to its admins and owners
+func (s *URLService) GetWorkspaceAudit(ctx context.Context, userID, workspaceID uuid.UUID, filter repository.AuditFilter) ([]model.AuditEntry, error) {
+	if s.workspaces == nil {
+		return nil, repository.ErrWorkspaceNotFound
+	}
+	role, err := s.workspaces.Role(ctx, workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !role.AtLeast(model.RoleAdmin) {
+		return nil, ErrForbidden
+	}
+	if s.audit == nil {
+		return []model.AuditEntry{}, nil
+	}
+	return s.audit.ListForWorkspace(ctx, workspaceID, normalizeAuditFilter(filter))
+}
+
+func normalizeAuditFilter(filter repository.AuditFilter) repository.AuditFilter {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditPageSize
+	}
+	if filter.Limit > maxAuditPageSize {
+		filter.Limit = maxAuditPageSize
+	}
+	if filter.BeforeID < 0 {
+		filter.BeforeID = 0
+	}
+	return filter
+}
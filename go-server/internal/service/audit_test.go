@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryAudit keeps entries in memory. Append fails with err when set.
+type memoryAudit struct {
+	entries []model.AuditEntry
+	filters []repository.AuditFilter
+	err     error
+}
+
+// InTx drops the entries fn appended when it fails, as a rollback would
+func (m *memoryAudit) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	n := len(m.entries)
+	if err := fn(ctx); err != nil {
+		m.entries = m.entries[:n]
+		return err
+	}
+	return nil
+}
+
+func (m *memoryAudit) Append(ctx context.Context, entry *model.AuditEntry) error {
+	if m.err != nil {
+		return m.err
+	}
+	entry.ID = int64(len(m.entries) + 1)
+	m.entries = append(m.entries, *entry)
+	return nil
+}
+
+func (m *memoryAudit) ListForTarget(ctx context.Context, targetType, targetID string, filter repository.AuditFilter) ([]model.AuditEntry, error) {
+	m.filters = append(m.filters, filter)
+	var entries []model.AuditEntry
+	for _, e := range m.entries {
+		if e.TargetType == targetType && e.TargetID == targetID {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func (m *memoryAudit) ListForWorkspace(ctx context.Context, workspaceID uuid.UUID, filter repository.AuditFilter) ([]model.AuditEntry, error) {
+	m.filters = append(m.filters, filter)
+	var entries []model.AuditEntry
+	for _, e := range m.entries {
+		if e.WorkspaceID != nil && *e.WorkspaceID == workspaceID {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func (m *memoryAudit) ListAll(ctx context.Context, filter repository.AuditFilter) ([]model.AuditEntry, error) {
+	m.filters = append(m.filters, filter)
+	return m.entries, nil
+}
+
+func TestUpdateURL_RecordsAudit(t *testing.T) {
+	setupService(t)
+	mockRepo := new(MockURLRepository)
+	audit := &memoryAudit{}
+	service := NewURLService(mockRepo, WithAudit(audit))
+	ctx := WithRequestInfo(context.Background(), "req-1", "203.0.113.7")
+	userID := uuid.New()
+	title := "After"
+
+	mockRepo.On("FindByID", ctx, "abc123").
+		Return(&model.URL{ID: "abc123", UserID: &userID, OriginalURL: "https://example.com", Title: "Before"}, nil).Times(2)
+	mockRepo.On("UpdateDetails", ctx, "abc123", repository.URLChanges{Title: &title}).Return(nil)
+	mockRepo.On("FindByID", ctx, "abc123").
+		Return(&model.URL{ID: "abc123", UserID: &userID, OriginalURL: "https://example.com", Title: "After"}, nil).Once()
+
+	require.NoError(t, service.UpdateURL(ctx, userID, "abc123", URLUpdate{Title: &title}))
+
+	require.Len(t, audit.entries, 1)
+	entry := audit.entries[0]
+	assert.Equal(t, model.AuditLinkUpdated, entry.Action)
+	assert.Equal(t, userID, *entry.ActorID)
+	assert.Equal(t, "link", entry.TargetType)
+	assert.Equal(t, "abc123", entry.TargetID)
+	assert.Equal(t, "req-1", entry.RequestID)
+	assert.Equal(t, "203.0.113.7", entry.IP)
+
+	var before, after linkSnapshot
+	require.NoError(t, json.Unmarshal(entry.Before, &before))
+	require.NoError(t, json.Unmarshal(entry.After, &after))
+	assert.Equal(t, "Before", before.Title)
+	assert.Equal(t, "After", after.Title)
+}
+
+func TestUpdateURL_FailsWhenAuditFails(t *testing.T) {
+	setupService(t)
+	mockRepo := new(MockURLRepository)
+	audit := &memoryAudit{err: repository.ErrDatabaseError}
+	service := NewURLService(mockRepo, WithAudit(audit))
+	ctx := context.Background()
+	userID := uuid.New()
+	title := "After"
+
+	mockRepo.On("FindByID", ctx, "abc123").
+		Return(&model.URL{ID: "abc123", UserID: &userID, OriginalURL: "https://example.com"}, nil)
+	mockRepo.On("UpdateDetails", ctx, "abc123", repository.URLChanges{Title: &title}).Return(nil)
+
+	// The change is written in the audit transaction, which fails with it
+	err := service.UpdateURL(ctx, userID, "abc123", URLUpdate{Title: &title})
+	assert.ErrorIs(t, err, repository.ErrDatabaseError)
+	assert.Empty(t, audit.entries)
+}
+
+func TestShortenURL_RecordsAnonymousCreation(t *testing.T) {
+	setupService(t)
+	mockRepo := new(MockURLRepository)
+	audit := &memoryAudit{}
+	service := NewURLService(mockRepo, WithAudit(audit))
+	ctx := context.Background()
+
+	mockRepo.On("IDExists", ctx, mock.AnythingOfType("string")).Return(false, nil)
+	mockRepo.On("CreateOrGet", ctx, mock.AnythingOfType("*model.URL")).Return("abc123", true, nil)
+
+	_, _, err := service.ShortenURL(ctx, "https://example.com", nil, ShortenOptions{})
+	require.NoError(t, err)
+
+	require.Len(t, audit.entries, 1)
+	assert.Equal(t, model.AuditLinkCreated, audit.entries[0].Action)
+	assert.Nil(t, audit.entries[0].ActorID)
+	assert.Empty(t, audit.entries[0].Before)
+	assert.NotContains(t, string(audit.entries[0].After), "claim")
+}
+
+func TestAuditAccess(t *testing.T) {
+	setupService(t)
+	ctx := context.Background()
+	workspaces := newMemoryWorkspaces()
+	audit := &memoryAudit{}
+	service := NewURLService(new(MockURLRepository), WithWorkspaces(workspaces), WithAudit(audit))
+	workspaceID, ownerID, ids := sharedWorkspace(t, workspaces, model.RoleAdmin, model.RoleViewer)
+	adminID, viewerID := ids[0], ids[1]
+	workspaces.links["abc123"] = workspaceID
+	require.NoError(t, audit.Append(ctx, &model.AuditEntry{
+		ActorID: &ownerID, Action: model.AuditLinkUpdated, TargetType: "link", TargetID: "abc123", WorkspaceID: &workspaceID,
+	}))
+
+	// Every member sees a link's history, only admins the whole workspace
+	history, err := service.GetLinkHistory(ctx, viewerID, "abc123", repository.AuditFilter{Limit: 1000})
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+	assert.Equal(t, maxAuditPageSize, audit.filters[0].Limit)
+
+	_, err = service.GetLinkHistory(ctx, uuid.New(), "abc123", repository.AuditFilter{})
+	assert.ErrorIs(t, err, repository.ErrURLNotFound)
+
+	_, err = service.GetWorkspaceAudit(ctx, viewerID, workspaceID, repository.AuditFilter{})
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	entries, err := service.GetWorkspaceAudit(ctx, adminID, workspaceID, repository.AuditFilter{})
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, defaultAuditPageSize, audit.filters[1].Limit)
+}
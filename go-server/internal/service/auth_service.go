@@ -2,35 +2,63 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 
 	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
 	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
 	"github.com/fonsecaaso/TinyUrl/go-server/internal/token"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthService interface {
 	Register(ctx context.Context, username, email, password string) (*model.User, error)
-	Login(ctx context.Context, email, password string) (string, error)
+	// Login checks the password. Accounts with two-factor authentication
+	// get a challenge to complete with CompleteMFALogin instead of a session.
+	Login(ctx context.Context, email, password string) (*LoginResult, error)
+	// CompleteMFALogin signs in with the challenge Login returned and a code
+	// from the authenticator or a recovery code
+	CompleteMFALogin(ctx context.Context, mfaToken, code string) (string, error)
+}
+
+// LoginResult holds either a session Token or, when a second factor is
+// needed, an MFAToken to complete the login with
+type LoginResult struct {
+	Token    string
+	MFAToken string
 }
 
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrEmailAlreadyExists = errors.New("email already exists")
+	ErrAccountDisabled    = errors.New("account is disabled")
 )
 
 type authService struct {
-	userRepo repository.UserRepository
-	logger   *zap.Logger
+	userRepo     repository.UserRepository
+	audit        repository.AuditRepository
+	verification EmailVerificationService
+	throttle     *loginThrottle
+	mfa          MFAService
+	logger       *zap.Logger
 }
 
-func NewAuthService(userRepo repository.UserRepository) AuthService {
-	return &authService{
+// AuthServiceOption enables an optional part of sign-up and sign-in
+type AuthServiceOption func(*authService)
+
+// NewAuthService records account changes in audit when it is not nil
+func NewAuthService(userRepo repository.UserRepository, audit repository.AuditRepository, opts ...AuthServiceOption) AuthService {
+	s := &authService{
 		userRepo: userRepo,
+		audit:    audit,
 		logger:   zap.L().With(zap.String("component", "AuthService")),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *authService) Register(ctx context.Context, username, email, password string) (*model.User, error) {
@@ -42,28 +70,134 @@ func (s *authService) Register(ctx context.Context, username, email, password st
 		PasswordHash: string(passwordHash),
 	}
 
-	err := s.userRepo.Create(ctx, user)
+	err := audited(ctx, s.audit, func(ctx context.Context) error {
+		if err := s.userRepo.Create(ctx, user); err != nil {
+			s.logger.Error("Failed to create user", zap.Error(err))
+			return ErrEmailAlreadyExists
+		}
+		return s.auditUser(ctx, user, model.AuditUserRegistered)
+	})
 	if err != nil {
-		s.logger.Error("Failed to create user", zap.Error(err))
-		return nil, ErrEmailAlreadyExists
+		return nil, err
+	}
+
+	// The account is usable meanwhile; the link can be sent again
+	if s.verification != nil {
+		if err := s.verification.SendVerification(ctx, user); err != nil {
+			s.logger.Warn("Failed to send verification email", zap.Error(err), zap.String("userID", user.ID))
+		}
 	}
 	return user, nil
 }
 
-func (s *authService) Login(ctx context.Context, email, password string) (string, error) {
+func (s *authService) auditUser(ctx context.Context, user *model.User, action string) error {
+	userID, err := uuid.Parse(user.ID)
+	if err != nil {
+		return nil
+	}
+	after, _ := json.Marshal(map[string]string{"username": user.Username, "email": user.Email})
+	return appendAudit(ctx, s.audit, s.logger, model.AuditEntry{
+		ActorID:    &userID,
+		Action:     action,
+		TargetType: "user",
+		TargetID:   user.ID,
+		After:      after,
+	})
+}
+
+func (s *authService) Login(ctx context.Context, email, password string) (*LoginResult, error) {
+	var failures int
+	if s.throttle != nil {
+		var err error
+		if failures, err = s.throttle.admitLogin(ctx, accountLoginKey(email)); err != nil {
+			return nil, err
+		}
+	}
+
 	user, err := s.userRepo.GetByEmail(ctx, email)
 	if err != nil || user == nil {
-		return "", ErrInvalidCredentials
+		if s.throttle != nil {
+			s.throttle.loginFailed(ctx, nil, failures, "password")
+		}
+		return nil, ErrInvalidCredentials
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return "", ErrInvalidCredentials
+		if s.throttle != nil {
+			s.throttle.loginFailed(ctx, user, failures, "password")
+		}
+		return nil, ErrInvalidCredentials
+	}
+	if s.throttle != nil {
+		s.throttle.loginSucceeded(ctx, accountLoginKey(email))
+	}
+	// Only revealed to someone who knows the password
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+
+	if user.MFAEnabled {
+		mfaToken, err := token.GenerateMFAToken(user.ID, user.SessionVersion)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{MFAToken: mfaToken}, nil
 	}
 
-	tokenString, err := token.GenerateToken(user.ID)
+	tokenString, err := token.GenerateToken(user.ID, user.SessionVersion)
 	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{Token: tokenString}, nil
+}
+
+func (s *authService) CompleteMFALogin(ctx context.Context, mfaToken, code string) (string, error) {
+	if s.mfa == nil {
+		return "", errors.New("two-factor authentication is not configured")
+	}
+	userID, sessionVersion, err := token.ValidateMFAToken(mfaToken)
+	if err != nil {
+		return "", ErrInvalidMFAToken
+	}
+
+	var failures int
+	if s.throttle != nil {
+		if failures, err = s.throttle.admitLogin(ctx, mfaLoginKey(userID)); err != nil {
+			return "", err
+		}
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return "", ErrInvalidMFAToken
+		}
+		return "", err
+	}
+	// The password was reset since the challenge was issued
+	if user.SessionVersion != sessionVersion {
+		return "", ErrInvalidMFAToken
+	}
+	if user.DisabledAt != nil {
+		return "", ErrAccountDisabled
+	}
+
+	if err := s.mfa.Verify(ctx, userID, code); err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidMFACode):
+			if s.throttle != nil {
+				s.throttle.loginFailed(ctx, user, failures, "two-factor code")
+			}
+		case errors.Is(err, repository.ErrMFANotEnrolled):
+			// Turned off since the challenge was issued
+			return "", ErrInvalidMFAToken
+		}
 		return "", err
 	}
+	if s.throttle != nil {
+		s.throttle.loginSucceeded(ctx, mfaLoginKey(userID))
+	}
 
-	return tokenString, nil
+	return token.GenerateToken(user.ID, user.SessionVersion)
 }
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/mail"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryUsers is an in-memory UserRepository
+type memoryUsers struct {
+	users map[uuid.UUID]*model.User
+}
+
+func newMemoryUsers() *memoryUsers {
+	return &memoryUsers{users: map[uuid.UUID]*model.User{}}
+}
+
+func (m *memoryUsers) Create(ctx context.Context, user *model.User) error {
+	for _, u := range m.users {
+		if u.Email == user.Email {
+			return repository.ErrDatabaseError
+		}
+	}
+	id := uuid.New()
+	user.ID = id.String()
+	user.Role = model.UserRoleUser
+	user.CreatedAt = time.Now()
+	stored := *user
+	m.users[id] = &stored
+	return nil
+}
+
+func (m *memoryUsers) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	for _, u := range m.users {
+		if u.Email == email {
+			user := *u
+			return &user, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (m *memoryUsers) GetByID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	if u, ok := m.users[id]; ok {
+		user := *u
+		return &user, nil
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+// authHarness is an AuthService backed by in-memory fakes. Account emails
+// go to mailer and audit entries to audit; the optional features are only
+// set when a test asks for them.
+type authHarness struct {
+	auth         AuthService
+	users        *memoryUsers
+	audit        *memoryAudit
+	mailer       *mail.MemoryMailer
+	attempts     *memoryAttempts
+	throttle     LoginThrottleConfig
+	verification EmailVerificationService
+	resets       PasswordResetService
+	mfa          MFAService
+	options      []AuthServiceOption
+}
+
+type authHarnessOption func(h *authHarness)
+
+func newAuthHarness(t *testing.T, opts ...authHarnessOption) *authHarness {
+	t.Helper()
+	h := &authHarness{users: newMemoryUsers(), audit: &memoryAudit{}, mailer: mail.NewMemoryMailer()}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.auth = NewAuthService(h.users, h.audit, h.options...)
+	return h
+}
+
+func withVerification(cfg VerificationConfig) authHarnessOption {
+	return func(h *authHarness) {
+		h.verification = NewEmailVerificationService(h.users,
+			&memoryVerifications{users: h.users, tokens: map[uuid.UUID]memoryVerification{}}, h.mailer, h.audit, cfg)
+		h.options = append(h.options, WithEmailVerification(h.verification))
+	}
+}
+
+func withPasswordReset(cfg PasswordResetConfig) authHarnessOption {
+	return func(h *authHarness) {
+		h.resets = NewPasswordResetService(h.users,
+			&memoryResets{users: h.users, tokens: map[uuid.UUID]memoryVerification{}}, h.mailer, h.audit, cfg)
+	}
+}
+
+func withLoginThrottle(cfg LoginThrottleConfig) authHarnessOption {
+	return func(h *authHarness) {
+		h.attempts = newMemoryAttempts()
+		h.throttle = cfg
+		h.options = append(h.options, WithLoginThrottle(h.attempts, h.mailer, cfg))
+	}
+}
+
+// withMFA counts wrong codes with the login throttle when it comes after
+// withLoginThrottle
+func withMFA() authHarnessOption {
+	return func(h *authHarness) {
+		var opts []MFAServiceOption
+		if h.attempts != nil {
+			opts = append(opts, WithMFALoginThrottle(h.attempts, h.mailer, h.throttle))
+		}
+		h.mfa = NewMFAService(h.users, &memoryMFA{users: h.users, settings: map[uuid.UUID]*memoryAuthenticator{}}, h.audit, "", opts...)
+		h.options = append(h.options, WithMFA(h.mfa))
+	}
+}
+
+// registerAna signs up the account most tests sign in with, password
+// secret123
+func (h *authHarness) registerAna(t *testing.T) uuid.UUID {
+	t.Helper()
+	user, err := h.auth.Register(context.Background(), "ana", "ana@example.com", "secret123")
+	require.NoError(t, err)
+	return uuid.MustParse(user.ID)
+}
+
+// waitForEmails waits for account emails sent in the background
+func waitForEmails(t *testing.T, mailer *mail.MemoryMailer, n int) []mail.Message {
+	t.Helper()
+	require.Eventually(t, func() bool { return len(mailer.Messages()) >= n }, time.Second, 5*time.Millisecond)
+	return mailer.Messages()
+}
+
+// tokenFromEmail reads the token out of the link in an account email
+func tokenFromEmail(t *testing.T, msg mail.Message) string {
+	t.Helper()
+	for _, field := range strings.Fields(msg.Text) {
+		if u, err := url.Parse(field); err == nil && u.Query().Get("token") != "" {
+			return u.Query().Get("token")
+		}
+	}
+	t.Fatalf("no verification link in %q", msg.Text)
+	return ""
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrLinkExpired     = errors.New("link has expired")
+	ErrLinkExhausted   = errors.New("link has reached its click limit")
+	ErrLinkDisabled    = errors.New("link is disabled")
+	ErrLinkBlocked     = errors.New("link is unavailable for legal reasons")
+	ErrLinkTakenDown   = errors.New("link has been disabled for abuse")
+	ErrInvalidLimits   = errors.New("invalid link limits")
+	ErrInvalidFallback = errors.New("invalid fallback")
+)
+
+const (
+	maxFallbackTitleLength   = 100
+	maxFallbackMessageLength = 1000
+	maxFallbackButtonLength  = 50
+)
+
+var accentColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// UnavailableError is returned when a link exists but cannot be resolved.
+// It unwraps to the sentinel error of its state.
+type UnavailableError struct {
+	State model.LinkState
+	// Fallback is what the visitor should see instead, nil when nothing is configured
+	Fallback *model.Fallback
+}
+
+func (e *UnavailableError) Error() string {
+	return e.Unwrap().Error()
+}
+
+func (e *UnavailableError) Unwrap() error {
+	switch e.State {
+	case model.LinkExpired:
+		return ErrLinkExpired
+	case model.LinkExhausted:
+		return ErrLinkExhausted
+	case model.LinkBlocked:
+		return ErrLinkBlocked
+	case model.LinkTakenDown:
+		return ErrLinkTakenDown
+	case model.LinkArchived:
+		return ErrLinkArchived
+	case model.LinkDeleted:
+		return ErrLinkDeleted
+	default:
+		return ErrLinkDisabled
+	}
+}
+
+// WithFallbacks enables per-workspace fallback defaults, with global used
+// when neither the link nor its workspace configured one. Workspace defaults
+// need WithWorkspaces.
+func WithFallbacks(repo repository.FallbackRepository, global *model.Fallback) URLServiceOption {
+	return func(s *URLService) {
+		s.fallbacks = repo
+		s.globalFallback = global
+	}
+}
+
+// unavailable builds the error for a link in state, resolving its fallback
+// from the link, then its workspace's default, then the global default. Links
+// disabled by a moderator always get the page of their state, their owner's
+// fallback could lead visitors to the same destination.
+func (s *URLService) unavailable(ctx context.Context, link *model.URL, state model.LinkState) error {
+	if link.Moderated() {
+		return &UnavailableError{State: state}
+	}
+
+	fallback := link.Fallback
+	if fallback == nil && link.WorkspaceID != nil && s.fallbacks != nil {
+		def, err := s.fallbacks.Get(ctx, *link.WorkspaceID)
+		switch {
+		case err == nil:
+			fallback = &def.Fallback
+		case !errors.Is(err, repository.ErrFallbackNotFound):
+			s.logger.Warn("Failed to load fallback default", zap.Error(err), zap.String("shortCode", link.ID))
+		}
+	}
+	if fallback == nil {
+		fallback = s.globalFallback
+	}
+
+	return &UnavailableError{State: state, Fallback: fallback}
+}
+
+// SetAvailability replaces the expiry, click limit and disabled flag of a
+// link userID can edit
+func (s *URLService) SetAvailability(ctx context.Context, userID uuid.UUID, shortCode string, availability repository.Availability) error {
+	if !s.isValidID(shortCode) {
+		return ErrInvalidToken
+	}
+	if err := validateLimits(availability.ExpiresAt, availability.MaxClicks, time.Now()); err != nil {
+		return err
+	}
+
+	if err := s.authorizeLink(ctx, userID, shortCode, model.RoleEditor); err != nil {
+		return err
+	}
+	err := audited(ctx, s.audit, func(ctx context.Context) error {
+		before := s.snapshotLink(ctx, shortCode)
+		if err := s.repo.SetAvailability(ctx, shortCode, availability); err != nil {
+			return err
+		}
+		return s.auditLink(ctx, userID, model.AuditLinkAvailability, shortCode, before)
+	})
+	if err != nil {
+		if !errors.Is(err, repository.ErrURLNotFound) {
+			s.logger.Error("Failed to update availability", zap.Error(err), zap.String("shortCode", shortCode))
+		}
+		return err
+	}
+
+	s.logger.Info("Availability updated", zap.String("shortCode", shortCode), zap.Bool("disabled", availability.Disabled))
+	return nil
+}
+
+// SetLinkFallback sets the fallback of a link userID can edit. A nil
+// fallback removes it so the workspace or global default applies again.
+func (s *URLService) SetLinkFallback(ctx context.Context, userID uuid.UUID, shortCode string, fallback *model.Fallback) (*model.Fallback, error) {
+	if !s.isValidID(shortCode) {
+		return nil, ErrInvalidToken
+	}
+	if fallback != nil {
+		normalized, err := s.normalizeFallback(*fallback)
+		if err != nil {
+			return nil, err
+		}
+		fallback = &normalized
+	}
+
+	if err := s.authorizeLink(ctx, userID, shortCode, model.RoleEditor); err != nil {
+		return nil, err
+	}
+	err := audited(ctx, s.audit, func(ctx context.Context) error {
+		before := s.snapshotLink(ctx, shortCode)
+		if err := s.repo.SetFallback(ctx, shortCode, fallback); err != nil {
+			return err
+		}
+		return s.auditLink(ctx, userID, model.AuditLinkFallback, shortCode, before)
+	})
+	if err != nil {
+		if !errors.Is(err, repository.ErrURLNotFound) {
+			s.logger.Error("Failed to update fallback", zap.Error(err), zap.String("shortCode", shortCode))
+		}
+		return nil, err
+	}
+
+	return fallback, nil
+}
+
+// GetFallbackDefault returns the fallback applied to all links of a
+// workspace userID belongs to, their personal one when workspaceID is nil
+func (s *URLService) GetFallbackDefault(ctx context.Context, userID uuid.UUID, workspaceID *uuid.UUID) (*model.FallbackDefault, error) {
+	workspaceID, err := s.memberWorkspace(ctx, userID, workspaceID, model.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	if workspaceID == nil || s.fallbacks == nil {
+		return nil, repository.ErrFallbackNotFound
+	}
+	return s.fallbacks.Get(ctx, *workspaceID)
+}
+
+// SetFallbackDefault validates and stores the fallback applied to all links
+// of a workspace. Like members, it is managed by the workspace's admins.
+func (s *URLService) SetFallbackDefault(ctx context.Context, userID uuid.UUID, workspaceID *uuid.UUID, fallback model.Fallback) (*model.FallbackDefault, error) {
+	normalized, err := s.normalizeFallback(fallback)
+	if err != nil {
+		return nil, err
+	}
+	if s.fallbacks == nil {
+		return nil, ErrInvalidFallback
+	}
+
+	workspaceID, err = s.memberWorkspace(ctx, userID, workspaceID, model.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if workspaceID == nil {
+		return nil, repository.ErrWorkspaceNotFound
+	}
+
+	def, err := s.fallbacks.Upsert(ctx, *workspaceID, normalized)
+	if err != nil {
+		s.logger.Error("Failed to save fallback default", zap.Error(err), zap.String("workspaceID", workspaceID.String()))
+		return nil, err
+	}
+
+	return def, nil
+}
+
+// DeleteFallbackDefault removes the fallback default of a workspace
+func (s *URLService) DeleteFallbackDefault(ctx context.Context, userID uuid.UUID, workspaceID *uuid.UUID) error {
+	workspaceID, err := s.memberWorkspace(ctx, userID, workspaceID, model.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if workspaceID == nil || s.fallbacks == nil {
+		return repository.ErrFallbackNotFound
+	}
+	return s.fallbacks.Delete(ctx, *workspaceID)
+}
+
+func validateLimits(expiresAt *time.Time, maxClicks *int64, now time.Time) error {
+	if expiresAt != nil && !expiresAt.After(now) {
+		return ErrInvalidLimits
+	}
+	if maxClicks != nil && *maxClicks < 1 {
+		return ErrInvalidLimits
+	}
+	return nil
+}
+
+func (s *URLService) normalizeFallback(fallback model.Fallback) (model.Fallback, error) {
+	var err error
+	if fallback.URL, err = s.normalizeOptionalURL(fallback.URL); err != nil {
+		return model.Fallback{}, err
+	}
+
+	if fallback.Page != nil {
+		page := *fallback.Page
+		page.Title = strings.TrimSpace(page.Title)
+		page.Message = strings.TrimSpace(page.Message)
+		page.ButtonText = strings.TrimSpace(page.ButtonText)
+		page.AccentColor = strings.TrimSpace(page.AccentColor)
+
+		if utf8.RuneCountInString(page.Title) > maxFallbackTitleLength ||
+			utf8.RuneCountInString(page.Message) > maxFallbackMessageLength ||
+			utf8.RuneCountInString(page.ButtonText) > maxFallbackButtonLength {
+			return model.Fallback{}, ErrInvalidFallback
+		}
+		if page.AccentColor != "" && !accentColorPattern.MatchString(page.AccentColor) {
+			return model.Fallback{}, ErrInvalidFallback
+		}
+		if page.LogoURL, err = s.normalizeOptionalURL(page.LogoURL); err != nil {
+			return model.Fallback{}, err
+		}
+		if page.ButtonURL, err = s.normalizeOptionalURL(page.ButtonURL); err != nil {
+			return model.Fallback{}, err
+		}
+		fallback.Page = &page
+	}
+
+	if fallback.URL == "" && fallback.Page == nil {
+		return model.Fallback{}, ErrInvalidFallback
+	}
+	return fallback, nil
+}
+
+// normalizeOptionalURL validates a URL rendered to visitors. Only http(s) is
+// accepted so a fallback can never carry a javascript: or data: URL.
+func (s *URLService) normalizeOptionalURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	if strings.Contains(raw, "://") && !strings.HasPrefix(raw, "http://") && !strings.HasPrefix(raw, "https://") {
+		return "", ErrInvalidFallback
+	}
+	if !s.isValidURL(raw) {
+		return "", ErrInvalidFallback
+	}
+	if !strings.HasPrefix(raw, "http://") && !strings.HasPrefix(raw, "https://") {
+		raw = "https://" + raw
+	}
+	return raw, nil
+}
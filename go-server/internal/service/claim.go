@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/token"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var ErrInvalidClaimToken = errors.New("invalid claim token")
+
+const (
+	claimNonceBytes = 16
+	maxClaimTokens  = 100
+)
+
+// prepareClaim gives a new anonymous link a signed claim token. Only the
+// hash of its random ID is stored with the link.
+func prepareClaim(urlModel *model.URL) error {
+	raw := make([]byte, claimNonceBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return fmt.Errorf("failed to generate claim nonce: %w", err)
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(raw)
+
+	signed, err := token.GenerateClaimToken(urlModel.ID, nonce)
+	if err != nil {
+		return fmt.Errorf("failed to sign claim token: %w", err)
+	}
+
+	urlModel.ClaimToken = signed
+	urlModel.ClaimHash = hashClaimNonce(nonce)
+	return nil
+}
+
+// ClaimURLs attaches the anonymous links of the given claim tokens to
+// userID and returns their ids. A forged or expired token fails the whole
+// request; tokens of links already claimed are skipped, so replaying one
+// has no effect.
+func (s *URLService) ClaimURLs(ctx context.Context, userID uuid.UUID, tokens []string) ([]string, error) {
+	if len(tokens) == 0 || len(tokens) > maxClaimTokens {
+		return nil, ErrInvalidClaimToken
+	}
+
+	claims := make([]*token.ClaimClaims, 0, len(tokens))
+	for _, t := range tokens {
+		c, err := token.ValidateClaimToken(t)
+		if err != nil {
+			s.logger.Warn("Rejected claim token", zap.Error(err), zap.String("userID", userID.String()))
+			return nil, ErrInvalidClaimToken
+		}
+		claims = append(claims, c)
+	}
+
+	workspaceID, err := s.workspaceForLink(ctx, userID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := make([]string, 0, len(claims))
+	for _, c := range claims {
+		err := audited(ctx, s.audit, func(ctx context.Context) error {
+			if err := s.repo.Claim(ctx, c.Subject, hashClaimNonce(c.ID), userID, workspaceID); err != nil {
+				return err
+			}
+			return s.auditLink(ctx, userID, model.AuditLinkClaimed, c.Subject, nil)
+		})
+		if errors.Is(err, repository.ErrURLNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		claimed = append(claimed, c.Subject)
+	}
+
+	s.logger.Info("URLs claimed", zap.String("userID", userID.String()), zap.Int("count", len(claimed)))
+	return claimed, nil
+}
+
+func hashClaimNonce(nonce string) string {
+	sum := sha256.Sum256([]byte(nonce))
+	return hex.EncodeToString(sum[:])
+}
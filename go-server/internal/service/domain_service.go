@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidDomain     = errors.New("invalid domain")
+	ErrDomainNotVerified = errors.New("domain verification record not found")
+	ErrInvalidCode       = errors.New("invalid short code")
+)
+
+const (
+	maxHostnameLength      = 253
+	maxCodeLength          = 64
+	verificationTokenBytes = 16
+	dnsLookupTimeout       = 10 * time.Second
+)
+
+// reservedCodes are paths served by the API itself on every host
+var reservedCodes = map[string]bool{"api": true, "health": true, "healthz": true, "metrics": true}
+
+// TXTResolver looks up DNS TXT records. *net.Resolver implements it.
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+type DomainService interface {
+	AddDomain(ctx context.Context, userID uuid.UUID, hostname string) (*model.Domain, error)
+	ListDomains(ctx context.Context, userID uuid.UUID) ([]model.Domain, error)
+	VerifyDomain(ctx context.Context, userID, domainID uuid.UUID) (*model.Domain, error)
+	DeleteDomain(ctx context.Context, userID, domainID uuid.UUID) error
+}
+
+type domainService struct {
+	repo     repository.DomainRepository
+	resolver TXTResolver
+	logger   *zap.Logger
+}
+
+// NewDomainService uses the system resolver when resolver is nil
+func NewDomainService(repo repository.DomainRepository, resolver TXTResolver) DomainService {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &domainService{
+		repo:     repo,
+		resolver: resolver,
+		logger:   zap.L().With(zap.String("component", "DomainService")),
+	}
+}
+
+func (s *domainService) AddDomain(ctx context.Context, userID uuid.UUID, hostname string) (*model.Domain, error) {
+	hostname, err := normalizeHostname(hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	token := make([]byte, verificationTokenBytes)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("failed to generate verification token: %w", err)
+	}
+
+	domain := &model.Domain{
+		UserID:            userID,
+		Hostname:          hostname,
+		VerificationToken: hex.EncodeToString(token),
+	}
+	if err := s.repo.Create(ctx, domain); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Domain added", zap.String("hostname", hostname), zap.String("userID", userID.String()))
+	return domain, nil
+}
+
+func (s *domainService) ListDomains(ctx context.Context, userID uuid.UUID) ([]model.Domain, error) {
+	domains, err := s.repo.List(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to list domains", zap.Error(err), zap.String("userID", userID.String()))
+		return nil, err
+	}
+	return domains, nil
+}
+
+// VerifyDomain checks the domain's TXT record and, when it carries the
+// expected token, starts serving links on the hostname
+func (s *domainService) VerifyDomain(ctx context.Context, userID, domainID uuid.UUID) (*model.Domain, error) {
+	domain, err := s.repo.Get(ctx, userID, domainID)
+	if err != nil {
+		return nil, err
+	}
+	if domain.Verified() {
+		return domain, nil
+	}
+
+	name, expected := domain.VerificationRecord()
+	lookupCtx, cancel := context.WithTimeout(ctx, dnsLookupTimeout)
+	defer cancel()
+
+	records, err := s.resolver.LookupTXT(lookupCtx, name)
+	if err != nil {
+		s.logger.Info("Domain verification lookup failed", zap.Error(err), zap.String("hostname", domain.Hostname))
+		return nil, fmt.Errorf("%w: %v", ErrDomainNotVerified, err)
+	}
+
+	found := false
+	for _, record := range records {
+		if strings.TrimSpace(record) == expected {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, ErrDomainNotVerified
+	}
+
+	now := time.Now()
+	if err := s.repo.MarkVerified(ctx, userID, domainID, now); err != nil {
+		return nil, err
+	}
+	domain.VerifiedAt = &now
+
+	s.logger.Info("Domain verified", zap.String("hostname", domain.Hostname), zap.String("userID", userID.String()))
+	return domain, nil
+}
+
+func (s *domainService) DeleteDomain(ctx context.Context, userID, domainID uuid.UUID) error {
+	if err := s.repo.Delete(ctx, userID, domainID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Domain deleted", zap.String("domainID", domainID.String()), zap.String("userID", userID.String()))
+	return nil
+}
+
+// normalizeHostname lower-cases a hostname and checks it is a plain DNS name
+// with at least two labels. IP addresses, ports and paths are rejected.
+func normalizeHostname(hostname string) (string, error) {
+	hostname = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(hostname)), ".")
+	if hostname == "" || len(hostname) > maxHostnameLength || net.ParseIP(hostname) != nil {
+		return "", ErrInvalidDomain
+	}
+
+	labels := strings.Split(hostname, ".")
+	if len(labels) < 2 {
+		return "", ErrInvalidDomain
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return "", ErrInvalidDomain
+		}
+		for _, char := range label {
+			if (char < 'a' || char > 'z') && (char < '0' || char > '9') && char != '-' {
+				return "", ErrInvalidDomain
+			}
+		}
+	}
+	return hostname, nil
+}
+
+// requestHost strips the port from a Host header and lower-cases it
+func requestHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// WithDomains enables links on verified custom domains, resolved by the
+// visitor's Host
+func WithDomains(repo repository.DomainRepository) URLServiceOption {
+	return func(s *URLService) {
+		s.domains = repo
+	}
+}
+
+// domainForHost returns the verified custom domain serving host, or nil when
+// the host uses the shared namespace
+func (s *URLService) domainForHost(ctx context.Context, host string) (*model.Domain, error) {
+	if s.domains == nil || host == "" {
+		return nil, nil
+	}
+
+	domain, err := s.domains.FindVerified(ctx, requestHost(host))
+	if errors.Is(err, repository.ErrDomainNotFound) {
+		return nil, nil
+	}
+	return domain, err
+}
+
+// ownedDomain returns the verified domain hostname of userID that new links
+// are created on
+func (s *URLService) ownedDomain(ctx context.Context, userID uuid.UUID, hostname string) (*model.Domain, error) {
+	if s.domains == nil {
+		return nil, repository.ErrDomainNotFound
+	}
+	hostname, err := normalizeHostname(hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	domain, err := s.domains.FindVerified(ctx, hostname)
+	if err != nil {
+		return nil, err
+	}
+	if domain.UserID != userID {
+		return nil, repository.ErrDomainNotFound
+	}
+	return domain, nil
+}
+
+// isValidCode checks an owner-chosen code on a custom domain: letters,
+// digits, '-' and '_', and none of the paths the API itself serves
+func isValidCode(code string) bool {
+	if code == "" || len(code) > maxCodeLength || reservedCodes[strings.ToLower(code)] {
+		return false
+	}
+	for _, char := range code {
+		if (char < 'a' || char > 'z') && (char < 'A' || char > 'Z') && (char < '0' || char > '9') && char != '-' && char != '_' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryDomains struct {
+	domains map[uuid.UUID]*model.Domain
+}
+
+func newMemoryDomains() *memoryDomains {
+	return &memoryDomains{domains: make(map[uuid.UUID]*model.Domain)}
+}
+
+func (m *memoryDomains) Create(ctx context.Context, domain *model.Domain) error {
+	for _, d := range m.domains {
+		if d.UserID == domain.UserID && d.Hostname == domain.Hostname {
+			return repository.ErrDomainExists
+		}
+	}
+	domain.ID = uuid.New()
+	domain.CreatedAt = time.Now()
+	stored := *domain
+	m.domains[domain.ID] = &stored
+	return nil
+}
+
+func (m *memoryDomains) List(ctx context.Context, userID uuid.UUID) ([]model.Domain, error) {
+	var domains []model.Domain
+	for _, d := range m.domains {
+		if d.UserID == userID {
+			domains = append(domains, *d)
+		}
+	}
+	return domains, nil
+}
+
+func (m *memoryDomains) Get(ctx context.Context, userID, id uuid.UUID) (*model.Domain, error) {
+	d, ok := m.domains[id]
+	if !ok || d.UserID != userID {
+		return nil, repository.ErrDomainNotFound
+	}
+	domain := *d
+	return &domain, nil
+}
+
+func (m *memoryDomains) MarkVerified(ctx context.Context, userID, id uuid.UUID, at time.Time) error {
+	d, ok := m.domains[id]
+	if !ok || d.UserID != userID {
+		return repository.ErrDomainNotFound
+	}
+	if _, err := m.FindVerified(ctx, d.Hostname); err == nil {
+		return repository.ErrDomainTaken
+	}
+	d.VerifiedAt = &at
+	return nil
+}
+
+func (m *memoryDomains) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	if _, err := m.Get(ctx, userID, id); err != nil {
+		return err
+	}
+	delete(m.domains, id)
+	return nil
+}
+
+func (m *memoryDomains) FindVerified(ctx context.Context, hostname string) (*model.Domain, error) {
+	for _, d := range m.domains {
+		if d.Hostname == hostname && d.Verified() {
+			domain := *d
+			return &domain, nil
+		}
+	}
+	return nil, repository.ErrDomainNotFound
+}
+
+// staticTXT answers TXT lookups from a fixed table
+type staticTXT map[string][]string
+
+func (s staticTXT) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	records, ok := s[name]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return records, nil
+}
+
+func TestAddDomain_NormalizesHostname(t *testing.T) {
+	svc := NewDomainService(newMemoryDomains(), staticTXT{})
+	userID := uuid.New()
+
+	domain, err := svc.AddDomain(context.Background(), userID, " Go.Example.COM. ")
+
+	require.NoError(t, err)
+	assert.Equal(t, "go.example.com", domain.Hostname)
+	assert.False(t, domain.Verified())
+	assert.Len(t, domain.VerificationToken, 2*verificationTokenBytes)
+
+	name, value := domain.VerificationRecord()
+	assert.Equal(t, "_tinyurl-challenge.go.example.com", name)
+	assert.Equal(t, "tinyurl-verification="+domain.VerificationToken, value)
+
+	_, err = svc.AddDomain(context.Background(), userID, "go.example.com")
+	assert.ErrorIs(t, err, repository.ErrDomainExists)
+}
+
+func TestAddDomain_InvalidHostname(t *testing.T) {
+	svc := NewDomainService(newMemoryDomains(), staticTXT{})
+
+	for _, hostname := range []string{"", "localhost", "192.168.0.1", "go.example.com:8080", "example.com/path", "-bad.example.com", "a..b"} {
+		t.Run(hostname, func(t *testing.T) {
+			_, err := svc.AddDomain(context.Background(), uuid.New(), hostname)
+			assert.ErrorIs(t, err, ErrInvalidDomain)
+		})
+	}
+}
+
+func TestVerifyDomain(t *testing.T) {
+	repo := newMemoryDomains()
+	userID := uuid.New()
+	ctx := context.Background()
+
+	records := staticTXT{}
+	svc := NewDomainService(repo, records)
+	domain, err := svc.AddDomain(ctx, userID, "go.example.com")
+	require.NoError(t, err)
+	name, value := domain.VerificationRecord()
+
+	// No record published yet
+	_, err = svc.VerifyDomain(ctx, userID, domain.ID)
+	assert.ErrorIs(t, err, ErrDomainNotVerified)
+
+	// Someone else's token does not count
+	records[name] = []string{"v=spf1 -all", "tinyurl-verification=other"}
+	_, err = svc.VerifyDomain(ctx, userID, domain.ID)
+	assert.ErrorIs(t, err, ErrDomainNotVerified)
+
+	// Only the owner can verify
+	_, err = svc.VerifyDomain(ctx, uuid.New(), domain.ID)
+	assert.ErrorIs(t, err, repository.ErrDomainNotFound)
+
+	records[name] = append(records[name], value)
+	verified, err := svc.VerifyDomain(ctx, userID, domain.ID)
+	require.NoError(t, err)
+	assert.True(t, verified.Verified())
+
+	found, err := repo.FindVerified(ctx, "go.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ID, found.ID)
+}
+
+func TestVerifyDomain_TakenByAnotherAccount(t *testing.T) {
+	repo := newMemoryDomains()
+	ctx := context.Background()
+	records := staticTXT{}
+	svc := NewDomainService(repo, records)
+
+	first, err := svc.AddDomain(ctx, uuid.New(), "go.example.com")
+	require.NoError(t, err)
+	second, err := svc.AddDomain(ctx, uuid.New(), "go.example.com")
+	require.NoError(t, err)
+
+	name, firstValue := first.VerificationRecord()
+	_, secondValue := second.VerificationRecord()
+	records[name] = []string{firstValue, secondValue}
+
+	_, err = svc.VerifyDomain(ctx, first.UserID, first.ID)
+	require.NoError(t, err)
+	_, err = svc.VerifyDomain(ctx, second.UserID, second.ID)
+	assert.ErrorIs(t, err, repository.ErrDomainTaken)
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Tokens mailed to account owners, e.g. to verify their address or reset
+// their password. Only their hash is stored.
+const emailTokenBytes = 32
+
+func newEmailToken() (string, error) {
+	raw := make([]byte, emailTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func hashEmailToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// emailLink appends token to base as the token query parameter
+func emailLink(base, token string) string {
+	sep := "?"
+	if strings.Contains(base, "?") {
+		sep = "&"
+	}
+	return base + sep + "token=" + url.QueryEscape(token)
+}
+
+// formatTTL writes ttl in whole hours, or minutes when shorter, e.g.
+// "48 hours"
+func formatTTL(ttl time.Duration) string {
+	if hours := int(ttl.Hours()); hours > 0 {
+		if hours == 1 {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", hours)
+	}
+	if minutes := int(ttl.Minutes()); minutes > 0 {
+		if minutes == 1 {
+			return "1 minute"
+		}
+		return fmt.Sprintf("%d minutes", minutes)
+	}
+	return ttl.String()
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/mail"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrEmailAlreadyVerified = errors.New("email already verified")
+	ErrMailUnavailable      = errors.New("email delivery is not configured")
+)
+
+const defaultVerificationTTL = 48 * time.Hour
+
+// VerificationConfig shapes the verification email. The link opens LinkURL
+// with the token as ?token=; without LinkURL the email carries the bare
+// token.
+type VerificationConfig struct {
+	LinkURL string
+	TTL     time.Duration
+}
+
+// EmailVerificationService proves accounts own the address they signed up
+// with
+type EmailVerificationService interface {
+	// SendVerification mails user a verification link. Links sent earlier
+	// stop working.
+	SendVerification(ctx context.Context, user *model.User) error
+	// ResendVerification sends a new link to an account not verified yet
+	ResendVerification(ctx context.Context, userID uuid.UUID) error
+	// VerifyEmail marks the account a token was sent to verified
+	VerifyEmail(ctx context.Context, token string) error
+}
+
+type emailVerificationService struct {
+	users  repository.UserRepository
+	repo   repository.EmailVerificationRepository
+	mailer mail.Mailer
+	audit  repository.AuditRepository
+	cfg    VerificationConfig
+	logger *zap.Logger
+}
+
+// NewEmailVerificationService sends links through mailer; without one
+// sending fails with ErrMailUnavailable
+func NewEmailVerificationService(users repository.UserRepository, repo repository.EmailVerificationRepository,
+	mailer mail.Mailer, audit repository.AuditRepository, cfg VerificationConfig) EmailVerificationService {
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultVerificationTTL
+	}
+	return &emailVerificationService{
+		users:  users,
+		repo:   repo,
+		mailer: mailer,
+		audit:  audit,
+		cfg:    cfg,
+		logger: zap.L().With(zap.String("component", "EmailVerificationService")),
+	}
+}
+
+func (s *emailVerificationService) SendVerification(ctx context.Context, user *model.User) error {
+	if s.mailer == nil {
+		return ErrMailUnavailable
+	}
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+	userID, err := uuid.Parse(user.ID)
+	if err != nil {
+		return fmt.Errorf("invalid user id %q: %w", user.ID, err)
+	}
+
+	token, err := newEmailToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate verification token: %w", err)
+	}
+
+	if err := s.repo.Save(ctx, userID, hashEmailToken(token), time.Now().Add(s.cfg.TTL)); err != nil {
+		return err
+	}
+
+	if err := s.mailer.Send(ctx, s.verificationMessage(user, token)); err != nil {
+		s.logger.Error("Failed to send verification email", zap.Error(err), zap.String("userID", user.ID))
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+
+	s.logger.Info("Verification email sent", zap.String("userID", user.ID))
+	return nil
+}
+
+func (s *emailVerificationService) verificationMessage(user *model.User, token string) mail.Message {
+	var b strings.Builder
+	if user.Username != "" {
+		fmt.Fprintf(&b, "Hi %s,\n\n", user.Username)
+	} else {
+		b.WriteString("Hi,\n\n")
+	}
+
+	if s.cfg.LinkURL != "" {
+		fmt.Fprintf(&b, "Please confirm your email address by opening this link:\n\n%s\n\n", emailLink(s.cfg.LinkURL, token))
+	} else {
+		fmt.Fprintf(&b, "Please confirm your email address with this verification token:\n\n%s\n\n", token)
+	}
+	fmt.Fprintf(&b, "It expires in %s. If you did not sign up, you can ignore this email.\n", formatTTL(s.cfg.TTL))
+
+	return mail.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Text:    b.String(),
+	}
+}
+
+func (s *emailVerificationService) ResendVerification(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.SendVerification(ctx, user)
+}
+
+func (s *emailVerificationService) VerifyEmail(ctx context.Context, token string) error {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return repository.ErrVerificationNotFound
+	}
+
+	var userID uuid.UUID
+	err := audited(ctx, s.audit, func(ctx context.Context) error {
+		var err error
+		if userID, err = s.repo.Verify(ctx, hashEmailToken(token), time.Now()); err != nil {
+			return err
+		}
+		return appendAudit(ctx, s.audit, s.logger, model.AuditEntry{
+			ActorID:    &userID,
+			Action:     model.AuditUserVerified,
+			TargetType: "user",
+			TargetID:   userID.String(),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.Info("Email verified", zap.String("userID", userID.String()))
+	return nil
+}
+
+// WithEmailVerification sends new accounts a link to verify their address
+func WithEmailVerification(verification EmailVerificationService) AuthServiceOption {
+	return func(s *authService) {
+		s.verification = verification
+	}
+}
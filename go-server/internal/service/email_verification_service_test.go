@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryVerifications is an in-memory EmailVerificationRepository
+// marking accounts verified in users
+type memoryVerifications struct {
+	users  *memoryUsers
+	tokens map[uuid.UUID]memoryVerification
+}
+
+type memoryVerification struct {
+	hash      string
+	expiresAt time.Time
+}
+
+func (m *memoryVerifications) Save(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	m.tokens[userID] = memoryVerification{hash: tokenHash, expiresAt: expiresAt}
+	return nil
+}
+
+func (m *memoryVerifications) Verify(ctx context.Context, tokenHash string, now time.Time) (uuid.UUID, error) {
+	for userID, v := range m.tokens {
+		if v.hash != tokenHash {
+			continue
+		}
+		delete(m.tokens, userID)
+		if !v.expiresAt.After(now) {
+			return uuid.Nil, repository.ErrVerificationNotFound
+		}
+		m.users.users[userID].EmailVerified = true
+		return userID, nil
+	}
+	return uuid.Nil, repository.ErrVerificationNotFound
+}
+
+func TestRegister_SendsVerificationEmail(t *testing.T) {
+	h := newAuthHarness(t, withVerification(VerificationConfig{LinkURL: "https://tinyurl.test/verify-email"}))
+	ctx := context.Background()
+
+	user, err := h.auth.Register(ctx, "ana", "ana@example.com", "secret123")
+	require.NoError(t, err)
+	assert.False(t, user.EmailVerified)
+
+	sent := h.mailer.Messages()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "ana@example.com", sent[0].To)
+	assert.Contains(t, sent[0].Text, "https://tinyurl.test/verify-email?token=")
+	assert.Contains(t, sent[0].Text, "48 hours")
+
+	require.NoError(t, h.verification.VerifyEmail(ctx, tokenFromEmail(t, sent[0])))
+	stored, err := h.users.GetByEmail(ctx, "ana@example.com")
+	require.NoError(t, err)
+	assert.True(t, stored.EmailVerified)
+	assert.Equal(t, model.AuditUserVerified, h.audit.entries[len(h.audit.entries)-1].Action)
+
+	// Tokens work once
+	assert.ErrorIs(t, h.verification.VerifyEmail(ctx, tokenFromEmail(t, sent[0])), repository.ErrVerificationNotFound)
+}
+
+func TestRegister_MailFailureKeepsAccount(t *testing.T) {
+	h := newAuthHarness(t, withVerification(VerificationConfig{}))
+	h.mailer.Err = assert.AnError
+
+	user, err := h.auth.Register(context.Background(), "ana", "ana@example.com", "secret123")
+	require.NoError(t, err)
+	assert.Contains(t, h.users.users, uuid.MustParse(user.ID))
+}
+
+func TestResendVerification(t *testing.T) {
+	h := newAuthHarness(t, withVerification(VerificationConfig{LinkURL: "https://tinyurl.test/verify?from=email"}))
+	ctx := context.Background()
+
+	user, err := h.auth.Register(ctx, "ana", "ana@example.com", "secret123")
+	require.NoError(t, err)
+	userID := uuid.MustParse(user.ID)
+
+	require.NoError(t, h.verification.ResendVerification(ctx, userID))
+	sent := h.mailer.Messages()
+	require.Len(t, sent, 2)
+	assert.Contains(t, sent[1].Text, "https://tinyurl.test/verify?from=email&token=")
+
+	// Only the latest link works
+	first, latest := tokenFromEmail(t, sent[0]), tokenFromEmail(t, sent[1])
+	assert.ErrorIs(t, h.verification.VerifyEmail(ctx, first), repository.ErrVerificationNotFound)
+	require.NoError(t, h.verification.VerifyEmail(ctx, latest))
+
+	assert.ErrorIs(t, h.verification.ResendVerification(ctx, userID), ErrEmailAlreadyVerified)
+	assert.ErrorIs(t, h.verification.ResendVerification(ctx, uuid.New()), repository.ErrUserNotFound)
+}
+
+func TestVerifyEmail_Expired(t *testing.T) {
+	h := newAuthHarness(t, withVerification(VerificationConfig{LinkURL: "https://tinyurl.test/v", TTL: time.Nanosecond}))
+	ctx := context.Background()
+
+	_, err := h.auth.Register(ctx, "ana", "ana@example.com", "secret123")
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+
+	assert.ErrorIs(t, h.verification.VerifyEmail(ctx, tokenFromEmail(t, h.mailer.Messages()[0])), repository.ErrVerificationNotFound)
+	assert.ErrorIs(t, h.verification.VerifyEmail(ctx, "  "), repository.ErrVerificationNotFound)
+}
+
+func TestSendVerification_WithoutMailer(t *testing.T) {
+	users := newMemoryUsers()
+	verification := NewEmailVerificationService(users,
+		&memoryVerifications{users: users, tokens: map[uuid.UUID]memoryVerification{}}, nil, nil, VerificationConfig{})
+
+	err := verification.SendVerification(context.Background(), &model.User{ID: uuid.NewString(), Email: "ana@example.com"})
+	assert.ErrorIs(t, err, ErrMailUnavailable)
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrLinkArchived       = errors.New("link is archived")
+	ErrLinkDeleted        = errors.New("link is deleted")
+	ErrNotArchived        = errors.New("link is neither archived nor deleted")
+	ErrRestoreExpired     = errors.New("link can no longer be restored")
+	ErrInvalidStateFilter = errors.New("invalid link state filter")
+)
+
+const (
+	defaultArchiveRetention = 180 * 24 * time.Hour
+	defaultDeleteRetention  = 30 * 24 * time.Hour
+)
+
+// Retention is how long archived and deleted links can be restored before
+// they are purged
+type Retention struct {
+	Archived time.Duration
+	Deleted  time.Duration
+}
+
+// WithRetention overrides the restore windows. Zero durations keep the
+// defaults.
+func WithRetention(retention Retention) URLServiceOption {
+	return func(s *URLService) {
+		if retention.Archived > 0 {
+			s.retention.Archived = retention.Archived
+		}
+		if retention.Deleted > 0 {
+			s.retention.Deleted = retention.Deleted
+		}
+	}
+}
+
+// purgeAt is when a link taken out of service will be purged, zero for
+// live links. A deleted link follows the delete window even if it was
+// archived first.
+func (r Retention) purgeAt(link *model.URL) time.Time {
+	switch {
+	case link.DeletedAt != nil:
+		return link.DeletedAt.Add(r.Deleted)
+	case link.ArchivedAt != nil:
+		return link.ArchivedAt.Add(r.Archived)
+	default:
+		return time.Time{}
+	}
+}
+
+// ArchiveURL takes a link userID can edit out of service. Archiving an
+// archived link keeps its original date.
+func (s *URLService) ArchiveURL(ctx context.Context, userID uuid.UUID, shortCode string) error {
+	link, err := s.lifecycleLink(ctx, userID, shortCode)
+	if err != nil {
+		return err
+	}
+	if link.DeletedAt != nil {
+		return ErrLinkDeleted
+	}
+	if link.ArchivedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	return s.setLifecycle(ctx, userID, model.AuditLinkArchived, link, &now, nil)
+}
+
+// DeleteURL soft-deletes a link userID can edit. It can be restored until
+// the delete retention window has passed.
+func (s *URLService) DeleteURL(ctx context.Context, userID uuid.UUID, shortCode string) error {
+	link, err := s.lifecycleLink(ctx, userID, shortCode)
+	if err != nil {
+		return err
+	}
+	if link.DeletedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	return s.setLifecycle(ctx, userID, model.AuditLinkDeleted, link, link.ArchivedAt, &now)
+}
+
+// RestoreURL puts an archived or deleted link back in service while it is
+// within its retention window
+func (s *URLService) RestoreURL(ctx context.Context, userID uuid.UUID, shortCode string) error {
+	link, err := s.lifecycleLink(ctx, userID, shortCode)
+	if err != nil {
+		return err
+	}
+	purgeAt := s.retention.purgeAt(link)
+	if purgeAt.IsZero() {
+		return ErrNotArchived
+	}
+	if !time.Now().Before(purgeAt) {
+		return ErrRestoreExpired
+	}
+
+	return s.setLifecycle(ctx, userID, model.AuditLinkRestored, link, nil, nil)
+}
+
+// PurgeExpiredLinks permanently removes up to limit links whose retention
+// window has passed and returns how many were removed
+func (s *URLService) PurgeExpiredLinks(ctx context.Context, limit int) (int, error) {
+	now := time.Now()
+	var links []model.URL
+	err := audited(ctx, s.audit, func(ctx context.Context) error {
+		var err error
+		links, err = s.repo.Purge(ctx, now.Add(-s.retention.Archived), now.Add(-s.retention.Deleted), limit)
+		if err != nil {
+			return err
+		}
+		for i := range links {
+			if err := s.recordLinkAudit(ctx, nil, model.AuditLinkPurged, links[i].ID, newLinkSnapshot(&links[i]), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to purge links", zap.Error(err))
+		return 0, err
+	}
+	return len(links), nil
+}
+
+func (s *URLService) lifecycleLink(ctx context.Context, userID uuid.UUID, shortCode string) (*model.URL, error) {
+	if !s.isValidID(shortCode) {
+		return nil, ErrInvalidToken
+	}
+	if err := s.authorizeLink(ctx, userID, shortCode, model.RoleEditor); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByID(ctx, shortCode)
+}
+
+func (s *URLService) setLifecycle(ctx context.Context, userID uuid.UUID, action string, link *model.URL, archivedAt, deletedAt *time.Time) error {
+	err := audited(ctx, s.audit, func(ctx context.Context) error {
+		before := s.snapshotLink(ctx, link.ID)
+		if err := s.repo.SetLifecycle(ctx, link.ID, archivedAt, deletedAt); err != nil {
+			return err
+		}
+		return s.auditLink(ctx, userID, action, link.ID, before)
+	})
+	if err != nil {
+		if !errors.Is(err, repository.ErrURLNotFound) && !errors.Is(err, repository.ErrDuplicateURL) {
+			s.logger.Error("Failed to update link lifecycle", zap.Error(err), zap.String("shortCode", link.ID))
+		}
+		return err
+	}
+
+	s.logger.Info("Link lifecycle updated", zap.String("shortCode", link.ID), zap.String("action", action))
+	return nil
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveURL(t *testing.T) {
+	service, mockRepo := setupService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	mockRepo.On("FindByID", ctx, "abc123").Return(&model.URL{ID: "abc123", UserID: &userID}, nil)
+	mockRepo.On("SetLifecycle", ctx, "abc123", mock.MatchedBy(func(at *time.Time) bool {
+		return at != nil && time.Since(*at) < time.Minute
+	}), (*time.Time)(nil)).Return(nil)
+
+	require.NoError(t, service.ArchiveURL(ctx, userID, "abc123"))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestArchiveURL_AlreadyArchivedKeepsDate(t *testing.T) {
+	service, mockRepo := setupService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	archivedAt := time.Now().Add(-48 * time.Hour)
+
+	mockRepo.On("FindByID", ctx, "abc123").Return(&model.URL{ID: "abc123", UserID: &userID, ArchivedAt: &archivedAt}, nil)
+
+	require.NoError(t, service.ArchiveURL(ctx, userID, "abc123"))
+	mockRepo.AssertNotCalled(t, "SetLifecycle", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestArchiveURL_DeletedLink(t *testing.T) {
+	service, mockRepo := setupService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	deletedAt := time.Now().Add(-time.Hour)
+
+	mockRepo.On("FindByID", ctx, "abc123").Return(&model.URL{ID: "abc123", UserID: &userID, DeletedAt: &deletedAt}, nil)
+
+	assert.ErrorIs(t, service.ArchiveURL(ctx, userID, "abc123"), ErrLinkDeleted)
+}
+
+func TestDeleteURL_KeepsArchiveDate(t *testing.T) {
+	service, mockRepo := setupService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	archivedAt := time.Now().Add(-48 * time.Hour)
+
+	mockRepo.On("FindByID", ctx, "abc123").Return(&model.URL{ID: "abc123", UserID: &userID, ArchivedAt: &archivedAt}, nil)
+	mockRepo.On("SetLifecycle", ctx, "abc123", &archivedAt, mock.MatchedBy(func(at *time.Time) bool {
+		return at != nil && time.Since(*at) < time.Minute
+	})).Return(nil)
+
+	require.NoError(t, service.DeleteURL(ctx, userID, "abc123"))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDeleteURL_NotOwned(t *testing.T) {
+	service, mockRepo := setupService(t)
+	ctx := context.Background()
+	owner := uuid.New()
+
+	mockRepo.On("FindByID", ctx, "abc123").Return(&model.URL{ID: "abc123", UserID: &owner}, nil)
+
+	assert.ErrorIs(t, service.DeleteURL(ctx, uuid.New(), "abc123"), repository.ErrURLNotFound)
+	mockRepo.AssertNotCalled(t, "SetLifecycle", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDeleteURL_ViewerForbidden(t *testing.T) {
+	setupService(t)
+	mockRepo := new(MockURLRepository)
+	workspaces := newMemoryWorkspaces()
+	service := NewURLService(mockRepo, WithWorkspaces(workspaces))
+	ctx := context.Background()
+	wsID, _, members := sharedWorkspace(t, workspaces, model.RoleViewer)
+	workspaces.links["abc123"] = wsID
+
+	assert.ErrorIs(t, service.DeleteURL(ctx, members[0], "abc123"), ErrForbidden)
+	mockRepo.AssertNotCalled(t, "SetLifecycle", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRestoreURL(t *testing.T) {
+	recent := time.Now().Add(-time.Hour)
+	old := time.Now().Add(-31 * 24 * time.Hour)
+
+	testCases := []struct {
+		name    string
+		link    model.URL
+		restore bool
+		err     error
+	}{
+		{"archived", model.URL{ArchivedAt: &recent}, true, nil},
+		{"deleted", model.URL{DeletedAt: &recent}, true, nil},
+		{"archived a month ago", model.URL{ArchivedAt: &old}, true, nil},
+		{"deleted a month ago", model.URL{ArchivedAt: &recent, DeletedAt: &old}, false, ErrRestoreExpired},
+		{"live", model.URL{}, false, ErrNotArchived},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockRepo := setupService(t)
+			ctx := context.Background()
+			userID := uuid.New()
+			link := tc.link
+			link.ID, link.UserID = "abc123", &userID
+
+			mockRepo.On("FindByID", ctx, "abc123").Return(&link, nil)
+			mockRepo.On("SetLifecycle", ctx, "abc123", (*time.Time)(nil), (*time.Time)(nil)).Return(nil)
+
+			err := service.RestoreURL(ctx, userID, "abc123")
+
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			if tc.restore {
+				mockRepo.AssertCalled(t, "SetLifecycle", ctx, "abc123", (*time.Time)(nil), (*time.Time)(nil))
+			} else {
+				mockRepo.AssertNotCalled(t, "SetLifecycle", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestRestoreURL_DestinationTaken(t *testing.T) {
+	service, mockRepo := setupService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	archivedAt := time.Now().Add(-time.Hour)
+
+	mockRepo.On("FindByID", ctx, "abc123").Return(&model.URL{ID: "abc123", UserID: &userID, ArchivedAt: &archivedAt}, nil)
+	mockRepo.On("SetLifecycle", ctx, "abc123", (*time.Time)(nil), (*time.Time)(nil)).Return(repository.ErrDuplicateURL)
+
+	assert.ErrorIs(t, service.RestoreURL(ctx, userID, "abc123"), repository.ErrDuplicateURL)
+}
+
+func TestPurgeExpiredLinks(t *testing.T) {
+	setupService(t)
+	mockRepo := new(MockURLRepository)
+	audit := &memoryAudit{}
+	service := NewURLService(mockRepo, WithAudit(audit), WithRetention(Retention{Archived: 48 * time.Hour}))
+	ctx := context.Background()
+	wsID := uuid.New()
+	deletedAt := time.Now().Add(-40 * 24 * time.Hour)
+
+	var archivedBefore, deletedBefore time.Time
+	mockRepo.On("Purge", ctx, mock.Anything, mock.Anything, 100).
+		Run(func(args mock.Arguments) {
+			archivedBefore, deletedBefore = args.Get(1).(time.Time), args.Get(2).(time.Time)
+		}).
+		Return([]model.URL{{ID: "abc123", OriginalURL: "https://example.com", WorkspaceID: &wsID, DeletedAt: &deletedAt}}, nil)
+
+	purged, err := service.PurgeExpiredLinks(ctx, 100)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	assert.WithinDuration(t, time.Now().Add(-48*time.Hour), archivedBefore, time.Minute)
+	assert.WithinDuration(t, time.Now().Add(-defaultDeleteRetention), deletedBefore, time.Minute)
+
+	require.Len(t, audit.entries, 1)
+	entry := audit.entries[0]
+	assert.Equal(t, model.AuditLinkPurged, entry.Action)
+	assert.Nil(t, entry.ActorID)
+	assert.Equal(t, &wsID, entry.WorkspaceID)
+	assert.NotEmpty(t, entry.Before)
+	assert.Empty(t, entry.After)
+}
+
+func TestPurgeExpiredLinks_Cutoffs(t *testing.T) {
+	tests := []struct {
+		name           string
+		retention      Retention
+		archivedBefore time.Duration
+		deletedBefore  time.Duration
+	}{
+		{"defaults", Retention{}, defaultArchiveRetention, defaultDeleteRetention},
+		{"configured", Retention{Archived: 7 * 24 * time.Hour, Deleted: time.Hour}, 7 * 24 * time.Hour, time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockURLRepository)
+			service := NewURLService(mockRepo, WithRetention(tt.retention))
+			ctx := context.Background()
+
+			var archivedBefore, deletedBefore time.Time
+			mockRepo.On("Purge", ctx, mock.Anything, mock.Anything, 10).
+				Run(func(args mock.Arguments) {
+					archivedBefore, deletedBefore = args.Get(1).(time.Time), args.Get(2).(time.Time)
+				}).
+				Return([]model.URL(nil), nil)
+
+			purged, err := service.PurgeExpiredLinks(ctx, 10)
+
+			require.NoError(t, err)
+			assert.Zero(t, purged)
+			assert.WithinDuration(t, time.Now().Add(-tt.archivedBefore), archivedBefore, time.Minute)
+			assert.WithinDuration(t, time.Now().Add(-tt.deletedBefore), deletedBefore, time.Minute)
+		})
+	}
+}
+
+func TestGetUserURLs_InvalidState(t *testing.T) {
+	service, mockRepo := setupService(t)
+
+	_, err := service.GetUserURLs(context.Background(), uuid.New(), repository.URLFilter{State: model.LinkExpired})
+
+	assert.ErrorIs(t, err, ErrInvalidStateFilter)
+	mockRepo.AssertNotCalled(t, "GetUserURLs", mock.Anything, mock.Anything, mock.Anything)
+}
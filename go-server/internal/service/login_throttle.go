@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/mail"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrAccountLocked        = errors.New("account is temporarily locked")
+	ErrTooManyLoginAttempts = errors.New("too many failed login attempts")
+)
+
+const (
+	defaultFreeLoginAttempts  = 3
+	defaultLoginBaseDelay     = time.Second
+	defaultMaxAccountFailures = 10
+	defaultMaxIPFailures      = 50
+	defaultLoginFailureWindow = time.Hour
+	defaultLockoutDuration    = 15 * time.Minute
+	lockoutNoticeTimeout      = 30 * time.Second
+)
+
+// LoginThrottleConfig limits failed sign-ins. After FreeAttempts failures
+// an account waits BaseDelay before the next attempt, twice as long after
+// every further failure, and MaxAccountFailures lock it for
+// LockoutDuration. A client IP is locked as long after MaxIPFailures,
+// whichever accounts it tried. Failures are forgotten Window after the
+// last one, or once the account signs in. Wrong two-factor codes, also
+// those given to change the authenticator, count the same way, apart from
+// wrong passwords.
+type LoginThrottleConfig struct {
+	FreeAttempts       int
+	BaseDelay          time.Duration
+	MaxAccountFailures int
+	MaxIPFailures      int
+	Window             time.Duration
+	LockoutDuration    time.Duration
+}
+
+// LoginLockedError is returned while sign-in is locked. It unwraps to
+// ErrAccountLocked, or ErrTooManyLoginAttempts when the client IP is.
+type LoginLockedError struct {
+	// RetryAfter is how long until the next attempt is allowed
+	RetryAfter time.Duration
+	byIP       bool
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("%s, retry in %s", e.Unwrap(), e.RetryAfter.Round(time.Second))
+}
+
+func (e *LoginLockedError) Unwrap() error {
+	if e.byIP {
+		return ErrTooManyLoginAttempts
+	}
+	return ErrAccountLocked
+}
+
+type loginThrottle struct {
+	attempts repository.LoginAttemptRepository
+	mailer   mail.Mailer
+	cfg      LoginThrottleConfig
+	logger   *zap.Logger
+}
+
+// WithLoginThrottle slows down and then locks out repeated failed sign-ins,
+// counted in attempts. Owners of locked accounts are told through mailer
+// when it is not nil.
+func WithLoginThrottle(attempts repository.LoginAttemptRepository, mailer mail.Mailer, cfg LoginThrottleConfig) AuthServiceOption {
+	return func(s *authService) {
+		s.throttle = newLoginThrottle(attempts, mailer, cfg, s.logger)
+	}
+}
+
+func newLoginThrottle(attempts repository.LoginAttemptRepository, mailer mail.Mailer, cfg LoginThrottleConfig, logger *zap.Logger) *loginThrottle {
+	if cfg.FreeAttempts <= 0 {
+		cfg.FreeAttempts = defaultFreeLoginAttempts
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = defaultLoginBaseDelay
+	}
+	if cfg.MaxAccountFailures <= 0 {
+		cfg.MaxAccountFailures = defaultMaxAccountFailures
+	}
+	if cfg.MaxIPFailures <= 0 {
+		cfg.MaxIPFailures = defaultMaxIPFailures
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultLoginFailureWindow
+	}
+	if cfg.LockoutDuration <= 0 {
+		cfg.LockoutDuration = defaultLockoutDuration
+	}
+	return &loginThrottle{attempts: attempts, mailer: mailer, cfg: cfg, logger: logger}
+}
+
+// Accounts are counted by the address they are signed in with, whether or
+// not one uses it, so locking reveals nothing about which accounts exist
+func accountLoginKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// Second factors are counted per account, their challenge names it
+func mfaLoginKey(userID uuid.UUID) string {
+	return "mfa:" + userID.String()
+}
+
+func ipLoginKey(ip string) string {
+	return "ip:" + ip
+}
+
+// delay returns how long an account waits after its nth failure
+func (t *loginThrottle) delay(failures int) time.Duration {
+	if failures >= t.cfg.MaxAccountFailures {
+		return t.cfg.LockoutDuration
+	}
+	if failures <= t.cfg.FreeAttempts {
+		return 0
+	}
+	delay := t.cfg.BaseDelay
+	for i := t.cfg.FreeAttempts + 1; i < failures && delay < t.cfg.LockoutDuration; i++ {
+		delay *= 2
+	}
+	return min(delay, t.cfg.LockoutDuration)
+}
+
+// admitLogin refuses attempts while the account or client is locked, and
+// otherwise counts the attempt as failed until the password or code turned
+// out right, so concurrent guesses cannot get past the limit. The Redis
+// counters failing must not keep everyone out, so that only disables the
+// throttle.
+func (t *loginThrottle) admitLogin(ctx context.Context, accountKey string) (failures int, err error) {
+	keys := []string{accountKey}
+	if ip := requestIP(ctx); ip != "" {
+		keys = append(keys, ipLoginKey(ip))
+	}
+
+	locked, err := t.attempts.LockedFor(ctx, keys...)
+	if err != nil {
+		t.logger.Warn("Login throttle unavailable", zap.Error(err))
+		return 0, nil
+	}
+	if len(locked) > 1 && locked[1] > 0 {
+		return 0, &LoginLockedError{RetryAfter: locked[1], byIP: true}
+	}
+	if locked[0] > 0 {
+		return 0, &LoginLockedError{RetryAfter: locked[0]}
+	}
+
+	failures, err = t.attempts.AddFailure(ctx, accountKey, t.cfg.Window)
+	if err != nil {
+		t.logger.Warn("Login throttle unavailable", zap.Error(err))
+		return 0, nil
+	}
+	// Attempts racing the lock of an earlier failure
+	if failures > t.cfg.MaxAccountFailures {
+		return failures, &LoginLockedError{RetryAfter: t.cfg.LockoutDuration}
+	}
+	// Taken now so attempts made while this one checks the password wait
+	// too; signing in lifts it
+	if delay := t.delay(failures); delay > 0 {
+		if err := t.attempts.Lock(ctx, accountKey, delay); err != nil {
+			t.logger.Warn("Failed to delay login", zap.Error(err))
+		}
+	}
+	return failures, nil
+}
+
+// loginSucceeded forgets the failures of the account. Those of the client
+// stay, otherwise signing in to an account of their own would let a client
+// guess on.
+func (t *loginThrottle) loginSucceeded(ctx context.Context, accountKey string) {
+	if err := t.attempts.Clear(ctx, accountKey); err != nil {
+		t.logger.Warn("Failed to clear failed logins", zap.Error(err))
+	}
+}
+
+// loginFailed tells the owner when the failure locked their account and
+// counts it against the client. user is nil when no account uses the
+// address; factor names what was wrong, e.g. "password".
+func (t *loginThrottle) loginFailed(ctx context.Context, user *model.User, failures int, factor string) {
+	if failures == t.cfg.MaxAccountFailures && user != nil {
+		t.logger.Warn("Account locked after failed logins", zap.String("userID", user.ID), zap.Int("failures", failures))
+		t.sendLockoutNotice(ctx, user, failures, factor)
+	}
+
+	ip := requestIP(ctx)
+	if ip == "" {
+		return
+	}
+	ipFailures, err := t.attempts.AddFailure(ctx, ipLoginKey(ip), t.cfg.Window)
+	if err != nil {
+		return
+	}
+	if ipFailures >= t.cfg.MaxIPFailures {
+		if ipFailures == t.cfg.MaxIPFailures {
+			t.logger.Warn("Client locked after failed logins", zap.String("ip", ip), zap.Int("failures", ipFailures))
+		}
+		if err := t.attempts.Lock(ctx, ipLoginKey(ip), t.cfg.LockoutDuration); err != nil {
+			t.logger.Warn("Failed to lock client", zap.Error(err), zap.String("ip", ip))
+		}
+	}
+}
+
+// sendLockoutNotice tells the owner their account was locked. It is sent
+// in the background so the answer takes as long as for unknown accounts.
+func (t *loginThrottle) sendLockoutNotice(ctx context.Context, user *model.User, failures int, factor string) {
+	if t.mailer == nil {
+		return
+	}
+	msg := t.lockoutMessage(user, failures, factor, requestIP(ctx))
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lockoutNoticeTimeout)
+		defer cancel()
+		if err := t.mailer.Send(ctx, msg); err != nil {
+			t.logger.Error("Failed to send lockout notice", zap.Error(err), zap.String("userID", user.ID))
+		}
+	}()
+}
+
+func (t *loginThrottle) lockoutMessage(user *model.User, failures int, factor, ip string) mail.Message {
+	var b strings.Builder
+	if user.Username != "" {
+		fmt.Fprintf(&b, "Hi %s,\n\n", user.Username)
+	} else {
+		b.WriteString("Hi,\n\n")
+	}
+
+	fmt.Fprintf(&b, "Someone entered a wrong %s for your account %d times", factor, failures)
+	if ip != "" {
+		fmt.Fprintf(&b, ", last from %s", ip)
+	}
+	fmt.Fprintf(&b, ". To protect it, signing in is locked for %s.\n\n", formatTTL(t.cfg.LockoutDuration))
+	b.WriteString("If this was you, you can try again once the lock ends. If it was not, we recommend resetting your password.\n")
+
+	return mail.Message{
+		To:      user.Email,
+		Subject: "Your account was temporarily locked",
+		Text:    b.String(),
+	}
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryAttempts is an in-memory LoginAttemptRepository. Failures do not
+// expire; Err makes every call fail.
+type memoryAttempts struct {
+	mu       sync.Mutex
+	failures map[string]int
+	locks    map[string]time.Time
+	Err      error
+}
+
+// expireLocks lifts all locks as if they ran out
+func (m *memoryAttempts) expireLocks() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.locks = map[string]time.Time{}
+}
+
+func newMemoryAttempts() *memoryAttempts {
+	return &memoryAttempts{failures: map[string]int{}, locks: map[string]time.Time{}}
+}
+
+func (m *memoryAttempts) AddFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	m.failures[key]++
+	return m.failures[key], nil
+}
+
+func (m *memoryAttempts) Lock(ctx context.Context, key string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	m.locks[key] = time.Now().Add(ttl)
+	return nil
+}
+
+func (m *memoryAttempts) LockedFor(ctx context.Context, keys ...string) ([]time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	locked := make([]time.Duration, len(keys))
+	for i, key := range keys {
+		if left := time.Until(m.locks[key]); left > 0 {
+			locked[i] = left
+		}
+	}
+	return locked, nil
+}
+
+func (m *memoryAttempts) Clear(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	delete(m.failures, key)
+	delete(m.locks, key)
+	return nil
+}
+
+func TestLogin_ProgressiveDelayAndLockout(t *testing.T) {
+	h := newAuthHarness(t, withLoginThrottle(LoginThrottleConfig{
+		FreeAttempts:       2,
+		BaseDelay:          time.Minute,
+		MaxAccountFailures: 4,
+		LockoutDuration:    time.Hour,
+	}))
+	h.registerAna(t)
+	ctx := WithRequestInfo(context.Background(), "req-1", "203.0.113.7")
+
+	// Counted per address however it is spelled
+	for _, email := range []string{"ana@example.com", "ANA@example.com", " ana@example.com"} {
+		_, err := h.auth.Login(ctx, email, "wrong")
+		require.ErrorIs(t, err, ErrInvalidCredentials, email)
+	}
+
+	// The third failure delays the next attempt, even with the right password
+	_, err := h.auth.Login(ctx, "ana@example.com", "secret123")
+	var locked *LoginLockedError
+	require.ErrorAs(t, err, &locked)
+	assert.ErrorIs(t, err, ErrAccountLocked)
+	assert.InDelta(t, time.Minute, locked.RetryAfter, float64(5*time.Second))
+
+	h.attempts.expireLocks()
+	_, err = h.auth.Login(ctx, "ana@example.com", "wrong")
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	// The fourth locks the account and tells its owner
+	_, err = h.auth.Login(ctx, "ana@example.com", "secret123")
+	require.ErrorAs(t, err, &locked)
+	assert.Greater(t, locked.RetryAfter, 59*time.Minute)
+
+	sent := waitForEmails(t, h.mailer, 1)
+	assert.Equal(t, "ana@example.com", sent[0].To)
+	assert.Contains(t, sent[0].Text, "4 times")
+	assert.Contains(t, sent[0].Text, "203.0.113.7")
+	assert.Contains(t, sent[0].Text, "1 hour")
+}
+
+func TestLogin_SuccessForgetsFailures(t *testing.T) {
+	h := newAuthHarness(t, withLoginThrottle(LoginThrottleConfig{FreeAttempts: 2, BaseDelay: time.Hour}))
+	h.registerAna(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := h.auth.Login(ctx, "ana@example.com", "wrong")
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+	_, err := h.auth.Login(ctx, "ana@example.com", "secret123")
+	require.NoError(t, err)
+	assert.Empty(t, h.attempts.failures)
+
+	for i := 0; i < 2; i++ {
+		_, err := h.auth.Login(ctx, "ana@example.com", "wrong")
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+}
+
+func TestLogin_LocksClientIP(t *testing.T) {
+	h := newAuthHarness(t, withLoginThrottle(LoginThrottleConfig{MaxIPFailures: 3, MaxAccountFailures: 2, FreeAttempts: 1}))
+	h.registerAna(t)
+	ctx := WithRequestInfo(context.Background(), "req-1", "203.0.113.7")
+
+	// Unknown accounts are counted and locked alike, but nobody is mailed
+	for i := 0; i < 2; i++ {
+		_, err := h.auth.Login(ctx, "bob@example.com", "wrong")
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+	_, err := h.auth.Login(ctx, "bob@example.com", "wrong")
+	assert.ErrorIs(t, err, ErrAccountLocked)
+
+	_, err = h.auth.Login(ctx, "eve@example.com", "wrong")
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = h.auth.Login(ctx, "ana@example.com", "secret123")
+	assert.ErrorIs(t, err, ErrTooManyLoginAttempts)
+
+	// Other clients can still sign in
+	_, err = h.auth.Login(WithRequestInfo(context.Background(), "req-2", "198.51.100.1"), "ana@example.com", "secret123")
+	assert.NoError(t, err)
+	assert.Empty(t, h.mailer.Messages())
+}
+
+func TestLogin_ThrottleUnavailable(t *testing.T) {
+	h := newAuthHarness(t, withLoginThrottle(LoginThrottleConfig{}))
+	h.registerAna(t)
+	h.attempts.Err = repository.ErrCacheError
+
+	_, err := h.auth.Login(context.Background(), "ana@example.com", "wrong")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = h.auth.Login(context.Background(), "ana@example.com", "secret123")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/mail"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/totp"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidMFACode  = errors.New("invalid two-factor code")
+	ErrInvalidMFAToken = errors.New("invalid or expired two-factor challenge")
+)
+
+const (
+	defaultMFAIssuer = "TinyUrl"
+	// Steps of clock drift tolerated between server and authenticator
+	totpSkew          = 1
+	recoveryCodeCount = 10
+	// Characters of a recovery code, shown in two halves, e.g. abcde-fghij
+	recoveryCodeLength = 10
+	// 32 characters without look-alikes such as 0 and o, 1 and l
+	recoveryCodeAlphabet = "abcdefghijkmnpqrstuvwxyz23456789"
+)
+
+// MFAService manages the TOTP authenticator that protects an account as a
+// second factor, and the recovery codes that replace it when it is lost
+type MFAService interface {
+	// Enroll starts setting up an authenticator with a new secret. It only
+	// protects the account once Confirm got a code generated from it.
+	Enroll(ctx context.Context, userID uuid.UUID) (*model.MFAEnrollment, error)
+	// Confirm enables the enrolled authenticator and returns the recovery
+	// codes, which are not shown again
+	Confirm(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	// RegenerateRecoveryCodes replaces the recovery codes, given a current
+	// code. Wrong codes count against the account like those given to sign
+	// in.
+	RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	// Disable removes the authenticator, given a current code, counted like
+	// in RegenerateRecoveryCodes
+	Disable(ctx context.Context, userID uuid.UUID, code string) error
+	// Verify checks a code from the authenticator or a recovery code. Each
+	// works once.
+	Verify(ctx context.Context, userID uuid.UUID, code string) error
+}
+
+type mfaService struct {
+	users    repository.UserRepository
+	repo     repository.MFARepository
+	audit    repository.AuditRepository
+	issuer   string
+	throttle *loginThrottle
+	logger   *zap.Logger
+}
+
+// MFAServiceOption enables an optional part of two-factor authentication
+type MFAServiceOption func(*mfaService)
+
+// NewMFAService names the service issuer in authenticator apps, "TinyUrl"
+// when empty
+func NewMFAService(users repository.UserRepository, repo repository.MFARepository, audit repository.AuditRepository, issuer string, opts ...MFAServiceOption) MFAService {
+	if issuer == "" {
+		issuer = defaultMFAIssuer
+	}
+	s := &mfaService{
+		users:  users,
+		repo:   repo,
+		audit:  audit,
+		issuer: issuer,
+		logger: zap.L().With(zap.String("component", "MFAService")),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// WithMFALoginThrottle counts wrong codes given to change the authenticator
+// in attempts, together with those given to sign in, so a stolen session
+// cannot guess its way to turning two-factor authentication off. Pass the
+// attempts and cfg given to WithLoginThrottle.
+func WithMFALoginThrottle(attempts repository.LoginAttemptRepository, mailer mail.Mailer, cfg LoginThrottleConfig) MFAServiceOption {
+	return func(s *mfaService) {
+		s.throttle = newLoginThrottle(attempts, mailer, cfg, s.logger)
+	}
+}
+
+func (s *mfaService) Enroll(ctx context.Context, userID uuid.UUID) (*model.MFAEnrollment, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, repository.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+	if err := s.repo.SavePending(ctx, userID, secret); err != nil {
+		return nil, err
+	}
+
+	return &model.MFAEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+func (s *mfaService) Confirm(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	settings, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings.EnabledAt != nil {
+		return nil, repository.ErrMFAAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(settings.Secret, normalizeMFACode(code), time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = audited(ctx, s.audit, func(ctx context.Context) error {
+		if err := s.repo.Enable(ctx, userID, step, hashes); err != nil {
+			return err
+		}
+		return s.auditMFA(ctx, userID, model.AuditUserMFAEnabled)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Two-factor authentication enabled", zap.String("userID", userID.String()))
+	return codes, nil
+}
+
+func (s *mfaService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	if err := s.verifyThrottled(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = audited(ctx, s.audit, func(ctx context.Context) error {
+		if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+			return err
+		}
+		return s.auditMFA(ctx, userID, model.AuditUserMFACodesReset)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *mfaService) Disable(ctx context.Context, userID uuid.UUID, code string) error {
+	if err := s.verifyThrottled(ctx, userID, code); err != nil {
+		return err
+	}
+	err := audited(ctx, s.audit, func(ctx context.Context) error {
+		if err := s.repo.Disable(ctx, userID); err != nil {
+			return err
+		}
+		return s.auditMFA(ctx, userID, model.AuditUserMFADisabled)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Two-factor authentication disabled", zap.String("userID", userID.String()))
+	return nil
+}
+
+func (s *mfaService) Verify(ctx context.Context, userID uuid.UUID, code string) error {
+	settings, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if settings.EnabledAt == nil {
+		return repository.ErrMFANotEnrolled
+	}
+
+	code = normalizeMFACode(code)
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(settings.Secret, code, time.Now(), totpSkew)
+		if !ok {
+			return ErrInvalidMFACode
+		}
+		// Codes stay valid for a while; someone who saw one must not reuse it
+		fresh, err := s.repo.UseStep(ctx, userID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := s.repo.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	s.logger.Info("Recovery code used", zap.String("userID", userID.String()))
+	return nil
+}
+
+// verifyThrottled is Verify counting wrong codes under the same key as
+// CompleteMFALogin
+func (s *mfaService) verifyThrottled(ctx context.Context, userID uuid.UUID, code string) error {
+	if s.throttle == nil {
+		return s.Verify(ctx, userID, code)
+	}
+
+	failures, err := s.throttle.admitLogin(ctx, mfaLoginKey(userID))
+	if err != nil {
+		return err
+	}
+	if err := s.Verify(ctx, userID, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			// Only needed to tell the owner about a lockout
+			user, _ := s.users.GetByID(ctx, userID)
+			s.throttle.loginFailed(ctx, user, failures, "two-factor code")
+		}
+		return err
+	}
+	s.throttle.loginSucceeded(ctx, mfaLoginKey(userID))
+	return nil
+}
+
+func (s *mfaService) auditMFA(ctx context.Context, userID uuid.UUID, action string) error {
+	return appendAudit(ctx, s.audit, s.logger, model.AuditEntry{
+		ActorID:    &userID,
+		Action:     action,
+		TargetType: "user",
+		TargetID:   userID.String(),
+	})
+}
+
+// normalizeMFACode accepts codes typed with spaces, dashes or capitals
+func normalizeMFACode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+}
+
+// newRecoveryCodes returns codes to show the owner and the hashes to store
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	codes = make([]string, recoveryCodeCount)
+	hashes = make([]string, recoveryCodeCount)
+	raw := make([]byte, recoveryCodeLength)
+	for i := range codes {
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+		}
+		code := make([]byte, recoveryCodeLength)
+		for j, b := range raw {
+			// 256 is a multiple of the 32 characters, so each is as likely
+			code[j] = recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)]
+		}
+		codes[i] = string(code[:recoveryCodeLength/2]) + "-" + string(code[recoveryCodeLength/2:])
+		hashes[i] = hashRecoveryCode(string(code))
+	}
+	return codes, hashes, nil
+}
+
+// Recovery codes are random enough that a fast hash does
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// WithMFA lets accounts with an authenticator complete their login with a
+// code from it
+func WithMFA(mfa MFAService) AuthServiceOption {
+	return func(s *authService) {
+		s.mfa = mfa
+	}
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/token"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/totp"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryMFA is an in-memory MFARepository flagging accounts in users
+type memoryMFA struct {
+	users    *memoryUsers
+	settings map[uuid.UUID]*memoryAuthenticator
+}
+
+type memoryAuthenticator struct {
+	model.MFASettings
+	lastStep int64
+	codes    map[string]bool
+}
+
+func (m *memoryMFA) Get(ctx context.Context, userID uuid.UUID) (*model.MFASettings, error) {
+	a, ok := m.settings[userID]
+	if !ok {
+		return nil, repository.ErrMFANotEnrolled
+	}
+	settings := a.MFASettings
+	return &settings, nil
+}
+
+func (m *memoryMFA) SavePending(ctx context.Context, userID uuid.UUID, secret string) error {
+	if a, ok := m.settings[userID]; ok && a.EnabledAt != nil {
+		return repository.ErrMFAAlreadyEnabled
+	}
+	m.settings[userID] = &memoryAuthenticator{MFASettings: model.MFASettings{Secret: secret}}
+	return nil
+}
+
+func (m *memoryMFA) Enable(ctx context.Context, userID uuid.UUID, step int64, recoveryHashes []string) error {
+	a, ok := m.settings[userID]
+	if !ok || a.EnabledAt != nil {
+		return repository.ErrMFAAlreadyEnabled
+	}
+	now := time.Now()
+	a.EnabledAt, a.lastStep = &now, step
+	m.users.users[userID].MFAEnabled = true
+	return m.ReplaceRecoveryCodes(ctx, userID, recoveryHashes)
+}
+
+func (m *memoryMFA) UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	a := m.settings[userID]
+	if step <= a.lastStep {
+		return false, nil
+	}
+	a.lastStep = step
+	return true, nil
+}
+
+func (m *memoryMFA) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	a := m.settings[userID]
+	if !a.codes[codeHash] {
+		return false, nil
+	}
+	delete(a.codes, codeHash)
+	return true, nil
+}
+
+func (m *memoryMFA) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, recoveryHashes []string) error {
+	a := m.settings[userID]
+	a.codes = map[string]bool{}
+	for _, hash := range recoveryHashes {
+		a.codes[hash] = true
+	}
+	return nil
+}
+
+func (m *memoryMFA) Disable(ctx context.Context, userID uuid.UUID) error {
+	if _, ok := m.settings[userID]; !ok {
+		return repository.ErrMFANotEnrolled
+	}
+	delete(m.settings, userID)
+	m.users.users[userID].MFAEnabled = false
+	return nil
+}
+
+// setupMFA registers ana and enables two-factor authentication for her
+func setupMFA(t *testing.T, opts ...authHarnessOption) (*authHarness, uuid.UUID, string, []string) {
+	t.Helper()
+	h := newAuthHarness(t, append(opts, withMFA())...)
+	userID := h.registerAna(t)
+	ctx := context.Background()
+
+	enrollment, err := h.mfa.Enroll(ctx, userID)
+	require.NoError(t, err)
+	code, err := totp.Code(enrollment.Secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	recovery, err := h.mfa.Confirm(ctx, userID, code)
+	require.NoError(t, err)
+	assert.Equal(t, model.AuditUserMFAEnabled, h.audit.entries[len(h.audit.entries)-1].Action)
+
+	return h, userID, enrollment.Secret, recovery
+}
+
+// nextCode returns a code not used yet, the one of the next time step
+func nextCode(t *testing.T, secret string) string {
+	t.Helper()
+	code, err := totp.Code(secret, totp.Step(time.Now())+1)
+	require.NoError(t, err)
+	return code
+}
+
+func TestMFA_Enroll(t *testing.T) {
+	users := newMemoryUsers()
+	mfa := NewMFAService(users, &memoryMFA{users: users, settings: map[uuid.UUID]*memoryAuthenticator{}}, nil, "Acme Links")
+	ctx := context.Background()
+	require.NoError(t, users.Create(ctx, &model.User{Username: "ana", Email: "ana@example.com"}))
+	stored, err := users.GetByEmail(ctx, "ana@example.com")
+	require.NoError(t, err)
+	userID := uuid.MustParse(stored.ID)
+
+	enrollment, err := mfa.Enroll(ctx, userID)
+	require.NoError(t, err)
+	uri, err := url.Parse(enrollment.URI)
+	require.NoError(t, err)
+	assert.Equal(t, "/Acme Links:ana@example.com", uri.Path)
+	assert.Equal(t, enrollment.Secret, uri.Query().Get("secret"))
+
+	// Not enabled until a code confirms it
+	assert.ErrorIs(t, mfa.Verify(ctx, userID, "123456"), repository.ErrMFANotEnrolled)
+	_, err = mfa.Confirm(ctx, userID, "000000")
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+
+	code, err := totp.Code(enrollment.Secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	recovery, err := mfa.Confirm(ctx, userID, code)
+	require.NoError(t, err)
+	require.Len(t, recovery, recoveryCodeCount)
+	for _, c := range recovery {
+		assert.Regexp(t, `^[a-z2-9]{5}-[a-z2-9]{5}$`, c)
+	}
+
+	_, err = mfa.Enroll(ctx, userID)
+	assert.ErrorIs(t, err, repository.ErrMFAAlreadyEnabled)
+}
+
+func TestLogin_WithMFA(t *testing.T) {
+	h, _, secret, recovery := setupMFA(t)
+	ctx := context.Background()
+
+	result, err := h.auth.Login(ctx, "ana@example.com", "secret123")
+	require.NoError(t, err)
+	assert.Empty(t, result.Token)
+	require.NotEmpty(t, result.MFAToken)
+
+	// The challenge is no session
+	_, err = token.ValidateToken(result.MFAToken)
+	assert.Error(t, err)
+
+	// The code that confirmed the authenticator was used already
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	_, err = h.auth.CompleteMFALogin(ctx, result.MFAToken, code)
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+
+	code = nextCode(t, secret)
+	session, err := h.auth.CompleteMFALogin(ctx, result.MFAToken, code)
+	require.NoError(t, err)
+	claims, err := token.ValidateToken(session)
+	require.NoError(t, err)
+	assert.NotNil(t, claims.UserID)
+
+	_, err = h.auth.CompleteMFALogin(ctx, result.MFAToken, code)
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+
+	// Recovery codes work once, however they are typed
+	typed := strings.ToUpper(strings.ReplaceAll(recovery[0], "-", " "))
+	_, err = h.auth.CompleteMFALogin(ctx, result.MFAToken, typed)
+	require.NoError(t, err)
+	_, err = h.auth.CompleteMFALogin(ctx, result.MFAToken, recovery[0])
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+
+	_, err = h.auth.CompleteMFALogin(ctx, session, recovery[1])
+	assert.ErrorIs(t, err, ErrInvalidMFAToken)
+}
+
+func TestCompleteMFALogin_AfterPasswordReset(t *testing.T) {
+	h, userID, secret, _ := setupMFA(t)
+	ctx := context.Background()
+
+	result, err := h.auth.Login(ctx, "ana@example.com", "secret123")
+	require.NoError(t, err)
+	h.users.users[userID].SessionVersion++
+
+	_, err = h.auth.CompleteMFALogin(ctx, result.MFAToken, nextCode(t, secret))
+	assert.ErrorIs(t, err, ErrInvalidMFAToken)
+}
+
+func TestMFA_RecoveryCodesAndDisable(t *testing.T) {
+	h, userID, secret, recovery := setupMFA(t)
+	ctx := context.Background()
+
+	fresh, err := h.mfa.RegenerateRecoveryCodes(ctx, userID, recovery[0])
+	require.NoError(t, err)
+	assert.ErrorIs(t, h.mfa.Verify(ctx, userID, recovery[1]), ErrInvalidMFACode)
+	require.NoError(t, h.mfa.Verify(ctx, userID, fresh[0]))
+
+	assert.ErrorIs(t, h.mfa.Disable(ctx, userID, "000000"), ErrInvalidMFACode)
+	require.NoError(t, h.mfa.Disable(ctx, userID, nextCode(t, secret)))
+
+	result, err := h.auth.Login(ctx, "ana@example.com", "secret123")
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.Token)
+	assert.Empty(t, result.MFAToken)
+}
+
+func TestMFA_ChangesCountWrongCodes(t *testing.T) {
+	h, userID, secret, recovery := setupMFA(t, withLoginThrottle(LoginThrottleConfig{
+		FreeAttempts:       5,
+		MaxAccountFailures: 3,
+		LockoutDuration:    time.Hour,
+	}))
+	ctx := context.Background()
+
+	_, err := h.mfa.RegenerateRecoveryCodes(ctx, userID, "000000")
+	require.ErrorIs(t, err, ErrInvalidMFACode)
+	require.ErrorIs(t, h.mfa.Disable(ctx, userID, "111111"), ErrInvalidMFACode)
+
+	// Wrong codes given to sign in count towards the same lock
+	result, err := h.auth.Login(ctx, "ana@example.com", "secret123")
+	require.NoError(t, err)
+	_, err = h.auth.CompleteMFALogin(ctx, result.MFAToken, "222222")
+	require.ErrorIs(t, err, ErrInvalidMFACode)
+
+	// Locked now, even with a right code, and the owner is told
+	err = h.mfa.Disable(ctx, userID, nextCode(t, secret))
+	var locked *LoginLockedError
+	require.ErrorAs(t, err, &locked)
+	assert.ErrorIs(t, err, ErrAccountLocked)
+	_, err = h.mfa.RegenerateRecoveryCodes(ctx, userID, recovery[0])
+	assert.ErrorAs(t, err, &locked)
+
+	sent := waitForEmails(t, h.mailer, 1)
+	assert.Equal(t, "ana@example.com", sent[0].To)
+	assert.Contains(t, sent[0].Text, "two-factor code")
+}
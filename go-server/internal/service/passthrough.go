@@ -0,0 +1,79 @@
+package service
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+)
+
+// Visit describes the incoming request a short code is resolved for
+type Visit struct {
+	// Query is the visitor's query string, forwarded when the link allows it
+	Query url.Values
+	// ExtraPath holds the path segments after the short code, e.g. "/docs/intro"
+	ExtraPath string
+	// UserAgent, AcceptLanguage and ClientIP are matched against the link's
+	// routing rules
+	UserAgent      string
+	AcceptLanguage string
+	ClientIP       string
+	// StickyVariant is the token of a previously assigned A/B variant
+	StickyVariant string
+	// Host is the requested hostname, custom domains resolve their own codes
+	Host string
+}
+
+// Resolution is the outcome of resolving a short code for one visit
+type Resolution struct {
+	URL string
+	// Variant is the index of the A/B destination served, nil when the link
+	// has no variants or a routing rule matched
+	Variant *int
+	// StickyVariant is the token to hand back to the visitor to keep them on
+	// the same variant, empty when the link is not sticky
+	StickyVariant string
+}
+
+// applyPassthrough builds the final destination from the stored URL and the
+// visit. Extra path segments on a link without path forwarding are treated as
+// an unknown link.
+func applyPassthrough(link *model.URL, visit Visit) (string, error) {
+	extraPath := strings.Trim(visit.ExtraPath, "/")
+	forwardQuery := link.ForwardQuery && len(visit.Query) > 0
+
+	if extraPath != "" && !link.ForwardPath {
+		return "", repository.ErrURLNotFound
+	}
+	if extraPath == "" && !forwardQuery {
+		return link.OriginalURL, nil
+	}
+
+	dest, err := url.Parse(link.OriginalURL)
+	if err != nil {
+		return "", err
+	}
+
+	if extraPath != "" {
+		// Clean against the root so ".." cannot climb above the destination path
+		suffix := path.Clean("/" + extraPath)
+		if strings.HasSuffix(visit.ExtraPath, "/") {
+			suffix += "/"
+		}
+		dest.Path = strings.TrimSuffix(dest.Path, "/") + suffix
+		dest.RawPath = ""
+	}
+
+	if forwardQuery {
+		// Visitor parameters win over the ones stored on the destination
+		merged := dest.Query()
+		for key, values := range visit.Query {
+			merged[key] = values
+		}
+		dest.RawQuery = merged.Encode()
+	}
+
+	return dest.String(), nil
+}
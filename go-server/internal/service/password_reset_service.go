@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/mail"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultPasswordResetTTL = time.Hour
+	// Bounds the lookup and delivery of a reset email, which outlive the request
+	passwordResetSendTimeout = 30 * time.Second
+)
+
+// PasswordResetConfig shapes the reset email. The link opens LinkURL with
+// the token as ?token=; without LinkURL the email carries the bare token.
+type PasswordResetConfig struct {
+	LinkURL string
+	TTL     time.Duration
+}
+
+// PasswordResetService lets owners who forgot their password choose a new
+// one through a link mailed to their address
+type PasswordResetService interface {
+	// RequestReset mails the account registered with email a reset link.
+	// It returns before anything is looked up and never tells whether the
+	// account exists.
+	RequestReset(ctx context.Context, email string) error
+	// ResetPassword sets the password of the account a token was sent to and
+	// signs it out everywhere
+	ResetPassword(ctx context.Context, token, password string) error
+}
+
+type passwordResetService struct {
+	users  repository.UserRepository
+	repo   repository.PasswordResetRepository
+	mailer mail.Mailer
+	audit  repository.AuditRepository
+	cfg    PasswordResetConfig
+	logger *zap.Logger
+}
+
+// NewPasswordResetService sends links through mailer; without one requests
+// are only logged
+func NewPasswordResetService(users repository.UserRepository, repo repository.PasswordResetRepository,
+	mailer mail.Mailer, audit repository.AuditRepository, cfg PasswordResetConfig) PasswordResetService {
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultPasswordResetTTL
+	}
+	return &passwordResetService{
+		users:  users,
+		repo:   repo,
+		mailer: mailer,
+		audit:  audit,
+		cfg:    cfg,
+		logger: zap.L().With(zap.String("component", "PasswordResetService")),
+	}
+}
+
+func (s *passwordResetService) RequestReset(ctx context.Context, email string) error {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil
+	}
+
+	// Answering only once the email went out would take measurably longer
+	// for registered addresses than for unknown ones
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), passwordResetSendTimeout)
+		defer cancel()
+		if err := s.sendReset(ctx, email); err != nil {
+			s.logger.Error("Failed to send password reset email", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+func (s *passwordResetService) sendReset(ctx context.Context, email string) error {
+	if s.mailer == nil {
+		s.logger.Warn("Password reset requested but email delivery is not configured")
+		return nil
+	}
+
+	user, err := s.users.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	// A new password would not let a disabled account back in
+	if user.DisabledAt != nil {
+		s.logger.Info("Password reset requested for a disabled account", zap.String("userID", user.ID))
+		return nil
+	}
+	userID, err := uuid.Parse(user.ID)
+	if err != nil {
+		return fmt.Errorf("invalid user id %q: %w", user.ID, err)
+	}
+
+	token, err := newEmailToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate reset token: %w", err)
+	}
+
+	if err := s.repo.Save(ctx, userID, hashEmailToken(token), time.Now().Add(s.cfg.TTL)); err != nil {
+		return err
+	}
+
+	if err := s.mailer.Send(ctx, s.resetMessage(user, token)); err != nil {
+		return fmt.Errorf("failed to send password reset email to user %s: %w", user.ID, err)
+	}
+
+	s.logger.Info("Password reset email sent", zap.String("userID", user.ID))
+	return nil
+}
+
+func (s *passwordResetService) resetMessage(user *model.User, token string) mail.Message {
+	var b strings.Builder
+	if user.Username != "" {
+		fmt.Fprintf(&b, "Hi %s,\n\n", user.Username)
+	} else {
+		b.WriteString("Hi,\n\n")
+	}
+
+	if s.cfg.LinkURL != "" {
+		fmt.Fprintf(&b, "Someone asked to reset the password of your account. Choose a new one by opening this link:\n\n%s\n\n", emailLink(s.cfg.LinkURL, token))
+	} else {
+		fmt.Fprintf(&b, "Someone asked to reset the password of your account. Choose a new one with this reset token:\n\n%s\n\n", token)
+	}
+	fmt.Fprintf(&b, "It expires in %s and works once. If you did not ask for it, you can ignore this email; your password stays the same.\n", formatTTL(s.cfg.TTL))
+
+	return mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Text:    b.String(),
+	}
+}
+
+func (s *passwordResetService) ResetPassword(ctx context.Context, token, password string) error {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return repository.ErrResetNotFound
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	var userID uuid.UUID
+	err = audited(ctx, s.audit, func(ctx context.Context) error {
+		var err error
+		if userID, err = s.repo.Reset(ctx, hashEmailToken(token), string(passwordHash), time.Now()); err != nil {
+			return err
+		}
+		return appendAudit(ctx, s.audit, s.logger, model.AuditEntry{
+			ActorID:    &userID,
+			Action:     model.AuditUserPasswordReset,
+			TargetType: "user",
+			TargetID:   userID.String(),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.Info("Password reset", zap.String("userID", userID.String()))
+	return nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryResets is an in-memory PasswordResetRepository updating accounts
+// in users
+type memoryResets struct {
+	mu     sync.Mutex
+	users  *memoryUsers
+	tokens map[uuid.UUID]memoryVerification
+}
+
+func (m *memoryResets) Save(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokens[userID] = memoryVerification{hash: tokenHash, expiresAt: expiresAt}
+	return nil
+}
+
+func (m *memoryResets) Reset(ctx context.Context, tokenHash, passwordHash string, now time.Time) (uuid.UUID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for userID, r := range m.tokens {
+		if r.hash != tokenHash {
+			continue
+		}
+		delete(m.tokens, userID)
+		if !r.expiresAt.After(now) {
+			return uuid.Nil, repository.ErrResetNotFound
+		}
+		user := m.users.users[userID]
+		user.PasswordHash = passwordHash
+		user.SessionVersion++
+		user.EmailVerified = true
+		return userID, nil
+	}
+	return uuid.Nil, repository.ErrResetNotFound
+}
+
+func TestResetPassword(t *testing.T) {
+	h := newAuthHarness(t, withPasswordReset(PasswordResetConfig{LinkURL: "https://tinyurl.test/reset-password"}))
+	ctx := context.Background()
+
+	user, err := h.auth.Register(ctx, "ana", "ana@example.com", "secret123")
+	require.NoError(t, err)
+
+	require.NoError(t, h.resets.RequestReset(ctx, "ana@example.com"))
+	sent := waitForEmails(t, h.mailer, 1)
+	assert.Equal(t, "ana@example.com", sent[0].To)
+	assert.Contains(t, sent[0].Text, "https://tinyurl.test/reset-password?token=")
+	assert.Contains(t, sent[0].Text, "1 hour")
+
+	token := tokenFromEmail(t, sent[0])
+	require.NoError(t, h.resets.ResetPassword(ctx, token, "n3w-secret"))
+
+	_, err = h.auth.Login(ctx, "ana@example.com", "secret123")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = h.auth.Login(ctx, "ana@example.com", "n3w-secret")
+	assert.NoError(t, err)
+
+	// Sessions issued before the reset are revoked
+	stored := h.users.users[uuid.MustParse(user.ID)]
+	assert.Equal(t, 1, stored.SessionVersion)
+	assert.True(t, stored.EmailVerified)
+	assert.Equal(t, model.AuditUserPasswordReset, h.audit.entries[len(h.audit.entries)-1].Action)
+
+	// Tokens work once
+	assert.ErrorIs(t, h.resets.ResetPassword(ctx, token, "another-one"), repository.ErrResetNotFound)
+}
+
+func TestRequestReset_DoesNotRevealAccounts(t *testing.T) {
+	h := newAuthHarness(t, withPasswordReset(PasswordResetConfig{}))
+	ctx := context.Background()
+
+	disabled, err := h.auth.Register(ctx, "bob", "bob@example.com", "secret123")
+	require.NoError(t, err)
+	now := time.Now()
+	h.users.users[uuid.MustParse(disabled.ID)].DisabledAt = &now
+	_, err = h.auth.Register(ctx, "ana", "ana@example.com", "secret123")
+	require.NoError(t, err)
+
+	assert.NoError(t, h.resets.RequestReset(ctx, "nobody@example.com"))
+	assert.NoError(t, h.resets.RequestReset(ctx, "bob@example.com"))
+	assert.NoError(t, h.resets.RequestReset(ctx, "ana@example.com"))
+
+	// Only the active account gets an email; without a link it carries the token
+	waitForEmails(t, h.mailer, 1)
+	time.Sleep(20 * time.Millisecond)
+	sent := h.mailer.Messages()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "ana@example.com", sent[0].To)
+	assert.NotContains(t, sent[0].Text, "token=")
+}
+
+func TestResetPassword_Expired(t *testing.T) {
+	h := newAuthHarness(t, withPasswordReset(PasswordResetConfig{LinkURL: "https://tinyurl.test/r", TTL: time.Nanosecond}))
+	ctx := context.Background()
+
+	_, err := h.auth.Register(ctx, "ana", "ana@example.com", "secret123")
+	require.NoError(t, err)
+	require.NoError(t, h.resets.RequestReset(ctx, "ana@example.com"))
+	sent := waitForEmails(t, h.mailer, 1)
+	time.Sleep(time.Millisecond)
+
+	assert.ErrorIs(t, h.resets.ResetPassword(ctx, tokenFromEmail(t, sent[0]), "n3w-secret"), repository.ErrResetNotFound)
+	assert.ErrorIs(t, h.resets.ResetPassword(ctx, " ", "n3w-secret"), repository.ErrResetNotFound)
+}
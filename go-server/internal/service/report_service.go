@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var ErrInvalidReport = errors.New("invalid report")
+
+const maxReportDescriptionLength = 2000
+
+// ReportRequest is an abuse report filed by a visitor. ReporterID is set
+// when they are signed in.
+type ReportRequest struct {
+	ShortCode   string
+	Reason      model.ReportReason
+	Description string
+	ReporterID  *uuid.UUID
+	ReporterIP  string
+}
+
+// ReportService queues abuse reports and lets site admins act on them
+type ReportService interface {
+	Report(ctx context.Context, req ReportRequest) error
+	ListReports(ctx context.Context, filter repository.ReportFilter) ([]model.Report, error)
+	Dismiss(ctx context.Context, adminID, reportID uuid.UUID) (*model.Report, error)
+	TakeDown(ctx context.Context, adminID, reportID uuid.UUID) (*model.Report, error)
+}
+
+type reportService struct {
+	repo   repository.ReportRepository
+	audit  repository.AuditRepository
+	logger *zap.Logger
+}
+
+// NewReportService records reviews in audit when it is not nil
+func NewReportService(repo repository.ReportRepository, audit repository.AuditRepository) ReportService {
+	return &reportService{
+		repo:   repo,
+		audit:  audit,
+		logger: zap.L().With(zap.String("component", "ReportService")),
+	}
+}
+
+func (s *reportService) Report(ctx context.Context, req ReportRequest) error {
+	req.ShortCode = strings.TrimSpace(req.ShortCode)
+	if !isValidCode(req.ShortCode) {
+		return ErrInvalidToken
+	}
+	req.Reason = model.ReportReason(strings.ToLower(strings.TrimSpace(string(req.Reason))))
+	req.Description = strings.TrimSpace(req.Description)
+	if !req.Reason.Valid() || utf8.RuneCountInString(req.Description) > maxReportDescriptionLength {
+		return ErrInvalidReport
+	}
+
+	report := &model.Report{
+		URLID:       req.ShortCode,
+		Reason:      req.Reason,
+		Description: req.Description,
+		ReporterID:  req.ReporterID,
+		ReporterIP:  req.ReporterIP,
+	}
+	if err := s.repo.Create(ctx, report); err != nil {
+		if !errors.Is(err, repository.ErrURLNotFound) {
+			s.logger.Error("Failed to store report", zap.Error(err), zap.String("shortCode", req.ShortCode))
+		}
+		return err
+	}
+
+	s.logger.Info("Link reported", zap.String("shortCode", req.ShortCode), zap.String("reason", string(req.Reason)))
+	return nil
+}
+
+// ListReports returns the moderation queue, open reports by default
+func (s *reportService) ListReports(ctx context.Context, filter repository.ReportFilter) ([]model.Report, error) {
+	switch filter.Status {
+	case "":
+		filter.Status = model.ReportOpen
+	case model.ReportOpen, model.ReportDismissed, model.ReportTakenDown:
+	default:
+		return nil, ErrInvalidReport
+	}
+	filter.Limit, filter.Offset = normalizeAdminPage(filter.Limit, filter.Offset)
+	return s.repo.List(ctx, filter)
+}
+
+// Dismiss closes the open reports of a link without acting on it
+func (s *reportService) Dismiss(ctx context.Context, adminID, reportID uuid.UUID) (*model.Report, error) {
+	var report *model.Report
+	err := audited(ctx, s.audit, func(ctx context.Context) error {
+		var err error
+		if report, err = s.repo.Dismiss(ctx, reportID, adminID, time.Now()); err != nil {
+			return err
+		}
+		return appendAudit(ctx, s.audit, s.logger, model.AuditEntry{
+			ActorID:    &adminID,
+			Action:     model.AuditReportDismissed,
+			TargetType: "report",
+			TargetID:   reportID.String(),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// TakeDown disables a reported link for abuse. Its owner cannot switch it
+// back on, only an admin can.
+func (s *reportService) TakeDown(ctx context.Context, adminID, reportID uuid.UUID) (*model.Report, error) {
+	var report *model.Report
+	err := audited(ctx, s.audit, func(ctx context.Context) error {
+		var err error
+		if report, err = s.repo.TakeDown(ctx, reportID, adminID, time.Now()); err != nil {
+			return err
+		}
+		return appendAudit(ctx, s.audit, s.logger, model.AuditEntry{
+			ActorID:    &adminID,
+			Action:     model.AuditLinkTakenDown,
+			TargetType: "link",
+			TargetID:   report.URLID,
+			After:      (&linkSnapshot{URL: report.URL, Disabled: true}).raw(),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Link taken down", zap.String("shortCode", report.URLID), zap.String("admin", adminID.String()))
+	return report, nil
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryReports is an in-memory ReportRepository for tests
+type memoryReports struct {
+	links   map[string]*model.URL
+	reports []model.Report
+	filters []repository.ReportFilter
+}
+
+func newMemoryReports(linkIDs ...string) *memoryReports {
+	m := &memoryReports{links: map[string]*model.URL{}}
+	for _, id := range linkIDs {
+		m.links[id] = &model.URL{ID: id, OriginalURL: "https://" + id + ".example"}
+	}
+	return m
+}
+
+func (m *memoryReports) Create(ctx context.Context, report *model.Report) error {
+	if _, ok := m.links[report.URLID]; !ok {
+		return repository.ErrURLNotFound
+	}
+	for _, r := range m.reports {
+		if r.URLID == report.URLID && r.ReporterIP == report.ReporterIP && r.Status == model.ReportOpen {
+			return nil
+		}
+	}
+	report.ID, report.Status, report.CreatedAt = uuid.New(), model.ReportOpen, time.Now()
+	m.reports = append(m.reports, *report)
+	return nil
+}
+
+func (m *memoryReports) List(ctx context.Context, filter repository.ReportFilter) ([]model.Report, error) {
+	m.filters = append(m.filters, filter)
+	return m.reports, nil
+}
+
+func (m *memoryReports) Dismiss(ctx context.Context, id, adminID uuid.UUID, now time.Time) (*model.Report, error) {
+	return m.review(id, adminID, now, model.ReportDismissed)
+}
+
+func (m *memoryReports) TakeDown(ctx context.Context, id, adminID uuid.UUID, now time.Time) (*model.Report, error) {
+	return m.review(id, adminID, now, model.ReportTakenDown)
+}
+
+func (m *memoryReports) review(id, adminID uuid.UUID, now time.Time, status model.ReportStatus) (*model.Report, error) {
+	var urlID string
+	for _, r := range m.reports {
+		if r.ID == id && r.Status == model.ReportOpen {
+			urlID = r.URLID
+		}
+	}
+	if urlID == "" {
+		return nil, repository.ErrReportNotFound
+	}
+	if status == model.ReportTakenDown {
+		m.links[urlID].Disabled, m.links[urlID].DisabledReason = true, model.DisabledForAbuse
+	}
+	var reviewed model.Report
+	for i := range m.reports {
+		if m.reports[i].URLID == urlID && m.reports[i].Status == model.ReportOpen {
+			m.reports[i].Status, m.reports[i].ReviewedAt, m.reports[i].ReviewedBy = status, &now, &adminID
+			if m.reports[i].ID == id {
+				reviewed = m.reports[i]
+			}
+		}
+	}
+	reviewed.URL = m.links[urlID].OriginalURL
+	return &reviewed, nil
+}
+
+func TestReport(t *testing.T) {
+	setupService(t)
+	repo := newMemoryReports("abc123")
+	service := NewReportService(repo, nil)
+	ctx := context.Background()
+
+	testCases := []struct {
+		name string
+		req  ReportRequest
+		err  error
+	}{
+		{"valid", ReportRequest{ShortCode: "abc123", Reason: "Phishing ", Description: " fake bank login ", ReporterIP: "203.0.113.7"}, nil},
+		{"repeat is accepted", ReportRequest{ShortCode: "abc123", Reason: model.ReportSpam, ReporterIP: "203.0.113.7"}, nil},
+		{"unknown reason", ReportRequest{ShortCode: "abc123", Reason: "ugly"}, ErrInvalidReport},
+		{"description too long", ReportRequest{ShortCode: "abc123", Reason: model.ReportOther, Description: string(make([]rune, 2001))}, ErrInvalidReport},
+		{"invalid code", ReportRequest{ShortCode: "../etc", Reason: model.ReportSpam}, ErrInvalidToken},
+		{"unknown link", ReportRequest{ShortCode: "zzz999", Reason: model.ReportSpam}, repository.ErrURLNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := service.Report(ctx, tc.req)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	require.Len(t, repo.reports, 1)
+	assert.Equal(t, model.ReportPhishing, repo.reports[0].Reason)
+	assert.Equal(t, "fake bank login", repo.reports[0].Description)
+}
+
+func TestTakeDown(t *testing.T) {
+	setupService(t)
+	repo := newMemoryReports("abc123", "def456")
+	audit := &memoryAudit{}
+	service := NewReportService(repo, audit)
+	ctx := context.Background()
+	adminID := uuid.New()
+
+	require.NoError(t, service.Report(ctx, ReportRequest{ShortCode: "abc123", Reason: model.ReportPhishing, ReporterIP: "203.0.113.7"}))
+	require.NoError(t, service.Report(ctx, ReportRequest{ShortCode: "abc123", Reason: model.ReportMalware, ReporterIP: "198.51.100.2"}))
+	require.NoError(t, service.Report(ctx, ReportRequest{ShortCode: "def456", Reason: model.ReportSpam, ReporterIP: "203.0.113.7"}))
+
+	report, err := service.TakeDown(ctx, adminID, repo.reports[0].ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, model.ReportTakenDown, report.Status)
+	assert.Equal(t, model.LinkTakenDown, repo.links["abc123"].State(time.Now()))
+	assert.Equal(t, model.ReportTakenDown, repo.reports[1].Status, "other reports on the link are closed too")
+	assert.Equal(t, model.ReportOpen, repo.reports[2].Status)
+	require.Len(t, audit.entries, 1)
+	assert.Equal(t, model.AuditLinkTakenDown, audit.entries[0].Action)
+	assert.Equal(t, "abc123", audit.entries[0].TargetID)
+
+	_, err = service.Dismiss(ctx, adminID, repo.reports[1].ID)
+	assert.ErrorIs(t, err, repository.ErrReportNotFound)
+
+	_, err = service.Dismiss(ctx, adminID, repo.reports[2].ID)
+	require.NoError(t, err)
+	assert.False(t, repo.links["def456"].Disabled)
+	assert.Equal(t, model.AuditReportDismissed, audit.entries[1].Action)
+}
+
+func TestListReports_Status(t *testing.T) {
+	setupService(t)
+	repo := newMemoryReports()
+	service := NewReportService(repo, nil)
+	ctx := context.Background()
+
+	_, err := service.ListReports(ctx, repository.ReportFilter{})
+	require.NoError(t, err)
+	_, err = service.ListReports(ctx, repository.ReportFilter{Status: "closed"})
+	assert.ErrorIs(t, err, ErrInvalidReport)
+
+	require.Len(t, repo.filters, 1)
+	assert.Equal(t, model.ReportOpen, repo.filters[0].Status)
+	assert.Equal(t, defaultAdminPageSize, repo.filters[0].Limit)
+}
+
+func TestResolve_TakenDownIgnoresFallback(t *testing.T) {
+	setupService(t)
+	mockRepo := new(MockURLRepository)
+	service := NewURLService(mockRepo, WithFallbacks(nil, &model.Fallback{URL: "https://global.example"}))
+	ctx := context.Background()
+	mockRepo.On("FindByID", ctx, "abc123").Return(&model.URL{
+		ID: "abc123", OriginalURL: "https://phish.example", Disabled: true, DisabledReason: model.DisabledForAbuse,
+		Fallback: &model.Fallback{URL: "https://phish.example/again"},
+	}, nil)
+
+	_, err := service.Resolve(ctx, "abc123", Visit{})
+
+	var unavailable *UnavailableError
+	require.ErrorAs(t, err, &unavailable)
+	assert.Equal(t, model.LinkTakenDown, unavailable.State)
+	assert.Nil(t, unavailable.Fallback)
+}
+
+func TestResolve_ModeratedLinksIgnoreFallback(t *testing.T) {
+	setupService(t)
+	global := &model.Fallback{URL: "https://global.example"}
+	ownFallback := &model.Fallback{URL: "https://owner.example/elsewhere"}
+
+	testCases := []struct {
+		reason   string
+		state    model.LinkState
+		fallback *model.Fallback
+	}{
+		{model.DisabledByAdmin, model.LinkDisabled, nil},
+		{model.DisabledLegal, model.LinkBlocked, nil},
+		{model.DisabledByOwner, model.LinkDisabled, ownFallback},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.reason, func(t *testing.T) {
+			mockRepo := new(MockURLRepository)
+			service := NewURLService(mockRepo, WithFallbacks(nil, global))
+			ctx := context.Background()
+			mockRepo.On("FindByID", ctx, "abc123").Return(&model.URL{
+				ID: "abc123", OriginalURL: "https://example.com", Disabled: true, DisabledReason: tc.reason,
+				Fallback: ownFallback,
+			}, nil)
+
+			_, err := service.Resolve(ctx, "abc123", Visit{})
+
+			var unavailable *UnavailableError
+			require.ErrorAs(t, err, &unavailable)
+			assert.Equal(t, tc.state, unavailable.State)
+			assert.Equal(t, tc.fallback, unavailable.Fallback)
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/useragent"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var ErrInvalidRule = errors.New("invalid routing rule")
+
+const maxRoutingRules = 20
+
+var (
+	languageTagPattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+	countryCodePattern = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// CountryResolver maps a client IP to an ISO 3166-1 alpha-2 country code,
+// returning "" when unknown
+type CountryResolver interface {
+	Country(ip net.IP) string
+}
+
+// WithCountryResolver enables country conditions in routing rules
+func WithCountryResolver(resolver CountryResolver) URLServiceOption {
+	return func(s *URLService) {
+		s.countries = resolver
+	}
+}
+
+// GetRoutingRules returns the ordered routing rules of a link userID can see
+func (s *URLService) GetRoutingRules(ctx context.Context, userID uuid.UUID, shortCode string) ([]model.RoutingRule, error) {
+	if !s.isValidID(shortCode) {
+		return nil, ErrInvalidToken
+	}
+
+	if err := s.authorizeLink(ctx, userID, shortCode, model.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	rules, err := s.repo.GetRules(ctx, shortCode)
+	if err != nil {
+		if !errors.Is(err, repository.ErrURLNotFound) {
+			s.logger.Error("Failed to load routing rules", zap.Error(err), zap.String("shortCode", shortCode))
+		}
+		return nil, err
+	}
+	if rules == nil {
+		rules = []model.RoutingRule{}
+	}
+
+	return rules, nil
+}
+
+// SetRoutingRules validates and replaces the routing rules of a link userID
+// can edit. An empty list removes all rules.
+func (s *URLService) SetRoutingRules(ctx context.Context, userID uuid.UUID, shortCode string, rules []model.RoutingRule) ([]model.RoutingRule, error) {
+	if !s.isValidID(shortCode) {
+		return nil, ErrInvalidToken
+	}
+
+	normalized, err := s.normalizeRules(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizeLink(ctx, userID, shortCode, model.RoleEditor); err != nil {
+		return nil, err
+	}
+	err = audited(ctx, s.audit, func(ctx context.Context) error {
+		before := s.snapshotLink(ctx, shortCode)
+		if err := s.repo.SetRules(ctx, shortCode, normalized); err != nil {
+			return err
+		}
+		return s.auditLink(ctx, userID, model.AuditLinkRules, shortCode, before)
+	})
+	if err != nil {
+		if !errors.Is(err, repository.ErrURLNotFound) {
+			s.logger.Error("Failed to save routing rules", zap.Error(err), zap.String("shortCode", shortCode))
+		}
+		return nil, err
+	}
+
+	s.logger.Info("Routing rules updated", zap.String("shortCode", shortCode), zap.Int("count", len(normalized)))
+	return normalized, nil
+}
+
+func (s *URLService) normalizeRules(rules []model.RoutingRule) ([]model.RoutingRule, error) {
+	if len(rules) > maxRoutingRules {
+		return nil, ErrInvalidRule
+	}
+
+	normalized := make([]model.RoutingRule, 0, len(rules))
+	for _, rule := range rules {
+		rule.OS = strings.ToLower(strings.TrimSpace(rule.OS))
+		rule.Device = strings.ToLower(strings.TrimSpace(rule.Device))
+		rule.Browser = strings.ToLower(strings.TrimSpace(rule.Browser))
+		rule.Language = normalizeLanguage(rule.Language)
+		rule.Country = strings.ToUpper(strings.TrimSpace(rule.Country))
+		rule.URL = strings.TrimSpace(rule.URL)
+
+		// A rule without conditions would hide the link's own destination
+		if rule.OS == "" && rule.Device == "" && rule.Browser == "" && rule.Language == "" && rule.Country == "" {
+			return nil, ErrInvalidRule
+		}
+		if !validCondition(rule.OS, useragent.OSes) ||
+			!validCondition(rule.Device, useragent.Devices) ||
+			!validCondition(rule.Browser, useragent.Browsers) {
+			return nil, ErrInvalidRule
+		}
+		if rule.Language != "" && !languageTagPattern.MatchString(rule.Language) {
+			return nil, ErrInvalidRule
+		}
+		if rule.Country != "" && !countryCodePattern.MatchString(rule.Country) {
+			return nil, ErrInvalidRule
+		}
+
+		if !s.isValidURL(rule.URL) {
+			return nil, ErrInvalidURL
+		}
+		if !strings.HasPrefix(rule.URL, "http://") && !strings.HasPrefix(rule.URL, "https://") {
+			rule.URL = "https://" + rule.URL
+		}
+
+		normalized = append(normalized, rule)
+	}
+
+	return normalized, nil
+}
+
+func validCondition(value string, allowed []string) bool {
+	return value == "" || slices.Contains(allowed, value)
+}
+
+// matchRule returns the destination of the first rule matching the visitor
+func (s *URLService) matchRule(link *model.URL, visit Visit) (string, bool) {
+	if len(link.Rules) == 0 {
+		return "", false
+	}
+
+	visitor := &visitorProfile{visit: visit, countries: s.countries}
+	for _, rule := range link.Rules {
+		if visitor.matches(rule) {
+			return rule.URL, true
+		}
+	}
+
+	return "", false
+}
+
+// visitorProfile derives the matched attributes of a visit on first use, so
+// links whose rules only look at the User-Agent never hit the GeoIP database
+type visitorProfile struct {
+	visit     Visit
+	countries CountryResolver
+
+	client      *useragent.Client
+	language    *string
+	countryCode *string
+}
+
+func (v *visitorProfile) matches(rule model.RoutingRule) bool {
+	if rule.OS != "" || rule.Device != "" || rule.Browser != "" {
+		client := v.userAgent()
+		if !matches(rule.OS, client.OS) || !matches(rule.Device, client.Device) || !matches(rule.Browser, client.Browser) {
+			return false
+		}
+	}
+	if rule.Language != "" && !languageMatches(rule.Language, v.preferredLanguage()) {
+		return false
+	}
+	if rule.Country != "" && rule.Country != v.country() {
+		return false
+	}
+	return true
+}
+
+func (v *visitorProfile) userAgent() useragent.Client {
+	if v.client == nil {
+		client := useragent.Parse(v.visit.UserAgent)
+		v.client = &client
+	}
+	return *v.client
+}
+
+func (v *visitorProfile) preferredLanguage() string {
+	if v.language == nil {
+		language := preferredLanguage(v.visit.AcceptLanguage)
+		v.language = &language
+	}
+	return *v.language
+}
+
+func (v *visitorProfile) country() string {
+	if v.countryCode == nil {
+		code := ""
+		if v.countries != nil {
+			code = v.countries.Country(net.ParseIP(v.visit.ClientIP))
+		}
+		v.countryCode = &code
+	}
+	return *v.countryCode
+}
+
+func matches(condition, value string) bool {
+	return condition == "" || condition == value
+}
+
+// languageMatches lets "pt" match any Portuguese variant while "pt-br" only
+// matches Brazilian Portuguese
+func languageMatches(condition, language string) bool {
+	return language == condition || strings.HasPrefix(language, condition+"-")
+}
+
+// preferredLanguage returns the highest weighted tag of an Accept-Language
+// header, the first one listed on ties
+func preferredLanguage(header string) string {
+	best, bestWeight := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = normalizeLanguage(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		weight := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			weight = parsed
+		}
+
+		if weight > bestWeight {
+			best, bestWeight = tag, weight
+		}
+	}
+	return best
+}
+
+func normalizeLanguage(tag string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(tag)), "_", "-")
+}
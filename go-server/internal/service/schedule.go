@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrLinkNotStarted  = errors.New("link is not active yet")
+	ErrInvalidSchedule = errors.New("invalid schedule")
+)
+
+const maxScheduledChanges = 20
+
+// SetSchedule replaces the activation time and the pending destination
+// changes of a link userID can edit. A nil startsAt makes the link active
+// immediately.
+func (s *URLService) SetSchedule(ctx context.Context, userID uuid.UUID, shortCode string, startsAt *time.Time, changes []model.ScheduledDestination) ([]model.ScheduledDestination, error) {
+	if !s.isValidID(shortCode) {
+		return nil, ErrInvalidToken
+	}
+
+	normalized, err := s.normalizeSchedule(changes, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizeLink(ctx, userID, shortCode, model.RoleEditor); err != nil {
+		return nil, err
+	}
+	err = audited(ctx, s.audit, func(ctx context.Context) error {
+		before := s.snapshotLink(ctx, shortCode)
+		if err := s.repo.SetSchedule(ctx, shortCode, startsAt, normalized); err != nil {
+			return err
+		}
+		return s.auditLink(ctx, userID, model.AuditLinkSchedule, shortCode, before)
+	})
+	if err != nil {
+		if !errors.Is(err, repository.ErrURLNotFound) {
+			s.logger.Error("Failed to save schedule", zap.Error(err), zap.String("shortCode", shortCode))
+		}
+		return nil, err
+	}
+
+	s.logger.Info("Schedule updated", zap.String("shortCode", shortCode), zap.Int("changes", len(normalized)))
+	return normalized, nil
+}
+
+func (s *URLService) normalizeSchedule(changes []model.ScheduledDestination, now time.Time) ([]model.ScheduledDestination, error) {
+	if len(changes) > maxScheduledChanges {
+		return nil, ErrInvalidSchedule
+	}
+
+	seen := make(map[time.Time]bool, len(changes))
+	normalized := make([]model.ScheduledDestination, 0, len(changes))
+	for _, change := range changes {
+		change.URL = strings.TrimSpace(change.URL)
+		change.EffectiveAt = change.EffectiveAt.UTC()
+
+		// Changes in the past are plain edits and would rewrite history
+		if !change.EffectiveAt.After(now) || seen[change.EffectiveAt] {
+			return nil, ErrInvalidSchedule
+		}
+		seen[change.EffectiveAt] = true
+
+		if !s.isValidURL(change.URL) {
+			return nil, ErrInvalidURL
+		}
+		if !strings.HasPrefix(change.URL, "http://") && !strings.HasPrefix(change.URL, "https://") {
+			change.URL = "https://" + change.URL
+		}
+		normalized = append(normalized, change)
+	}
+
+	return normalized, nil
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var ErrInvalidTag = errors.New("invalid tag")
+
+const maxTagLength = 50
+
+type TagService interface {
+	ListTags(ctx context.Context, userID uuid.UUID) ([]model.Tag, error)
+	RenameTag(ctx context.Context, userID, tagID uuid.UUID, name string) error
+	MergeTags(ctx context.Context, userID, sourceID, targetID uuid.UUID) error
+	DeleteTag(ctx context.Context, userID, tagID uuid.UUID) error
+}
+
+type tagService struct {
+	repo   repository.TagRepository
+	logger *zap.Logger
+}
+
+func NewTagService(repo repository.TagRepository) TagService {
+	return &tagService{
+		repo:   repo,
+		logger: zap.L().With(zap.String("component", "TagService")),
+	}
+}
+
+func (s *tagService) ListTags(ctx context.Context, userID uuid.UUID) ([]model.Tag, error) {
+	tags, err := s.repo.List(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to list tags", zap.Error(err), zap.String("userID", userID.String()))
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (s *tagService) RenameTag(ctx context.Context, userID, tagID uuid.UUID, name string) error {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Rename(ctx, userID, tagID, name); err != nil {
+		return err
+	}
+
+	s.logger.Info("Tag renamed", zap.String("tagID", tagID.String()), zap.String("name", name))
+	return nil
+}
+
+func (s *tagService) MergeTags(ctx context.Context, userID, sourceID, targetID uuid.UUID) error {
+	if sourceID == targetID {
+		return ErrInvalidTag
+	}
+
+	if err := s.repo.Merge(ctx, userID, sourceID, targetID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Tags merged", zap.String("sourceID", sourceID.String()), zap.String("targetID", targetID.String()))
+	return nil
+}
+
+func (s *tagService) DeleteTag(ctx context.Context, userID, tagID uuid.UUID) error {
+	if err := s.repo.Delete(ctx, userID, tagID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Tag deleted", zap.String("tagID", tagID.String()))
+	return nil
+}
+
+func normalizeTagName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxTagLength {
+		return "", ErrInvalidTag
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) || r == ',' {
+			return "", ErrInvalidTag
+		}
+	}
+	return name, nil
+}
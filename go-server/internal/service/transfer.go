@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidTransfer = errors.New("a transfer needs either link ids or a tag")
+	ErrNothingToMove   = errors.New("no links to transfer")
+)
+
+const (
+	maxTransferLinks = 500
+	transferTTL      = 14 * 24 * time.Hour
+)
+
+// TransferRequest selects the links to hand over, either by id or every
+// link of the sender carrying Tag
+type TransferRequest struct {
+	Email   string
+	LinkIDs []string
+	Tag     string
+}
+
+// WithTransfers enables handing links over to other accounts
+func WithTransfers(repo repository.TransferRepository) URLServiceOption {
+	return func(s *URLService) {
+		s.transfers = repo
+	}
+}
+
+// StartTransfer offers links userID owns to the account with the given
+// email. Nothing moves until an account that verified that address accepts.
+func (s *URLService) StartTransfer(ctx context.Context, userID uuid.UUID, req TransferRequest) (*model.Transfer, error) {
+	if s.transfers == nil {
+		return nil, ErrNothingToMove
+	}
+
+	addr, err := mail.ParseAddress(strings.TrimSpace(req.Email))
+	if err != nil || addr.Name != "" {
+		return nil, ErrInvalidEmail
+	}
+	tag := strings.TrimSpace(req.Tag)
+	if (len(req.LinkIDs) == 0) == (tag == "") || len(req.LinkIDs) > maxTransferLinks {
+		return nil, ErrInvalidTransfer
+	}
+
+	linkIDs := req.LinkIDs
+	if tag != "" {
+		urls, err := s.repo.GetUserURLs(ctx, userID, repository.URLFilter{Tag: tag})
+		if err != nil {
+			return nil, err
+		}
+		if len(urls) > maxTransferLinks {
+			return nil, ErrInvalidTransfer
+		}
+		linkIDs = make([]string, 0, len(urls))
+		for _, u := range urls {
+			linkIDs = append(linkIDs, u.ID)
+		}
+	}
+
+	// Only links the sender owns can be given away
+	seen := make(map[string]bool, len(linkIDs))
+	owned := make([]string, 0, len(linkIDs))
+	for _, id := range linkIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if !s.isValidID(id) {
+			return nil, ErrInvalidToken
+		}
+		err := s.authorizeLink(ctx, userID, id, model.RoleOwner)
+		if tag != "" && (errors.Is(err, ErrForbidden) || errors.Is(err, repository.ErrURLNotFound)) {
+			// Tagged links of workspaces the sender does not own are left out
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		owned = append(owned, id)
+	}
+	if len(owned) == 0 {
+		return nil, ErrNothingToMove
+	}
+
+	transfer := &model.Transfer{
+		FromUserID: userID,
+		ToEmail:    strings.ToLower(addr.Address),
+		LinkIDs:    owned,
+		ExpiresAt:  time.Now().Add(transferTTL),
+	}
+	err = audited(ctx, s.audit, func(ctx context.Context) error {
+		if err := s.transfers.Create(ctx, transfer); err != nil {
+			return err
+		}
+		return s.auditTransfer(ctx, userID, model.AuditTransferStarted, transfer.ID, transfer)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Transfer started", zap.String("transferID", transfer.ID.String()),
+		zap.String("userID", userID.String()), zap.Int("links", len(owned)))
+	return transfer, nil
+}
+
+// ListTransfers returns the transfers userID sent or received
+func (s *URLService) ListTransfers(ctx context.Context, userID uuid.UUID) ([]model.Transfer, error) {
+	if s.transfers == nil {
+		return []model.Transfer{}, nil
+	}
+	return s.transfers.ListForUser(ctx, userID)
+}
+
+// AcceptTransfer moves the links of a transfer addressed to userID into
+// their personal workspace. Short codes are unchanged.
+func (s *URLService) AcceptTransfer(ctx context.Context, userID, transferID uuid.UUID) (*model.Transfer, error) {
+	if s.transfers == nil {
+		return nil, repository.ErrTransferNotFound
+	}
+
+	workspaceID, err := s.workspaceForLink(ctx, userID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var transfer *model.Transfer
+	err = audited(ctx, s.audit, func(ctx context.Context) error {
+		var err error
+		transfer, err = s.transfers.Accept(ctx, transferID, userID, workspaceID, time.Now())
+		if err != nil {
+			return err
+		}
+		from := &linkSnapshot{UserID: &transfer.FromUserID}
+		for _, id := range transfer.LinkIDs {
+			if err := s.auditLink(ctx, userID, model.AuditLinkTransferred, id, from); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Transfer accepted", zap.String("transferID", transferID.String()),
+		zap.String("from", transfer.FromUserID.String()), zap.String("to", userID.String()),
+		zap.Strings("links", transfer.LinkIDs))
+	return transfer, nil
+}
+
+func (s *URLService) DeclineTransfer(ctx context.Context, userID, transferID uuid.UUID) error {
+	if s.transfers == nil {
+		return repository.ErrTransferNotFound
+	}
+	err := audited(ctx, s.audit, func(ctx context.Context) error {
+		if err := s.transfers.Decline(ctx, transferID, userID, time.Now()); err != nil {
+			return err
+		}
+		return s.auditTransfer(ctx, userID, model.AuditTransferDeclined, transferID, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Transfer declined", zap.String("transferID", transferID.String()), zap.String("userID", userID.String()))
+	return nil
+}
+
+func (s *URLService) CancelTransfer(ctx context.Context, userID, transferID uuid.UUID) error {
+	if s.transfers == nil {
+		return repository.ErrTransferNotFound
+	}
+	err := audited(ctx, s.audit, func(ctx context.Context) error {
+		if err := s.transfers.Cancel(ctx, transferID, userID, time.Now()); err != nil {
+			return err
+		}
+		return s.auditTransfer(ctx, userID, model.AuditTransferCancelled, transferID, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Transfer cancelled", zap.String("transferID", transferID.String()), zap.String("userID", userID.String()))
+	return nil
+}
+
+// auditTransfer records what userID did with a transfer as a whole; the
+// links an accepted transfer moves are recorded on each link
+func (s *URLService) auditTransfer(ctx context.Context, userID uuid.UUID, action string, transferID uuid.UUID, after *model.Transfer) error {
+	entry := model.AuditEntry{
+		ActorID:    &userID,
+		Action:     action,
+		TargetType: "transfer",
+		TargetID:   transferID.String(),
+	}
+	if after != nil {
+		entry.After, _ = json.Marshal(after)
+	}
+	return appendAudit(ctx, s.audit, s.logger, entry)
+}
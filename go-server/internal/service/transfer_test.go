@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTransfers keeps created transfers and accepts them into the
+// workspace it is given
+type recordingTransfers struct {
+	created  []model.Transfer
+	accepted map[uuid.UUID]*uuid.UUID
+}
+
+func newRecordingTransfers() *recordingTransfers {
+	return &recordingTransfers{accepted: make(map[uuid.UUID]*uuid.UUID)}
+}
+
+func (r *recordingTransfers) Create(ctx context.Context, transfer *model.Transfer) error {
+	transfer.ID = uuid.New()
+	transfer.Status = model.TransferPending
+	r.created = append(r.created, *transfer)
+	return nil
+}
+
+func (r *recordingTransfers) ListForUser(ctx context.Context, userID uuid.UUID) ([]model.Transfer, error) {
+	return r.created, nil
+}
+
+func (r *recordingTransfers) Accept(ctx context.Context, id, userID uuid.UUID, workspaceID *uuid.UUID, now time.Time) (*model.Transfer, error) {
+	for i := range r.created {
+		t := &r.created[i]
+		if t.ID == id && t.Status == model.TransferPending {
+			r.accepted[id] = workspaceID
+			t.Status, t.ToUserID, t.RespondedAt = model.TransferAccepted, &userID, &now
+			accepted := *t
+			return &accepted, nil
+		}
+	}
+	return nil, repository.ErrTransferNotFound
+}
+
+func (r *recordingTransfers) Decline(ctx context.Context, id, userID uuid.UUID, now time.Time) error {
+	return r.close(id, model.TransferDeclined, func(t *model.Transfer) bool { return t.FromUserID != userID })
+}
+
+func (r *recordingTransfers) Cancel(ctx context.Context, id, userID uuid.UUID, now time.Time) error {
+	return r.close(id, model.TransferCancelled, func(t *model.Transfer) bool { return t.FromUserID == userID })
+}
+
+func (r *recordingTransfers) close(id uuid.UUID, status model.TransferStatus, allowed func(t *model.Transfer) bool) error {
+	for i := range r.created {
+		t := &r.created[i]
+		if t.ID == id && t.Status == model.TransferPending && allowed(t) {
+			t.Status = status
+			return nil
+		}
+	}
+	return repository.ErrTransferNotFound
+}
+
+func setupTransfers(t *testing.T) (*URLService, *MockURLRepository, *memoryWorkspaces, *recordingTransfers) {
+	t.Helper()
+	setupService(t)
+	mockRepo := new(MockURLRepository)
+	workspaces := newMemoryWorkspaces()
+	transfers := newRecordingTransfers()
+	return NewURLService(mockRepo, WithWorkspaces(workspaces), WithTransfers(transfers)), mockRepo, workspaces, transfers
+}
+
+func TestStartTransfer_ByID(t *testing.T) {
+	service, _, workspaces, transfers := setupTransfers(t)
+	ctx := context.Background()
+	workspaceID, ownerID, ids := sharedWorkspace(t, workspaces, model.RoleAdmin)
+	workspaces.links["abc123"] = workspaceID
+
+	// Admins manage links but cannot give them away
+	_, err := service.StartTransfer(ctx, ids[0], TransferRequest{Email: "bob@example.com", LinkIDs: []string{"abc123"}})
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = service.StartTransfer(ctx, ownerID, TransferRequest{Email: "bob@example.com", LinkIDs: []string{"xyz789"}})
+	assert.ErrorIs(t, err, repository.ErrURLNotFound)
+
+	transfer, err := service.StartTransfer(ctx, ownerID, TransferRequest{Email: " Bob@Example.com ", LinkIDs: []string{"abc123", "abc123"}})
+	require.NoError(t, err)
+	assert.Equal(t, "bob@example.com", transfer.ToEmail)
+	assert.Equal(t, []string{"abc123"}, transfer.LinkIDs)
+	assert.Equal(t, model.TransferPending, transfer.Status)
+	assert.Len(t, transfers.created, 1)
+}
+
+func TestStartTransfer_ByTag(t *testing.T) {
+	service, mockRepo, workspaces, transfers := setupTransfers(t)
+	ctx := context.Background()
+	workspaceID, ownerID, _ := sharedWorkspace(t, workspaces)
+	otherID, _, _ := sharedWorkspace(t, workspaces)
+	workspaces.members[otherID][ownerID] = model.RoleEditor
+	workspaces.links["abc123"] = workspaceID
+	workspaces.links["def456"] = otherID
+
+	mockRepo.On("GetUserURLs", ctx, ownerID, repository.URLFilter{Tag: "launch"}).
+		Return([]model.URL{{ID: "abc123"}, {ID: "def456"}}, nil)
+
+	transfer, err := service.StartTransfer(ctx, ownerID, TransferRequest{Email: "bob@example.com", Tag: " launch "})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"abc123"}, transfer.LinkIDs)
+
+	mockRepo.On("GetUserURLs", ctx, ownerID, repository.URLFilter{Tag: "empty"}).Return([]model.URL{}, nil)
+	_, err = service.StartTransfer(ctx, ownerID, TransferRequest{Email: "bob@example.com", Tag: "empty"})
+	assert.ErrorIs(t, err, ErrNothingToMove)
+	assert.Len(t, transfers.created, 1)
+}
+
+func TestStartTransfer_Invalid(t *testing.T) {
+	service, _, _, _ := setupTransfers(t)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	testCases := []struct {
+		name string
+		req  TransferRequest
+		err  error
+	}{
+		{"nothing selected", TransferRequest{Email: "bob@example.com"}, ErrInvalidTransfer},
+		{"ids and tag", TransferRequest{Email: "bob@example.com", LinkIDs: []string{"abc123"}, Tag: "launch"}, ErrInvalidTransfer},
+		{"invalid email", TransferRequest{Email: "bob", LinkIDs: []string{"abc123"}}, ErrInvalidEmail},
+		{"invalid id", TransferRequest{Email: "bob@example.com", LinkIDs: []string{"no"}}, ErrInvalidToken},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := service.StartTransfer(ctx, userID, tc.req)
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestAcceptTransfer_MovesToPersonalWorkspace(t *testing.T) {
+	service, _, workspaces, transfers := setupTransfers(t)
+	ctx := context.Background()
+	workspaceID, ownerID, _ := sharedWorkspace(t, workspaces)
+	workspaces.links["abc123"] = workspaceID
+
+	transfer, err := service.StartTransfer(ctx, ownerID, TransferRequest{Email: "bob@example.com", LinkIDs: []string{"abc123"}})
+	require.NoError(t, err)
+
+	recipientID := uuid.New()
+	accepted, err := service.AcceptTransfer(ctx, recipientID, transfer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.TransferAccepted, accepted.Status)
+
+	personal, err := workspaces.Personal(ctx, recipientID)
+	require.NoError(t, err)
+	assert.Equal(t, personal.ID, *transfers.accepted[transfer.ID])
+
+	_, err = service.AcceptTransfer(ctx, recipientID, transfer.ID)
+	assert.ErrorIs(t, err, repository.ErrTransferNotFound)
+}
+
+func TestTransfers_AreAudited(t *testing.T) {
+	setupService(t)
+	ctx := context.Background()
+	workspaces := newMemoryWorkspaces()
+	audit := &memoryAudit{}
+	service := NewURLService(new(MockURLRepository), WithWorkspaces(workspaces),
+		WithTransfers(newRecordingTransfers()), WithAudit(audit))
+	workspaceID, ownerID, _ := sharedWorkspace(t, workspaces)
+	workspaces.links["abc123"] = workspaceID
+	recipientID := uuid.New()
+
+	declined, err := service.StartTransfer(ctx, ownerID, TransferRequest{Email: "bob@example.com", LinkIDs: []string{"abc123"}})
+	require.NoError(t, err)
+	cancelled, err := service.StartTransfer(ctx, ownerID, TransferRequest{Email: "bob@example.com", LinkIDs: []string{"abc123"}})
+	require.NoError(t, err)
+	require.NoError(t, service.DeclineTransfer(ctx, recipientID, declined.ID))
+	require.NoError(t, service.CancelTransfer(ctx, ownerID, cancelled.ID))
+
+	// Turning down a transfer twice does not record it twice
+	assert.ErrorIs(t, service.CancelTransfer(ctx, ownerID, cancelled.ID), repository.ErrTransferNotFound)
+
+	require.Len(t, audit.entries, 4)
+	for i, want := range []struct {
+		action string
+		actor  uuid.UUID
+		target uuid.UUID
+	}{
+		{model.AuditTransferStarted, ownerID, declined.ID},
+		{model.AuditTransferStarted, ownerID, cancelled.ID},
+		{model.AuditTransferDeclined, recipientID, declined.ID},
+		{model.AuditTransferCancelled, ownerID, cancelled.ID},
+	} {
+		entry := audit.entries[i]
+		assert.Equal(t, want.action, entry.Action)
+		assert.Equal(t, want.actor, *entry.ActorID)
+		assert.Equal(t, "transfer", entry.TargetType)
+		assert.Equal(t, want.target.String(), entry.TargetID)
+	}
+	assert.Contains(t, string(audit.entries[0].After), "bob@example.com")
+}
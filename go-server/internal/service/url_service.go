@@ -8,6 +8,7 @@ import (
 	"math/big"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/fonsecaaso/TinyUrl/go-server/internal/metrics"
 	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
@@ -21,29 +22,140 @@ var (
 	ErrInvalidURL      = errors.New("invalid URL format")
 	ErrInvalidToken    = errors.New("invalid token")
 	ErrIDGenerationMax = errors.New("failed to generate unique ID after max attempts")
+	ErrInvalidDetails  = errors.New("invalid link details")
+	ErrAuthRequired    = errors.New("authentication required")
 )
 
 const (
 	maxIDGenerationAttempts = 10
 	idLength                = 6
+	maxTitleLength          = 255
+	maxDescriptionLength    = 2000
+	maxTagsPerURL           = 20
 )
 
+// ShortenOptions holds the optional details accepted when creating a link
+type ShortenOptions struct {
+	Title        string
+	Description  string
+	Tags         []string
+	ForwardQuery bool
+	ForwardPath  bool
+	UTM          model.UTMParams
+	StartsAt     *time.Time
+	ExpiresAt    *time.Time
+	MaxClicks    *int64
+	// Domain is a verified custom domain of the owner to create the link on,
+	// Code an optional code of their choosing on that domain
+	Domain string
+	Code   string
+	// WorkspaceID is the workspace to create the link in, the owner's
+	// personal one when nil
+	WorkspaceID *uuid.UUID
+}
+
+// hasLinkOptions reports whether the link is set up for its creator, who
+// should not get a link made for someone else in its place. UTM parameters
+// are part of the destination.
+func (o ShortenOptions) hasLinkOptions() bool {
+	return o.Title != "" || o.Description != "" || len(o.Tags) > 0 || o.ForwardQuery || o.ForwardPath ||
+		o.StartsAt != nil || o.ExpiresAt != nil || o.MaxClicks != nil || o.Code != ""
+}
+
+// URLUpdate holds the link details to change. Nil fields are left untouched.
+type URLUpdate struct {
+	Title        *string
+	Description  *string
+	Tags         *[]string
+	ForwardQuery *bool
+	ForwardPath  *bool
+}
+
+// MetadataQueue schedules background metadata fetches for new links
+type MetadataQueue interface {
+	Enqueue(shortCode, rawURL string)
+}
+
 type URLService struct {
-	repo   repository.URLRepository
-	logger *zap.Logger
+	repo           repository.URLRepository
+	metadata       MetadataQueue
+	utmTemplates   repository.UTMTemplateRepository
+	countries      CountryResolver
+	clicks         ClickRecorder
+	clickStats     repository.ClickRepository
+	fallbacks      repository.FallbackRepository
+	globalFallback *model.Fallback
+	domains        repository.DomainRepository
+	workspaces     repository.WorkspaceRepository
+	transfers      repository.TransferRepository
+	audit          repository.AuditRepository
+	webhooks       WebhookEmitter
+	retention      Retention
+	logger         *zap.Logger
 }
 
-func NewURLService(repo repository.URLRepository) *URLService {
-	return &URLService{
-		repo:   repo,
-		logger: zap.L().With(zap.String("component", "URLService")),
+// URLServiceOption configures optional URLService collaborators
+type URLServiceOption func(*URLService)
+
+// WithMetadataQueue enables fetching page metadata after a link is created
+func WithMetadataQueue(q MetadataQueue) URLServiceOption {
+	return func(s *URLService) {
+		s.metadata = q
 	}
 }
 
-func (s *URLService) ShortenURL(ctx context.Context, rawURL string, userId *uuid.UUID) (string, bool, error) {
+func NewURLService(repo repository.URLRepository, opts ...URLServiceOption) *URLService {
+	s := &URLService{
+		repo:      repo,
+		retention: Retention{Archived: defaultArchiveRetention, Deleted: defaultDeleteRetention},
+		logger:    zap.L().With(zap.String("component", "URLService")),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ShortenURL stores a new link, or returns the existing link of the same
+// destination on the same domain with isNew false. Links with per-link
+// options are never shared, so the options hold for their creator alone.
+func (s *URLService) ShortenURL(ctx context.Context, rawURL string, userId *uuid.UUID, opts ShortenOptions) (*model.URL, bool, error) {
 	if !s.isValidURL(rawURL) {
 		s.logger.Warn("invalid URL format", zap.String("url", rawURL))
-		return "", false, ErrInvalidURL
+		return nil, false, ErrInvalidURL
+	}
+
+	tags, err := normalizeTags(opts.Tags)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := validateDetails(&opts.Title, &opts.Description); err != nil {
+		return nil, false, err
+	}
+	if (len(tags) > 0 || opts.Domain != "" || opts.WorkspaceID != nil) && userId == nil {
+		return nil, false, ErrAuthRequired
+	}
+	if err := validateLimits(opts.ExpiresAt, opts.MaxClicks, time.Now()); err != nil {
+		return nil, false, err
+	}
+
+	// Chosen codes are only offered on custom domains, the shared namespace
+	// keeps generated codes
+	var domain *model.Domain
+	if opts.Code != "" && (opts.Domain == "" || !isValidCode(opts.Code)) {
+		return nil, false, ErrInvalidCode
+	}
+	if opts.Domain != "" {
+		if domain, err = s.ownedDomain(ctx, *userId, opts.Domain); err != nil {
+			return nil, false, err
+		}
+	}
+
+	var workspaceID *uuid.UUID
+	if userId != nil {
+		if workspaceID, err = s.workspaceForLink(ctx, *userId, opts.WorkspaceID); err != nil {
+			return nil, false, err
+		}
 	}
 
 	normalizedURL := rawURL
@@ -51,61 +163,223 @@ func (s *URLService) ShortenURL(ctx context.Context, rawURL string, userId *uuid
 		normalizedURL = "https://" + rawURL
 	}
 
+	utm, err := s.resolveUTM(ctx, workspaceID, opts.UTM)
+	if err != nil {
+		return nil, false, err
+	}
+	if !utm.IsEmpty() {
+		if normalizedURL, err = applyUTM(normalizedURL, utm); err != nil {
+			return nil, false, err
+		}
+	}
+
 	shortCode, err := s.generateUniqueID(ctx)
 	if err != nil {
 		s.logger.Error("Failed to generate unique ID", zap.Error(err))
-		return "", false, err
+		return nil, false, err
 	}
 
 	urlModel := &model.URL{
-		ID:          shortCode,
-		OriginalURL: normalizedURL,
-		UserID:      userId,
+		ID:           shortCode,
+		Code:         shortCode,
+		OriginalURL:  normalizedURL,
+		UserID:       userId,
+		WorkspaceID:  workspaceID,
+		Title:        opts.Title,
+		Description:  opts.Description,
+		ForwardQuery: opts.ForwardQuery,
+		ForwardPath:  opts.ForwardPath,
+		StartsAt:     opts.StartsAt,
+		ExpiresAt:    opts.ExpiresAt,
+		MaxClicks:    opts.MaxClicks,
+		Tags:         tags,
+		Reusable:     !opts.hasLinkOptions(),
+	}
+	if !utm.IsEmpty() {
+		urlModel.UTM = &utm
+	}
+	if userId == nil {
+		if err := prepareClaim(urlModel); err != nil {
+			return nil, false, err
+		}
+	}
+	if domain != nil {
+		urlModel.DomainID = &domain.ID
+		urlModel.Domain = domain.Hostname
+		if opts.Code != "" {
+			urlModel.Code = opts.Code
+		}
 	}
 
-	resultCode, isNew, err := s.repo.CreateOrGet(ctx, urlModel)
+	var resultCode string
+	var isNew bool
+	err = audited(ctx, s.audit, func(ctx context.Context) error {
+		var err error
+		resultCode, isNew, err = s.repo.CreateOrGet(ctx, urlModel)
+		if err != nil || !isNew {
+			return err
+		}
+		return s.recordLinkAudit(ctx, userId, model.AuditLinkCreated, resultCode, nil, newLinkSnapshot(urlModel))
+	})
 	if err != nil {
-		s.logger.Error("Failed to store URL", zap.Error(err), zap.String("id", shortCode))
+		if !errors.Is(err, repository.ErrCodeTaken) {
+			s.logger.Error("Failed to store URL", zap.Error(err), zap.String("id", shortCode))
+		}
 		metrics.RecordURLCreation(ctx, "error")
-		return "", false, err
+		return nil, false, err
 	}
 
 	if isNew {
 		s.logger.Info("URL shortened successfully", zap.String("id", resultCode), zap.String("url", normalizedURL))
 		metrics.RecordURLCreation(ctx, "success")
-	} else {
-		s.logger.Info("URL already exists, returning existing short code", zap.String("id", resultCode), zap.String("url", normalizedURL))
+
+		if s.metadata != nil {
+			s.metadata.Enqueue(resultCode, normalizedURL)
+		}
+		urlModel.ID = resultCode
+		return urlModel, true, nil
+	}
+
+	s.logger.Info("URL already exists, returning existing short code", zap.String("id", resultCode), zap.String("url", normalizedURL))
+	if domain != nil {
+		// The existing link may use another code on the domain
+		existing, err := s.repo.FindByID(ctx, resultCode)
+		if err != nil {
+			return nil, false, err
+		}
+		return existing, false, nil
 	}
+	return &model.URL{ID: resultCode, Code: resultCode, OriginalURL: normalizedURL}, false, nil
+}
 
-	return resultCode, isNew, nil
+// GetOriginalURL resolves a short code to the destination for this visit
+func (s *URLService) GetOriginalURL(ctx context.Context, shortCode string, visit Visit) (string, error) {
+	resolution, err := s.Resolve(ctx, shortCode, visit)
+	if err != nil {
+		return "", err
+	}
+	return resolution.URL, nil
 }
 
-func (s *URLService) GetOriginalURL(ctx context.Context, shortCode string) (string, error) {
-	if !s.isValidID(shortCode) {
+// Resolve picks the destination of a short code for one visit, applying
+// the schedule, routing rules, A/B variants and passthrough in that order.
+// The visit's Host selects the namespace the code is looked up in.
+func (s *URLService) Resolve(ctx context.Context, shortCode string, visit Visit) (*Resolution, error) {
+	domain, err := s.domainForHost(ctx, visit.Host)
+	if err != nil {
+		s.logger.Error("Failed to look up domain", zap.Error(err), zap.String("host", visit.Host))
+		metrics.RecordURLAccess(ctx, "error")
+		return nil, err
+	}
+
+	if (domain == nil && !s.isValidID(shortCode)) || (domain != nil && !isValidCode(shortCode)) {
 		s.logger.Warn("Invalid short code format", zap.String("shortCode", shortCode))
 		metrics.RecordURLAccess(ctx, "error")
-		return "", ErrInvalidToken
+		return nil, ErrInvalidToken
 	}
 
-	urlModel, err := s.repo.FindByID(ctx, shortCode)
+	var urlModel *model.URL
+	if domain != nil {
+		urlModel, err = s.repo.FindByCode(ctx, domain.ID, shortCode)
+	} else if urlModel, err = s.repo.FindByID(ctx, shortCode); err == nil && urlModel.DomainID != nil {
+		// Links on custom domains are not reachable through the shared domain
+		urlModel, err = nil, repository.ErrURLNotFound
+	}
 	if err != nil {
 		if errors.Is(err, repository.ErrURLNotFound) {
 			s.logger.Info("URL not found", zap.String("shortCode", shortCode))
 			metrics.RecordURLAccess(ctx, "not_found")
-			return "", repository.ErrURLNotFound
+			return nil, repository.ErrURLNotFound
 		}
 		s.logger.Error("Failed to retrieve URL", zap.Error(err), zap.String("shortCode", shortCode))
 		metrics.RecordURLAccess(ctx, "error")
-		return "", err
+		return nil, err
+	}
+
+	now := time.Now()
+	if state := urlModel.State(now); state != model.LinkActive {
+		s.logger.Info("URL unavailable", zap.String("shortCode", shortCode), zap.String("state", string(state)))
+		metrics.RecordURLAccess(ctx, "unavailable")
+		return nil, s.unavailable(ctx, urlModel, state)
+	}
+	if !urlModel.IsActive(now) {
+		s.logger.Info("URL not active yet", zap.String("shortCode", shortCode))
+		metrics.RecordURLAccess(ctx, "not_found")
+		return nil, ErrLinkNotStarted
+	}
+
+	resolution := &Resolution{}
+	routed := *urlModel
+	routed.OriginalURL = urlModel.ActiveDestination(now)
+	if dest, ok := s.matchRule(urlModel, visit); ok {
+		routed.OriginalURL = dest
+	} else if len(urlModel.Variants) > 0 {
+		variant := pickVariant(urlModel, visit.StickyVariant)
+		routed.OriginalURL = urlModel.Variants[variant].URL
+		resolution.Variant = &variant
+		if urlModel.StickyVariants {
+			resolution.StickyVariant = variantToken(urlModel.Variants, variant)
+		}
+	}
+
+	resolution.URL, err = applyPassthrough(&routed, visit)
+	if err != nil {
+		s.logger.Info("Passthrough rejected", zap.Error(err), zap.String("shortCode", shortCode))
+		metrics.RecordURLAccess(ctx, "not_found")
+		return nil, err
+	}
+
+	if urlModel.MaxClicks != nil {
+		allowed, err := s.repo.ConsumeClick(ctx, urlModel.ID)
+		if err != nil {
+			s.logger.Error("Failed to count click", zap.Error(err), zap.String("shortCode", shortCode))
+			metrics.RecordURLAccess(ctx, "error")
+			return nil, err
+		}
+		if !allowed {
+			s.logger.Info("URL click limit reached", zap.String("shortCode", shortCode))
+			metrics.RecordURLAccess(ctx, "unavailable")
+			return nil, s.unavailable(ctx, urlModel, model.LinkExhausted)
+		}
+	}
+
+	click := model.Click{
+		URLID:     urlModel.ID,
+		Variant:   resolution.Variant,
+		CreatedAt: time.Now(),
+		Owner:     model.LinkOwner{WorkspaceID: urlModel.WorkspaceID, UserID: urlModel.UserID},
+	}
+	if resolution.Variant != nil {
+		click.VariantURL = urlModel.Variants[*resolution.Variant].URL
+	}
+	if s.clicks != nil {
+		s.clicks.RecordClick(click)
 	}
 
 	s.logger.Info("URL retrieved successfully", zap.String("shortCode", shortCode))
 	metrics.RecordURLAccess(ctx, "success")
-	return urlModel.OriginalURL, nil
+	return resolution, nil
 }
 
-func (s *URLService) GetUserURLs(ctx context.Context, userID uuid.UUID) ([]model.URL, error) {
-	urls, err := s.repo.GetUserURLs(ctx, userID)
+func (s *URLService) GetUserURLs(ctx context.Context, userID uuid.UUID, filter repository.URLFilter) ([]model.URL, error) {
+	filter.Tag = strings.TrimSpace(filter.Tag)
+	switch filter.State {
+	case "", model.LinkArchived, model.LinkDeleted:
+	default:
+		return nil, ErrInvalidStateFilter
+	}
+
+	if filter.WorkspaceID != nil {
+		if s.workspaces == nil {
+			return nil, repository.ErrWorkspaceNotFound
+		}
+		// Any member sees every link of the workspace
+		if _, err := s.workspaces.Role(ctx, *filter.WorkspaceID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	urls, err := s.repo.GetUserURLs(ctx, userID, filter)
 	if err != nil {
 		s.logger.Error("Failed to retrieve user URLs", zap.Error(err), zap.String("userID", userID.String()))
 		return nil, err
@@ -115,6 +389,94 @@ func (s *URLService) GetUserURLs(ctx context.Context, userID uuid.UUID) ([]model
 	return urls, nil
 }
 
+// UpdateURL changes the details, tags and redirect options of a link userID
+// can edit
+func (s *URLService) UpdateURL(ctx context.Context, userID uuid.UUID, shortCode string, update URLUpdate) error {
+	if !s.isValidID(shortCode) {
+		return ErrInvalidToken
+	}
+	if err := validateDetails(update.Title, update.Description); err != nil {
+		return err
+	}
+
+	var tags []string
+	if update.Tags != nil {
+		var err error
+		if tags, err = normalizeTags(*update.Tags); err != nil {
+			return err
+		}
+	}
+
+	changes := repository.URLChanges{
+		Title:        update.Title,
+		Description:  update.Description,
+		ForwardQuery: update.ForwardQuery,
+		ForwardPath:  update.ForwardPath,
+	}
+	if err := s.authorizeLink(ctx, userID, shortCode, model.RoleEditor); err != nil {
+		return err
+	}
+	err := audited(ctx, s.audit, func(ctx context.Context) error {
+		before := s.snapshotLink(ctx, shortCode)
+		if err := s.repo.UpdateDetails(ctx, shortCode, changes); err != nil {
+			return err
+		}
+		if update.Tags != nil {
+			if err := s.repo.SetTags(ctx, userID, shortCode, tags); err != nil {
+				return err
+			}
+		}
+		return s.auditLink(ctx, userID, model.AuditLinkUpdated, shortCode, before)
+	})
+	if err != nil {
+		if !errors.Is(err, repository.ErrURLNotFound) {
+			s.logger.Error("Failed to update URL", zap.Error(err), zap.String("shortCode", shortCode))
+		}
+		return err
+	}
+
+	s.logger.Info("URL updated successfully", zap.String("shortCode", shortCode), zap.String("userID", userID.String()))
+	return nil
+}
+
+func validateDetails(title, description *string) error {
+	if title != nil {
+		*title = strings.TrimSpace(*title)
+		if len(*title) > maxTitleLength {
+			return ErrInvalidDetails
+		}
+	}
+	if description != nil {
+		*description = strings.TrimSpace(*description)
+		if len(*description) > maxDescriptionLength {
+			return ErrInvalidDetails
+		}
+	}
+	return nil
+}
+
+// normalizeTags trims, validates and de-duplicates tag names
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) > maxTagsPerURL {
+		return nil, ErrInvalidTag
+	}
+
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		name, err := normalizeTagName(tag)
+		if err != nil {
+			return nil, err
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		normalized = append(normalized, name)
+	}
+	return normalized, nil
+}
+
 func (s *URLService) generateUniqueID(ctx context.Context) (string, error) {
 	for attempt := 0; attempt < maxIDGenerationAttempts; attempt++ {
 		id := s.createID()
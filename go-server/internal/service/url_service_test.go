@@ -3,13 +3,19 @@ package service
 import (
 	"context"
 	"errors"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/geoip"
 	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
 	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/token"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
@@ -36,6 +42,14 @@ func (m *MockURLRepository) FindByID(ctx context.Context, id string) (*model.URL
 	return args.Get(0).(*model.URL), args.Error(1)
 }
 
+func (m *MockURLRepository) FindByCode(ctx context.Context, domainID uuid.UUID, code string) (*model.URL, error) {
+	args := m.Called(ctx, domainID, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.URL), args.Error(1)
+}
+
 func (m *MockURLRepository) FindByURL(ctx context.Context, url string) (string, error) {
 	args := m.Called(ctx, url)
 	return args.String(0), args.Error(1)
@@ -46,8 +60,74 @@ func (m *MockURLRepository) IDExists(ctx context.Context, id string) (bool, erro
 	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockURLRepository) GetUserURLs(ctx context.Context, userId uuid.UUID) ([]model.URL, error) {
-	args := m.Called(ctx, userId)
+func (m *MockURLRepository) GetUserURLs(ctx context.Context, userId uuid.UUID, filter repository.URLFilter) ([]model.URL, error) {
+	args := m.Called(ctx, userId, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.URL), args.Error(1)
+}
+
+func (m *MockURLRepository) UpdateDetails(ctx context.Context, id string, changes repository.URLChanges) error {
+	args := m.Called(ctx, id, changes)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) SetTags(ctx context.Context, userId uuid.UUID, id string, tags []string) error {
+	args := m.Called(ctx, userId, id, tags)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) GetRules(ctx context.Context, id string) ([]model.RoutingRule, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.RoutingRule), args.Error(1)
+}
+
+func (m *MockURLRepository) SetRules(ctx context.Context, id string, rules []model.RoutingRule) error {
+	args := m.Called(ctx, id, rules)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) SetVariants(ctx context.Context, id string, variants []model.Variant, sticky bool) error {
+	args := m.Called(ctx, id, variants, sticky)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) SetSchedule(ctx context.Context, id string, startsAt *time.Time, changes []model.ScheduledDestination) error {
+	args := m.Called(ctx, id, startsAt, changes)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) SetAvailability(ctx context.Context, id string, availability repository.Availability) error {
+	args := m.Called(ctx, id, availability)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) SetFallback(ctx context.Context, id string, fallback *model.Fallback) error {
+	args := m.Called(ctx, id, fallback)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) ConsumeClick(ctx context.Context, id string) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockURLRepository) Claim(ctx context.Context, id, claimHash string, userId uuid.UUID, workspaceID *uuid.UUID) error {
+	args := m.Called(ctx, id, claimHash, userId, workspaceID)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) SetLifecycle(ctx context.Context, id string, archivedAt, deletedAt *time.Time) error {
+	args := m.Called(ctx, id, archivedAt, deletedAt)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) Purge(ctx context.Context, archivedBefore, deletedBefore time.Time, limit int) ([]model.URL, error) {
+	args := m.Called(ctx, archivedBefore, deletedBefore, limit)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -88,10 +168,10 @@ func TestShortenURL_Success_NewURL(t *testing.T) {
 	mockRepo.On("CreateOrGet", ctx, mock.AnythingOfType("*model.URL")).
 		Return(expectedShortCode, true, nil)
 
-	shortCode, isNew, err := service.ShortenURL(ctx, testURL, nil)
+	link, isNew, err := service.ShortenURL(ctx, testURL, nil, ShortenOptions{})
 
 	assert.NoError(t, err)
-	assert.Equal(t, expectedShortCode, shortCode)
+	assert.Equal(t, expectedShortCode, link.ID)
 	assert.True(t, isNew)
 	mockRepo.AssertExpectations(t)
 }
@@ -110,10 +190,10 @@ func TestShortenURL_Success_ExistingURL(t *testing.T) {
 	mockRepo.On("CreateOrGet", ctx, mock.AnythingOfType("*model.URL")).
 		Return(existingShortCode, false, nil)
 
-	shortCode, isNew, err := service.ShortenURL(ctx, testURL, nil)
+	link, isNew, err := service.ShortenURL(ctx, testURL, nil, ShortenOptions{})
 
 	assert.NoError(t, err)
-	assert.Equal(t, existingShortCode, shortCode)
+	assert.Equal(t, existingShortCode, link.ID)
 	assert.False(t, isNew)
 	mockRepo.AssertExpectations(t)
 }
@@ -133,7 +213,7 @@ func TestShortenURL_InvalidURL(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			_, _, err := service.ShortenURL(ctx, tc.url, nil)
+			_, _, err := service.ShortenURL(ctx, tc.url, nil, ShortenOptions{})
 			assert.ErrorIs(t, err, ErrInvalidURL)
 		})
 	}
@@ -150,10 +230,10 @@ func TestShortenURL_URLNormalization(t *testing.T) {
 	mockRepo.On("CreateOrGet", ctx, mock.AnythingOfType("*model.URL")).
 		Return(expectedShortCode, true, nil)
 
-	shortCode, isNew, err := service.ShortenURL(ctx, testURL, nil)
+	link, isNew, err := service.ShortenURL(ctx, testURL, nil, ShortenOptions{})
 
 	assert.NoError(t, err)
-	assert.Equal(t, expectedShortCode, shortCode)
+	assert.Equal(t, expectedShortCode, link.ID)
 	assert.True(t, isNew)
 	mockRepo.AssertExpectations(t)
 }
@@ -168,7 +248,7 @@ func TestShortenURL_IDGenerationFailure(t *testing.T) {
 	mockRepo.On("IDExists", ctx, mock.AnythingOfType("string")).
 		Return(true, nil).Times(maxIDGenerationAttempts)
 
-	_, _, err := service.ShortenURL(ctx, testURL, nil)
+	_, _, err := service.ShortenURL(ctx, testURL, nil, ShortenOptions{})
 
 	assert.ErrorIs(t, err, ErrIDGenerationMax)
 	mockRepo.AssertExpectations(t)
@@ -185,13 +265,265 @@ func TestShortenURL_RepositoryError(t *testing.T) {
 	mockRepo.On("CreateOrGet", ctx, mock.AnythingOfType("*model.URL")).
 		Return("", false, dbError)
 
-	_, _, err := service.ShortenURL(ctx, testURL, nil)
+	_, _, err := service.ShortenURL(ctx, testURL, nil, ShortenOptions{})
 
 	assert.Error(t, err)
 	assert.Equal(t, dbError, err)
 	mockRepo.AssertExpectations(t)
 }
 
+func TestShortenURL_WithTags(t *testing.T) {
+	service, mockRepo := setupService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	mockRepo.On("IDExists", ctx, mock.AnythingOfType("string")).Return(false, nil)
+	// Tags are stored with the link, which is not handed to anyone else
+	mockRepo.On("CreateOrGet", ctx, mock.MatchedBy(func(u *model.URL) bool {
+		return u.Title == "Docs" && u.Description == "Team handbook" &&
+			assert.ObjectsAreEqual([]string{"work", "docs"}, u.Tags) && !u.Reusable
+	})).Return("abc123", true, nil)
+
+	_, isNew, err := service.ShortenURL(ctx, "https://example.com", &userID, ShortenOptions{
+		Title:       "  Docs ",
+		Description: "Team handbook",
+		Tags:        []string{"work", " docs", "work"},
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, isNew)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestShortenURL_OnlyPlainLinksAreReusable(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	maxClicks := int64(1)
+	tests := []struct {
+		name     string
+		opts     ShortenOptions
+		reusable bool
+	}{
+		{"no options", ShortenOptions{}, true},
+		{"UTM parameters", ShortenOptions{UTM: model.UTMParams{Source: "newsletter"}}, true},
+		{"title", ShortenOptions{Title: "Launch"}, false},
+		{"forwarding", ShortenOptions{ForwardQuery: true}, false},
+		{"expiry", ShortenOptions{ExpiresAt: &expiresAt}, false},
+		{"click limit", ShortenOptions{MaxClicks: &maxClicks}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, mockRepo := setupService(t)
+			ctx := context.Background()
+
+			mockRepo.On("IDExists", ctx, mock.AnythingOfType("string")).Return(false, nil)
+			mockRepo.On("CreateOrGet", ctx, mock.MatchedBy(func(u *model.URL) bool {
+				return u.Reusable == tt.reusable
+			})).Return("abc123", true, nil)
+
+			_, _, err := service.ShortenURL(ctx, "https://example.com", nil, tt.opts)
+
+			assert.NoError(t, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+type recordingQueue struct {
+	jobs map[string]string
+}
+
+func (q *recordingQueue) Enqueue(shortCode, rawURL string) {
+	q.jobs[shortCode] = rawURL
+}
+
+func TestShortenURL_EnqueuesMetadataFetch(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	zap.ReplaceGlobals(logger)
+	ctx := context.Background()
+
+	mockRepo := new(MockURLRepository)
+	queue := &recordingQueue{jobs: make(map[string]string)}
+	service := NewURLService(mockRepo, WithMetadataQueue(queue))
+
+	mockRepo.On("IDExists", ctx, mock.AnythingOfType("string")).Return(false, nil)
+	mockRepo.On("CreateOrGet", ctx, mock.AnythingOfType("*model.URL")).Return("abc123", true, nil).Once()
+	mockRepo.On("CreateOrGet", ctx, mock.AnythingOfType("*model.URL")).Return("abc123", false, nil).Once()
+
+	_, _, err := service.ShortenURL(ctx, "example.com", nil, ShortenOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"abc123": "https://example.com"}, queue.jobs)
+
+	// Existing links are not fetched again
+	delete(queue.jobs, "abc123")
+	_, _, err = service.ShortenURL(ctx, "example.com", nil, ShortenOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, queue.jobs)
+}
+
+type memoryUTMTemplates struct {
+	templates map[uuid.UUID]model.UTMParams
+}
+
+func (m *memoryUTMTemplates) Get(ctx context.Context, workspaceID uuid.UUID) (*model.UTMTemplate, error) {
+	params, ok := m.templates[workspaceID]
+	if !ok {
+		return nil, repository.ErrUTMTemplateNotFound
+	}
+	return &model.UTMTemplate{WorkspaceID: workspaceID, UTMParams: params}, nil
+}
+
+func (m *memoryUTMTemplates) Upsert(ctx context.Context, workspaceID uuid.UUID, params model.UTMParams) (*model.UTMTemplate, error) {
+	m.templates[workspaceID] = params
+	return &model.UTMTemplate{WorkspaceID: workspaceID, UTMParams: params}, nil
+}
+
+func (m *memoryUTMTemplates) Delete(ctx context.Context, workspaceID uuid.UUID) error {
+	if _, ok := m.templates[workspaceID]; !ok {
+		return repository.ErrUTMTemplateNotFound
+	}
+	delete(m.templates, workspaceID)
+	return nil
+}
+
+func TestShortenURL_AppliesUTM(t *testing.T) {
+	service, mockRepo := setupService(t)
+	ctx := context.Background()
+
+	mockRepo.On("IDExists", ctx, mock.AnythingOfType("string")).Return(false, nil)
+	mockRepo.On("CreateOrGet", ctx, mock.MatchedBy(func(u *model.URL) bool {
+		return u.OriginalURL == "https://example.com/page?lang=en&utm_campaign=launch&utm_source=newsletter" &&
+			u.UTM != nil && u.UTM.Source == "newsletter"
+	})).Return("abc123", true, nil)
+
+	_, _, err := service.ShortenURL(ctx, "example.com/page?lang=en&utm_source=old", nil, ShortenOptions{
+		UTM: model.UTMParams{Source: " newsletter ", Campaign: "launch"},
+	})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestShortenURL_UTMTemplateDefaults(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	zap.ReplaceGlobals(logger)
+	ctx := context.Background()
+	adminID, editorID := uuid.New(), uuid.New()
+
+	mockRepo := new(MockURLRepository)
+	workspaces := newMemoryWorkspaces()
+	team := &model.Workspace{Name: "Marketing"}
+	require.NoError(t, workspaces.Create(ctx, team, adminID))
+	workspaces.members[team.ID][editorID] = model.RoleEditor
+	templates := &memoryUTMTemplates{templates: map[uuid.UUID]model.UTMParams{}}
+	service := NewURLService(mockRepo, WithUTMTemplates(templates), WithWorkspaces(workspaces))
+
+	// Only workspace admins set the defaults every member's links get
+	_, err := service.SetUTMTemplate(ctx, editorID, &team.ID, model.UTMParams{Source: "twitter"})
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = service.SetUTMTemplate(ctx, adminID, &team.ID, model.UTMParams{Source: "twitter", Medium: "social"})
+	require.NoError(t, err)
+	tpl, err := service.GetUTMTemplate(ctx, editorID, &team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, team.ID, tpl.WorkspaceID)
+
+	mockRepo.On("IDExists", ctx, mock.AnythingOfType("string")).Return(false, nil)
+	mockRepo.On("CreateOrGet", ctx, mock.MatchedBy(func(u *model.URL) bool {
+		return u.UTM != nil && *u.UTM == model.UTMParams{Source: "linkedin", Medium: "social", Campaign: "q3"}
+	})).Return("abc123", true, nil).Once()
+	mockRepo.On("CreateOrGet", ctx, mock.MatchedBy(func(u *model.URL) bool {
+		return u.UTM == nil
+	})).Return("def456", true, nil).Once()
+
+	// Explicit values win over the template
+	_, _, err = service.ShortenURL(ctx, "https://example.com", &editorID, ShortenOptions{
+		WorkspaceID: &team.ID,
+		UTM:         model.UTMParams{Source: "linkedin", Campaign: "q3"},
+	})
+	assert.NoError(t, err)
+
+	// The editor's personal workspace has no template
+	_, _, err = service.ShortenURL(ctx, "https://example.com", &editorID, ShortenOptions{})
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestShortenURL_InvalidUTM(t *testing.T) {
+	tests := []struct {
+		name string
+		utm  model.UTMParams
+	}{
+		{"missing source", model.UTMParams{Campaign: "launch"}},
+		{"control character", model.UTMParams{Source: "news\nletter"}},
+		{"too long", model.UTMParams{Source: strings.Repeat("a", maxUTMValueLength+1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, mockRepo := setupService(t)
+
+			_, _, err := service.ShortenURL(context.Background(), "https://example.com", nil, ShortenOptions{UTM: tt.utm})
+
+			assert.ErrorIs(t, err, ErrInvalidUTM)
+			mockRepo.AssertNotCalled(t, "CreateOrGet", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestShortenURL_TagsRequireUser(t *testing.T) {
+	service, _ := setupService(t)
+
+	_, _, err := service.ShortenURL(context.Background(), "https://example.com", nil, ShortenOptions{
+		Tags: []string{"work"},
+	})
+
+	assert.ErrorIs(t, err, ErrAuthRequired)
+}
+
+func TestShortenURL_InvalidTag(t *testing.T) {
+	service, _ := setupService(t)
+	userID := uuid.New()
+
+	_, _, err := service.ShortenURL(context.Background(), "https://example.com", &userID, ShortenOptions{
+		Tags: []string{"   "},
+	})
+
+	assert.ErrorIs(t, err, ErrInvalidTag)
+}
+
+func TestUpdateURL_Success(t *testing.T) {
+	service, mockRepo := setupService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	title := "New title"
+	tags := []string{"launch"}
+
+	mockRepo.On("FindByID", ctx, "abc123").Return(&model.URL{ID: "abc123", UserID: &userID}, nil)
+	mockRepo.On("UpdateDetails", ctx, "abc123", repository.URLChanges{Title: &title}).Return(nil)
+	mockRepo.On("SetTags", ctx, userID, "abc123", []string{"launch"}).Return(nil)
+
+	err := service.UpdateURL(ctx, userID, "abc123", URLUpdate{Title: &title, Tags: &tags})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateURL_NotOwned(t *testing.T) {
+	service, mockRepo := setupService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	tags := []string{"launch"}
+
+	otherID := uuid.New()
+	mockRepo.On("FindByID", ctx, "abc123").Return(&model.URL{ID: "abc123", UserID: &otherID}, nil)
+
+	err := service.UpdateURL(ctx, userID, "abc123", URLUpdate{Tags: &tags})
+
+	assert.ErrorIs(t, err, repository.ErrURLNotFound)
+	mockRepo.AssertNotCalled(t, "UpdateDetails", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "SetTags", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestGetOriginalURL_Success(t *testing.T) {
 	service, mockRepo := setupService(t)
 	ctx := context.Background()
@@ -205,13 +537,682 @@ func TestGetOriginalURL_Success(t *testing.T) {
 	}
 	mockRepo.On("FindByID", ctx, shortCode).Return(urlModel, nil)
 
-	url, err := service.GetOriginalURL(ctx, shortCode)
+	url, err := service.GetOriginalURL(ctx, shortCode, Visit{})
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedURL, url)
 	mockRepo.AssertExpectations(t)
 }
 
+func TestGetOriginalURL_Passthrough(t *testing.T) {
+	testCases := []struct {
+		name     string
+		link     model.URL
+		visit    Visit
+		expected string
+		err      error
+	}{
+		{
+			name:     "query ignored when disabled",
+			link:     model.URL{OriginalURL: "https://example.com/landing"},
+			visit:    Visit{Query: url.Values{"ref": {"twitter"}}},
+			expected: "https://example.com/landing",
+		},
+		{
+			name:     "query merged into destination",
+			link:     model.URL{OriginalURL: "https://example.com/landing?lang=en&ref=site", ForwardQuery: true},
+			visit:    Visit{Query: url.Values{"ref": {"twitter"}}},
+			expected: "https://example.com/landing?lang=en&ref=twitter",
+		},
+		{
+			name:     "extra path appended",
+			link:     model.URL{OriginalURL: "https://docs.example.com/v2/", ForwardPath: true},
+			visit:    Visit{ExtraPath: "/docs/intro"},
+			expected: "https://docs.example.com/v2/docs/intro",
+		},
+		{
+			name:     "extra path cannot climb above destination",
+			link:     model.URL{OriginalURL: "https://docs.example.com/v2", ForwardPath: true},
+			visit:    Visit{ExtraPath: "/../../admin"},
+			expected: "https://docs.example.com/v2/admin",
+		},
+		{
+			name:     "path and query together",
+			link:     model.URL{OriginalURL: "https://example.com/app?x=1", ForwardQuery: true, ForwardPath: true},
+			visit:    Visit{ExtraPath: "/settings/", Query: url.Values{"tab": {"billing"}}},
+			expected: "https://example.com/app/settings/?tab=billing&x=1",
+		},
+		{
+			name:  "extra path rejected when disabled",
+			link:  model.URL{OriginalURL: "https://example.com"},
+			visit: Visit{ExtraPath: "/docs"},
+			err:   repository.ErrURLNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockRepo := setupService(t)
+			ctx := context.Background()
+			link := tc.link
+			link.ID = "abc123"
+			mockRepo.On("FindByID", ctx, "abc123").Return(&link, nil)
+
+			dest, err := service.GetOriginalURL(ctx, "abc123", tc.visit)
+
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, dest)
+		})
+	}
+}
+
+func TestGetOriginalURL_RoutingRules(t *testing.T) {
+	const (
+		iPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"
+		android = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Mobile Safari/537.36"
+		desktop = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36"
+	)
+	link := model.URL{
+		ID:           "abc123",
+		OriginalURL:  "https://example.com/app",
+		ForwardQuery: true,
+		Rules: []model.RoutingRule{
+			{OS: "ios", URL: "https://apps.apple.com/app/id123"},
+			{OS: "android", Device: "mobile", URL: "https://play.google.com/store/apps/details?id=com.example"},
+		},
+	}
+
+	testCases := []struct {
+		name      string
+		userAgent string
+		query     url.Values
+		expected  string
+	}{
+		{"iOS goes to the App Store", iPhone, nil, "https://apps.apple.com/app/id123"},
+		{"Android phone goes to Play", android, nil, "https://play.google.com/store/apps/details?id=com.example"},
+		{"desktop falls back to the link", desktop, nil, "https://example.com/app"},
+		{"missing User-Agent falls back", "", nil, "https://example.com/app"},
+		{"passthrough applies to the routed destination", iPhone, url.Values{"ref": {"ad"}}, "https://apps.apple.com/app/id123?ref=ad"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockRepo := setupService(t)
+			ctx := context.Background()
+			cached := link
+			mockRepo.On("FindByID", ctx, "abc123").Return(&cached, nil)
+
+			dest, err := service.GetOriginalURL(ctx, "abc123", Visit{UserAgent: tc.userAgent, Query: tc.query})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, dest)
+		})
+	}
+}
+
+func TestGetOriginalURL_LocaleRouting(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	zap.ReplaceGlobals(logger)
+
+	resolver, err := geoip.Open("../geoip/testdata/country-test.mmdb")
+	require.NoError(t, err)
+	defer func() { _ = resolver.Close() }()
+
+	link := model.URL{
+		ID:          "abc123",
+		OriginalURL: "https://example.com/promo",
+		Rules: []model.RoutingRule{
+			{Language: "pt-br", URL: "https://example.com.br/promo"},
+			{Country: "BR", URL: "https://example.com.br/promo?src=geo"},
+			{Language: "pt", URL: "https://example.pt/promo"},
+			{Country: "GB", Device: "mobile", URL: "https://example.co.uk/m/promo"},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		visit    Visit
+		expected string
+	}{
+		{"Brazilian Portuguese", Visit{AcceptLanguage: "pt-BR,pt;q=0.9,en;q=0.8"}, "https://example.com.br/promo"},
+		{"highest weight wins", Visit{AcceptLanguage: "en;q=0.5, pt-BR;q=0.9"}, "https://example.com.br/promo"},
+		{"language prefix", Visit{AcceptLanguage: "pt-PT"}, "https://example.pt/promo"},
+		{"country from IPv4", Visit{AcceptLanguage: "en-US", ClientIP: "200.160.2.3"}, "https://example.com.br/promo?src=geo"},
+		{"country from IPv6", Visit{ClientIP: "2804:14c:65::1"}, "https://example.com.br/promo?src=geo"},
+		{"country and device", Visit{ClientIP: "81.2.69.160", UserAgent: "Mozilla/5.0 (Linux; Android 14) Mobile Safari/537.36"}, "https://example.co.uk/m/promo"},
+		{"country without device match", Visit{ClientIP: "81.2.69.160"}, "https://example.com/promo"},
+		{"unknown IP falls back", Visit{AcceptLanguage: "en", ClientIP: "8.8.8.8"}, "https://example.com/promo"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockRepo := new(MockURLRepository)
+			service := NewURLService(mockRepo, WithCountryResolver(resolver))
+			cached := link
+			mockRepo.On("FindByID", ctx, "abc123").Return(&cached, nil)
+
+			dest, err := service.GetOriginalURL(ctx, "abc123", tc.visit)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, dest)
+		})
+	}
+}
+
+func TestGetOriginalURL_CountryRulesWithoutResolver(t *testing.T) {
+	service, mockRepo := setupService(t)
+	ctx := context.Background()
+	mockRepo.On("FindByID", ctx, "abc123").Return(&model.URL{
+		ID:          "abc123",
+		OriginalURL: "https://example.com/promo",
+		Rules:       []model.RoutingRule{{Country: "BR", URL: "https://example.com.br/promo"}},
+	}, nil)
+
+	dest, err := service.GetOriginalURL(ctx, "abc123", Visit{ClientIP: "200.160.2.3"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/promo", dest)
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	assert.Equal(t, "pt-br", preferredLanguage("pt-BR,pt;q=0.9"))
+	assert.Equal(t, "de", preferredLanguage("fr;q=0.4, de;q=0.7, en;q=0.7"))
+	assert.Equal(t, "en-us", preferredLanguage("*, en_US;q=0.8"))
+	assert.Equal(t, "", preferredLanguage(""))
+	assert.Equal(t, "", preferredLanguage("en;q=0"))
+}
+
+func TestSetRoutingRules(t *testing.T) {
+	service, mockRepo := setupService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	expected := []model.RoutingRule{{OS: "ios", Device: "tablet", URL: "https://apps.apple.com/app/id123"}}
+	mockRepo.On("FindByID", ctx, "abc123").Return(&model.URL{ID: "abc123", UserID: &userID}, nil)
+	mockRepo.On("SetRules", ctx, "abc123", expected).Return(nil)
+
+	rules, err := service.SetRoutingRules(ctx, userID, "abc123", []model.RoutingRule{
+		{OS: " iOS ", Device: "Tablet", URL: "apps.apple.com/app/id123"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, rules)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSetRoutingRules_Invalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		rule model.RoutingRule
+		err  error
+	}{
+		{"no conditions", model.RoutingRule{URL: "https://example.com"}, ErrInvalidRule},
+		{"unknown OS", model.RoutingRule{OS: "symbian", URL: "https://example.com"}, ErrInvalidRule},
+		{"unknown device", model.RoutingRule{Device: "watch", URL: "https://example.com"}, ErrInvalidRule},
+		{"malformed language", model.RoutingRule{Language: "portuguese!", URL: "https://example.com"}, ErrInvalidRule},
+		{"malformed country", model.RoutingRule{Country: "BRA", URL: "https://example.com"}, ErrInvalidRule},
+		{"invalid destination", model.RoutingRule{OS: "ios", URL: "not a url"}, ErrInvalidURL},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockRepo := setupService(t)
+
+			_, err := service.SetRoutingRules(context.Background(), uuid.New(), "abc123", []model.RoutingRule{tc.rule})
+
+			assert.ErrorIs(t, err, tc.err)
+			mockRepo.AssertNotCalled(t, "SetRules", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+type recordingClicks struct {
+	clicks []model.Click
+}
+
+func (r *recordingClicks) RecordClick(click model.Click) {
+	r.clicks = append(r.clicks, click)
+}
+
+func splitLink(sticky bool, variants ...model.Variant) *model.URL {
+	return &model.URL{
+		ID:             "abc123",
+		OriginalURL:    "https://example.com",
+		Variants:       variants,
+		StickyVariants: sticky,
+	}
+}
+
+func TestResolve_RecordsVariantClick(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	zap.ReplaceGlobals(logger)
+	ctx := context.Background()
+
+	mockRepo := new(MockURLRepository)
+	clicks := &recordingClicks{}
+	service := NewURLService(mockRepo, WithClickAnalytics(clicks, nil))
+	mockRepo.On("FindByID", ctx, "abc123").Return(splitLink(false,
+		model.Variant{URL: "https://example.com/a", Weight: 0},
+		model.Variant{URL: "https://example.com/b", Weight: 5},
+	), nil)
+
+	resolution, err := service.Resolve(ctx, "abc123", Visit{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/b", resolution.URL)
+	require.NotNil(t, resolution.Variant)
+	assert.Equal(t, 1, *resolution.Variant)
+	assert.Empty(t, resolution.StickyVariant)
+	require.Len(t, clicks.clicks, 1)
+	assert.Equal(t, "abc123", clicks.clicks[0].URLID)
+	assert.Equal(t, 1, *clicks.clicks[0].Variant)
+	assert.Equal(t, "https://example.com/b", clicks.clicks[0].VariantURL)
+}
+
+func TestResolve_WeightedDistribution(t *testing.T) {
+	service, mockRepo := setupService(t)
+	ctx := context.Background()
+	mockRepo.On("FindByID", ctx, "abc123").Return(splitLink(false,
+		model.Variant{URL: "https://example.com/a", Weight: 75},
+		model.Variant{URL: "https://example.com/b", Weight: 25},
+	), nil)
+
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		resolution, err := service.Resolve(ctx, "abc123", Visit{})
+		require.NoError(t, err)
+		counts[resolution.URL]++
+	}
+
+	// Loose bounds keep the test stable while still catching a broken draw
+	assert.InDelta(t, 3000, counts["https://example.com/a"], 300)
+	assert.InDelta(t, 1000, counts["https://example.com/b"], 300)
+}
+
+func TestResolve_StickyVariant(t *testing.T) {
+	service, mockRepo := setupService(t)
+	ctx := context.Background()
+
+	link := splitLink(true,
+		model.Variant{URL: "https://example.com/a", Weight: 50},
+		model.Variant{URL: "https://example.com/b", Weight: 50},
+	)
+	mockRepo.On("FindByID", ctx, "abc123").Return(link, nil).Once()
+
+	first, err := service.Resolve(ctx, "abc123", Visit{})
+	require.NoError(t, err)
+	require.NotEmpty(t, first.StickyVariant)
+
+	// Reweighting keeps the assignment
+	reweighted := splitLink(true,
+		model.Variant{URL: "https://example.com/a", Weight: 10},
+		model.Variant{URL: "https://example.com/b", Weight: 90},
+	)
+	mockRepo.On("FindByID", ctx, "abc123").Return(reweighted, nil).Times(20)
+	for i := 0; i < 20; i++ {
+		again, err := service.Resolve(ctx, "abc123", Visit{StickyVariant: first.StickyVariant})
+		require.NoError(t, err)
+		assert.Equal(t, first.URL, again.URL)
+		assert.Equal(t, first.StickyVariant, again.StickyVariant)
+	}
+
+	// Replacing the destinations invalidates the token
+	replaced := splitLink(true,
+		model.Variant{URL: "https://example.com/c", Weight: 1},
+		model.Variant{URL: "https://example.com/d", Weight: 0},
+	)
+	mockRepo.On("FindByID", ctx, "abc123").Return(replaced, nil).Once()
+	moved, err := service.Resolve(ctx, "abc123", Visit{StickyVariant: first.StickyVariant})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/c", moved.URL)
+	assert.NotEqual(t, first.StickyVariant, moved.StickyVariant)
+}
+
+func TestResolve_RuleWinsOverVariants(t *testing.T) {
+	service, mockRepo := setupService(t)
+	ctx := context.Background()
+	link := splitLink(false,
+		model.Variant{URL: "https://example.com/a", Weight: 1},
+		model.Variant{URL: "https://example.com/b", Weight: 1},
+	)
+	link.Rules = []model.RoutingRule{{Device: "bot", URL: "https://example.com/preview"}}
+	mockRepo.On("FindByID", ctx, "abc123").Return(link, nil)
+
+	resolution, err := service.Resolve(ctx, "abc123", Visit{UserAgent: "Googlebot/2.1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/preview", resolution.URL)
+	assert.Nil(t, resolution.Variant)
+}
+
+func TestSetVariants_Invalid(t *testing.T) {
+	testCases := []struct {
+		name     string
+		variants []model.Variant
+		err      error
+	}{
+		{"single variant", []model.Variant{{URL: "https://example.com/a", Weight: 1}}, ErrInvalidVariants},
+		{"negative weight", []model.Variant{{URL: "https://example.com/a", Weight: -1}, {URL: "https://example.com/b", Weight: 2}}, ErrInvalidVariants},
+		{"all paused", []model.Variant{{URL: "https://example.com/a"}, {URL: "https://example.com/b"}}, ErrInvalidVariants},
+		{"invalid destination", []model.Variant{{URL: "nope", Weight: 1}, {URL: "https://example.com/b", Weight: 1}}, ErrInvalidURL},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockRepo := setupService(t)
+
+			_, err := service.SetVariants(context.Background(), uuid.New(), "abc123", tc.variants, false)
+
+			assert.ErrorIs(t, err, tc.err)
+			mockRepo.AssertNotCalled(t, "SetVariants", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestGetOriginalURL_Schedule(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	testCases := []struct {
+		name     string
+		link     model.URL
+		expected string
+		err      error
+	}{
+		{
+			name: "not started",
+			link: model.URL{OriginalURL: "https://example.com/launch", StartsAt: &future},
+			err:  ErrLinkNotStarted,
+		},
+		{
+			name:     "started",
+			link:     model.URL{OriginalURL: "https://example.com/launch", StartsAt: &past},
+			expected: "https://example.com/launch",
+		},
+		{
+			name: "latest change in effect wins",
+			link: model.URL{OriginalURL: "https://example.com/teaser", Schedule: []model.ScheduledDestination{
+				{URL: "https://example.com/sale", EffectiveAt: now.Add(-time.Minute)},
+				{URL: "https://example.com/launch", EffectiveAt: past},
+				{URL: "https://example.com/after", EffectiveAt: future},
+			}},
+			expected: "https://example.com/sale",
+		},
+		{
+			name: "pending change ignored",
+			link: model.URL{OriginalURL: "https://example.com/teaser", Schedule: []model.ScheduledDestination{
+				{URL: "https://example.com/launch", EffectiveAt: future},
+			}},
+			expected: "https://example.com/teaser",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockRepo := setupService(t)
+			ctx := context.Background()
+			link := tc.link
+			link.ID = "abc123"
+			mockRepo.On("FindByID", ctx, "abc123").Return(&link, nil)
+
+			dest, err := service.GetOriginalURL(ctx, "abc123", Visit{})
+
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, dest)
+		})
+	}
+}
+
+func TestSetSchedule(t *testing.T) {
+	service, mockRepo := setupService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	startsAt := time.Now().Add(time.Hour)
+	switchAt := time.Now().Add(2 * time.Hour).UTC()
+
+	expected := []model.ScheduledDestination{{URL: "https://example.com/live", EffectiveAt: switchAt}}
+	mockRepo.On("FindByID", ctx, "abc123").Return(&model.URL{ID: "abc123", UserID: &userID}, nil)
+	mockRepo.On("SetSchedule", ctx, "abc123", &startsAt, expected).Return(nil)
+
+	changes, err := service.SetSchedule(ctx, userID, "abc123", &startsAt, []model.ScheduledDestination{
+		{URL: "example.com/live", EffectiveAt: switchAt},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, changes)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSetSchedule_Invalid(t *testing.T) {
+	at := time.Now().Add(time.Hour)
+	testCases := []struct {
+		name    string
+		changes []model.ScheduledDestination
+		err     error
+	}{
+		{"change in the past", []model.ScheduledDestination{{URL: "https://example.com", EffectiveAt: time.Now().Add(-time.Minute)}}, ErrInvalidSchedule},
+		{"duplicate time", []model.ScheduledDestination{{URL: "https://example.com/a", EffectiveAt: at}, {URL: "https://example.com/b", EffectiveAt: at}}, ErrInvalidSchedule},
+		{"invalid destination", []model.ScheduledDestination{{URL: "nope", EffectiveAt: at}}, ErrInvalidURL},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockRepo := setupService(t)
+
+			_, err := service.SetSchedule(context.Background(), uuid.New(), "abc123", nil, tc.changes)
+
+			assert.ErrorIs(t, err, tc.err)
+			mockRepo.AssertNotCalled(t, "SetSchedule", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+type memoryFallbacks struct {
+	defaults map[uuid.UUID]model.Fallback
+}
+
+func (m *memoryFallbacks) Get(ctx context.Context, workspaceID uuid.UUID) (*model.FallbackDefault, error) {
+	fallback, ok := m.defaults[workspaceID]
+	if !ok {
+		return nil, repository.ErrFallbackNotFound
+	}
+	return &model.FallbackDefault{WorkspaceID: workspaceID, Fallback: fallback}, nil
+}
+
+func (m *memoryFallbacks) Upsert(ctx context.Context, workspaceID uuid.UUID, fallback model.Fallback) (*model.FallbackDefault, error) {
+	m.defaults[workspaceID] = fallback
+	return &model.FallbackDefault{WorkspaceID: workspaceID, Fallback: fallback}, nil
+}
+
+func (m *memoryFallbacks) Delete(ctx context.Context, workspaceID uuid.UUID) error {
+	if _, ok := m.defaults[workspaceID]; !ok {
+		return repository.ErrFallbackNotFound
+	}
+	delete(m.defaults, workspaceID)
+	return nil
+}
+
+func TestResolve_UnavailableStates(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+
+	testCases := []struct {
+		name  string
+		link  model.URL
+		state model.LinkState
+		err   error
+	}{
+		{"expired", model.URL{ExpiresAt: &past}, model.LinkExpired, ErrLinkExpired},
+		{"disabled by owner", model.URL{Disabled: true, DisabledReason: model.DisabledByOwner}, model.LinkDisabled, ErrLinkDisabled},
+		{"blocked", model.URL{Disabled: true, DisabledReason: model.DisabledLegal}, model.LinkBlocked, ErrLinkBlocked},
+		{"taken down", model.URL{Disabled: true, DisabledReason: model.DisabledForAbuse}, model.LinkTakenDown, ErrLinkTakenDown},
+		{"archived", model.URL{ArchivedAt: &past}, model.LinkArchived, ErrLinkArchived},
+		{"deleted", model.URL{ArchivedAt: &past, DeletedAt: &past}, model.LinkDeleted, ErrLinkDeleted},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockRepo := setupService(t)
+			ctx := context.Background()
+			link := tc.link
+			link.ID = "abc123"
+			link.OriginalURL = "https://example.com"
+			mockRepo.On("FindByID", ctx, "abc123").Return(&link, nil)
+
+			_, err := service.Resolve(ctx, "abc123", Visit{})
+
+			assert.ErrorIs(t, err, tc.err)
+			var unavailable *UnavailableError
+			require.ErrorAs(t, err, &unavailable)
+			assert.Equal(t, tc.state, unavailable.State)
+			assert.Nil(t, unavailable.Fallback)
+		})
+	}
+}
+
+func TestResolve_ClickLimit(t *testing.T) {
+	service, mockRepo := setupService(t)
+	ctx := context.Background()
+	limit := int64(1)
+	mockRepo.On("FindByID", ctx, "abc123").Return(&model.URL{
+		ID: "abc123", OriginalURL: "https://example.com", MaxClicks: &limit,
+	}, nil)
+	mockRepo.On("ConsumeClick", ctx, "abc123").Return(true, nil).Once()
+	mockRepo.On("ConsumeClick", ctx, "abc123").Return(false, nil).Once()
+
+	resolution, err := service.Resolve(ctx, "abc123", Visit{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", resolution.URL)
+
+	_, err = service.Resolve(ctx, "abc123", Visit{})
+	assert.ErrorIs(t, err, ErrLinkExhausted)
+}
+
+func TestResolve_FallbackPrecedence(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	zap.ReplaceGlobals(logger)
+	ctx := context.Background()
+	owner, workspace := uuid.New(), uuid.New()
+	past := time.Now().Add(-time.Minute)
+
+	linkFallback := &model.Fallback{URL: "https://example.com/link"}
+	workspaceFallback := model.Fallback{URL: "https://example.com/workspace"}
+	globalFallback := &model.Fallback{URL: "https://example.com/global"}
+
+	testCases := []struct {
+		name     string
+		link     model.URL
+		defaults map[uuid.UUID]model.Fallback
+		expected string
+	}{
+		{"link fallback first", model.URL{Fallback: linkFallback, UserID: &owner, WorkspaceID: &workspace}, map[uuid.UUID]model.Fallback{workspace: workspaceFallback}, "https://example.com/link"},
+		{"workspace default next", model.URL{UserID: &owner, WorkspaceID: &workspace}, map[uuid.UUID]model.Fallback{workspace: workspaceFallback}, "https://example.com/workspace"},
+		{"global default last", model.URL{UserID: &owner, WorkspaceID: &workspace}, map[uuid.UUID]model.Fallback{}, "https://example.com/global"},
+		{"anonymous links use the global default", model.URL{}, map[uuid.UUID]model.Fallback{workspace: workspaceFallback}, "https://example.com/global"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := new(MockURLRepository)
+			service := NewURLService(mockRepo, WithFallbacks(&memoryFallbacks{defaults: tc.defaults}, globalFallback))
+			link := tc.link
+			link.ID = "abc123"
+			link.OriginalURL = "https://example.com"
+			link.ExpiresAt = &past
+			mockRepo.On("FindByID", ctx, "abc123").Return(&link, nil)
+
+			_, err := service.Resolve(ctx, "abc123", Visit{})
+
+			var unavailable *UnavailableError
+			require.ErrorAs(t, err, &unavailable)
+			require.NotNil(t, unavailable.Fallback)
+			assert.Equal(t, tc.expected, unavailable.Fallback.URL)
+		})
+	}
+}
+
+func TestFallbackDefault_ManagedByWorkspaceAdmins(t *testing.T) {
+	setupService(t)
+	ctx := context.Background()
+	adminID, editorID := uuid.New(), uuid.New()
+
+	workspaces := newMemoryWorkspaces()
+	team := &model.Workspace{Name: "Marketing"}
+	require.NoError(t, workspaces.Create(ctx, team, adminID))
+	workspaces.members[team.ID][editorID] = model.RoleEditor
+	fallbacks := &memoryFallbacks{defaults: map[uuid.UUID]model.Fallback{}}
+	service := NewURLService(new(MockURLRepository), WithFallbacks(fallbacks, nil), WithWorkspaces(workspaces))
+	fallback := model.Fallback{URL: "https://example.com/gone"}
+
+	_, err := service.SetFallbackDefault(ctx, editorID, &team.ID, fallback)
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = service.SetFallbackDefault(ctx, adminID, &team.ID, fallback)
+	require.NoError(t, err)
+
+	def, err := service.GetFallbackDefault(ctx, editorID, &team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, team.ID, def.WorkspaceID)
+	assert.Equal(t, fallback.URL, def.URL)
+	_, err = service.GetFallbackDefault(ctx, uuid.New(), &team.ID)
+	assert.Error(t, err)
+
+	// The editor's personal workspace has none
+	_, err = service.GetFallbackDefault(ctx, editorID, nil)
+	assert.ErrorIs(t, err, repository.ErrFallbackNotFound)
+
+	assert.ErrorIs(t, service.DeleteFallbackDefault(ctx, editorID, &team.ID), ErrForbidden)
+	require.NoError(t, service.DeleteFallbackDefault(ctx, adminID, &team.ID))
+	assert.Empty(t, fallbacks.defaults)
+}
+
+func TestSetLinkFallback_Validation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fallback model.Fallback
+		err      error
+	}{
+		{"empty", model.Fallback{}, ErrInvalidFallback},
+		{"javascript URL", model.Fallback{URL: "javascript:alert(1)"}, ErrInvalidFallback},
+		{"javascript button", model.Fallback{Page: &model.FallbackPage{ButtonURL: "javascript://example.com/%0aalert(1)"}}, ErrInvalidFallback},
+		{"bad color", model.Fallback{Page: &model.FallbackPage{AccentColor: "red;background:url(x)"}}, ErrInvalidFallback},
+		{"title too long", model.Fallback{Page: &model.FallbackPage{Title: strings.Repeat("a", maxFallbackTitleLength+1)}}, ErrInvalidFallback},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockRepo := setupService(t)
+			fallback := tc.fallback
+
+			_, err := service.SetLinkFallback(context.Background(), uuid.New(), "abc123", &fallback)
+
+			assert.ErrorIs(t, err, tc.err)
+			mockRepo.AssertNotCalled(t, "SetFallback", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestShortenURL_InvalidLimits(t *testing.T) {
+	service, mockRepo := setupService(t)
+	past := time.Now().Add(-time.Hour)
+	zero := int64(0)
+
+	_, _, err := service.ShortenURL(context.Background(), "https://example.com", nil, ShortenOptions{ExpiresAt: &past})
+	assert.ErrorIs(t, err, ErrInvalidLimits)
+
+	_, _, err = service.ShortenURL(context.Background(), "https://example.com", nil, ShortenOptions{MaxClicks: &zero})
+	assert.ErrorIs(t, err, ErrInvalidLimits)
+
+	mockRepo.AssertNotCalled(t, "CreateOrGet", mock.Anything, mock.Anything)
+}
+
 func TestGetOriginalURL_InvalidShortCode(t *testing.T) {
 	service, _ := setupService(t)
 	ctx := context.Background()
@@ -228,7 +1229,7 @@ func TestGetOriginalURL_InvalidShortCode(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			_, err := service.GetOriginalURL(ctx, tc.shortCode)
+			_, err := service.GetOriginalURL(ctx, tc.shortCode, Visit{})
 			assert.Error(t, err)
 			assert.Contains(t, err.Error(), "invalid token")
 		})
@@ -243,7 +1244,7 @@ func TestGetOriginalURL_NotFound(t *testing.T) {
 
 	mockRepo.On("FindByID", ctx, shortCode).Return(nil, repository.ErrURLNotFound)
 
-	_, err := service.GetOriginalURL(ctx, shortCode)
+	_, err := service.GetOriginalURL(ctx, shortCode, Visit{})
 
 	assert.ErrorIs(t, err, repository.ErrURLNotFound)
 	mockRepo.AssertExpectations(t)
@@ -258,7 +1259,7 @@ func TestGetOriginalURL_RepositoryError(t *testing.T) {
 
 	mockRepo.On("FindByID", ctx, shortCode).Return(nil, dbError)
 
-	_, err := service.GetOriginalURL(ctx, shortCode)
+	_, err := service.GetOriginalURL(ctx, shortCode, Visit{})
 
 	assert.Error(t, err)
 	assert.Equal(t, dbError, err)
@@ -409,3 +1410,202 @@ func TestGenerateUniqueID_RepositoryError(t *testing.T) {
 	assert.Equal(t, dbError, err)
 	mockRepo.AssertExpectations(t)
 }
+
+func verifiedDomain(t *testing.T, repo *memoryDomains, userID uuid.UUID, hostname string) *model.Domain {
+	t.Helper()
+	domain := &model.Domain{UserID: userID, Hostname: hostname}
+	require.NoError(t, repo.Create(context.Background(), domain))
+	require.NoError(t, repo.MarkVerified(context.Background(), userID, domain.ID, time.Now()))
+	return domain
+}
+
+func TestResolve_CustomDomain(t *testing.T) {
+	domains := newMemoryDomains()
+	domain := verifiedDomain(t, domains, uuid.New(), "go.example.com")
+	mockRepo := new(MockURLRepository)
+	service := NewURLService(mockRepo, WithDomains(domains))
+	ctx := context.Background()
+
+	link := &model.URL{ID: "k3j2h1", Code: "launch", DomainID: &domain.ID, OriginalURL: "https://example.com/launch"}
+	mockRepo.On("FindByCode", ctx, domain.ID, "launch").Return(link, nil)
+
+	url, err := service.GetOriginalURL(ctx, "launch", Visit{Host: "Go.Example.com:443"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/launch", url)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestResolve_SharedHostHidesCustomDomainLinks(t *testing.T) {
+	domains := newMemoryDomains()
+	domain := verifiedDomain(t, domains, uuid.New(), "go.example.com")
+	mockRepo := new(MockURLRepository)
+	service := NewURLService(mockRepo, WithDomains(domains))
+	ctx := context.Background()
+
+	link := &model.URL{ID: "k3j2h1", Code: "launch", DomainID: &domain.ID, OriginalURL: "https://example.com/launch"}
+	mockRepo.On("FindByID", ctx, "k3j2h1").Return(link, nil)
+
+	_, err := service.GetOriginalURL(ctx, "k3j2h1", Visit{Host: "tinyurl.example"})
+
+	assert.ErrorIs(t, err, repository.ErrURLNotFound)
+}
+
+func TestShortenURL_CustomDomain(t *testing.T) {
+	domains := newMemoryDomains()
+	userID := uuid.New()
+	domain := verifiedDomain(t, domains, userID, "go.example.com")
+	mockRepo := new(MockURLRepository)
+	service := NewURLService(mockRepo, WithDomains(domains))
+	ctx := context.Background()
+
+	mockRepo.On("IDExists", ctx, mock.AnythingOfType("string")).Return(false, nil)
+	mockRepo.On("CreateOrGet", ctx, mock.MatchedBy(func(url *model.URL) bool {
+		return url.DomainID != nil && *url.DomainID == domain.ID && url.Code == "launch" && url.ID != "launch"
+	})).Return("k3j2h1", true, nil)
+
+	link, isNew, err := service.ShortenURL(ctx, "https://example.com/launch", &userID, ShortenOptions{
+		Domain: "GO.example.com",
+		Code:   "launch",
+	})
+
+	require.NoError(t, err)
+	assert.True(t, isNew)
+	assert.Equal(t, "k3j2h1", link.ID)
+	assert.Equal(t, "launch", link.Code)
+	assert.Equal(t, "go.example.com", link.Domain)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestShortenURL_CustomDomainErrors(t *testing.T) {
+	domains := newMemoryDomains()
+	owner := uuid.New()
+	verifiedDomain(t, domains, owner, "go.example.com")
+	unverified := &model.Domain{UserID: owner, Hostname: "links.example.com"}
+	require.NoError(t, domains.Create(context.Background(), unverified))
+	other := uuid.New()
+
+	testCases := []struct {
+		name   string
+		userID *uuid.UUID
+		opts   ShortenOptions
+		err    error
+	}{
+		{"anonymous", nil, ShortenOptions{Domain: "go.example.com"}, ErrAuthRequired},
+		{"code on shared domain", &owner, ShortenOptions{Code: "launch"}, ErrInvalidCode},
+		{"reserved code", &owner, ShortenOptions{Domain: "go.example.com", Code: "api"}, ErrInvalidCode},
+		{"malformed code", &owner, ShortenOptions{Domain: "go.example.com", Code: "a/b"}, ErrInvalidCode},
+		{"someone else's domain", &other, ShortenOptions{Domain: "go.example.com"}, repository.ErrDomainNotFound},
+		{"unverified domain", &owner, ShortenOptions{Domain: "links.example.com"}, repository.ErrDomainNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := NewURLService(new(MockURLRepository), WithDomains(domains))
+
+			_, _, err := service.ShortenURL(context.Background(), "https://example.com", tc.userID, tc.opts)
+
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+// shortenAnonymously creates a link without an account and returns it
+// with the hash stored for its claim token
+func shortenAnonymously(t *testing.T, service *URLService, mockRepo *MockURLRepository) (*model.URL, string) {
+	t.Helper()
+	ctx := context.Background()
+
+	var claimHash string
+	mockRepo.On("IDExists", ctx, mock.AnythingOfType("string")).Return(false, nil)
+	// Inserted under the generated id, which the claim token refers to
+	call := mockRepo.On("CreateOrGet", ctx, mock.AnythingOfType("*model.URL")).Once()
+	call.Run(func(args mock.Arguments) {
+		stored := args.Get(1).(*model.URL)
+		claimHash = stored.ClaimHash
+		call.ReturnArguments = mock.Arguments{stored.ID, true, nil}
+	})
+
+	link, isNew, err := service.ShortenURL(ctx, "https://example.com", nil, ShortenOptions{})
+	require.NoError(t, err)
+	require.True(t, isNew)
+	return link, claimHash
+}
+
+func TestShortenURL_AnonymousClaimToken(t *testing.T) {
+	service, mockRepo := setupService(t)
+
+	link, claimHash := shortenAnonymously(t, service, mockRepo)
+
+	assert.NotEmpty(t, link.ClaimToken)
+	assert.NotEmpty(t, claimHash)
+	assert.NotContains(t, link.ClaimToken, claimHash)
+
+	// Signed-in users own their links already
+	ctx := context.Background()
+	userID := uuid.New()
+	mockRepo.On("CreateOrGet", ctx, mock.MatchedBy(func(u *model.URL) bool { return u.ClaimHash == "" })).
+		Return("abc123", true, nil).Once()
+	owned, _, err := service.ShortenURL(ctx, "https://example.org", &userID, ShortenOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, owned.ClaimToken)
+}
+
+func TestShortenURL_SharedAnonymousLinkHasNoClaimToken(t *testing.T) {
+	service, mockRepo := setupService(t)
+	ctx := context.Background()
+
+	mockRepo.On("IDExists", ctx, mock.AnythingOfType("string")).Return(false, nil)
+	mockRepo.On("CreateOrGet", ctx, mock.AnythingOfType("*model.URL")).Return("abc123", false, nil)
+
+	// The existing link's claim is revoked, so the caller gets no token either
+	link, isNew, err := service.ShortenURL(ctx, "https://example.com", nil, ShortenOptions{})
+	require.NoError(t, err)
+	assert.False(t, isNew)
+	assert.Equal(t, "abc123", link.ID)
+	assert.Empty(t, link.ClaimToken)
+}
+
+func TestClaimURLs(t *testing.T) {
+	service, mockRepo := setupService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	link, claimHash := shortenAnonymously(t, service, mockRepo)
+
+	mockRepo.On("Claim", ctx, link.ID, claimHash, userID, (*uuid.UUID)(nil)).Return(nil).Once()
+	claimed, err := service.ClaimURLs(ctx, userID, []string{link.ClaimToken})
+	require.NoError(t, err)
+	assert.Equal(t, []string{link.ID}, claimed)
+
+	// Replaying the token finds nothing left to claim
+	mockRepo.On("Claim", ctx, link.ID, claimHash, userID, (*uuid.UUID)(nil)).Return(repository.ErrURLNotFound).Once()
+	claimed, err = service.ClaimURLs(ctx, userID, []string{link.ClaimToken})
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestClaimURLs_RejectsForgedTokens(t *testing.T) {
+	service, mockRepo := setupService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	link, _ := shortenAnonymously(t, service, mockRepo)
+	parts := strings.Split(link.ClaimToken, ".")
+	require.Len(t, parts, 3)
+
+	// The payload of another token with the original signature
+	other, _ := shortenAnonymously(t, service, mockRepo)
+	forged := strings.Split(other.ClaimToken, ".")[0] + "." + strings.Split(other.ClaimToken, ".")[1] + "." + parts[2]
+
+	// A login token is not a claim token
+	session, err := token.GenerateToken(userID.String(), 0)
+	require.NoError(t, err)
+
+	for _, tok := range []string{"", "not-a-token", forged, session} {
+		_, err := service.ClaimURLs(ctx, userID, []string{link.ClaimToken, tok})
+		assert.ErrorIs(t, err, ErrInvalidClaimToken)
+	}
+	mockRepo.AssertNotCalled(t, "Claim", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var ErrInvalidUTM = errors.New("invalid UTM parameters")
+
+const maxUTMValueLength = 100
+
+// WithUTMTemplates enables per-workspace default UTM parameters. They need
+// WithWorkspaces.
+func WithUTMTemplates(repo repository.UTMTemplateRepository) URLServiceOption {
+	return func(s *URLService) {
+		s.utmTemplates = repo
+	}
+}
+
+// GetUTMTemplate returns the default UTM parameters of a workspace userID
+// belongs to, their personal one when workspaceID is nil
+func (s *URLService) GetUTMTemplate(ctx context.Context, userID uuid.UUID, workspaceID *uuid.UUID) (*model.UTMTemplate, error) {
+	workspaceID, err := s.memberWorkspace(ctx, userID, workspaceID, model.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	if workspaceID == nil || s.utmTemplates == nil {
+		return nil, repository.ErrUTMTemplateNotFound
+	}
+	return s.utmTemplates.Get(ctx, *workspaceID)
+}
+
+// SetUTMTemplate validates and stores the default UTM parameters of a
+// workspace. Like members, they are managed by its admins.
+func (s *URLService) SetUTMTemplate(ctx context.Context, userID uuid.UUID, workspaceID *uuid.UUID, params model.UTMParams) (*model.UTMTemplate, error) {
+	params, err := normalizeUTM(params)
+	if err != nil {
+		return nil, err
+	}
+	if params.IsEmpty() || s.utmTemplates == nil {
+		return nil, ErrInvalidUTM
+	}
+
+	workspaceID, err = s.memberWorkspace(ctx, userID, workspaceID, model.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if workspaceID == nil {
+		return nil, repository.ErrWorkspaceNotFound
+	}
+
+	tpl, err := s.utmTemplates.Upsert(ctx, *workspaceID, params)
+	if err != nil {
+		s.logger.Error("Failed to save UTM template", zap.Error(err), zap.String("workspaceID", workspaceID.String()))
+		return nil, err
+	}
+
+	s.logger.Info("UTM template saved", zap.String("workspaceID", workspaceID.String()), zap.String("userID", userID.String()))
+	return tpl, nil
+}
+
+// DeleteUTMTemplate stops applying default UTM parameters in a workspace
+func (s *URLService) DeleteUTMTemplate(ctx context.Context, userID uuid.UUID, workspaceID *uuid.UUID) error {
+	workspaceID, err := s.memberWorkspace(ctx, userID, workspaceID, model.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if workspaceID == nil || s.utmTemplates == nil {
+		return repository.ErrUTMTemplateNotFound
+	}
+	return s.utmTemplates.Delete(ctx, *workspaceID)
+}
+
+// resolveUTM fills the parameters the caller left empty from the template
+// of the workspace the link is created in
+func (s *URLService) resolveUTM(ctx context.Context, workspaceID *uuid.UUID, explicit model.UTMParams) (model.UTMParams, error) {
+	params, err := normalizeUTM(explicit)
+	if err != nil {
+		return model.UTMParams{}, err
+	}
+
+	if workspaceID != nil && s.utmTemplates != nil {
+		tpl, err := s.utmTemplates.Get(ctx, *workspaceID)
+		switch {
+		case err == nil:
+			params = mergeUTM(params, tpl.UTMParams)
+		case !errors.Is(err, repository.ErrUTMTemplateNotFound):
+			s.logger.Error("Failed to load UTM template", zap.Error(err), zap.String("workspaceID", workspaceID.String()))
+			return model.UTMParams{}, err
+		}
+	}
+
+	// Analytics tools ignore campaign parameters without a source
+	if !params.IsEmpty() && params.Source == "" {
+		return model.UTMParams{}, ErrInvalidUTM
+	}
+
+	return params, nil
+}
+
+func normalizeUTM(p model.UTMParams) (model.UTMParams, error) {
+	fields := []*string{&p.Source, &p.Medium, &p.Campaign, &p.Term, &p.Content}
+	for _, f := range fields {
+		*f = strings.TrimSpace(*f)
+		if len(*f) > maxUTMValueLength {
+			return model.UTMParams{}, ErrInvalidUTM
+		}
+		for _, r := range *f {
+			if unicode.IsControl(r) {
+				return model.UTMParams{}, ErrInvalidUTM
+			}
+		}
+	}
+	return p, nil
+}
+
+// mergeUTM keeps explicit values and takes the rest from defaults
+func mergeUTM(explicit, defaults model.UTMParams) model.UTMParams {
+	pick := func(a, b string) string {
+		if a != "" {
+			return a
+		}
+		return b
+	}
+	return model.UTMParams{
+		Source:   pick(explicit.Source, defaults.Source),
+		Medium:   pick(explicit.Medium, defaults.Medium),
+		Campaign: pick(explicit.Campaign, defaults.Campaign),
+		Term:     pick(explicit.Term, defaults.Term),
+		Content:  pick(explicit.Content, defaults.Content),
+	}
+}
+
+// applyUTM sets the UTM query parameters on rawURL, replacing existing ones
+func applyUTM(rawURL string, p model.UTMParams) (string, error) {
+	dest, err := url.Parse(rawURL)
+	if err != nil {
+		return "", ErrInvalidURL
+	}
+
+	query := dest.Query()
+	set := func(key, value string) {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	set("utm_source", p.Source)
+	set("utm_medium", p.Medium)
+	set("utm_campaign", p.Campaign)
+	set("utm_term", p.Term)
+	set("utm_content", p.Content)
+	dest.RawQuery = query.Encode()
+
+	return dest.String(), nil
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var ErrInvalidVariants = errors.New("invalid variants")
+
+const (
+	minVariants      = 2
+	maxVariants      = 10
+	maxVariantWeight = 1000
+)
+
+// ClickRecorder stores resolved visits for analytics without blocking
+type ClickRecorder interface {
+	RecordClick(click model.Click)
+}
+
+// WithClickAnalytics records a click for every resolved visit and serves
+// per-link stats from repo
+func WithClickAnalytics(recorder ClickRecorder, repo repository.ClickRepository) URLServiceOption {
+	return func(s *URLService) {
+		s.clicks = recorder
+		s.clickStats = repo
+	}
+}
+
+// SetVariants validates and replaces the A/B destinations of a link userID
+// can edit. An empty list turns the split off.
+func (s *URLService) SetVariants(ctx context.Context, userID uuid.UUID, shortCode string, variants []model.Variant, sticky bool) ([]model.Variant, error) {
+	if !s.isValidID(shortCode) {
+		return nil, ErrInvalidToken
+	}
+
+	normalized, err := s.normalizeVariants(variants)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizeLink(ctx, userID, shortCode, model.RoleEditor); err != nil {
+		return nil, err
+	}
+	err = audited(ctx, s.audit, func(ctx context.Context) error {
+		before := s.snapshotLink(ctx, shortCode)
+		if err := s.repo.SetVariants(ctx, shortCode, normalized, sticky); err != nil {
+			return err
+		}
+		return s.auditLink(ctx, userID, model.AuditLinkVariants, shortCode, before)
+	})
+	if err != nil {
+		if !errors.Is(err, repository.ErrURLNotFound) {
+			s.logger.Error("Failed to save variants", zap.Error(err), zap.String("shortCode", shortCode))
+		}
+		return nil, err
+	}
+
+	s.logger.Info("Variants updated", zap.String("shortCode", shortCode), zap.Int("count", len(normalized)))
+	return normalized, nil
+}
+
+// GetLinkStats returns the click counts of a link userID can see
+func (s *URLService) GetLinkStats(ctx context.Context, userID uuid.UUID, shortCode string) (*model.LinkStats, error) {
+	if !s.isValidID(shortCode) {
+		return nil, ErrInvalidToken
+	}
+	if s.clickStats == nil {
+		return nil, repository.ErrURLNotFound
+	}
+
+	if err := s.authorizeLink(ctx, userID, shortCode, model.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	stats, err := s.clickStats.GetStats(ctx, shortCode)
+	if err != nil {
+		if !errors.Is(err, repository.ErrURLNotFound) {
+			s.logger.Error("Failed to load link stats", zap.Error(err), zap.String("shortCode", shortCode))
+		}
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func (s *URLService) normalizeVariants(variants []model.Variant) ([]model.Variant, error) {
+	if len(variants) == 0 {
+		return []model.Variant{}, nil
+	}
+	if len(variants) < minVariants || len(variants) > maxVariants {
+		return nil, ErrInvalidVariants
+	}
+
+	total := 0
+	normalized := make([]model.Variant, 0, len(variants))
+	for _, variant := range variants {
+		variant.URL = strings.TrimSpace(variant.URL)
+		if variant.Weight < 0 || variant.Weight > maxVariantWeight {
+			return nil, ErrInvalidVariants
+		}
+		if !s.isValidURL(variant.URL) {
+			return nil, ErrInvalidURL
+		}
+		if !strings.HasPrefix(variant.URL, "http://") && !strings.HasPrefix(variant.URL, "https://") {
+			variant.URL = "https://" + variant.URL
+		}
+		total += variant.Weight
+		normalized = append(normalized, variant)
+	}
+
+	// Weight 0 pauses a variant, but at least one must stay live
+	if total == 0 {
+		return nil, ErrInvalidVariants
+	}
+
+	return normalized, nil
+}
+
+// pickVariant keeps the visitor's previous assignment when the link is sticky
+// and the variant is still live, otherwise draws one by weight
+func pickVariant(link *model.URL, token string) int {
+	if link.StickyVariants {
+		if index, ok := parseVariantToken(link.Variants, token); ok {
+			return index
+		}
+	}
+
+	total := 0
+	for _, variant := range link.Variants {
+		total += variant.Weight
+	}
+	if total <= 0 {
+		return 0
+	}
+
+	n := rand.IntN(total)
+	for i, variant := range link.Variants {
+		if n < variant.Weight {
+			return i
+		}
+		n -= variant.Weight
+	}
+	return len(link.Variants) - 1
+}
+
+// variantToken encodes an assignment together with a fingerprint of the
+// variant destinations, so replacing them reassigns visitors instead of
+// sending them to whatever now sits at their old index. Weights are left out
+// so ramping a test up keeps existing assignments.
+func variantToken(variants []model.Variant, index int) string {
+	return fmt.Sprintf("%d.%s", index, variantsFingerprint(variants))
+}
+
+func parseVariantToken(variants []model.Variant, token string) (int, bool) {
+	indexPart, fingerprint, ok := strings.Cut(token, ".")
+	if !ok || fingerprint != variantsFingerprint(variants) {
+		return 0, false
+	}
+
+	index, err := strconv.Atoi(indexPart)
+	if err != nil || index < 0 || index >= len(variants) || variants[index].Weight == 0 {
+		return 0, false
+	}
+	return index, true
+}
+
+func variantsFingerprint(variants []model.Variant) string {
+	h := fnv.New32a()
+	for _, variant := range variants {
+		_, _ = h.Write([]byte(variant.URL + "\x00"))
+	}
+	return strconv.FormatUint(uint64(h.Sum32()), 36)
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/safehttp"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+const (
+	webhookSecretBytes  = 32
+	webhookSecretPrefix = "whsec_"
+	maxWebhookURLLength = 2048
+)
+
+// WebhookUpdate holds the webhook settings to change. Nil fields are left
+// untouched.
+type WebhookUpdate struct {
+	URL    *string
+	Events *[]model.WebhookEvent
+	Active *bool
+}
+
+// WebhookService manages the webhooks of an account and their delivery log
+type WebhookService interface {
+	// CreateWebhook registers an endpoint. The returned webhook carries the
+	// signing secret, which is not shown again.
+	CreateWebhook(ctx context.Context, userID uuid.UUID, rawURL string, events []model.WebhookEvent) (*model.Webhook, error)
+	ListWebhooks(ctx context.Context, userID uuid.UUID) ([]model.Webhook, error)
+	UpdateWebhook(ctx context.Context, userID, id uuid.UUID, update WebhookUpdate) (*model.Webhook, error)
+	DeleteWebhook(ctx context.Context, userID, id uuid.UUID) error
+	ListDeliveries(ctx context.Context, userID, id uuid.UUID, filter repository.DeliveryFilter) ([]model.WebhookDelivery, error)
+	// ReplayDelivery queues a delivery again, whatever its outcome was
+	ReplayDelivery(ctx context.Context, userID, id, deliveryID uuid.UUID) (*model.WebhookDelivery, error)
+}
+
+type webhookService struct {
+	repo   repository.WebhookRepository
+	logger *zap.Logger
+}
+
+func NewWebhookService(repo repository.WebhookRepository) WebhookService {
+	return &webhookService{
+		repo:   repo,
+		logger: zap.L().With(zap.String("component", "WebhookService")),
+	}
+}
+
+func (s *webhookService) CreateWebhook(ctx context.Context, userID uuid.UUID, rawURL string, events []model.WebhookEvent) (*model.Webhook, error) {
+	endpoint, err := normalizeWebhookURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	events, err = normalizeWebhookEvents(events)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, webhookSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	webhook := &model.Webhook{
+		UserID: userID,
+		URL:    endpoint,
+		Secret: webhookSecretPrefix + hex.EncodeToString(secret),
+		Events: events,
+		Active: true,
+	}
+	if err := s.repo.Create(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Webhook created", zap.String("webhook_id", webhook.ID.String()), zap.String("userID", userID.String()))
+	return webhook, nil
+}
+
+func (s *webhookService) ListWebhooks(ctx context.Context, userID uuid.UUID) ([]model.Webhook, error) {
+	webhooks, err := s.repo.List(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to list webhooks", zap.Error(err), zap.String("userID", userID.String()))
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (s *webhookService) UpdateWebhook(ctx context.Context, userID, id uuid.UUID, update WebhookUpdate) (*model.Webhook, error) {
+	webhook, err := s.repo.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.URL != nil {
+		if webhook.URL, err = normalizeWebhookURL(*update.URL); err != nil {
+			return nil, err
+		}
+	}
+	if update.Events != nil {
+		if webhook.Events, err = normalizeWebhookEvents(*update.Events); err != nil {
+			return nil, err
+		}
+	}
+	if update.Active != nil {
+		webhook.Active = *update.Active
+	}
+
+	if err := s.repo.Update(ctx, webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (s *webhookService) DeleteWebhook(ctx context.Context, userID, id uuid.UUID) error {
+	if err := s.repo.Delete(ctx, userID, id); err != nil {
+		return err
+	}
+	s.logger.Info("Webhook deleted", zap.String("webhook_id", id.String()), zap.String("userID", userID.String()))
+	return nil
+}
+
+func (s *webhookService) ListDeliveries(ctx context.Context, userID, id uuid.UUID, filter repository.DeliveryFilter) ([]model.WebhookDelivery, error) {
+	switch filter.Status {
+	case "", model.DeliveryPending, model.DeliverySucceeded, model.DeliveryFailed:
+	default:
+		return nil, ErrInvalidWebhook
+	}
+	if _, err := s.repo.Get(ctx, userID, id); err != nil {
+		return nil, err
+	}
+
+	filter.Limit, filter.Offset = normalizeAdminPage(filter.Limit, filter.Offset)
+	return s.repo.ListDeliveries(ctx, id, filter)
+}
+
+func (s *webhookService) ReplayDelivery(ctx context.Context, userID, id, deliveryID uuid.UUID) (*model.WebhookDelivery, error) {
+	delivery, err := s.repo.Replay(ctx, userID, id, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Webhook delivery replayed", zap.String("delivery_id", deliveryID.String()),
+		zap.String("replay_id", delivery.ID.String()))
+	return delivery, nil
+}
+
+// normalizeWebhookURL accepts absolute http(s) URLs that do not point at
+// an internal address. Hostnames are checked again when delivering.
+func normalizeWebhookURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" || len(rawURL) > maxWebhookURLLength {
+		return "", ErrInvalidWebhook
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || safehttp.CheckURL(u) != nil {
+		return "", ErrInvalidWebhook
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !safehttp.IsPublicIP(ip) {
+		return "", ErrInvalidWebhook
+	}
+	if strings.EqualFold(u.Hostname(), "localhost") {
+		return "", ErrInvalidWebhook
+	}
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+func normalizeWebhookEvents(events []model.WebhookEvent) ([]model.WebhookEvent, error) {
+	seen := make(map[model.WebhookEvent]bool, len(events))
+	normalized := make([]model.WebhookEvent, 0, len(events))
+	for _, e := range events {
+		e = model.WebhookEvent(strings.ToLower(strings.TrimSpace(string(e))))
+		if !e.Valid() {
+			return nil, ErrInvalidWebhook
+		}
+		if !seen[e] {
+			seen[e] = true
+			normalized = append(normalized, e)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil, ErrInvalidWebhook
+	}
+	return normalized, nil
+}
+
+// WebhookEmitter queues webhook deliveries for events on a link of owner,
+// in the transaction ctx carries
+type WebhookEmitter interface {
+	Emit(ctx context.Context, owner model.LinkOwner, event model.WebhookEvent, data any) error
+}
+
+// WithWebhooks notifies the webhooks of a link's workspace members, or of its
+// creator outside any workspace, of changes to the link. Clicks are reported
+// by the store the click recorder writes to.
+func WithWebhooks(emitter WebhookEmitter) URLServiceOption {
+	return func(s *URLService) {
+		s.webhooks = emitter
+	}
+}
+
+// linkEventData is the data of link.created, link.updated and link.deleted
+// events. Change is the audit action behind the event, e.g. link.archived
+// for a link.updated.
+type linkEventData struct {
+	ID     string        `json:"id"`
+	Change string        `json:"change"`
+	Link   *linkSnapshot `json:"link,omitempty"`
+}
+
+// emitLinkChange notifies the link owner's webhooks of a change recorded
+// with recordLinkAudit
+func (s *URLService) emitLinkChange(ctx context.Context, action, id string, before, after *linkSnapshot) error {
+	if s.webhooks == nil {
+		return nil
+	}
+	link := after
+	if link == nil {
+		link = before
+	}
+	if link == nil || (link.WorkspaceID == nil && link.UserID == nil) {
+		return nil
+	}
+
+	event := model.EventLinkUpdated
+	switch action {
+	case model.AuditLinkCreated:
+		event = model.EventLinkCreated
+	case model.AuditLinkDeleted, model.AuditLinkPurged:
+		event = model.EventLinkDeleted
+	}
+	return s.webhooks.Emit(ctx, model.LinkOwner{WorkspaceID: link.WorkspaceID, UserID: link.UserID}, event, linkEventData{ID: id, Change: action, Link: link})
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrForbidden         = errors.New("insufficient workspace role")
+	ErrInvalidWorkspace  = errors.New("invalid workspace name")
+	ErrInvalidRole       = errors.New("invalid role")
+	ErrInvalidEmail      = errors.New("invalid email")
+	ErrPersonalWorkspace = errors.New("personal workspaces cannot be shared")
+)
+
+const (
+	maxWorkspaceNameLength = 100
+	invitationTokenBytes   = 32
+	invitationTTL          = 7 * 24 * time.Hour
+)
+
+type WorkspaceService interface {
+	ListWorkspaces(ctx context.Context, userID uuid.UUID) ([]model.Workspace, error)
+	CreateWorkspace(ctx context.Context, userID uuid.UUID, name string) (*model.Workspace, error)
+	ListMembers(ctx context.Context, userID, workspaceID uuid.UUID) ([]model.WorkspaceMember, error)
+	SetMemberRole(ctx context.Context, userID, workspaceID, memberID uuid.UUID, role model.Role) error
+	RemoveMember(ctx context.Context, userID, workspaceID, memberID uuid.UUID) error
+	Invite(ctx context.Context, userID, workspaceID uuid.UUID, email string, role model.Role) (*model.Invitation, string, error)
+	ListInvitations(ctx context.Context, userID, workspaceID uuid.UUID) ([]model.Invitation, error)
+	RevokeInvitation(ctx context.Context, userID, workspaceID, invitationID uuid.UUID) error
+	AcceptInvitation(ctx context.Context, userID uuid.UUID, token string) (*model.WorkspaceMember, error)
+}
+
+type workspaceService struct {
+	repo   repository.WorkspaceRepository
+	logger *zap.Logger
+}
+
+func NewWorkspaceService(repo repository.WorkspaceRepository) WorkspaceService {
+	return &workspaceService{
+		repo:   repo,
+		logger: zap.L().With(zap.String("component", "WorkspaceService")),
+	}
+}
+
+// ListWorkspaces returns the workspaces userID belongs to, personal first
+func (s *workspaceService) ListWorkspaces(ctx context.Context, userID uuid.UUID) ([]model.Workspace, error) {
+	// Makes sure accounts created since workspaces were introduced have one
+	if _, err := s.repo.Personal(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	workspaces, err := s.repo.ListForUser(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to list workspaces", zap.Error(err), zap.String("userID", userID.String()))
+		return nil, err
+	}
+	return workspaces, nil
+}
+
+func (s *workspaceService) CreateWorkspace(ctx context.Context, userID uuid.UUID, name string) (*model.Workspace, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxWorkspaceNameLength {
+		return nil, ErrInvalidWorkspace
+	}
+
+	workspace := &model.Workspace{Name: name}
+	if err := s.repo.Create(ctx, workspace, userID); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Workspace created", zap.String("workspaceID", workspace.ID.String()), zap.String("userID", userID.String()))
+	return workspace, nil
+}
+
+func (s *workspaceService) ListMembers(ctx context.Context, userID, workspaceID uuid.UUID) ([]model.WorkspaceMember, error) {
+	if _, err := s.authorize(ctx, userID, workspaceID, model.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.repo.Members(ctx, workspaceID)
+}
+
+// SetMemberRole lets admins manage editors and viewers. Granting or taking
+// away ownership, or changing an owner's role, needs an owner.
+func (s *workspaceService) SetMemberRole(ctx context.Context, userID, workspaceID, memberID uuid.UUID, role model.Role) error {
+	if !role.Valid() {
+		return ErrInvalidRole
+	}
+
+	actorRole, err := s.authorize(ctx, userID, workspaceID, model.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if err := s.checkManages(ctx, actorRole, workspaceID, memberID, role); err != nil {
+		return err
+	}
+
+	if err := s.repo.SetRole(ctx, workspaceID, memberID, role); err != nil {
+		return err
+	}
+
+	s.logger.Info("Member role changed", zap.String("workspaceID", workspaceID.String()),
+		zap.String("memberID", memberID.String()), zap.String("role", string(role)))
+	return nil
+}
+
+// RemoveMember takes someone out of a workspace. Anyone can leave; removing
+// others follows the same rules as changing their role.
+func (s *workspaceService) RemoveMember(ctx context.Context, userID, workspaceID, memberID uuid.UUID) error {
+	if userID != memberID {
+		actorRole, err := s.authorize(ctx, userID, workspaceID, model.RoleAdmin)
+		if err != nil {
+			return err
+		}
+		if err := s.checkManages(ctx, actorRole, workspaceID, memberID, model.RoleViewer); err != nil {
+			return err
+		}
+	} else if _, err := s.authorize(ctx, userID, workspaceID, model.RoleViewer); err != nil {
+		return err
+	}
+
+	if err := s.repo.RemoveMember(ctx, workspaceID, memberID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Member removed", zap.String("workspaceID", workspaceID.String()), zap.String("memberID", memberID.String()))
+	return nil
+}
+
+// Invite creates an invitation and returns it with its token, which is only
+// available now and has to reach the invitee, e.g. as a link
+func (s *workspaceService) Invite(ctx context.Context, userID, workspaceID uuid.UUID, email string, role model.Role) (*model.Invitation, string, error) {
+	if !role.Valid() {
+		return nil, "", ErrInvalidRole
+	}
+	addr, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || addr.Name != "" {
+		return nil, "", ErrInvalidEmail
+	}
+
+	actorRole, err := s.authorize(ctx, userID, workspaceID, model.RoleAdmin)
+	if err != nil {
+		return nil, "", err
+	}
+	if role == model.RoleOwner && actorRole != model.RoleOwner {
+		return nil, "", ErrForbidden
+	}
+
+	workspace, err := s.repo.Get(ctx, workspaceID)
+	if err != nil {
+		return nil, "", err
+	}
+	if workspace.Personal {
+		return nil, "", ErrPersonalWorkspace
+	}
+
+	raw := make([]byte, invitationTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	invitation := &model.Invitation{
+		WorkspaceID: workspaceID,
+		Email:       strings.ToLower(addr.Address),
+		Role:        role,
+		InvitedBy:   userID,
+		ExpiresAt:   time.Now().Add(invitationTTL),
+	}
+	if err := s.repo.CreateInvitation(ctx, invitation, hashInvitationToken(token)); err != nil {
+		return nil, "", err
+	}
+
+	s.logger.Info("Invitation created", zap.String("workspaceID", workspaceID.String()), zap.String("role", string(role)))
+	return invitation, token, nil
+}
+
+func (s *workspaceService) ListInvitations(ctx context.Context, userID, workspaceID uuid.UUID) ([]model.Invitation, error) {
+	if _, err := s.authorize(ctx, userID, workspaceID, model.RoleAdmin); err != nil {
+		return nil, err
+	}
+	return s.repo.ListInvitations(ctx, workspaceID)
+}
+
+func (s *workspaceService) RevokeInvitation(ctx context.Context, userID, workspaceID, invitationID uuid.UUID) error {
+	if _, err := s.authorize(ctx, userID, workspaceID, model.RoleAdmin); err != nil {
+		return err
+	}
+	return s.repo.DeleteInvitation(ctx, workspaceID, invitationID)
+}
+
+// AcceptInvitation joins the workspace of a pending invitation addressed to
+// the verified email of userID
+func (s *workspaceService) AcceptInvitation(ctx context.Context, userID uuid.UUID, token string) (*model.WorkspaceMember, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, repository.ErrInvitationNotFound
+	}
+
+	member, err := s.repo.AcceptInvitation(ctx, hashInvitationToken(token), userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Invitation accepted", zap.String("workspaceID", member.WorkspaceID.String()), zap.String("userID", userID.String()))
+	return member, nil
+}
+
+// authorize returns the role of userID in a workspace, failing when it is
+// below min. Non-members get ErrWorkspaceNotFound.
+func (s *workspaceService) authorize(ctx context.Context, userID, workspaceID uuid.UUID, min model.Role) (model.Role, error) {
+	role, err := s.repo.Role(ctx, workspaceID, userID)
+	if err != nil {
+		return "", err
+	}
+	if !role.AtLeast(min) {
+		return "", ErrForbidden
+	}
+	return role, nil
+}
+
+// checkManages makes sure an admin does not touch owners or hand out ownership
+func (s *workspaceService) checkManages(ctx context.Context, actorRole model.Role, workspaceID, memberID uuid.UUID, role model.Role) error {
+	if actorRole == model.RoleOwner {
+		return nil
+	}
+	if role == model.RoleOwner {
+		return ErrForbidden
+	}
+
+	current, err := s.repo.Role(ctx, workspaceID, memberID)
+	if errors.Is(err, repository.ErrWorkspaceNotFound) {
+		return repository.ErrMemberNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if current == model.RoleOwner {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// WithWorkspaces enables workspace-owned links and role checks on them.
+// Without it a link can only be managed by its creator.
+func WithWorkspaces(repo repository.WorkspaceRepository) URLServiceOption {
+	return func(s *URLService) {
+		s.workspaces = repo
+	}
+}
+
+// authorizeLink fails unless userID has at least min on the workspace
+// owning the link. Links the user cannot see at all are reported as not
+// found.
+func (s *URLService) authorizeLink(ctx context.Context, userID uuid.UUID, shortCode string, min model.Role) error {
+	var role model.Role
+	if s.workspaces != nil {
+		var err error
+		if role, err = s.workspaces.LinkRole(ctx, shortCode, userID); err != nil {
+			return err
+		}
+	} else {
+		link, err := s.repo.FindByID(ctx, shortCode)
+		if err != nil {
+			return err
+		}
+		if link.UserID == nil || *link.UserID != userID {
+			return repository.ErrURLNotFound
+		}
+		role = model.RoleOwner
+	}
+
+	if !role.AtLeast(min) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// workspaceForLink returns the workspace a new link of userID goes to: the
+// requested one when they may create links there, else their personal one
+func (s *URLService) workspaceForLink(ctx context.Context, userID uuid.UUID, workspaceID *uuid.UUID) (*uuid.UUID, error) {
+	return s.memberWorkspace(ctx, userID, workspaceID, model.RoleEditor)
+}
+
+// memberWorkspace returns workspaceID when userID has at least min there,
+// or their personal workspace when it is nil. It is nil without
+// workspaces.
+func (s *URLService) memberWorkspace(ctx context.Context, userID uuid.UUID, workspaceID *uuid.UUID, min model.Role) (*uuid.UUID, error) {
+	if s.workspaces == nil {
+		if workspaceID != nil {
+			return nil, repository.ErrWorkspaceNotFound
+		}
+		return nil, nil
+	}
+
+	if workspaceID == nil {
+		personal, err := s.workspaces.Personal(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		return &personal.ID, nil
+	}
+
+	role, err := s.workspaces.Role(ctx, *workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !role.AtLeast(min) {
+		return nil, ErrForbidden
+	}
+	return workspaceID, nil
+}
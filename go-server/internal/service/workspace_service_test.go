@@ -0,0 +1,418 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type storedInvitation struct {
+	model.Invitation
+	tokenHash string
+}
+
+type memoryWorkspaces struct {
+	workspaces  map[uuid.UUID]*model.Workspace
+	members     map[uuid.UUID]map[uuid.UUID]model.Role
+	invitations map[uuid.UUID]*storedInvitation
+	emails      map[uuid.UUID]string
+	verified    map[uuid.UUID]bool
+	links       map[string]uuid.UUID
+}
+
+func newMemoryWorkspaces() *memoryWorkspaces {
+	return &memoryWorkspaces{
+		workspaces:  make(map[uuid.UUID]*model.Workspace),
+		members:     make(map[uuid.UUID]map[uuid.UUID]model.Role),
+		invitations: make(map[uuid.UUID]*storedInvitation),
+		emails:      make(map[uuid.UUID]string),
+		verified:    make(map[uuid.UUID]bool),
+		links:       make(map[string]uuid.UUID),
+	}
+}
+
+func (m *memoryWorkspaces) Create(ctx context.Context, workspace *model.Workspace, ownerID uuid.UUID) error {
+	workspace.ID = uuid.New()
+	workspace.CreatedAt = time.Now()
+	stored := *workspace
+	m.workspaces[workspace.ID] = &stored
+	m.members[workspace.ID] = map[uuid.UUID]model.Role{ownerID: model.RoleOwner}
+	return nil
+}
+
+func (m *memoryWorkspaces) Personal(ctx context.Context, userID uuid.UUID) (*model.Workspace, error) {
+	for id, w := range m.workspaces {
+		if w.Personal && m.members[id][userID] == model.RoleOwner {
+			workspace := *w
+			return &workspace, nil
+		}
+	}
+	workspace := &model.Workspace{Name: "Personal", Personal: true}
+	if err := m.Create(ctx, workspace, userID); err != nil {
+		return nil, err
+	}
+	return workspace, nil
+}
+
+func (m *memoryWorkspaces) ListForUser(ctx context.Context, userID uuid.UUID) ([]model.Workspace, error) {
+	var workspaces []model.Workspace
+	for id, w := range m.workspaces {
+		if role, ok := m.members[id][userID]; ok {
+			workspace := *w
+			workspace.Role = role
+			workspaces = append(workspaces, workspace)
+		}
+	}
+	return workspaces, nil
+}
+
+func (m *memoryWorkspaces) Get(ctx context.Context, workspaceID uuid.UUID) (*model.Workspace, error) {
+	w, ok := m.workspaces[workspaceID]
+	if !ok {
+		return nil, repository.ErrWorkspaceNotFound
+	}
+	workspace := *w
+	return &workspace, nil
+}
+
+func (m *memoryWorkspaces) Role(ctx context.Context, workspaceID, userID uuid.UUID) (model.Role, error) {
+	role, ok := m.members[workspaceID][userID]
+	if !ok {
+		return "", repository.ErrWorkspaceNotFound
+	}
+	return role, nil
+}
+
+func (m *memoryWorkspaces) LinkRole(ctx context.Context, linkID string, userID uuid.UUID) (model.Role, error) {
+	workspaceID, ok := m.links[linkID]
+	if !ok {
+		return "", repository.ErrURLNotFound
+	}
+	role, ok := m.members[workspaceID][userID]
+	if !ok {
+		return "", repository.ErrURLNotFound
+	}
+	return role, nil
+}
+
+func (m *memoryWorkspaces) Members(ctx context.Context, workspaceID uuid.UUID) ([]model.WorkspaceMember, error) {
+	var members []model.WorkspaceMember
+	for userID, role := range m.members[workspaceID] {
+		members = append(members, model.WorkspaceMember{WorkspaceID: workspaceID, UserID: userID, Email: m.emails[userID], Role: role})
+	}
+	return members, nil
+}
+
+func (m *memoryWorkspaces) SetRole(ctx context.Context, workspaceID, userID uuid.UUID, role model.Role) error {
+	return m.changeMember(workspaceID, userID, role)
+}
+
+func (m *memoryWorkspaces) RemoveMember(ctx context.Context, workspaceID, userID uuid.UUID) error {
+	return m.changeMember(workspaceID, userID, "")
+}
+
+// changeMember sets the role of a member, removing them when role is empty
+func (m *memoryWorkspaces) changeMember(workspaceID, userID uuid.UUID, role model.Role) error {
+	members := m.members[workspaceID]
+	if _, ok := members[userID]; !ok {
+		return repository.ErrMemberNotFound
+	}
+	owners := 0
+	for id, r := range members {
+		if r == model.RoleOwner && id != userID {
+			owners++
+		}
+	}
+	if owners == 0 && role != model.RoleOwner {
+		return repository.ErrLastOwner
+	}
+	if role == "" {
+		delete(members, userID)
+	} else {
+		members[userID] = role
+	}
+	return nil
+}
+
+func (m *memoryWorkspaces) CreateInvitation(ctx context.Context, invitation *model.Invitation, tokenHash string) error {
+	invitation.ID = uuid.New()
+	invitation.CreatedAt = time.Now()
+	m.invitations[invitation.ID] = &storedInvitation{Invitation: *invitation, tokenHash: tokenHash}
+	return nil
+}
+
+func (m *memoryWorkspaces) ListInvitations(ctx context.Context, workspaceID uuid.UUID) ([]model.Invitation, error) {
+	var invitations []model.Invitation
+	for _, inv := range m.invitations {
+		if inv.WorkspaceID == workspaceID && inv.AcceptedAt == nil {
+			invitations = append(invitations, inv.Invitation)
+		}
+	}
+	return invitations, nil
+}
+
+func (m *memoryWorkspaces) DeleteInvitation(ctx context.Context, workspaceID, invitationID uuid.UUID) error {
+	inv, ok := m.invitations[invitationID]
+	if !ok || inv.WorkspaceID != workspaceID {
+		return repository.ErrInvitationNotFound
+	}
+	delete(m.invitations, invitationID)
+	return nil
+}
+
+func (m *memoryWorkspaces) AcceptInvitation(ctx context.Context, tokenHash string, userID uuid.UUID, now time.Time) (*model.WorkspaceMember, error) {
+	for _, inv := range m.invitations {
+		if inv.tokenHash != tokenHash || inv.AcceptedAt != nil || !now.Before(inv.ExpiresAt) ||
+			!strings.EqualFold(inv.Email, m.emails[userID]) || !m.verified[userID] {
+			continue
+		}
+		inv.AcceptedAt = &now
+		if _, ok := m.members[inv.WorkspaceID][userID]; !ok {
+			m.members[inv.WorkspaceID][userID] = inv.Role
+		}
+		return &model.WorkspaceMember{WorkspaceID: inv.WorkspaceID, UserID: userID, Role: m.members[inv.WorkspaceID][userID]}, nil
+	}
+	return nil, repository.ErrInvitationNotFound
+}
+
+// sharedWorkspace creates a team workspace owned by a new user and adds
+// one member per role given
+func sharedWorkspace(t *testing.T, repo *memoryWorkspaces, roles ...model.Role) (uuid.UUID, uuid.UUID, []uuid.UUID) {
+	t.Helper()
+	ownerID := uuid.New()
+	workspace := &model.Workspace{Name: "Team"}
+	require.NoError(t, repo.Create(context.Background(), workspace, ownerID))
+
+	var members []uuid.UUID
+	for _, role := range roles {
+		memberID := uuid.New()
+		repo.members[workspace.ID][memberID] = role
+		members = append(members, memberID)
+	}
+	return workspace.ID, ownerID, members
+}
+
+func TestListWorkspaces_CreatesPersonal(t *testing.T) {
+	svc := NewWorkspaceService(newMemoryWorkspaces())
+	userID := uuid.New()
+
+	workspaces, err := svc.ListWorkspaces(context.Background(), userID)
+	require.NoError(t, err)
+	require.Len(t, workspaces, 1)
+	assert.True(t, workspaces[0].Personal)
+	assert.Equal(t, model.RoleOwner, workspaces[0].Role)
+
+	// Listing again does not create another one
+	workspaces, err = svc.ListWorkspaces(context.Background(), userID)
+	require.NoError(t, err)
+	assert.Len(t, workspaces, 1)
+}
+
+func TestCreateWorkspace_InvalidName(t *testing.T) {
+	svc := NewWorkspaceService(newMemoryWorkspaces())
+
+	for _, name := range []string{"", "   ", strings.Repeat("a", maxWorkspaceNameLength+1)} {
+		_, err := svc.CreateWorkspace(context.Background(), uuid.New(), name)
+		assert.ErrorIs(t, err, ErrInvalidWorkspace)
+	}
+}
+
+func TestSetMemberRole_Permissions(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryWorkspaces()
+	svc := NewWorkspaceService(repo)
+	workspaceID, ownerID, ids := sharedWorkspace(t, repo, model.RoleAdmin, model.RoleEditor, model.RoleViewer)
+	adminID, editorID, viewerID := ids[0], ids[1], ids[2]
+
+	// Editors cannot manage members, outsiders do not see the workspace
+	assert.ErrorIs(t, svc.SetMemberRole(ctx, editorID, workspaceID, viewerID, model.RoleEditor), ErrForbidden)
+	assert.ErrorIs(t, svc.SetMemberRole(ctx, uuid.New(), workspaceID, viewerID, model.RoleEditor), repository.ErrWorkspaceNotFound)
+
+	// Admins manage editors and viewers but not ownership
+	assert.NoError(t, svc.SetMemberRole(ctx, adminID, workspaceID, viewerID, model.RoleEditor))
+	assert.ErrorIs(t, svc.SetMemberRole(ctx, adminID, workspaceID, editorID, model.RoleOwner), ErrForbidden)
+	assert.ErrorIs(t, svc.SetMemberRole(ctx, adminID, workspaceID, ownerID, model.RoleViewer), ErrForbidden)
+	assert.ErrorIs(t, svc.SetMemberRole(ctx, adminID, workspaceID, uuid.New(), model.RoleViewer), repository.ErrMemberNotFound)
+
+	assert.ErrorIs(t, svc.SetMemberRole(ctx, ownerID, workspaceID, editorID, "superuser"), ErrInvalidRole)
+	assert.NoError(t, svc.SetMemberRole(ctx, ownerID, workspaceID, editorID, model.RoleOwner))
+	assert.Equal(t, model.RoleOwner, repo.members[workspaceID][editorID])
+}
+
+func TestRemoveMember(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryWorkspaces()
+	svc := NewWorkspaceService(repo)
+	workspaceID, ownerID, ids := sharedWorkspace(t, repo, model.RoleAdmin, model.RoleViewer)
+	adminID, viewerID := ids[0], ids[1]
+
+	assert.ErrorIs(t, svc.RemoveMember(ctx, adminID, workspaceID, ownerID), ErrForbidden)
+
+	// Anyone can leave
+	require.NoError(t, svc.RemoveMember(ctx, viewerID, workspaceID, viewerID))
+	assert.NotContains(t, repo.members[workspaceID], viewerID)
+
+	// The last owner cannot leave or be demoted
+	assert.ErrorIs(t, svc.RemoveMember(ctx, ownerID, workspaceID, ownerID), repository.ErrLastOwner)
+	assert.ErrorIs(t, svc.SetMemberRole(ctx, ownerID, workspaceID, ownerID, model.RoleAdmin), repository.ErrLastOwner)
+
+	require.NoError(t, svc.RemoveMember(ctx, ownerID, workspaceID, adminID))
+	assert.Len(t, repo.members[workspaceID], 1)
+}
+
+func TestInvite_AcceptOnce(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryWorkspaces()
+	svc := NewWorkspaceService(repo)
+	workspaceID, ownerID, _ := sharedWorkspace(t, repo)
+
+	inviteeID := uuid.New()
+	repo.emails[inviteeID] = "Bob@Example.com"
+	repo.verified[inviteeID] = true
+
+	invitation, token, err := svc.Invite(ctx, ownerID, workspaceID, "bob@example.com", model.RoleEditor)
+	require.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, "bob@example.com", invitation.Email)
+	assert.NotEqual(t, token, repo.invitations[invitation.ID].tokenHash)
+
+	// Someone else holding the token cannot use it
+	strangerID := uuid.New()
+	repo.emails[strangerID] = "eve@example.com"
+	_, err = svc.AcceptInvitation(ctx, strangerID, token)
+	assert.ErrorIs(t, err, repository.ErrInvitationNotFound)
+
+	member, err := svc.AcceptInvitation(ctx, inviteeID, token)
+	require.NoError(t, err)
+	assert.Equal(t, model.RoleEditor, member.Role)
+	assert.Equal(t, workspaceID, member.WorkspaceID)
+
+	// Tokens cannot be replayed
+	_, err = svc.AcceptInvitation(ctx, inviteeID, token)
+	assert.ErrorIs(t, err, repository.ErrInvitationNotFound)
+}
+
+func TestInvite_Expired(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryWorkspaces()
+	svc := NewWorkspaceService(repo)
+	workspaceID, ownerID, _ := sharedWorkspace(t, repo)
+	inviteeID := uuid.New()
+	repo.emails[inviteeID] = "bob@example.com"
+	repo.verified[inviteeID] = true
+
+	invitation, token, err := svc.Invite(ctx, ownerID, workspaceID, "bob@example.com", model.RoleViewer)
+	require.NoError(t, err)
+	repo.invitations[invitation.ID].ExpiresAt = time.Now().Add(-time.Minute)
+
+	_, err = svc.AcceptInvitation(ctx, inviteeID, token)
+	assert.ErrorIs(t, err, repository.ErrInvitationNotFound)
+}
+
+func TestInvite_RequiresVerifiedEmail(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryWorkspaces()
+	svc := NewWorkspaceService(repo)
+	workspaceID, ownerID, _ := sharedWorkspace(t, repo)
+
+	// Registering with the invited address does not prove owning it
+	inviteeID := uuid.New()
+	repo.emails[inviteeID] = "bob@example.com"
+
+	_, token, err := svc.Invite(ctx, ownerID, workspaceID, "bob@example.com", model.RoleAdmin)
+	require.NoError(t, err)
+
+	_, err = svc.AcceptInvitation(ctx, inviteeID, token)
+	assert.ErrorIs(t, err, repository.ErrInvitationNotFound)
+	assert.NotContains(t, repo.members[workspaceID], inviteeID)
+
+	// The invitation stays open until the address is verified
+	repo.verified[inviteeID] = true
+	member, err := svc.AcceptInvitation(ctx, inviteeID, token)
+	require.NoError(t, err)
+	assert.Equal(t, model.RoleAdmin, member.Role)
+}
+
+func TestInvite_Rules(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryWorkspaces()
+	svc := NewWorkspaceService(repo)
+	workspaceID, ownerID, ids := sharedWorkspace(t, repo, model.RoleAdmin, model.RoleEditor)
+
+	_, _, err := svc.Invite(ctx, ids[1], workspaceID, "bob@example.com", model.RoleViewer)
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, _, err = svc.Invite(ctx, ids[0], workspaceID, "bob@example.com", model.RoleOwner)
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, _, err = svc.Invite(ctx, ownerID, workspaceID, "Bob <bob@example.com>", model.RoleViewer)
+	assert.ErrorIs(t, err, ErrInvalidEmail)
+
+	personal, err := repo.Personal(ctx, ownerID)
+	require.NoError(t, err)
+	_, _, err = svc.Invite(ctx, ownerID, personal.ID, "bob@example.com", model.RoleViewer)
+	assert.ErrorIs(t, err, ErrPersonalWorkspace)
+}
+
+func TestURLService_WorkspaceRoles(t *testing.T) {
+	ctx := context.Background()
+	workspaces := newMemoryWorkspaces()
+	mockRepo := new(MockURLRepository)
+	svc := NewURLService(mockRepo, WithWorkspaces(workspaces))
+	workspaceID, _, ids := sharedWorkspace(t, workspaces, model.RoleEditor, model.RoleViewer)
+	editorID, viewerID := ids[0], ids[1]
+	workspaces.links["abc123"] = workspaceID
+
+	title := "Shared"
+	mockRepo.On("UpdateDetails", ctx, "abc123", repository.URLChanges{Title: &title}).Return(nil)
+
+	assert.NoError(t, svc.UpdateURL(ctx, editorID, "abc123", URLUpdate{Title: &title}))
+	assert.ErrorIs(t, svc.UpdateURL(ctx, viewerID, "abc123", URLUpdate{Title: &title}), ErrForbidden)
+	assert.ErrorIs(t, svc.UpdateURL(ctx, uuid.New(), "abc123", URLUpdate{Title: &title}), repository.ErrURLNotFound)
+	mockRepo.AssertNumberOfCalls(t, "UpdateDetails", 1)
+
+	// Viewers list the workspace links, outsiders cannot
+	filter := repository.URLFilter{WorkspaceID: &workspaceID}
+	mockRepo.On("GetUserURLs", ctx, viewerID, filter).Return([]model.URL{{ID: "abc123"}}, nil)
+	urls, err := svc.GetUserURLs(ctx, viewerID, filter)
+	require.NoError(t, err)
+	assert.Len(t, urls, 1)
+
+	_, err = svc.GetUserURLs(ctx, uuid.New(), filter)
+	assert.ErrorIs(t, err, repository.ErrWorkspaceNotFound)
+}
+
+func TestShortenURL_Workspace(t *testing.T) {
+	ctx := context.Background()
+	workspaces := newMemoryWorkspaces()
+	mockRepo := new(MockURLRepository)
+	svc := NewURLService(mockRepo, WithWorkspaces(workspaces))
+	workspaceID, _, ids := sharedWorkspace(t, workspaces, model.RoleEditor, model.RoleViewer)
+	editorID, viewerID := ids[0], ids[1]
+
+	mockRepo.On("IDExists", ctx, mock.AnythingOfType("string")).Return(false, nil)
+	mockRepo.On("CreateOrGet", ctx, mock.MatchedBy(func(u *model.URL) bool {
+		return u.WorkspaceID != nil && *u.WorkspaceID == workspaceID
+	})).Return("abc123", true, nil)
+
+	link, _, err := svc.ShortenURL(ctx, "https://example.com", &editorID, ShortenOptions{WorkspaceID: &workspaceID})
+	require.NoError(t, err)
+	assert.Equal(t, workspaceID, *link.WorkspaceID)
+
+	_, _, err = svc.ShortenURL(ctx, "https://example.com", &viewerID, ShortenOptions{WorkspaceID: &workspaceID})
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	// Without a workspace links go to the personal one
+	mockRepo.On("CreateOrGet", ctx, mock.Anything).Return("def456", true, nil)
+	link, _, err = svc.ShortenURL(ctx, "https://example.org", &viewerID, ShortenOptions{})
+	require.NoError(t, err)
+	personal, err := workspaces.Personal(ctx, viewerID)
+	require.NoError(t, err)
+	assert.Equal(t, personal.ID, *link.WorkspaceID)
+}
@@ -0,0 +1,54 @@
+package token
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ClaimTokenTTL is how long after creating a link anonymously it can be
+// claimed
+const ClaimTokenTTL = 30 * 24 * time.Hour
+
+// claimAudience keeps claim tokens from being accepted anywhere else
+const claimAudience = "link-claim"
+
+var ErrInvalidClaim = errors.New("invalid claim token")
+
+// ClaimClaims identify an anonymous link by its id (Subject). The random
+// ID (jti) is only valid until the link is claimed.
+type ClaimClaims struct {
+	jwt.RegisteredClaims
+}
+
+func GenerateClaimToken(linkID, nonce string) (string, error) {
+	now := time.Now()
+	claims := ClaimClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   linkID,
+			ID:        nonce,
+			Audience:  jwt.ClaimStrings{claimAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ClaimTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(secret)
+}
+
+func ValidateClaimToken(tokenStr string) (*ClaimClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &ClaimClaims{}, func(t *jwt.Token) (interface{}, error) {
+		return secret, nil
+	}, jwt.WithAudience(claimAudience), jwt.WithExpirationRequired(), jwt.WithValidMethods([]string{"HS256"}))
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*ClaimClaims)
+	if !ok || !token.Valid || claims.Subject == "" || claims.ID == "" {
+		return nil, ErrInvalidClaim
+	}
+	return claims, nil
+}
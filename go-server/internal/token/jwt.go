@@ -9,19 +9,23 @@ import (
 
 var secret = []byte("SUPER_SECRET_KEY")
 
+// CustomClaims identify a session of UserID. Bumping the account's session
+// version revokes every session issued before.
 type CustomClaims struct {
-	UserID *uuid.UUID `json:"user_id"`
+	UserID         *uuid.UUID `json:"user_id"`
+	SessionVersion int        `json:"sv,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID string) (string, error) {
+func GenerateToken(userID string, sessionVersion int) (string, error) {
 	parsedUserID, err := uuid.Parse(userID)
 	if err != nil {
 		return "", err
 	}
 
 	claims := CustomClaims{
-		UserID: &parsedUserID,
+		UserID:         &parsedUserID,
+		SessionVersion: sessionVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -41,7 +45,8 @@ func ValidateToken(tokenStr string) (*CustomClaims, error) {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*CustomClaims); ok && token.Valid {
+	// Tokens without a user, such as claim tokens, do not authenticate anyone
+	if claims, ok := token.Claims.(*CustomClaims); ok && token.Valid && claims.UserID != nil {
 		return claims, nil
 	}
 
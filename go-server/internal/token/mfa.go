@@ -0,0 +1,67 @@
+package token
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// MFATokenTTL is how long after the password step a login can be completed
+// with a second factor
+const MFATokenTTL = 5 * time.Minute
+
+// mfaAudience keeps MFA challenge tokens from being accepted anywhere else
+const mfaAudience = "mfa-challenge"
+
+var ErrInvalidMFAToken = errors.New("invalid MFA challenge token")
+
+// MFAClaims identify the account (Subject) whose password was checked. The
+// account is not signed in until a second factor completes the login, so
+// the user id is not where ValidateToken looks for it.
+type MFAClaims struct {
+	SessionVersion int `json:"sv,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func GenerateMFAToken(userID string, sessionVersion int) (string, error) {
+	parsedUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := MFAClaims{
+		SessionVersion: sessionVersion,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   parsedUserID.String(),
+			Audience:  jwt.ClaimStrings{mfaAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(MFATokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(secret)
+}
+
+// ValidateMFAToken returns the account and session version of a challenge
+func ValidateMFAToken(tokenStr string) (uuid.UUID, int, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &MFAClaims{}, func(t *jwt.Token) (interface{}, error) {
+		return secret, nil
+	}, jwt.WithAudience(mfaAudience), jwt.WithExpirationRequired(), jwt.WithValidMethods([]string{"HS256"}))
+	if err != nil {
+		return uuid.Nil, 0, err
+	}
+
+	claims, ok := token.Claims.(*MFAClaims)
+	if !ok || !token.Valid {
+		return uuid.Nil, 0, ErrInvalidMFAToken
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, 0, ErrInvalidMFAToken
+	}
+	return userID, claims.SessionVersion, nil
+}
@@ -0,0 +1,119 @@
+// Package useragent classifies User-Agent headers into the coarse OS, device
+// and browser families used by link routing rules.
+package useragent
+
+import "strings"
+
+// Operating system families
+const (
+	OSIOS      = "ios"
+	OSAndroid  = "android"
+	OSWindows  = "windows"
+	OSMacOS    = "macos"
+	OSLinux    = "linux"
+	OSChromeOS = "chromeos"
+	OSOther    = "other"
+)
+
+// Device classes
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+)
+
+// Browser families
+const (
+	BrowserChrome  = "chrome"
+	BrowserSafari  = "safari"
+	BrowserFirefox = "firefox"
+	BrowserEdge    = "edge"
+	BrowserOpera   = "opera"
+	BrowserSamsung = "samsung"
+	BrowserOther   = "other"
+)
+
+var (
+	OSes     = []string{OSIOS, OSAndroid, OSWindows, OSMacOS, OSLinux, OSChromeOS, OSOther}
+	Devices  = []string{DeviceMobile, DeviceTablet, DeviceDesktop, DeviceBot}
+	Browsers = []string{BrowserChrome, BrowserSafari, BrowserFirefox, BrowserEdge, BrowserOpera, BrowserSamsung, BrowserOther}
+)
+
+// Client is the classification of a single User-Agent
+type Client struct {
+	OS      string
+	Device  string
+	Browser string
+}
+
+var botMarkers = []string{"bot", "crawler", "spider", "slurp", "facebookexternalhit", "curl/", "wget/", "python-requests", "go-http-client"}
+
+// Parse classifies a User-Agent header. Unknown values fall back to
+// OSOther, DeviceDesktop and BrowserOther.
+func Parse(ua string) Client {
+	s := strings.ToLower(ua)
+	return Client{
+		OS:      parseOS(s),
+		Device:  parseDevice(s),
+		Browser: parseBrowser(s),
+	}
+}
+
+func parseOS(s string) string {
+	switch {
+	case strings.Contains(s, "iphone"), strings.Contains(s, "ipad"), strings.Contains(s, "ipod"):
+		return OSIOS
+	case strings.Contains(s, "android"):
+		return OSAndroid
+	case strings.Contains(s, "windows"):
+		return OSWindows
+	case strings.Contains(s, "cros"):
+		return OSChromeOS
+	case strings.Contains(s, "macintosh"), strings.Contains(s, "mac os x"):
+		return OSMacOS
+	case strings.Contains(s, "linux"):
+		return OSLinux
+	default:
+		return OSOther
+	}
+}
+
+func parseDevice(s string) string {
+	for _, marker := range botMarkers {
+		if strings.Contains(s, marker) {
+			return DeviceBot
+		}
+	}
+	switch {
+	case strings.Contains(s, "ipad"), strings.Contains(s, "tablet"):
+		return DeviceTablet
+	// Android tablets omit the "Mobile" token
+	case strings.Contains(s, "android") && !strings.Contains(s, "mobile"):
+		return DeviceTablet
+	case strings.Contains(s, "mobile"), strings.Contains(s, "iphone"), strings.Contains(s, "ipod"):
+		return DeviceMobile
+	default:
+		return DeviceDesktop
+	}
+}
+
+func parseBrowser(s string) string {
+	// Order matters: most browsers also claim to be Chrome and Safari
+	switch {
+	case strings.Contains(s, "edg/"), strings.Contains(s, "edga/"), strings.Contains(s, "edgios/"):
+		return BrowserEdge
+	case strings.Contains(s, "opr/"), strings.Contains(s, "opera"):
+		return BrowserOpera
+	case strings.Contains(s, "samsungbrowser/"):
+		return BrowserSamsung
+	case strings.Contains(s, "firefox/"), strings.Contains(s, "fxios/"):
+		return BrowserFirefox
+	case strings.Contains(s, "chrome/"), strings.Contains(s, "crios/"):
+		return BrowserChrome
+	case strings.Contains(s, "safari/"):
+		return BrowserSafari
+	default:
+		return BrowserOther
+	}
+}
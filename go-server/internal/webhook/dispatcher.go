@@ -0,0 +1,273 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/safehttp"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultInterval     = 5 * time.Second
+	defaultBatchSize    = 100
+	defaultConcurrency  = 8
+	defaultTimeout      = 10 * time.Second
+	defaultMaxAttempts  = 10
+	defaultBaseBackoff  = 30 * time.Second
+	defaultMaxBackoff   = 12 * time.Hour
+	maxErrorLength      = 500
+	maxResponseDrain    = 64 << 10
+	userAgent           = "TinyUrlWebhooks/1.0 (+https://fonsecaaso.com)"
+	deliveryLeaseMargin = 30 * time.Second
+)
+
+// Store hands out deliveries that are due and records their outcome
+type Store interface {
+	ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]model.WebhookDelivery, error)
+	SaveDeliveryAttempt(ctx context.Context, delivery *model.WebhookDelivery) error
+}
+
+// DispatcherConfig controls how often the queue is polled and how failed
+// deliveries are retried. The n-th retry waits BaseBackoff * 2^(n-1), at
+// most MaxBackoff; after MaxAttempts a delivery is marked failed.
+type DispatcherConfig struct {
+	Interval    time.Duration
+	BatchSize   int
+	Concurrency int
+	Timeout     time.Duration
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// AllowPrivateNetworks lets tests deliver to httptest servers on loopback
+	AllowPrivateNetworks bool
+}
+
+// Dispatcher sends queued deliveries. Claimed deliveries are leased, so
+// every replica may run one.
+type Dispatcher struct {
+	store  Store
+	client *http.Client
+	cfg    DispatcherConfig
+	now    func() time.Time
+	wg     sync.WaitGroup
+	logger *zap.Logger
+}
+
+func NewDispatcher(store Store, cfg DispatcherConfig) *Dispatcher {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaultConcurrency
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = defaultBaseBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultMaxBackoff
+	}
+
+	client := safehttp.NewClient(safehttp.Config{
+		Timeout:              cfg.Timeout,
+		AllowPrivateNetworks: cfg.AllowPrivateNetworks,
+	})
+	// A redirect is not a successful delivery
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &Dispatcher{
+		store:  store,
+		client: client,
+		cfg:    cfg,
+		now:    time.Now,
+		logger: zap.L().With(zap.String("component", "WebhookDispatcher")),
+	}
+}
+
+// Start sends due deliveries every Interval until ctx is cancelled
+func (d *Dispatcher) Start(ctx context.Context) {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(d.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := d.RunOnce(ctx); err != nil {
+					d.logger.Warn("Webhook delivery round failed", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// Wait blocks until the round in progress, if any, has finished after ctx
+// was cancelled. Deliveries it did not get to stay due for the next start.
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// RunOnce sends one batch of due deliveries
+func (d *Dispatcher) RunOnce(ctx context.Context) error {
+	now := d.now()
+	// Long enough for a full batch to go out before another replica may
+	// pick up the same deliveries
+	lease := d.cfg.Timeout*time.Duration(d.cfg.BatchSize/d.cfg.Concurrency+1) + deliveryLeaseMargin
+	deliveries, err := d.store.ClaimDueDeliveries(ctx, now, now.Add(lease), d.cfg.BatchSize)
+	if err != nil {
+		return err
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	sem := make(chan struct{}, d.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(delivery model.WebhookDelivery) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			d.Deliver(ctx, &delivery)
+		}(delivery)
+	}
+	wg.Wait()
+
+	d.logger.Info("Webhook delivery round completed", zap.Int("deliveries", len(deliveries)))
+	return nil
+}
+
+// Deliver makes one attempt at sending delivery, updates its status and
+// schedules the next attempt on failure
+func (d *Dispatcher) Deliver(ctx context.Context, delivery *model.WebhookDelivery) {
+	status, err := d.send(ctx, delivery)
+	now := d.now()
+
+	delivery.Attempts++
+	delivery.LastStatusCode = nil
+	if status != 0 {
+		delivery.LastStatusCode = &status
+	}
+	delivery.LastError = ""
+
+	switch {
+	case err == nil && status >= 200 && status < 300:
+		delivery.Status = model.DeliverySucceeded
+		delivery.DeliveredAt = &now
+	default:
+		if err != nil {
+			delivery.LastError = truncate(describeError(err), maxErrorLength)
+		} else {
+			delivery.LastError = "unexpected status " + strconv.Itoa(status)
+		}
+		if delivery.Attempts >= d.cfg.MaxAttempts {
+			delivery.Status = model.DeliveryFailed
+		} else {
+			delivery.Status = model.DeliveryPending
+			delivery.NextAttemptAt = now.Add(d.Backoff(delivery.Attempts))
+		}
+	}
+
+	if err := d.store.SaveDeliveryAttempt(ctx, delivery); err != nil {
+		d.logger.Warn("Failed to save delivery attempt", zap.Error(err), zap.String("delivery_id", delivery.ID.String()))
+	}
+	if delivery.Status == model.DeliveryFailed {
+		d.logger.Info("Webhook delivery failed for good", zap.String("delivery_id", delivery.ID.String()),
+			zap.String("webhook_id", delivery.WebhookID.String()), zap.String("error", delivery.LastError))
+	}
+}
+
+// Backoff returns how long to wait before the retry following attempt
+func (d *Dispatcher) Backoff(attempt int) time.Duration {
+	backoff := d.cfg.BaseBackoff
+	for i := 1; i < attempt && backoff < d.cfg.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, d.cfg.MaxBackoff)
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery *model.WebhookDelivery) (int, error) {
+	target, err := url.Parse(delivery.URL)
+	if err != nil {
+		return 0, err
+	}
+	if err := safehttp.CheckURL(target); err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	sentAt := d.now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(EventHeader, string(delivery.Event))
+	req.Header.Set(DeliveryHeader, delivery.ID.String())
+	req.Header.Set(TimestampHeader, strconv.FormatInt(sentAt.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, sentAt, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	// Reading a little of the body lets the connection be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseDrain))
+
+	return resp.StatusCode, nil
+}
+
+func describeError(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout"
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if urlErr.Timeout() {
+			return "timeout"
+		}
+		return urlErr.Err.Error()
+	}
+	return err.Error()
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,313 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is an in-memory delivery queue
+type memoryStore struct {
+	mu         sync.Mutex
+	deliveries []model.WebhookDelivery
+	saved      []model.WebhookDelivery
+	events     []json.RawMessage
+	err        error
+}
+
+func (m *memoryStore) ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]model.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []model.WebhookDelivery
+	for i := range m.deliveries {
+		d := &m.deliveries[i]
+		if d.Status == model.DeliveryPending && !d.NextAttemptAt.After(now) && len(due) < limit {
+			d.NextAttemptAt = leaseUntil
+			due = append(due, *d)
+		}
+	}
+	return due, nil
+}
+
+func (m *memoryStore) SaveDeliveryAttempt(ctx context.Context, delivery *model.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.saved = append(m.saved, *delivery)
+	for i := range m.deliveries {
+		if m.deliveries[i].ID == delivery.ID {
+			m.deliveries[i] = *delivery
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) EnqueueEvent(ctx context.Context, owner model.LinkOwner, event model.WebhookEvent, payload json.RawMessage) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return 0, m.err
+	}
+	m.events = append(m.events, payload)
+	return 1, nil
+}
+
+func newTestDispatcher(store Store) *Dispatcher {
+	return NewDispatcher(store, DispatcherConfig{
+		Timeout:              time.Second,
+		MaxAttempts:          3,
+		BaseBackoff:          time.Minute,
+		MaxBackoff:           time.Hour,
+		AllowPrivateNetworks: true,
+	})
+}
+
+func newDelivery(target string) model.WebhookDelivery {
+	return model.WebhookDelivery{
+		ID:        uuid.New(),
+		WebhookID: uuid.New(),
+		Event:     model.EventLinkCreated,
+		Payload:   json.RawMessage(`{"event":"link.created","data":{"id":"abc123"}}`),
+		Status:    model.DeliveryPending,
+		URL:       target,
+		Secret:    "whsec_test",
+	}
+}
+
+func TestDeliver_SignsRequest(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	store := &memoryStore{}
+	delivery := newDelivery(srv.URL + "/hooks")
+	newTestDispatcher(store).Deliver(context.Background(), &delivery)
+
+	require.NotNil(t, got)
+	assert.Equal(t, http.MethodPost, got.Method)
+	assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+	assert.Equal(t, "link.created", got.Header.Get(EventHeader))
+	assert.Equal(t, delivery.ID.String(), got.Header.Get(DeliveryHeader))
+	assert.JSONEq(t, string(delivery.Payload), string(body))
+
+	// What a receiver does to check the request
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte(got.Header.Get(TimestampHeader) + "." + string(body)))
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), got.Header.Get(SignatureHeader))
+	ts, err := strconv.ParseInt(got.Header.Get(TimestampHeader), 10, 64)
+	require.NoError(t, err)
+	assert.True(t, Verify("whsec_test", time.Unix(ts, 0), body, got.Header.Get(SignatureHeader)))
+	assert.False(t, Verify("other", time.Unix(ts, 0), body, got.Header.Get(SignatureHeader)))
+
+	assert.Equal(t, model.DeliverySucceeded, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	require.NotNil(t, delivery.LastStatusCode)
+	assert.Equal(t, http.StatusNoContent, *delivery.LastStatusCode)
+	assert.NotNil(t, delivery.DeliveredAt)
+	assert.Len(t, store.saved, 1)
+}
+
+func TestDeliver_RetriesWithBackoff(t *testing.T) {
+	testCases := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+	}{
+		{"server error", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) }, http.StatusInternalServerError},
+		{"redirect", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/elsewhere", http.StatusFound) }, http.StatusFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(tc.handler)
+			defer srv.Close()
+
+			now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+			d := newTestDispatcher(&memoryStore{})
+			d.now = func() time.Time { return now }
+
+			delivery := newDelivery(srv.URL)
+			delivery.Attempts = 1
+			d.Deliver(context.Background(), &delivery)
+
+			assert.Equal(t, model.DeliveryPending, delivery.Status)
+			assert.Equal(t, 2, delivery.Attempts)
+			assert.Equal(t, now.Add(2*time.Minute), delivery.NextAttemptAt)
+			require.NotNil(t, delivery.LastStatusCode)
+			assert.Equal(t, tc.status, *delivery.LastStatusCode)
+			assert.Contains(t, delivery.LastError, strconv.Itoa(tc.status))
+			assert.Nil(t, delivery.DeliveredAt)
+		})
+	}
+}
+
+func TestDeliver_GivesUpAfterMaxAttempts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	delivery := newDelivery(srv.URL)
+	delivery.Attempts = 2
+	newTestDispatcher(&memoryStore{}).Deliver(context.Background(), &delivery)
+
+	assert.Equal(t, model.DeliveryFailed, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+}
+
+func TestDeliver_UnreachableEndpoint(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	target := srv.URL
+	srv.Close()
+
+	delivery := newDelivery(target)
+	newTestDispatcher(&memoryStore{}).Deliver(context.Background(), &delivery)
+
+	assert.Equal(t, model.DeliveryPending, delivery.Status)
+	assert.Nil(t, delivery.LastStatusCode)
+	assert.NotEmpty(t, delivery.LastError)
+}
+
+func TestDeliver_BlocksPrivateNetworks(t *testing.T) {
+	hit := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hit = true }))
+	defer srv.Close()
+
+	d := NewDispatcher(&memoryStore{}, DispatcherConfig{Timeout: time.Second})
+	delivery := newDelivery(srv.URL)
+	d.Deliver(context.Background(), &delivery)
+
+	assert.False(t, hit)
+	assert.Equal(t, model.DeliveryPending, delivery.Status)
+	assert.Contains(t, delivery.LastError, "not allowed")
+}
+
+func TestRunOnce_SendsDueDeliveries(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received[r.Header.Get(DeliveryHeader)]++
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	due, later, done := newDelivery(srv.URL), newDelivery(srv.URL), newDelivery(srv.URL)
+	later.NextAttemptAt = time.Now().Add(time.Hour)
+	done.Status = model.DeliverySucceeded
+	store := &memoryStore{deliveries: []model.WebhookDelivery{due, later, done}}
+	d := newTestDispatcher(store)
+
+	require.NoError(t, d.RunOnce(context.Background()))
+	// The delivery is no longer due
+	require.NoError(t, d.RunOnce(context.Background()))
+
+	assert.Equal(t, map[string]int{due.ID.String(): 1}, received)
+	assert.Equal(t, model.DeliverySucceeded, store.deliveries[0].Status)
+	assert.Equal(t, model.DeliveryPending, store.deliveries[1].Status)
+}
+
+func TestBackoff(t *testing.T) {
+	d := newTestDispatcher(&memoryStore{})
+
+	assert.Equal(t, time.Minute, d.Backoff(1))
+	assert.Equal(t, 2*time.Minute, d.Backoff(2))
+	assert.Equal(t, 32*time.Minute, d.Backoff(6))
+	assert.Equal(t, time.Hour, d.Backoff(7))
+	assert.Equal(t, time.Hour, d.Backoff(100))
+}
+
+func TestEmitter_QueuesEnvelope(t *testing.T) {
+	store := &memoryStore{}
+	emitter := NewEmitter(store)
+
+	ownerID := uuid.New()
+	err := emitter.Emit(context.Background(), model.LinkOwner{UserID: &ownerID}, model.EventLinkClicked, model.Click{URLID: "abc123"})
+	require.NoError(t, err)
+
+	require.Len(t, store.events, 1)
+	var envelope model.WebhookEnvelope
+	require.NoError(t, json.Unmarshal(store.events[0], &envelope))
+	assert.NotEqual(t, uuid.Nil, envelope.ID)
+	assert.Equal(t, model.EventLinkClicked, envelope.Event)
+	assert.False(t, envelope.OccurredAt.IsZero())
+	assert.Contains(t, string(envelope.Data), `"url_id":"abc123"`)
+}
+
+func TestEmitter_ReturnsQueueError(t *testing.T) {
+	store := &memoryStore{err: errors.New("connection refused")}
+	emitter := NewEmitter(store)
+
+	ownerID := uuid.New()
+	err := emitter.Emit(context.Background(), model.LinkOwner{UserID: &ownerID}, model.EventLinkClicked, model.Click{URLID: "abc123"})
+	assert.ErrorIs(t, err, store.err)
+}
+
+// inlineTx runs fn without a transaction, counting the calls
+type inlineTx struct {
+	calls int
+}
+
+func (t *inlineTx) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	t.calls++
+	return fn(ctx)
+}
+
+type memoryClicks struct {
+	clicks []model.Click
+}
+
+func (m *memoryClicks) Record(ctx context.Context, click *model.Click) error {
+	m.clicks = append(m.clicks, *click)
+	return nil
+}
+
+func TestClickStore_RecordsClickWithEvent(t *testing.T) {
+	store := &memoryStore{}
+	tx := &inlineTx{}
+	clicks := &memoryClicks{}
+	clickStore := NewClickStore(tx, clicks, NewEmitter(store))
+	ctx := context.Background()
+	ownerID := uuid.New()
+
+	require.NoError(t, clickStore.Record(ctx, &model.Click{URLID: "abc123", Owner: model.LinkOwner{UserID: &ownerID}}))
+	require.NoError(t, clickStore.Record(ctx, &model.Click{URLID: "def456"}))
+
+	assert.Equal(t, 2, tx.calls)
+	assert.Len(t, clicks.clicks, 2)
+	// The anonymous link's click has nobody to notify
+	require.Len(t, store.events, 1)
+	assert.Contains(t, string(store.events[0]), `"url_id":"abc123"`)
+	assert.NotContains(t, string(store.events[0]), ownerID.String())
+}
+
+func TestClickStore_FailsWhenEventCannotBeQueued(t *testing.T) {
+	store := &memoryStore{err: errors.New("connection refused")}
+	clickStore := NewClickStore(&inlineTx{}, &memoryClicks{}, NewEmitter(store))
+	ownerID := uuid.New()
+
+	err := clickStore.Record(context.Background(), &model.Click{URLID: "abc123", Owner: model.LinkOwner{UserID: &ownerID}})
+	assert.ErrorIs(t, err, store.err)
+}
@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/fonsecaaso/TinyUrl/go-server/internal/model"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// EventStore queues an event for the webhooks of a link's owner
+type EventStore interface {
+	EnqueueEvent(ctx context.Context, owner model.LinkOwner, event model.WebhookEvent, payload json.RawMessage) (int, error)
+}
+
+// Emitter writes events to the persistent delivery queue. Called with the
+// ctx of a transaction, the deliveries commit or roll back with the change
+// they report, so none is lost or sent for a change that did not happen.
+type Emitter struct {
+	store  EventStore
+	logger *zap.Logger
+}
+
+func NewEmitter(store EventStore) *Emitter {
+	return &Emitter{
+		store:  store,
+		logger: zap.L().With(zap.String("component", "WebhookEmitter")),
+	}
+}
+
+// Emit queues event on a link of owner. data becomes the data field of the
+// delivered envelope.
+func (e *Emitter) Emit(ctx context.Context, owner model.LinkOwner, event model.WebhookEvent, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", event, err)
+	}
+
+	payload, err := json.Marshal(model.WebhookEnvelope{
+		ID:         uuid.New(),
+		Event:      event,
+		OccurredAt: time.Now().UTC(),
+		Data:       raw,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s envelope: %w", event, err)
+	}
+	if _, err := e.store.EnqueueEvent(ctx, owner, event, payload); err != nil {
+		e.logger.Error("Failed to queue webhook event", zap.Error(err), zap.String("event", string(event)))
+		return err
+	}
+	return nil
+}
+
+// Transactor runs fn in one database transaction, which the stores fn
+// calls with its ctx join
+type Transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// ClickWriter persists a single click
+type ClickWriter interface {
+	Record(ctx context.Context, click *model.Click) error
+}
+
+// ClickStore records clicks together with their link.clicked event, so
+// webhooks hear of exactly the clicks that were recorded
+type ClickStore struct {
+	tx      Transactor
+	clicks  ClickWriter
+	emitter *Emitter
+}
+
+func NewClickStore(tx Transactor, clicks ClickWriter, emitter *Emitter) *ClickStore {
+	return &ClickStore{tx: tx, clicks: clicks, emitter: emitter}
+}
+
+func (s *ClickStore) Record(ctx context.Context, click *model.Click) error {
+	return s.tx.InTx(ctx, func(ctx context.Context) error {
+		if err := s.clicks.Record(ctx, click); err != nil {
+			return err
+		}
+		// Anonymous links have nobody to notify
+		if click.Owner.WorkspaceID == nil && click.Owner.UserID == nil {
+			return nil
+		}
+		return s.emitter.Emit(ctx, click.Owner, model.EventLinkClicked, click)
+	})
+}
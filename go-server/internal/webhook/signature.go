@@ -0,0 +1,37 @@
+// Package webhook notifies the endpoints accounts registered of changes to
+// and clicks on their links. Events are queued in the database and sent in
+// the background, with retries, so neither side has to be up at the same time.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	EventHeader     = "X-TinyUrl-Event"
+	DeliveryHeader  = "X-TinyUrl-Delivery"
+	TimestampHeader = "X-TinyUrl-Timestamp"
+	SignatureHeader = "X-TinyUrl-Signature"
+	signaturePrefix = "sha256="
+)
+
+// Sign returns the signature header value of body sent at timestamp: the
+// hex HMAC-SHA256 of "<unix timestamp>.<body>" keyed with the secret.
+// Covering the timestamp lets receivers reject old, replayed requests.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature header the way receivers are expected to
+func Verify(secret string, timestamp time.Time, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
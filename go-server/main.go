@@ -68,7 +68,13 @@ func main() {
 		obs.Logger.Info("redis connection established")
 	}
 
-	r := route.SetupRouter(redisClient, pgClient, obs.PrometheusHandler)
+	// Background workers stop once the server no longer takes requests
+	workersCtx, stopWorkers := context.WithCancel(ctx)
+	defer stopWorkers()
+	r, workers, err := route.SetupRouter(workersCtx, redisClient, pgClient, obs.PrometheusHandler)
+	if err != nil {
+		obs.Logger.Fatal("failed to set up the API", zap.Error(err))
+	}
 	obs.Logger.Info("starting server on :8080")
 
 	// Create HTTP server with explicit configuration
@@ -101,5 +107,18 @@ func main() {
 		obs.Logger.Error("server forced to shutdown", zap.Error(err))
 	}
 
+	// Let workers write what they still hold, e.g. queued clicks
+	stopWorkers()
+	workersDone := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(workersDone)
+	}()
+	select {
+	case <-workersDone:
+	case <-shutdownCtx.Done():
+		obs.Logger.Error("background workers did not stop in time")
+	}
+
 	obs.Logger.Info("server stopped, flushing observability components...")
 }
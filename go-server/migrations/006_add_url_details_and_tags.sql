@@ -0,0 +1,21 @@
+-- Free-form title and description for organizing links
+ALTER TABLE urls ADD COLUMN title TEXT;
+ALTER TABLE urls ADD COLUMN description TEXT;
+
+-- Tags are owned by a user and attached to links through url_tags
+CREATE TABLE tags (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT tags_user_name_unique UNIQUE (user_id, name)
+);
+
+CREATE TABLE url_tags (
+    url_id VARCHAR NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (url_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_url_tags_tag_id ON url_tags(tag_id);
+CREATE INDEX IF NOT EXISTS idx_urls_user_id ON urls(user_id);
@@ -0,0 +1,6 @@
+-- Page metadata fetched in the background from the destination
+ALTER TABLE urls ADD COLUMN meta_title TEXT;
+ALTER TABLE urls ADD COLUMN meta_description TEXT;
+ALTER TABLE urls ADD COLUMN meta_image_url TEXT;
+ALTER TABLE urls ADD COLUMN favicon_url TEXT;
+ALTER TABLE urls ADD COLUMN metadata_fetched_at TIMESTAMP WITH TIME ZONE;
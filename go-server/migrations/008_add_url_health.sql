@@ -0,0 +1,7 @@
+-- Result of the periodic destination health check
+ALTER TABLE urls ADD COLUMN health_status_code INTEGER;
+ALTER TABLE urls ADD COLUMN health_redirect_chain TEXT[];
+ALTER TABLE urls ADD COLUMN health_error TEXT;
+ALTER TABLE urls ADD COLUMN health_checked_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_urls_health_checked_at ON urls(health_checked_at NULLS FIRST);
@@ -0,0 +1,4 @@
+-- Per-link options to forward the visitor's query string and extra path
+-- segments to the destination on redirect
+ALTER TABLE urls ADD COLUMN forward_query BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE urls ADD COLUMN forward_path BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,19 @@
+-- Campaign parameters merged into the destination, kept apart for analytics
+ALTER TABLE urls ADD COLUMN utm_source TEXT;
+ALTER TABLE urls ADD COLUMN utm_medium TEXT;
+ALTER TABLE urls ADD COLUMN utm_campaign TEXT;
+ALTER TABLE urls ADD COLUMN utm_term TEXT;
+ALTER TABLE urls ADD COLUMN utm_content TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_urls_utm_campaign ON urls(utm_campaign) WHERE utm_campaign IS NOT NULL;
+
+-- Default UTM values applied to every link an account creates
+CREATE TABLE utm_templates (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    utm_source TEXT,
+    utm_medium TEXT,
+    utm_campaign TEXT,
+    utm_term TEXT,
+    utm_content TEXT,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
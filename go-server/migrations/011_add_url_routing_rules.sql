@@ -0,0 +1,11 @@
+-- Ordered routing rules evaluated on the visitor's User-Agent. An empty
+-- condition matches anything; the link's url is the fallback destination.
+CREATE TABLE url_routing_rules (
+    url_id VARCHAR NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    os TEXT,
+    device TEXT,
+    browser TEXT,
+    destination TEXT NOT NULL,
+    PRIMARY KEY (url_id, position)
+);
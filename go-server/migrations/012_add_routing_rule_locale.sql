@@ -0,0 +1,3 @@
+-- Accept-Language and client country conditions for routing rules
+ALTER TABLE url_routing_rules ADD COLUMN language TEXT;
+ALTER TABLE url_routing_rules ADD COLUMN country CHAR(2);
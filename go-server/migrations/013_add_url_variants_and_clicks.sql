@@ -0,0 +1,22 @@
+-- Weighted A/B destinations. When a link has variants, one is picked per
+-- visit instead of the link's own url.
+ALTER TABLE urls ADD COLUMN sticky_variants BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE url_variants (
+    url_id VARCHAR NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    destination TEXT NOT NULL,
+    weight INT NOT NULL CHECK (weight >= 0),
+    PRIMARY KEY (url_id, position)
+);
+
+-- One row per resolved visit. variant is the url_variants position that
+-- served it, NULL when the link has no variants or a routing rule matched.
+CREATE TABLE clicks (
+    id BIGSERIAL PRIMARY KEY,
+    url_id VARCHAR NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    variant INT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_clicks_url_id_created_at ON clicks(url_id, created_at);
@@ -0,0 +1,11 @@
+-- Links with a starts_at in the future do not resolve yet
+ALTER TABLE urls ADD COLUMN starts_at TIMESTAMP WITH TIME ZONE;
+
+-- Future destination changes. The latest entry whose effective_at has passed
+-- replaces the link's url at resolve time.
+CREATE TABLE url_schedule (
+    url_id VARCHAR NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    effective_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    destination TEXT NOT NULL,
+    PRIMARY KEY (url_id, effective_at)
+);
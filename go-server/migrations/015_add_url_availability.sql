@@ -0,0 +1,19 @@
+-- Link lifetime limits. Expired and exhausted links answer 410, disabled
+-- links 404 and links blocked for legal reasons 451.
+ALTER TABLE urls ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE urls ADD COLUMN max_clicks BIGINT CHECK (max_clicks > 0);
+ALTER TABLE urls ADD COLUMN click_count BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE urls ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT FALSE;
+-- 'owner' when switched off by the owner, 'legal' when blocked for legal reasons
+ALTER TABLE urls ADD COLUMN disabled_reason TEXT;
+
+-- What visitors see instead of an unavailable link: a redirect URL and/or a
+-- branded page, see model.Fallback
+ALTER TABLE urls ADD COLUMN fallback JSONB;
+
+-- Account-wide fallback used by links without their own
+CREATE TABLE fallback_defaults (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    fallback JSONB NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,26 @@
+-- Custom hostnames links can be served from. Several accounts may claim the
+-- same hostname but only one can prove control of it through DNS.
+CREATE TABLE domains (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    hostname TEXT NOT NULL,
+    verification_token TEXT NOT NULL,
+    verified_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT domains_user_hostname_unique UNIQUE (user_id, hostname)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_domains_verified_hostname ON domains(hostname) WHERE verified_at IS NOT NULL;
+
+-- urls.id stays the internal key other tables reference. The code visitors
+-- type is now unique per domain, NULL being the shared domain, so the same
+-- code can exist on several domains. Shared links keep code = id.
+ALTER TABLE urls ADD COLUMN domain_id UUID REFERENCES domains(id) ON DELETE RESTRICT;
+ALTER TABLE urls ADD COLUMN code VARCHAR;
+UPDATE urls SET code = id;
+ALTER TABLE urls ALTER COLUMN code SET NOT NULL;
+ALTER TABLE urls ADD CONSTRAINT urls_domain_code_unique UNIQUE NULLS NOT DISTINCT (domain_id, code);
+
+-- Destinations are no longer de-duplicated globally; the per-domain index
+-- replacing this constraint is created in 032_add_url_owner_dedup.sql
+ALTER TABLE urls DROP CONSTRAINT urls_original_url_unique;
@@ -0,0 +1,4 @@
+-- Links created anonymously can be claimed once with the token returned on
+-- creation. Only the SHA-256 of the token's random ID is stored and it is
+-- cleared when the link is claimed.
+ALTER TABLE urls ADD COLUMN claim_token_hash TEXT;
@@ -0,0 +1,25 @@
+-- Link ownership transfers. Rows are kept after they are answered so they
+-- record who handed which links to whom.
+CREATE TABLE link_transfers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    from_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    to_email TEXT NOT NULL,
+    to_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined', 'cancelled')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    responded_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_transfers_from_user_id ON link_transfers(from_user_id);
+CREATE INDEX IF NOT EXISTS idx_link_transfers_to_email ON link_transfers(lower(to_email));
+
+-- from_workspace_id is where the link was when offered. Links moved
+-- elsewhere in the meantime are left alone on acceptance.
+CREATE TABLE link_transfer_items (
+    transfer_id UUID NOT NULL REFERENCES link_transfers(id) ON DELETE CASCADE,
+    url_id VARCHAR NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    from_workspace_id UUID REFERENCES workspaces(id) ON DELETE SET NULL,
+    transferred BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (transfer_id, url_id)
+);
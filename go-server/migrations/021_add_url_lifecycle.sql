@@ -0,0 +1,8 @@
+-- Archived and deleted links stop resolving (410) but can be restored by
+-- their owner until the purge job removes them for good after the
+-- retention window.
+ALTER TABLE urls ADD COLUMN archived_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE urls ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_urls_archived_at ON urls(archived_at) WHERE archived_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_urls_deleted_at ON urls(deleted_at) WHERE deleted_at IS NOT NULL;
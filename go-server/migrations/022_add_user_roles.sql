@@ -0,0 +1,9 @@
+-- Site-wide roles, separate from workspace roles. Promote the first admin
+-- by hand: UPDATE users SET role = 'admin' WHERE email = '...';
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin'));
+
+-- Disabled accounts cannot sign in or use the API
+ALTER TABLE users ADD COLUMN disabled_at TIMESTAMP WITH TIME ZONE;
+
+-- urls.disabled_reason gains 'admin' for links taken down by an admin,
+-- which their owner cannot switch back on
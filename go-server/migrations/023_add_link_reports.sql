@@ -0,0 +1,21 @@
+-- Abuse reports filed by visitors, reviewed by site admins. Taking a
+-- reported link down disables it with disabled_reason 'abuse'.
+CREATE TABLE link_reports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    url_id VARCHAR NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL CHECK (reason IN ('phishing', 'malware', 'spam', 'illegal', 'other')),
+    description TEXT,
+    reporter_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    reporter_ip INET,
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'dismissed', 'taken_down')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_reports_status ON link_reports(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_link_reports_url_id ON link_reports(url_id);
+
+-- One open report per link and address, repeats are ignored
+CREATE UNIQUE INDEX IF NOT EXISTS idx_link_reports_open_reporter ON link_reports(url_id, reporter_ip)
+    WHERE status = 'open';
@@ -0,0 +1,10 @@
+-- Transactional outbox. Link changes append their domain events here in the
+-- same transaction, and the relay publishes them in id order to the
+-- configured broker, deleting each row once the broker acknowledged it.
+CREATE TABLE outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    aggregate_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,12 @@
+-- Bumped to revoke every session of an account, e.g. on a password reset.
+-- Session tokens carry the version they were issued for.
+ALTER TABLE users ADD COLUMN session_version INTEGER NOT NULL DEFAULT 0;
+
+-- Pending password resets, one per account. Requesting a new link replaces
+-- the previous one. Only the SHA-256 of the token is stored.
+CREATE TABLE password_resets (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,19 @@
+-- TOTP two-factor authentication, at most one authenticator per account.
+-- The secret is stored on enrolment and only takes effect once a code
+-- confirmed it (enabled_at). last_step is the time step of the last code
+-- accepted, so a code cannot be replayed.
+CREATE TABLE user_mfa (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    enabled_at TIMESTAMP WITH TIME ZONE,
+    last_step BIGINT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Single-use recovery codes for accounts that lost their authenticator.
+-- Only the SHA-256 of each code is stored.
+CREATE TABLE mfa_recovery_codes (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    PRIMARY KEY (user_id, code_hash)
+);
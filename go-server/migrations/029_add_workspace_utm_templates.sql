@@ -0,0 +1,14 @@
+-- UTM templates belong to workspaces, so every member's new links share
+-- them. Account templates move to the account's personal workspace.
+ALTER TABLE utm_templates ADD COLUMN workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;
+
+UPDATE utm_templates t SET workspace_id = w.id
+FROM workspaces w
+WHERE w.personal AND w.created_by = t.user_id;
+
+DELETE FROM utm_templates WHERE workspace_id IS NULL;
+
+ALTER TABLE utm_templates DROP CONSTRAINT utm_templates_pkey;
+ALTER TABLE utm_templates DROP COLUMN user_id;
+ALTER TABLE utm_templates ALTER COLUMN workspace_id SET NOT NULL;
+ALTER TABLE utm_templates ADD PRIMARY KEY (workspace_id);
@@ -0,0 +1,8 @@
+-- The destination of the variant that served a click. Positions are reused
+-- when the variants of a link are replaced, so stats match on both.
+ALTER TABLE clicks ADD COLUMN variant_url TEXT;
+
+-- Earlier clicks are attributed to the variants they match today
+UPDATE clicks c SET variant_url = v.destination
+FROM url_variants v
+WHERE v.url_id = c.url_id AND v.position = c.variant;
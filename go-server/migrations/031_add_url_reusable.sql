@@ -0,0 +1,8 @@
+-- Only links created without per-link options are handed out again for
+-- the same destination; options one creator chose must not bind another
+ALTER TABLE urls ADD COLUMN reusable BOOLEAN NOT NULL DEFAULT TRUE;
+
+UPDATE urls SET reusable = FALSE
+WHERE title IS NOT NULL OR description IS NOT NULL OR forward_query OR forward_path
+    OR starts_at IS NOT NULL OR expires_at IS NOT NULL OR max_clicks IS NOT NULL OR code <> id
+    OR EXISTS (SELECT 1 FROM url_tags t WHERE t.url_id = urls.id);
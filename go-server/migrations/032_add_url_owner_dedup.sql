@@ -0,0 +1,8 @@
+-- A destination is de-duplicated per domain, among the links of the same
+-- owner: the link's workspace, or its creator for links outside any
+-- workspace. Only live, reusable links take part, so shortening the
+-- destination of an archived link or one created with per-link options
+-- creates a new one. Codes stay taken until the link is purged.
+CREATE UNIQUE INDEX urls_domain_original_url_unique
+    ON urls(domain_id, original_url, (COALESCE(workspace_id, user_id))) NULLS NOT DISTINCT
+    WHERE archived_at IS NULL AND deleted_at IS NULL AND reusable;
@@ -0,0 +1,14 @@
+-- Fallback defaults belong to workspaces, so every link of a workspace
+-- shares one. Account defaults move to the account's personal workspace.
+ALTER TABLE fallback_defaults ADD COLUMN workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;
+
+UPDATE fallback_defaults f SET workspace_id = w.id
+FROM workspaces w
+WHERE w.personal AND w.created_by = f.user_id;
+
+DELETE FROM fallback_defaults WHERE workspace_id IS NULL;
+
+ALTER TABLE fallback_defaults DROP CONSTRAINT fallback_defaults_pkey;
+ALTER TABLE fallback_defaults DROP COLUMN user_id;
+ALTER TABLE fallback_defaults ALTER COLUMN workspace_id SET NOT NULL;
+ALTER TABLE fallback_defaults ADD PRIMARY KEY (workspace_id);
@@ -0,0 +1,15 @@
+-- Purging a link keeps the abuse reports filed against it and the record of
+-- the transfers it was part of; they only lose the reference to the link
+ALTER TABLE link_reports ALTER COLUMN url_id DROP NOT NULL;
+ALTER TABLE link_reports DROP CONSTRAINT link_reports_url_id_fkey;
+ALTER TABLE link_reports ADD CONSTRAINT link_reports_url_id_fkey
+    FOREIGN KEY (url_id) REFERENCES urls(id) ON DELETE SET NULL;
+
+ALTER TABLE link_transfer_items DROP CONSTRAINT link_transfer_items_pkey;
+ALTER TABLE link_transfer_items ALTER COLUMN url_id DROP NOT NULL;
+ALTER TABLE link_transfer_items DROP CONSTRAINT link_transfer_items_url_id_fkey;
+ALTER TABLE link_transfer_items ADD CONSTRAINT link_transfer_items_url_id_fkey
+    FOREIGN KEY (url_id) REFERENCES urls(id) ON DELETE SET NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS link_transfer_items_transfer_url_unique
+    ON link_transfer_items(transfer_id, url_id);
+CREATE INDEX IF NOT EXISTS idx_link_transfer_items_transfer_id ON link_transfer_items(transfer_id);